@@ -0,0 +1,152 @@
+// Command backfill stores the funding rates the exchanges settled before the
+// monitor started logging, read from their funding history APIs into the
+// configured log storage. A settlement stored again is not duplicated, so an
+// interrupted backfill can simply be run again.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"fundingmonitor/internal/domain"
+	"fundingmonitor/internal/infrastructure"
+	"fundingmonitor/internal/usecase"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run backfills the requested range and returns the exit code, failing when
+// any market failed
+func run() int {
+	var symbols, exchanges, from, to string
+	flag.StringVar(&symbols, "symbols", "", "comma separated venue or canonical symbols to backfill, e.g. BTCUSDT,ETHUSDT")
+	flag.StringVar(&exchanges, "exchanges", "", "comma separated exchanges to read (default every exchange serving funding history)")
+	flag.StringVar(&from, "from", "", "start of the range, YYYY-MM-DD or RFC 3339 (default 30 days before -to)")
+	flag.StringVar(&to, "to", "", "end of the range, excluded, YYYY-MM-DD or RFC 3339 (default now)")
+	flag.Parse()
+
+	request := domain.BackfillRequest{
+		Symbols:   splitList(symbols, strings.TrimSpace),
+		Exchanges: splitList(exchanges, strings.ToLower),
+	}
+	var err error
+	if request.From, err = parseDate(from); err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	if request.To, err = parseDate(to); err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	config, err := infrastructure.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	logDir := config.LogDirectory
+	if logDir == "" {
+		logDir = "funding_logs"
+	}
+
+	factory := infrastructure.NewExchangeFactory(logger)
+	exchangeRepos, err := factory.CreateExchanges(config)
+	if err != nil {
+		log.Fatalf("Failed to initialize exchanges: %v", err)
+	}
+	logRepo, err := factory.CreateLogRepository(config, logDir, logger)
+	if err != nil {
+		log.Fatalf("Failed to initialize log repository: %v", err)
+	}
+	if closer, ok := logRepo.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Symbols are resolved among the markets the exchanges list right now
+	fundingUseCase := factory.CreateUseCases(exchangeRepos, logRepo)
+	fundingUseCase.SetSymbolNormalizer(factory.CreateSymbolMapper(config))
+	fundingUseCase.SetTimeouts(infrastructure.ExchangeTimeouts(config))
+	backfillUseCase := usecase.NewBackfillUseCase(exchangeRepos, fundingUseCase, logRepo, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	progress, err := backfillUseCase.Backfill(ctx, request, newProgressPrinter())
+	if err != nil && progress.StartedAt.IsZero() {
+		log.Printf("Failed to start backfill: %v", err)
+		return 1
+	}
+
+	completed, total := progress.Windows()
+	log.Printf("Backfill %s: %d settlements stored, %d of %d windows in %v",
+		progress.Status, progress.Settlements, completed, total, progress.FinishedAt.Sub(progress.StartedAt).Round(time.Second))
+	if progress.Status != domain.BackfillStatusDone {
+		return 1
+	}
+	return 0
+}
+
+// newProgressPrinter returns a progress observer printing the plan and then
+// every task as it moves
+func newProgressPrinter() func(domain.BackfillProgress) {
+	planned := false
+	reported := make(map[int]domain.BackfillTask)
+	return func(progress domain.BackfillProgress) {
+		if !planned {
+			planned = true
+			log.Printf("Backfilling %d markets on %s from %s to %s",
+				len(progress.Tasks), strings.Join(progress.Request.Exchanges, ", "),
+				progress.Request.From.Format(time.RFC3339), progress.Request.To.Format(time.RFC3339))
+			if len(progress.Unmatched) > 0 {
+				log.Printf("No exchange lists %s", strings.Join(progress.Unmatched, ", "))
+			}
+		}
+
+		for i, task := range progress.Tasks {
+			if task == reported[i] {
+				continue
+			}
+			reported[i] = task
+			switch {
+			case task.Error != "":
+				log.Printf("%s %s: failed after %d of %d windows: %s", task.Exchange, task.Symbol, task.Completed, task.Windows, task.Error)
+			case task.Completed > 0:
+				log.Printf("%s %s: %d of %d windows, %d settlements stored under %s", task.Exchange, task.Symbol, task.Completed, task.Windows, task.Settlements, task.LogSymbol)
+			}
+		}
+	}
+}
+
+// parseDate parses a YYYY-MM-DD date in UTC or an RFC 3339 time, zero when empty
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return day, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// splitList splits a comma separated flag, normalizing each item and
+// dropping empty ones
+func splitList(value string, normalize func(string) string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = normalize(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
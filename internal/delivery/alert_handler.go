@@ -0,0 +1,75 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// defaultAlertLimit caps the response when no limit is requested
+const defaultAlertLimit = 100
+
+type AlertHandler struct {
+	alertUseCase domain.AlertUseCaseInterface
+}
+
+func NewAlertHandler(alertUseCase domain.AlertUseCaseInterface) *AlertHandler {
+	return &AlertHandler{
+		alertUseCase: alertUseCase,
+	}
+}
+
+// GetAlerts lists fired alerts, newest first.
+// Query parameters: rule, symbol (canonical, e.g. BTCUSDT), exchange,
+// margin_type (linear or inverse), since (unix seconds or RFC3339) and limit.
+func (h *AlertHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	filter := domain.AlertFilter{
+		Rule:     strings.TrimSpace(query.Get("rule")),
+		Symbol:   strings.ToUpper(strings.TrimSpace(query.Get("symbol"))),
+		Exchange: strings.ToLower(strings.TrimSpace(query.Get("exchange"))),
+		Limit:    defaultAlertLimit,
+	}
+
+	marginType, ok := parseMarginTypeParam(w, r)
+	if !ok {
+		return
+	}
+	filter.MarginType = marginType
+
+	if since := query.Get("since"); since != "" {
+		value, err := parseTimeParam(since)
+		if err != nil {
+			http.Error(w, "Invalid since value. Use unix seconds or RFC3339", http.StatusBadRequest)
+			return
+		}
+		filter.Since = value
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = value
+	}
+
+	alerts := h.alertUseCase.GetAlerts(filter)
+
+	response := map[string]interface{}{
+		"timestamp": time.Now().Unix(),
+		"count":     len(alerts),
+		"alerts":    alerts,
+		"rules":     h.alertUseCase.GetAlertRules(),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,107 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// MockAlertUseCase records the filter it was called with
+type MockAlertUseCase struct {
+	alerts []domain.Alert
+	rules  []domain.AlertRule
+	filter domain.AlertFilter
+}
+
+func (m *MockAlertUseCase) GetAlerts(filter domain.AlertFilter) []domain.Alert {
+	m.filter = filter
+	return m.alerts
+}
+
+func (m *MockAlertUseCase) GetAlertRules() []domain.AlertRule {
+	return m.rules
+}
+
+func TestAlertHandler_GetAlerts(t *testing.T) {
+	mockUseCase := &MockAlertUseCase{
+		alerts: []domain.Alert{
+			{ID: 2, Rule: "extreme", Type: domain.AlertThreshold, Symbol: "BTCUSDT", Exchanges: []string{"binance"}, Value: 0.005},
+		},
+		rules: []domain.AlertRule{{Name: "extreme", Type: domain.AlertThreshold, Threshold: 0.004}},
+	}
+	handler := NewAlertHandler(mockUseCase)
+
+	req, _ := http.NewRequest("GET", "/api/alerts?rule=extreme&symbol=btcusdt&exchange=Binance&since=1704067200&limit=5", nil)
+	rr := httptest.NewRecorder()
+	handler.GetAlerts(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	filter := mockUseCase.filter
+	if filter.Rule != "extreme" || filter.Symbol != "BTCUSDT" || filter.Exchange != "binance" {
+		t.Errorf("Expected rule extreme, symbol BTCUSDT and exchange binance, got %+v", filter)
+	}
+	if !filter.Since.Equal(time.Unix(1704067200, 0)) || filter.Limit != 5 {
+		t.Errorf("Expected since 1704067200 and limit 5, got %v and %d", filter.Since, filter.Limit)
+	}
+
+	var response struct {
+		Count  int                `json:"count"`
+		Alerts []domain.Alert     `json:"alerts"`
+		Rules  []domain.AlertRule `json:"rules"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Count != 1 || response.Alerts[0].ID != 2 || len(response.Rules) != 1 {
+		t.Errorf("Expected one alert and one rule, got %+v", response)
+	}
+}
+
+func TestAlertHandler_GetAlertsDefaults(t *testing.T) {
+	mockUseCase := &MockAlertUseCase{}
+	handler := NewAlertHandler(mockUseCase)
+
+	req, _ := http.NewRequest("GET", "/api/alerts", nil)
+	rr := httptest.NewRecorder()
+	handler.GetAlerts(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if mockUseCase.filter.Limit != defaultAlertLimit || !mockUseCase.filter.Since.IsZero() {
+		t.Errorf("Expected the default limit and no since, got %+v", mockUseCase.filter)
+	}
+}
+
+func TestAlertHandler_GetAlertsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"since", "since=yesterday"},
+		{"limit", "limit=-1"},
+		{"limit not a number", "limit=ten"},
+		{"margin type", "margin_type=cross"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAlertHandler(&MockAlertUseCase{})
+
+			req, _ := http.NewRequest("GET", "/api/alerts?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.GetAlerts(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// defaultArbitrageLimit caps the response when no limit is requested
+const defaultArbitrageLimit = 50
+
+type ArbitrageHandler struct {
+	arbitrageUseCase domain.ArbitrageUseCaseInterface
+}
+
+func NewArbitrageHandler(arbitrageUseCase domain.ArbitrageUseCaseInterface) *ArbitrageHandler {
+	return &ArbitrageHandler{
+		arbitrageUseCase: arbitrageUseCase,
+	}
+}
+
+// GetArbitrage lists cross-exchange funding spreads.
+// Query parameters: min_spread (0.0005 or 0.05%), exchanges (comma separated),
+// symbol (canonical, e.g. BTCUSDT), margin_type (linear or inverse) and limit.
+func (h *ArbitrageHandler) GetArbitrage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	filter := domain.ArbitrageFilter{
+		Symbol: strings.ToUpper(strings.TrimSpace(query.Get("symbol"))),
+		Limit:  defaultArbitrageLimit,
+	}
+
+	if minSpread := query.Get("min_spread"); minSpread != "" {
+		value, err := parseRateParam(minSpread)
+		if err != nil || value < 0 {
+			http.Error(w, "Invalid min_spread value. Use a decimal (e.g., 0.0005) or a percentage (e.g., 0.05%)", http.StatusBadRequest)
+			return
+		}
+		filter.MinSpread = value
+	}
+
+	if exchanges := query.Get("exchanges"); exchanges != "" {
+		for _, exchange := range strings.Split(exchanges, ",") {
+			if exchange = strings.ToLower(strings.TrimSpace(exchange)); exchange != "" {
+				filter.Exchanges = append(filter.Exchanges, exchange)
+			}
+		}
+	}
+
+	marginType, ok := parseMarginTypeParam(w, r)
+	if !ok {
+		return
+	}
+	filter.MarginType = marginType
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = value
+	}
+
+	opportunities, err := h.arbitrageUseCase.GetOpportunities(r.Context(), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get arbitrage opportunities: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"timestamp":     time.Now().Unix(),
+		"count":         len(opportunities),
+		"opportunities": opportunities,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseRateParam parses a rate given as a decimal (0.001) or a percentage (0.1%)
+func parseRateParam(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+		if err != nil {
+			return 0, err
+		}
+		return parsed / 100, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
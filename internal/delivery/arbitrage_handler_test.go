@@ -0,0 +1,94 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fundingmonitor/internal/domain"
+)
+
+// MockArbitrageUseCase records the filter it was called with
+type MockArbitrageUseCase struct {
+	opportunities []domain.ArbitrageOpportunity
+	err           error
+	filter        domain.ArbitrageFilter
+}
+
+func (m *MockArbitrageUseCase) GetOpportunities(ctx context.Context, filter domain.ArbitrageFilter) ([]domain.ArbitrageOpportunity, error) {
+	m.filter = filter
+	return m.opportunities, m.err
+}
+
+func TestArbitrageHandler_GetArbitrage(t *testing.T) {
+	mockUseCase := &MockArbitrageUseCase{
+		opportunities: []domain.ArbitrageOpportunity{
+			{
+				Symbol: "BTCUSDT",
+				Long:   domain.ArbitrageLeg{Exchange: "binance", FundingRate: 0.0001},
+				Short:  domain.ArbitrageLeg{Exchange: "okx", FundingRate: 0.0004},
+				Spread: 0.0003,
+			},
+		},
+	}
+	handler := NewArbitrageHandler(mockUseCase)
+
+	req, _ := http.NewRequest("GET", "/api/arbitrage?min_spread=0.02%25&exchanges=Binance,%20okx&symbol=btcusdt&margin_type=linear&limit=10", nil)
+	rr := httptest.NewRecorder()
+	handler.GetArbitrage(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	filter := mockUseCase.filter
+	if math.Abs(filter.MinSpread-0.0002) > 1e-12 {
+		t.Errorf("Expected min spread 0.0002, got %v", filter.MinSpread)
+	}
+	if len(filter.Exchanges) != 2 || filter.Exchanges[0] != "binance" || filter.Exchanges[1] != "okx" {
+		t.Errorf("Expected exchanges [binance okx], got %v", filter.Exchanges)
+	}
+	if filter.Symbol != "BTCUSDT" || filter.Limit != 10 {
+		t.Errorf("Expected symbol BTCUSDT and limit 10, got %s and %d", filter.Symbol, filter.Limit)
+	}
+	if filter.MarginType != domain.MarginTypeLinear {
+		t.Errorf("Expected margin type linear, got %q", filter.MarginType)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if opportunities, ok := response["opportunities"].([]interface{}); !ok || len(opportunities) != 1 {
+		t.Errorf("Expected 1 opportunity, got %v", response["opportunities"])
+	}
+}
+
+func TestArbitrageHandler_GetArbitrageInvalidParams(t *testing.T) {
+	handler := NewArbitrageHandler(&MockArbitrageUseCase{})
+
+	for _, query := range []string{"min_spread=abc", "min_spread=-0.1", "limit=x", "limit=-1", "margin_type=cross"} {
+		req, _ := http.NewRequest("GET", "/api/arbitrage?"+query, nil)
+		rr := httptest.NewRecorder()
+		handler.GetArbitrage(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, rr.Code)
+		}
+	}
+}
+
+func TestArbitrageHandler_GetArbitrageError(t *testing.T) {
+	handler := NewArbitrageHandler(&MockArbitrageUseCase{err: assertAnError()})
+
+	req, _ := http.NewRequest("GET", "/api/arbitrage", nil)
+	rr := httptest.NewRecorder()
+	handler.GetArbitrage(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+type BackfillHandler struct {
+	backfillUseCase domain.BackfillUseCaseInterface
+}
+
+func NewBackfillHandler(backfillUseCase domain.BackfillUseCaseInterface) *BackfillHandler {
+	return &BackfillHandler{
+		backfillUseCase: backfillUseCase,
+	}
+}
+
+// StartBackfill starts storing the settled funding history of the exchanges
+// in the logs, answering 202 with the backfill's progress.
+// Query parameters: symbols (comma separated, venue or canonical), exchanges
+// (comma separated, every exchange serving funding history by default), from
+// and to (unix seconds or RFC 3339, the last 30 days by default).
+func (h *BackfillHandler) StartBackfill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	request := domain.BackfillRequest{
+		Symbols:   splitListParam(query.Get("symbols"), strings.TrimSpace),
+		Exchanges: splitListParam(query.Get("exchanges"), strings.ToLower),
+	}
+
+	var err error
+	if request.From, err = parseTimeParam(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from value. Use unix seconds or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if request.To, err = parseTimeParam(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to value. Use unix seconds or RFC 3339", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.backfillUseCase.StartBackfill(request)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidBackfill):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrExchangeNotFound):
+			http.Error(w, "Exchange not found", http.StatusNotFound)
+		case errors.Is(err, domain.ErrHistoryUnsupported):
+			http.Error(w, fmt.Sprintf("Exchange has no funding history: %v", err), http.StatusNotImplemented)
+		case errors.Is(err, domain.ErrBackfillRunning):
+			http.Error(w, "A backfill is already running", http.StatusConflict)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to start backfill: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(backfillResponse(progress))
+}
+
+// GetBackfill reports the progress of the running backfill, or of the last one
+func (h *BackfillHandler) GetBackfill(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	progress, ok := h.backfillUseCase.GetBackfillProgress()
+	if !ok {
+		http.Error(w, "No backfill has been started", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(backfillResponse(progress))
+}
+
+func backfillResponse(progress domain.BackfillProgress) map[string]interface{} {
+	completed, total := progress.Windows()
+	return map[string]interface{}{
+		"timestamp":         time.Now().Unix(),
+		"backfill":          progress,
+		"completed_windows": completed,
+		"total_windows":     total,
+	}
+}
+
+// splitListParam splits a comma separated parameter, normalizing each item
+// and dropping empty ones
+func splitListParam(value string, normalize func(string) string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = normalize(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
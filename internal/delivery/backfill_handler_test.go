@@ -0,0 +1,129 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// MockBackfillUseCase records the request it was started with
+type MockBackfillUseCase struct {
+	progress *domain.BackfillProgress
+	err      error
+	request  domain.BackfillRequest
+}
+
+func (m *MockBackfillUseCase) StartBackfill(request domain.BackfillRequest) (domain.BackfillProgress, error) {
+	m.request = request
+	if m.err != nil {
+		return domain.BackfillProgress{}, m.err
+	}
+	return domain.BackfillProgress{Status: domain.BackfillStatusRunning, Request: request}, nil
+}
+
+func (m *MockBackfillUseCase) GetBackfillProgress() (domain.BackfillProgress, bool) {
+	if m.progress == nil {
+		return domain.BackfillProgress{}, false
+	}
+	return *m.progress, true
+}
+
+func TestBackfillHandler_StartBackfill(t *testing.T) {
+	mockUseCase := &MockBackfillUseCase{}
+	handler := NewBackfillHandler(mockUseCase)
+
+	req, _ := http.NewRequest("POST", "/api/backfill?symbols=BTCUSDT,%20BTC-USDT-SWAP,&exchanges=Binance,okx&from=1704067200&to=2024-01-31T00:00:00Z", nil)
+	rr := httptest.NewRecorder()
+	handler.StartBackfill(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+	request := mockUseCase.request
+	if len(request.Symbols) != 2 || request.Symbols[1] != "BTC-USDT-SWAP" {
+		t.Errorf("Expected symbols [BTCUSDT BTC-USDT-SWAP], got %v", request.Symbols)
+	}
+	if len(request.Exchanges) != 2 || request.Exchanges[0] != "binance" {
+		t.Errorf("Expected exchanges [binance okx], got %v", request.Exchanges)
+	}
+	if !request.From.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !request.To.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected January 2024, got %v to %v", request.From, request.To)
+	}
+
+	var response struct {
+		Backfill domain.BackfillProgress `json:"backfill"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Backfill.Status != domain.BackfillStatusRunning {
+		t.Errorf("Expected a running backfill, got %s", response.Backfill.Status)
+	}
+}
+
+func TestBackfillHandler_StartBackfillErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		expected int
+	}{
+		{"invalid from", "?symbols=BTCUSDT&from=yesterday", nil, http.StatusBadRequest},
+		{"invalid to", "?symbols=BTCUSDT&to=tomorrow", nil, http.StatusBadRequest},
+		{"invalid request", "", fmt.Errorf("%w: no symbols", domain.ErrInvalidBackfill), http.StatusBadRequest},
+		{"unknown exchange", "?symbols=BTCUSDT&exchanges=kraken", domain.ErrExchangeNotFound, http.StatusNotFound},
+		{"no history", "?symbols=BTCUSDT&exchanges=mexc", domain.ErrHistoryUnsupported, http.StatusNotImplemented},
+		{"already running", "?symbols=BTCUSDT", domain.ErrBackfillRunning, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewBackfillHandler(&MockBackfillUseCase{err: tt.err})
+
+			req, _ := http.NewRequest("POST", "/api/backfill"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.StartBackfill(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestBackfillHandler_GetBackfill(t *testing.T) {
+	mockUseCase := &MockBackfillUseCase{}
+	handler := NewBackfillHandler(mockUseCase)
+
+	req, _ := http.NewRequest("GET", "/api/backfill", nil)
+	rr := httptest.NewRecorder()
+	handler.GetBackfill(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d before any backfill, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	mockUseCase.progress = &domain.BackfillProgress{
+		Status: domain.BackfillStatusRunning,
+		Tasks: []domain.BackfillTask{
+			{Exchange: "binance", Symbol: "BTCUSDT", Windows: 5, Completed: 5},
+			{Exchange: "okx", Symbol: "BTC-USDT-SWAP", Windows: 5, Completed: 2},
+		},
+	}
+	rr = httptest.NewRecorder()
+	handler.GetBackfill(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response["completed_windows"] != float64(7) || response["total_windows"] != float64(10) {
+		t.Errorf("Expected 7 of 10 windows, got %v of %v", response["completed_windows"], response["total_windows"])
+	}
+}
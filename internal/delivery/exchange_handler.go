@@ -0,0 +1,41 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+type ExchangeHandler struct {
+	exchanges []domain.ExchangeDescriptor
+}
+
+func NewExchangeHandler(exchanges []domain.ExchangeDescriptor) *ExchangeHandler {
+	return &ExchangeHandler{
+		exchanges: exchanges,
+	}
+}
+
+// GetExchanges lists the registered exchanges, their capabilities and
+// which of them are enabled
+func (h *ExchangeHandler) GetExchanges(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	enabled := []string{}
+	for _, exchange := range h.exchanges {
+		if exchange.Enabled {
+			enabled = append(enabled, exchange.Name)
+		}
+	}
+
+	response := map[string]interface{}{
+		"timestamp": time.Now().Unix(),
+		"exchanges": h.exchanges,
+		"enabled":   enabled,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
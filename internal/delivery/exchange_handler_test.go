@@ -0,0 +1,38 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fundingmonitor/internal/domain"
+)
+
+func TestExchangeHandler_GetExchanges(t *testing.T) {
+	handler := NewExchangeHandler([]domain.ExchangeDescriptor{
+		{Name: "binance", DefaultBaseURL: "https://fapi.binance.com", RateLimit: 4, Capabilities: domain.ExchangeCapabilities{MarkPrice: true}, Enabled: true},
+		{Name: "bitget", DefaultBaseURL: "https://api.bitget.com", RateLimit: 10},
+	})
+
+	rr := httptest.NewRecorder()
+	handler.GetExchanges(rr, httptest.NewRequest("GET", "/api/exchanges", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Exchanges []domain.ExchangeDescriptor `json:"exchanges"`
+		Enabled   []string                    `json:"enabled"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Exchanges) != 2 || !response.Exchanges[0].Capabilities.MarkPrice || response.Exchanges[1].Enabled {
+		t.Errorf("Expected both registered exchanges, got %+v", response.Exchanges)
+	}
+	if len(response.Enabled) != 1 || response.Enabled[0] != "binance" {
+		t.Errorf("Expected only binance enabled, got %v", response.Enabled)
+	}
+}
@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+type HealthHandler struct {
+	healthUseCase domain.HealthUseCaseInterface
+}
+
+func NewHealthHandler(healthUseCase domain.HealthUseCaseInterface) *HealthHandler {
+	return &HealthHandler{
+		healthUseCase: healthUseCase,
+	}
+}
+
+// Health reports the health of every exchange, derived from its recent
+// polls, and the overall status. It answers 503 when the service is down.
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	report := h.healthUseCase.GetHealth()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if report.Status == domain.HealthDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	response := map[string]interface{}{
+		"status":        report.Status,
+		"timestamp":     time.Now().Unix(),
+		"exchanges":     len(report.Exchanges),
+		"exchange_info": report.Exchanges,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// Live is the liveness probe: it answers as long as the server runs
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]interface{}{
+		"status":    "ok",
+		"timestamp": time.Now().Unix(),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// Ready is the readiness probe: it answers 503 until every exchange has been
+// polled and while none of them serves data
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := "ready"
+	if !h.healthUseCase.IsReady() {
+		status = "not ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	response := map[string]interface{}{
+		"status":    status,
+		"timestamp": time.Now().Unix(),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
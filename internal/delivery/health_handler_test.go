@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fundingmonitor/internal/domain"
+)
+
+// MockHealthUseCase returns a fixed report
+type MockHealthUseCase struct {
+	report domain.HealthReport
+	ready  bool
+}
+
+func (m *MockHealthUseCase) GetHealth() domain.HealthReport {
+	return m.report
+}
+
+func (m *MockHealthUseCase) IsReady() bool {
+	return m.ready
+}
+
+func TestHealthHandler_Health(t *testing.T) {
+	tests := []struct {
+		status     string
+		statusCode int
+	}{
+		{domain.HealthHealthy, http.StatusOK},
+		{domain.HealthDegraded, http.StatusOK},
+		{domain.HealthDown, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			handler := NewHealthHandler(&MockHealthUseCase{report: domain.HealthReport{
+				Status: tt.status,
+				Exchanges: map[string]domain.ExchangeHealth{
+					"binance": {Name: "binance", Status: tt.status, ConsecutiveFailures: 2, LastError: "boom"},
+				},
+			}})
+
+			rr := httptest.NewRecorder()
+			handler.Health(rr, httptest.NewRequest("GET", "/api/health", nil))
+
+			if rr.Code != tt.statusCode {
+				t.Errorf("Expected status %d, got %d", tt.statusCode, rr.Code)
+			}
+
+			var response struct {
+				Status       string                           `json:"status"`
+				Exchanges    int                              `json:"exchanges"`
+				ExchangeInfo map[string]domain.ExchangeHealth `json:"exchange_info"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Status != tt.status || response.Exchanges != 1 {
+				t.Errorf("Expected status %s for 1 exchange, got %+v", tt.status, response)
+			}
+			if binance := response.ExchangeInfo["binance"]; binance.ConsecutiveFailures != 2 || binance.LastError != "boom" {
+				t.Errorf("Expected the binance poll record, got %+v", binance)
+			}
+		})
+	}
+}
+
+func TestHealthHandler_Probes(t *testing.T) {
+	useCase := &MockHealthUseCase{}
+	handler := NewHealthHandler(useCase)
+
+	rr := httptest.NewRecorder()
+	handler.Live(rr, httptest.NewRequest("GET", "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected liveness status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected readiness status %d before the first polls, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+
+	useCase.ready = true
+	rr = httptest.NewRecorder()
+	handler.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected readiness status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
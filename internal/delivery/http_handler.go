@@ -2,8 +2,10 @@ package delivery
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -27,7 +29,16 @@ func (h *FundingHandler) GetFundingRates(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	rates, err := h.multiExchangeUseCase.GetAllFundingRates()
+	marginType, ok := parseMarginTypeParam(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.refreshIfRequested(w, r) {
+		return
+	}
+
+	snapshot, err := h.multiExchangeUseCase.GetFundingSnapshot(r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get funding rates: %v", err), http.StatusInternalServerError)
 		return
@@ -35,7 +46,8 @@ func (h *FundingHandler) GetFundingRates(w http.ResponseWriter, r *http.Request)
 
 	response := map[string]interface{}{
 		"timestamp": time.Now().Unix(),
-		"rates":     rates,
+		"rates":     domain.FilterByMarginType(snapshot.Rates, marginType),
+		"exchanges": snapshot.Exchanges,
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -80,25 +92,37 @@ func (h *FundingHandler) GetFundingRatesTop(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	rates, err := h.multiExchangeUseCase.GetAllFundingRates()
+	// The threshold applies to the raw rate by default, or to a normalized one
+	// (1h, 8h, apr) so venues with different funding cycles are comparable
+	basis := r.URL.Query().Get("basis")
+	if basis == "" {
+		basis = "raw"
+	}
+	if !domain.ValidRateBasis(basis) {
+		http.Error(w, "Invalid basis value. Use one of: raw, 1h, 8h, apr", http.StatusBadRequest)
+		return
+	}
+
+	marginType, ok := parseMarginTypeParam(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.refreshIfRequested(w, r) {
+		return
+	}
+
+	rates, err := h.multiExchangeUseCase.GetAllFundingRates(r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get top funding rates: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	topRates := make([]domain.FundingRate, 0, len(rates))
-	for _, rate := range rates {
-		absFaundingRate := rate.FundingRate
-		if absFaundingRate < 0 {
-			absFaundingRate = -absFaundingRate
-		}
-		if absFaundingRate > topRate { // Example threshold for top rates
-			topRates = append(topRates, rate)
-		}
-	}
+	topRates := domain.SelectTopFundingRates(domain.FilterByMarginType(rates, marginType), topRate, basis)
 
 	response := map[string]interface{}{
 		"timestamp": time.Now().Unix(),
+		"basis":     basis,
 		"rates":     topRates,
 	}
 
@@ -106,16 +130,58 @@ func (h *FundingHandler) GetFundingRatesTop(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 }
 
+// refreshIfRequested forces a refresh of the given exchanges (all when none)
+// when the request carries refresh=true. It reports whether the request may
+// proceed, having already replied with an error otherwise.
+func (h *FundingHandler) refreshIfRequested(w http.ResponseWriter, r *http.Request, exchangeNames ...string) bool {
+	refreshValue := r.URL.Query().Get("refresh")
+	if refreshValue == "" {
+		return true
+	}
+
+	refresh, err := strconv.ParseBool(refreshValue)
+	if err != nil {
+		http.Error(w, "Invalid refresh value. Use true or false", http.StatusBadRequest)
+		return false
+	}
+	if !refresh {
+		return true
+	}
+
+	if err := h.multiExchangeUseCase.RefreshFundingRates(r.Context(), exchangeNames...); err != nil {
+		if err == domain.ErrExchangeNotFound {
+			http.Error(w, "Exchange not found", http.StatusNotFound)
+			return false
+		}
+		http.Error(w, fmt.Sprintf("Failed to refresh funding rates: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
 func (h *FundingHandler) GetExchangeFunding(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	exchangeName := vars["exchange"]
 
-	rates, err := h.multiExchangeUseCase.GetExchangeFundingRates(exchangeName)
+	marginType, ok := parseMarginTypeParam(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.refreshIfRequested(w, r, exchangeName) {
+		return
+	}
+
+	rates, err := h.multiExchangeUseCase.GetExchangeFundingRates(r.Context(), exchangeName)
 	if err != nil {
 		if err == domain.ErrExchangeNotFound {
 			http.Error(w, "Exchange not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, domain.ErrExchangeTimeout) {
+			http.Error(w, fmt.Sprintf("Failed to get funding rates: %v", err), http.StatusGatewayTimeout)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to get funding rates: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -126,14 +192,14 @@ func (h *FundingHandler) GetExchangeFunding(w http.ResponseWriter, r *http.Reque
 	response := map[string]interface{}{
 		"exchange":  exchangeName,
 		"timestamp": time.Now().Unix(),
-		"rates":     rates,
+		"rates":     domain.FilterByMarginType(rates, marginType),
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
 func (h *FundingHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	exchangeInfo := h.multiExchangeUseCase.GetExchangeInfo()
+	exchangeInfo := h.multiExchangeUseCase.GetExchangeInfo(r.Context())
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -269,26 +335,113 @@ func (h *FundingHandler) GetAllLogs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *FundingHandler) FundingWebSocket(w http.ResponseWriter, r *http.Request) {
-	// WebSocket implementation for real-time funding rate updates
-	// This would require additional implementation
-	http.Error(w, "WebSocket not implemented yet", http.StatusNotImplemented)
-}
-
 func (h *FundingHandler) GetHistoricalFundingRates(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	symbol := vars["symbol"]
-	exchange := r.URL.Query().Get("exchange")
-	if exchange == "" {
-		http.Error(w, "Missing exchange parameter", http.StatusBadRequest)
+	query := r.URL.Query()
+
+	historyQuery := domain.HistoryQuery{Symbol: vars["symbol"]}
+
+	// exchange is the single exchange form kept for existing callers
+	for _, param := range []string{"exchange", "exchanges"} {
+		for _, exchange := range strings.Split(query.Get(param), ",") {
+			if exchange = strings.ToLower(strings.TrimSpace(exchange)); exchange != "" {
+				historyQuery.Exchanges = append(historyQuery.Exchanges, exchange)
+			}
+		}
+	}
+
+	var err error
+	if historyQuery.From, err = parseTimeParam(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from value. Use unix seconds or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if historyQuery.To, err = parseTimeParam(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to value. Use unix seconds or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if !historyQuery.From.IsZero() && !historyQuery.To.IsZero() && !historyQuery.From.Before(historyQuery.To) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
 		return
 	}
-	history, err := h.multiExchangeUseCase.GetHistoricalFundingRates(symbol, exchange)
+
+	if historyQuery.Resolution, err = domain.ParseHistoryResolution(query.Get("resolution")); err != nil {
+		http.Error(w, "Invalid resolution. Use raw, 1h, 8h or 1d", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.multiExchangeUseCase.GetHistoricalFundingRates(historyQuery)
 	if err != nil {
 		http.Error(w, "Failed to get historical funding rates", http.StatusInternalServerError)
 		return
 	}
+
+	// Points come sorted by exchange and time, group them per exchange
+	byExchange := make(map[string][]domain.FundingRateHistory)
+	for _, point := range history {
+		byExchange[point.Exchange] = append(byExchange[point.Exchange], point)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	json.NewEncoder(w).Encode(history)
+
+	// A lone exchange is the endpoint's original form, answered with the bare
+	// array its callers expect
+	if isLegacyHistoryQuery(query) {
+		json.NewEncoder(w).Encode(history)
+		return
+	}
+
+	response := map[string]interface{}{
+		"symbol":     historyQuery.Symbol,
+		"resolution": historyQuery.Resolution,
+		"timestamp":  time.Now().Unix(),
+		"history":    byExchange,
+		"count":      len(history),
+	}
+	if !historyQuery.From.IsZero() {
+		response["from"] = historyQuery.From.Unix()
+	}
+	if !historyQuery.To.IsZero() {
+		response["to"] = historyQuery.To.Unix()
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// isLegacyHistoryQuery reports whether a history query names a single
+// exchange and none of the parameters added since
+func isLegacyHistoryQuery(query url.Values) bool {
+	exchange := query.Get("exchange")
+	if exchange == "" || strings.Contains(exchange, ",") {
+		return false
+	}
+	for _, param := range []string{"exchanges", "from", "to", "resolution"} {
+		if query.Get(param) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMarginTypeParam reads the margin_type filter (linear or inverse, any
+// when absent). It reports whether the request may proceed, having already
+// replied with an error otherwise.
+func parseMarginTypeParam(w http.ResponseWriter, r *http.Request) (domain.MarginType, bool) {
+	marginType, err := domain.ParseMarginType(r.URL.Query().Get("margin_type"))
+	if err != nil {
+		http.Error(w, "Invalid margin_type value. Use linear or inverse", http.StatusBadRequest)
+		return "", false
+	}
+	return marginType, true
+}
+
+// parseTimeParam parses unix seconds or an RFC 3339 time, empty meaning unbounded
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
@@ -1,10 +1,12 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,18 +17,30 @@ import (
 
 // MockMultiExchangeUseCase for testing
 type MockMultiExchangeUseCase struct {
-	rates        []domain.FundingRate
-	ratesErr     error
-	exchangeInfo map[string]domain.ExchangeInfo
-	logFiles     []domain.LogFile
-	logErr       error
+	rates          []domain.FundingRate
+	ratesErr       error
+	exchangeInfo   map[string]domain.ExchangeInfo
+	exchangeStatus map[string]domain.ExchangeStatus
+	logFiles       []domain.LogFile
+	logErr         error
+	refreshed      [][]string
+	history        []domain.FundingRateHistory
+	historyQuery   domain.HistoryQuery
 }
 
-func (m *MockMultiExchangeUseCase) GetAllFundingRates() ([]domain.FundingRate, error) {
+func (m *MockMultiExchangeUseCase) GetAllFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
 	return m.rates, m.ratesErr
 }
 
-func (m *MockMultiExchangeUseCase) GetExchangeFundingRates(exchangeName string) ([]domain.FundingRate, error) {
+func (m *MockMultiExchangeUseCase) GetFundingSnapshot(ctx context.Context) (domain.FundingSnapshot, error) {
+	return domain.FundingSnapshot{
+		Timestamp: time.Now(),
+		Rates:     m.rates,
+		Exchanges: m.exchangeStatus,
+	}, m.ratesErr
+}
+
+func (m *MockMultiExchangeUseCase) GetExchangeFundingRates(ctx context.Context, exchangeName string) ([]domain.FundingRate, error) {
 	if exchangeName == "nonexistent" {
 		return nil, domain.ErrExchangeNotFound
 	}
@@ -36,11 +50,21 @@ func (m *MockMultiExchangeUseCase) GetExchangeFundingRates(exchangeName string)
 	return m.rates, nil
 }
 
-func (m *MockMultiExchangeUseCase) GetExchangeInfo() map[string]domain.ExchangeInfo {
+func (m *MockMultiExchangeUseCase) RefreshFundingRates(ctx context.Context, exchangeNames ...string) error {
+	for _, name := range exchangeNames {
+		if name == "nonexistent" {
+			return domain.ErrExchangeNotFound
+		}
+	}
+	m.refreshed = append(m.refreshed, exchangeNames)
+	return nil
+}
+
+func (m *MockMultiExchangeUseCase) GetExchangeInfo(ctx context.Context) map[string]domain.ExchangeInfo {
 	return m.exchangeInfo
 }
 
-func (m *MockMultiExchangeUseCase) LogAllFundingRates() error {
+func (m *MockMultiExchangeUseCase) LogAllFundingRates(ctx context.Context) error {
 	return m.logErr
 }
 
@@ -52,8 +76,9 @@ func (m *MockMultiExchangeUseCase) GetAllLogs() ([]domain.LogFile, error) {
 	return m.logFiles, m.logErr
 }
 
-func (m *MockMultiExchangeUseCase) GetHistoricalFundingRates(symbol string, exchange string) ([]domain.FundingRateHistory, error) {
-	return []domain.FundingRateHistory{}, m.logErr
+func (m *MockMultiExchangeUseCase) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
+	m.historyQuery = query
+	return m.history, m.logErr
 }
 
 func TestFundingHandler_GetFundingRates(t *testing.T) {
@@ -62,6 +87,11 @@ func TestFundingHandler_GetFundingRates(t *testing.T) {
 			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: time.Now()},
 			{Symbol: "ETHUSDT", Exchange: "bybit", FundingRate: 0.0002, Timestamp: time.Now()},
 		},
+		exchangeStatus: map[string]domain.ExchangeStatus{
+			"binance": {Status: domain.ExchangeStatusOK, Rows: 1},
+			"bybit":   {Status: domain.ExchangeStatusOK, Rows: 1},
+			"okx":     {Status: domain.ExchangeStatusTimeout, Error: "context deadline exceeded"},
+		},
 	}
 
 	handler := NewFundingHandler(mockUseCase)
@@ -86,6 +116,71 @@ func TestFundingHandler_GetFundingRates(t *testing.T) {
 	if rates, ok := response["rates"].([]interface{}); !ok || len(rates) != 2 {
 		t.Errorf("Expected 2 rates, got %d", len(rates))
 	}
+
+	exchanges, ok := response["exchanges"].(map[string]interface{})
+	if !ok || len(exchanges) != 3 {
+		t.Fatalf("Expected 3 exchange statuses, got %v", response["exchanges"])
+	}
+	if okx := exchanges["okx"].(map[string]interface{}); okx["status"] != domain.ExchangeStatusTimeout {
+		t.Errorf("Expected okx status timeout, got %v", okx["status"])
+	}
+}
+
+func TestFundingHandler_GetFundingRatesMarginType(t *testing.T) {
+	mockUseCase := &MockMultiExchangeUseCase{
+		rates: []domain.FundingRate{
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, MarginType: domain.MarginTypeLinear},
+			{Symbol: "BTCUSD_PERP", Exchange: "binance", FundingRate: 0.0002, MarginType: domain.MarginTypeInverse},
+			{Symbol: "BTC-PERPETUAL", Exchange: "deribit", FundingRate: 0.0003, MarginType: domain.MarginTypeInverse},
+		},
+	}
+	handler := NewFundingHandler(mockUseCase)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/funding", handler.GetFundingRates)
+	router.HandleFunc("/api/funding/top", handler.GetFundingRatesTop)
+	router.HandleFunc("/api/funding/{exchange}", handler.GetExchangeFunding)
+
+	tests := []struct {
+		url      string
+		key      string
+		expected int
+	}{
+		{"/api/funding", "rates", 3},
+		{"/api/funding?margin_type=inverse", "rates", 2},
+		{"/api/funding?margin_type=LINEAR", "rates", 1},
+		{"/api/funding/top?top=0.0001&margin_type=inverse", "rates", 2},
+		{"/api/funding/deribit?margin_type=linear", "rates", 1},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusOK, tt.url, rr.Code)
+			continue
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if rates, ok := response[tt.key].([]interface{}); !ok || len(rates) != tt.expected {
+			t.Errorf("Expected %d %s for %s, got %v", tt.expected, tt.key, tt.url, response[tt.key])
+		}
+	}
+
+	for _, url := range []string{"/api/funding?margin_type=cross", "/api/funding/top?margin_type=cross", "/api/funding/binance?margin_type=cross"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, rr.Code)
+		}
+	}
 }
 
 func TestFundingHandler_HealthCheck(t *testing.T) {
@@ -125,9 +220,11 @@ func TestFundingHandler_HealthCheck(t *testing.T) {
 }
 
 func TestFundingHandler_GetExchangeFunding(t *testing.T) {
+	settledAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	mockUseCase := &MockMultiExchangeUseCase{
 		rates: []domain.FundingRate{
-			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: time.Now()},
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: time.Now(), LastFundingRate: 0.0002, LastFundingTime: &settledAt},
+			{Symbol: "ETHUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: time.Now()},
 		},
 	}
 
@@ -150,6 +247,11 @@ func TestFundingHandler_GetExchangeFunding(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
 	}
 
+	// The last settlement time is left out until one is known
+	if body := rr.Body.String(); strings.Count(body, `"last_funding_time"`) != 1 || !strings.Contains(body, `"last_funding_time":"2024-01-01T00:00:00Z"`) {
+		t.Errorf("Expected only the BTCUSDT settlement time, got %s", body)
+	}
+
 	// Test non-existing exchange
 	req, err = http.NewRequest("GET", "/api/funding/nonexistent", nil)
 	if err != nil {
@@ -168,6 +270,27 @@ func TestFundingHandler_GetExchangeFunding(t *testing.T) {
 	}
 }
 
+func TestFundingHandler_GetExchangeFundingTimeout(t *testing.T) {
+	mockUseCase := &MockMultiExchangeUseCase{
+		ratesErr: fmt.Errorf("deribit: %w", domain.ErrExchangeTimeout),
+	}
+
+	handler := NewFundingHandler(mockUseCase)
+
+	req, err := http.NewRequest("GET", "/api/funding/deribit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"exchange": "deribit"})
+
+	rr := httptest.NewRecorder()
+	handler.GetExchangeFunding(rr, req)
+
+	if status := rr.Code; status != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusGatewayTimeout, status)
+	}
+}
+
 func TestFundingHandler_GetFundingRatesTop(t *testing.T) {
 	mockUseCase := &MockMultiExchangeUseCase{
 		rates: []domain.FundingRate{
@@ -251,6 +374,53 @@ func TestFundingHandler_GetFundingRatesTop(t *testing.T) {
 	}
 }
 
+func TestFundingHandler_GetFundingRatesTopBasis(t *testing.T) {
+	mockUseCase := &MockMultiExchangeUseCase{
+		rates: []domain.FundingRate{
+			// 0.1% per hour is 0.8% per 8h
+			{Symbol: "ORDIUSDT", Exchange: "bybit", FundingRate: 0.001, FundingIntervalHours: 1},
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.005, FundingIntervalHours: 8},
+		},
+	}
+	handler := NewFundingHandler(mockUseCase)
+
+	tests := []struct {
+		query          string
+		expectedCount  int
+		expectedStatus int
+	}{
+		{"top=0.004", 1, http.StatusOK},
+		{"top=0.004&basis=raw", 1, http.StatusOK},
+		{"top=0.004&basis=8h", 2, http.StatusOK},
+		{"top=0.0007&basis=1h", 1, http.StatusOK},
+		{"top=0.5&basis=apr", 2, http.StatusOK},
+		{"top=0.004&basis=weekly", 0, http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/funding-top?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.GetFundingRatesTop(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if rates, _ := response["rates"].([]interface{}); len(rates) != tc.expectedCount {
+				t.Errorf("Expected %d rates, got %d", tc.expectedCount, len(rates))
+			}
+		})
+	}
+}
+
 func TestFundingHandler_GetFundingRatesTop_ErrorFromUseCase(t *testing.T) {
 	mockUseCase := &MockMultiExchangeUseCase{
 		ratesErr: assertAnError(),
@@ -268,3 +438,191 @@ func TestFundingHandler_GetFundingRatesTop_ErrorFromUseCase(t *testing.T) {
 func assertAnError() error {
 	return fmt.Errorf("mock error")
 }
+
+func TestFundingHandler_Refresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		exchange     string
+		handler      func(h *FundingHandler) http.HandlerFunc
+		expectedCode int
+		refreshed    []string
+	}{
+		{"no refresh", "/api/funding", "", func(h *FundingHandler) http.HandlerFunc { return h.GetFundingRates }, http.StatusOK, nil},
+		{"refresh all", "/api/funding?refresh=true", "", func(h *FundingHandler) http.HandlerFunc { return h.GetFundingRates }, http.StatusOK, []string{}},
+		{"refresh false", "/api/funding?refresh=false", "", func(h *FundingHandler) http.HandlerFunc { return h.GetFundingRates }, http.StatusOK, nil},
+		{"invalid refresh", "/api/funding?refresh=soon", "", func(h *FundingHandler) http.HandlerFunc { return h.GetFundingRates }, http.StatusBadRequest, nil},
+		{"refresh top", "/api/funding-top?refresh=1", "", func(h *FundingHandler) http.HandlerFunc { return h.GetFundingRatesTop }, http.StatusOK, []string{}},
+		{"refresh exchange", "/api/funding/binance?refresh=true", "binance", func(h *FundingHandler) http.HandlerFunc { return h.GetExchangeFunding }, http.StatusOK, []string{"binance"}},
+		{"refresh unknown exchange", "/api/funding/nonexistent?refresh=true", "nonexistent", func(h *FundingHandler) http.HandlerFunc { return h.GetExchangeFunding }, http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUseCase := &MockMultiExchangeUseCase{
+				rates: []domain.FundingRate{
+					{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: time.Now()},
+				},
+			}
+			handler := NewFundingHandler(mockUseCase)
+
+			req, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.exchange != "" {
+				req = mux.SetURLVars(req, map[string]string{"exchange": tt.exchange})
+			}
+
+			rr := httptest.NewRecorder()
+			tt.handler(handler)(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.refreshed == nil {
+				if len(mockUseCase.refreshed) != 0 {
+					t.Errorf("Expected no refresh, got %v", mockUseCase.refreshed)
+				}
+				return
+			}
+			if len(mockUseCase.refreshed) != 1 || len(mockUseCase.refreshed[0]) != len(tt.refreshed) {
+				t.Fatalf("Expected one refresh of %v, got %v", tt.refreshed, mockUseCase.refreshed)
+			}
+			for i, name := range tt.refreshed {
+				if mockUseCase.refreshed[0][i] != name {
+					t.Errorf("Expected refresh of %v, got %v", tt.refreshed, mockUseCase.refreshed[0])
+				}
+			}
+		})
+	}
+}
+
+func TestFundingHandler_GetHistoricalFundingRates(t *testing.T) {
+	mockUseCase := &MockMultiExchangeUseCase{
+		history: []domain.FundingRateHistory{
+			{Exchange: "binance", Timestamp: 1704067200, FundingRate: 0.0001, MarkPrice: 42000},
+			{Exchange: "binance", Timestamp: 1704070800, FundingRate: 0.0002, MarkPrice: 42100},
+			{Exchange: "bybit", Timestamp: 1704067200, FundingRate: -0.0001, MarkPrice: 42001},
+		},
+	}
+	handler := NewFundingHandler(mockUseCase)
+
+	req, err := http.NewRequest("GET", "/api/logs/BTCUSDT/history?exchanges=Binance,bybit&from=1704067200&to=2024-01-02T00:00:00Z&resolution=1h", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"symbol": "BTCUSDT"})
+
+	rr := httptest.NewRecorder()
+	handler.GetHistoricalFundingRates(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	query := mockUseCase.historyQuery
+	if query.Symbol != "BTCUSDT" || len(query.Exchanges) != 2 || query.Exchanges[0] != "binance" || query.Exchanges[1] != "bybit" {
+		t.Errorf("Expected BTCUSDT on binance and bybit, got %+v", query)
+	}
+	if query.From.Unix() != 1704067200 || query.To.Unix() != 1704153600 {
+		t.Errorf("Expected range 1704067200-1704153600, got %d-%d", query.From.Unix(), query.To.Unix())
+	}
+	if query.Resolution != domain.Resolution1h {
+		t.Errorf("Expected 1h resolution, got %s", query.Resolution)
+	}
+
+	var response struct {
+		Resolution string                                 `json:"resolution"`
+		History    map[string][]domain.FundingRateHistory `json:"history"`
+		Count      int                                    `json:"count"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Count != 3 || len(response.History["binance"]) != 2 || len(response.History["bybit"]) != 1 {
+		t.Errorf("Expected points grouped per exchange, got %+v", response.History)
+	}
+	if response.History["binance"][1].MarkPrice != 42100 {
+		t.Errorf("Expected mark prices in the response, got %+v", response.History["binance"][1])
+	}
+}
+
+func TestFundingHandler_GetHistoricalFundingRatesLegacy(t *testing.T) {
+	tests := []struct {
+		query  string
+		legacy bool
+	}{
+		{"exchange=binance", true},
+		{"exchange=binance,bybit", false},
+		{"exchange=binance&resolution=8h", false},
+		{"exchange=binance&from=1704067200", false},
+		{"exchanges=binance", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		mockUseCase := &MockMultiExchangeUseCase{
+			history: []domain.FundingRateHistory{
+				{Exchange: "binance", Timestamp: 1704067200, FundingRate: 0.0001, MarkPrice: 42000},
+			},
+		}
+		handler := NewFundingHandler(mockUseCase)
+
+		req, err := http.NewRequest("GET", "/api/logs/BTCUSDT/history?"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"symbol": "BTCUSDT"})
+
+		rr := httptest.NewRecorder()
+		handler.GetHistoricalFundingRates(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d: %s", tt.query, rr.Code, rr.Body.String())
+		}
+
+		// The single exchange form keeps answering with a bare array
+		var history []domain.FundingRateHistory
+		isArray := json.Unmarshal(rr.Body.Bytes(), &history) == nil
+		if isArray != tt.legacy {
+			t.Errorf("Expected array response %v for %q, got %s", tt.legacy, tt.query, rr.Body.String())
+		}
+		if tt.legacy && (len(history) != 1 || history[0].MarkPrice != 42000) {
+			t.Errorf("Expected the binance point, got %+v", history)
+		}
+	}
+}
+
+func TestFundingHandler_GetHistoricalFundingRatesInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"invalid from", "from=yesterday"},
+		{"invalid to", "to=2024-13-01"},
+		{"empty range", "from=1704067200&to=1704067200"},
+		{"invalid resolution", "resolution=5m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUseCase := &MockMultiExchangeUseCase{}
+			handler := NewFundingHandler(mockUseCase)
+
+			req, err := http.NewRequest("GET", "/api/logs/BTCUSDT/history?"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = mux.SetURLVars(req, map[string]string{"symbol": "BTCUSDT"})
+
+			rr := httptest.NewRecorder()
+			handler.GetHistoricalFundingRates(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultSettlementRange is served when no from is requested
+const defaultSettlementRange = 7 * 24 * time.Hour
+
+// maxSettlementRange bounds a request, each being read from the exchange
+const maxSettlementRange = 90 * 24 * time.Hour
+
+type SettlementHandler struct {
+	settlementUseCase domain.SettlementUseCaseInterface
+}
+
+func NewSettlementHandler(settlementUseCase domain.SettlementUseCaseInterface) *SettlementHandler {
+	return &SettlementHandler{
+		settlementUseCase: settlementUseCase,
+	}
+}
+
+// GetSettlements lists the funding rates an exchange settled for one of its
+// symbols, read from the exchange's funding history.
+// Query parameters: from and to (unix seconds or RFC 3339), the last 7 days
+// by default and at most 90 days apart.
+func (h *SettlementHandler) GetSettlements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	settlementQuery := domain.SettlementQuery{
+		Exchange: strings.ToLower(vars["exchange"]),
+		Symbol:   vars["symbol"],
+	}
+
+	var err error
+	if settlementQuery.From, err = parseTimeParam(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from value. Use unix seconds or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if settlementQuery.To, err = parseTimeParam(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to value. Use unix seconds or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if settlementQuery.To.IsZero() {
+		settlementQuery.To = time.Now()
+	}
+	if settlementQuery.From.IsZero() {
+		settlementQuery.From = settlementQuery.To.Add(-defaultSettlementRange)
+	}
+	if !settlementQuery.From.Before(settlementQuery.To) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	if settlementQuery.To.Sub(settlementQuery.From) > maxSettlementRange {
+		http.Error(w, "Invalid range. Ask for at most 90 days", http.StatusBadRequest)
+		return
+	}
+
+	settlements, err := h.settlementUseCase.GetSettlements(r.Context(), settlementQuery)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrExchangeNotFound):
+			http.Error(w, "Exchange not found", http.StatusNotFound)
+		case errors.Is(err, domain.ErrHistoryUnsupported):
+			http.Error(w, "Exchange has no funding history", http.StatusNotImplemented)
+		case errors.Is(err, domain.ErrExchangeTimeout):
+			http.Error(w, fmt.Sprintf("Failed to get settlements: %v", err), http.StatusGatewayTimeout)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to get settlements: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"timestamp":   time.Now().Unix(),
+		"exchange":    settlementQuery.Exchange,
+		"symbol":      settlementQuery.Symbol,
+		"from":        settlementQuery.From.Unix(),
+		"to":          settlementQuery.To.Unix(),
+		"count":       len(settlements),
+		"settlements": settlements,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
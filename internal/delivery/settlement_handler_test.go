@@ -0,0 +1,111 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// MockSettlementUseCase records the query it was called with
+type MockSettlementUseCase struct {
+	settlements []domain.FundingSettlement
+	err         error
+	query       domain.SettlementQuery
+}
+
+func (m *MockSettlementUseCase) GetSettlements(ctx context.Context, query domain.SettlementQuery) ([]domain.FundingSettlement, error) {
+	m.query = query
+	return m.settlements, m.err
+}
+
+func TestSettlementHandler_GetSettlements(t *testing.T) {
+	settledAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockUseCase := &MockSettlementUseCase{
+		settlements: []domain.FundingSettlement{{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0001, FundingTime: settledAt}},
+	}
+	handler := NewSettlementHandler(mockUseCase)
+
+	req, _ := http.NewRequest("GET", "/api/funding/Binance/BTCUSDT/settlements?from=1703980800&to=2024-01-02T00:00:00Z", nil)
+	req = mux.SetURLVars(req, map[string]string{"exchange": "Binance", "symbol": "BTCUSDT"})
+	rr := httptest.NewRecorder()
+	handler.GetSettlements(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	query := mockUseCase.query
+	if query.Exchange != "binance" || query.Symbol != "BTCUSDT" {
+		t.Errorf("Expected binance BTCUSDT, got %s %s", query.Exchange, query.Symbol)
+	}
+	if !query.From.Equal(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)) || !query.To.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2023-12-31 to 2024-01-02, got %v to %v", query.From, query.To)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response["count"] != float64(1) {
+		t.Errorf("Expected count 1, got %v", response["count"])
+	}
+}
+
+func TestSettlementHandler_GetSettlementsDefaultRange(t *testing.T) {
+	mockUseCase := &MockSettlementUseCase{}
+	handler := NewSettlementHandler(mockUseCase)
+
+	req, _ := http.NewRequest("GET", "/api/funding/okx/BTC-USDT-SWAP/settlements", nil)
+	req = mux.SetURLVars(req, map[string]string{"exchange": "okx", "symbol": "BTC-USDT-SWAP"})
+	rr := httptest.NewRecorder()
+	handler.GetSettlements(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if span := mockUseCase.query.To.Sub(mockUseCase.query.From); span != 7*24*time.Hour {
+		t.Errorf("Expected the last 7 days, got %v", span)
+	}
+	if time.Since(mockUseCase.query.To) > time.Minute {
+		t.Errorf("Expected the range to end now, got %v", mockUseCase.query.To)
+	}
+}
+
+func TestSettlementHandler_GetSettlementsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		expected int
+	}{
+		{"invalid from", "?from=yesterday", nil, http.StatusBadRequest},
+		{"invalid to", "?to=tomorrow", nil, http.StatusBadRequest},
+		{"reversed range", "?from=1704067200&to=1703980800", nil, http.StatusBadRequest},
+		{"range too long", "?from=1672531200&to=1704067200", nil, http.StatusBadRequest},
+		{"unknown exchange", "", domain.ErrExchangeNotFound, http.StatusNotFound},
+		{"no history", "", domain.ErrHistoryUnsupported, http.StatusNotImplemented},
+		{"timeout", "", domain.ErrExchangeTimeout, http.StatusGatewayTimeout},
+		{"exchange error", "", errors.New("unavailable"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSettlementHandler(&MockSettlementUseCase{err: tt.err})
+
+			req, _ := http.NewRequest("GET", "/api/funding/binance/BTCUSDT/settlements"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"exchange": "binance", "symbol": "BTCUSDT"})
+			rr := httptest.NewRecorder()
+			handler.GetSettlements(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsMaxMessageSize = 4096
+	wsSendBuffer     = 32
+)
+
+// FundingHub pushes funding snapshots and per-poll diffs to WebSocket subscribers.
+//
+// Clients send {"action":"subscribe","exchanges":[...],"symbols":[...],
+// "margin_types":[...]} to start receiving data (empty lists mean everything)
+// and "unsubscribe" with the same shape to narrow or stop it. On subscribe the
+// client gets a "snapshot" of the current rates, then an "update" with changed
+// and removed rates after each poll.
+type FundingHub struct {
+	logger     *logrus.Logger
+	upgrader   websocket.Upgrader
+	pingPeriod time.Duration
+	pongWait   time.Duration
+	sendBuffer int
+
+	mu        sync.Mutex
+	clients   map[*wsClient]bool
+	latest    map[wsRateKey]domain.FundingRate
+	timestamp time.Time
+}
+
+type wsClient struct {
+	hub       *FundingHub
+	conn      *websocket.Conn
+	send      chan []byte
+	closeOnce sync.Once
+
+	// Subscription state, guarded by hub.mu
+	subscribed  bool
+	exchanges   map[string]bool
+	symbols     map[string]bool
+	marginTypes map[domain.MarginType]bool
+}
+
+type wsRateKey struct {
+	Exchange string `json:"exchange"`
+	Symbol   string `json:"symbol"`
+}
+
+type wsRequest struct {
+	Action      string   `json:"action"`
+	Exchanges   []string `json:"exchanges"`
+	Symbols     []string `json:"symbols"`
+	MarginTypes []string `json:"margin_types"`
+}
+
+type wsMessage struct {
+	Type      string               `json:"type"`
+	Timestamp int64                `json:"timestamp,omitempty"`
+	Rates     []domain.FundingRate `json:"rates,omitempty"`
+	Updated   []domain.FundingRate `json:"updated,omitempty"`
+	Removed   []wsRateKey          `json:"removed,omitempty"`
+	Exchanges []string             `json:"exchanges,omitempty"`
+	Symbols   []string             `json:"symbols,omitempty"`
+	Error     string               `json:"error,omitempty"`
+
+	MarginTypes []domain.MarginType `json:"margin_types,omitempty"`
+}
+
+func NewFundingHub(logger *logrus.Logger) *FundingHub {
+	return &FundingHub{
+		logger: logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			// The REST API is served with Access-Control-Allow-Origin: *
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		pingPeriod: (wsPongWait * 9) / 10,
+		pongWait:   wsPongWait,
+		sendBuffer: wsSendBuffer,
+		clients:    make(map[*wsClient]bool),
+		latest:     make(map[wsRateKey]domain.FundingRate),
+	}
+}
+
+// ServeWS upgrades the request and serves the subscriber until it disconnects
+func (h *FundingHub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error
+		h.logger.Warnf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	client := &wsClient{
+		hub:         h,
+		conn:        conn,
+		send:        make(chan []byte, h.sendBuffer),
+		exchanges:   make(map[string]bool),
+		symbols:     make(map[string]bool),
+		marginTypes: make(map[domain.MarginType]bool),
+	}
+
+	h.mu.Lock()
+	h.clients[client] = true
+	h.mu.Unlock()
+
+	go client.writePump()
+	client.readPump()
+}
+
+// OnFundingSnapshot diffs the snapshot against the previous one and pushes
+// the changes to every subscriber they concern
+func (h *FundingHub) OnFundingSnapshot(snapshot domain.FundingSnapshot) {
+	current := make(map[wsRateKey]domain.FundingRate, len(snapshot.Rates))
+	for _, rate := range snapshot.Rates {
+		current[wsRateKey{Exchange: rate.Exchange, Symbol: rate.Symbol}] = rate
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var updated []domain.FundingRate
+	for key, rate := range current {
+		if previous, ok := h.latest[key]; !ok || rateChanged(previous, rate) {
+			updated = append(updated, rate)
+		}
+	}
+
+	var removed []domain.FundingRate
+	for key, rate := range h.latest {
+		if _, ok := current[key]; !ok {
+			removed = append(removed, rate)
+		}
+	}
+
+	h.latest = current
+	h.timestamp = snapshot.Timestamp
+
+	if len(updated) == 0 && len(removed) == 0 {
+		return
+	}
+
+	for client := range h.clients {
+		if !client.subscribed {
+			continue
+		}
+
+		message := wsMessage{Type: "update", Timestamp: snapshot.Timestamp.Unix()}
+		for _, rate := range updated {
+			if client.matches(rate) {
+				message.Updated = append(message.Updated, rate)
+			}
+		}
+		for _, rate := range removed {
+			if client.matches(rate) {
+				message.Removed = append(message.Removed, wsRateKey{Exchange: rate.Exchange, Symbol: rate.Symbol})
+			}
+		}
+
+		if len(message.Updated) > 0 || len(message.Removed) > 0 {
+			h.enqueue(client, message)
+		}
+	}
+}
+
+// Close disconnects every subscriber, used on shutdown since hijacked
+// connections are not closed by http.Server.Shutdown
+func (h *FundingHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		delete(h.clients, client)
+		client.close()
+	}
+}
+
+// ClientCount returns the number of connected subscribers
+func (h *FundingHub) ClientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// handleRequest applies a subscribe or unsubscribe request from a client
+func (h *FundingHub) handleRequest(client *wsClient, data []byte) {
+	var request wsRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		h.mu.Lock()
+		h.enqueue(client, wsMessage{Type: "error", Error: "invalid message: " + err.Error()})
+		h.mu.Unlock()
+		return
+	}
+
+	marginTypes := make([]domain.MarginType, 0, len(request.MarginTypes))
+	for _, value := range request.MarginTypes {
+		marginType, err := domain.ParseMarginType(value)
+		if err != nil || marginType == "" {
+			h.mu.Lock()
+			h.enqueue(client, wsMessage{Type: "error", Error: "unknown margin type: " + value})
+			h.mu.Unlock()
+			return
+		}
+		marginTypes = append(marginTypes, marginType)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch request.Action {
+	case "subscribe":
+		client.subscribed = true
+		for _, exchange := range request.Exchanges {
+			client.exchanges[strings.ToLower(exchange)] = true
+		}
+		for _, symbol := range request.Symbols {
+			client.symbols[strings.ToUpper(symbol)] = true
+		}
+		for _, marginType := range marginTypes {
+			client.marginTypes[marginType] = true
+		}
+		h.enqueue(client, client.ack("subscribed"))
+
+		snapshot := wsMessage{Type: "snapshot", Timestamp: h.timestamp.Unix(), Rates: []domain.FundingRate{}}
+		for _, rate := range h.latest {
+			if client.matches(rate) {
+				snapshot.Rates = append(snapshot.Rates, rate)
+			}
+		}
+		h.enqueue(client, snapshot)
+	case "unsubscribe":
+		// An empty filter means everything, so removing the last exchange,
+		// symbol or margin type of one ends the subscription instead. Only
+		// the filters this request empties count, not those left unset.
+		exchanges, symbols, margins := len(client.exchanges), len(client.symbols), len(client.marginTypes)
+		for _, exchange := range request.Exchanges {
+			delete(client.exchanges, strings.ToLower(exchange))
+		}
+		for _, symbol := range request.Symbols {
+			delete(client.symbols, strings.ToUpper(symbol))
+		}
+		for _, marginType := range marginTypes {
+			delete(client.marginTypes, marginType)
+		}
+		emptied := (exchanges > 0 && len(client.exchanges) == 0) ||
+			(symbols > 0 && len(client.symbols) == 0) ||
+			(margins > 0 && len(client.marginTypes) == 0)
+		if len(request.Exchanges) == 0 && len(request.Symbols) == 0 && len(marginTypes) == 0 || emptied {
+			client.subscribed = false
+			client.exchanges = make(map[string]bool)
+			client.symbols = make(map[string]bool)
+			client.marginTypes = make(map[domain.MarginType]bool)
+		}
+		h.enqueue(client, client.ack("unsubscribed"))
+	default:
+		h.enqueue(client, wsMessage{Type: "error", Error: "unknown action: " + request.Action})
+	}
+}
+
+// enqueue queues a message for a client, evicting it when its buffer is full
+// so one slow consumer can't hold up the others. Must be called with h.mu held.
+func (h *FundingHub) enqueue(client *wsClient, message wsMessage) {
+	if !h.clients[client] {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Errorf("Failed to marshal WebSocket message: %v", err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+		h.logger.Warnf("Evicting slow WebSocket consumer %s", client.remoteAddr())
+		delete(h.clients, client)
+		client.close()
+	}
+}
+
+func (h *FundingHub) unregister(client *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, client)
+	client.close()
+}
+
+// matches reports whether a rate falls within the client's subscription.
+// Must be called with hub.mu held.
+func (c *wsClient) matches(rate domain.FundingRate) bool {
+	if !c.subscribed {
+		return false
+	}
+	if len(c.exchanges) > 0 && !c.exchanges[rate.Exchange] {
+		return false
+	}
+	if len(c.symbols) > 0 && !c.symbols[strings.ToUpper(rate.Symbol)] && !c.symbols[rate.CanonicalSymbol] {
+		return false
+	}
+	if len(c.marginTypes) > 0 && !c.marginTypes[rate.MarginType] {
+		return false
+	}
+	return true
+}
+
+func (c *wsClient) ack(messageType string) wsMessage {
+	message := wsMessage{Type: messageType}
+	for exchange := range c.exchanges {
+		message.Exchanges = append(message.Exchanges, exchange)
+	}
+	for symbol := range c.symbols {
+		message.Symbols = append(message.Symbols, symbol)
+	}
+	for marginType := range c.marginTypes {
+		message.MarginTypes = append(message.MarginTypes, marginType)
+	}
+	return message
+}
+
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.send)
+	})
+}
+
+func (c *wsClient) remoteAddr() string {
+	if c.conn == nil {
+		return "unknown"
+	}
+	return c.conn.RemoteAddr().String()
+}
+
+// readPump handles client requests and pongs until the connection fails
+func (c *wsClient) readPump() {
+	defer c.hub.unregister(c)
+
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.hub.handleRequest(c, data)
+	}
+}
+
+// writePump writes queued messages and heartbeat pings; it owns the connection
+// and closes it once the send channel is closed or a write fails
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(c.hub.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// rateChanged reports whether a rate differs in any field subscribers care about
+func rateChanged(previous, current domain.FundingRate) bool {
+	return previous.FundingRate != current.FundingRate ||
+		previous.MarkPrice != current.MarkPrice ||
+		previous.IndexPrice != current.IndexPrice ||
+		previous.LastFundingRate != current.LastFundingRate ||
+		!previous.NextFundingTime.Equal(current.NextFundingTime)
+}
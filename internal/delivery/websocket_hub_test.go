@@ -0,0 +1,314 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestHub() *FundingHub {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return NewFundingHub(logger)
+}
+
+func dialHub(t *testing.T, hub *FundingHub) *websocket.Conn {
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial hub: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message wsMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return message
+}
+
+func subscribe(t *testing.T, conn *websocket.Conn, request wsRequest) wsMessage {
+	if err := conn.WriteJSON(request); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	if ack := readMessage(t, conn); ack.Type != "subscribed" {
+		t.Fatalf("Expected subscribed ack, got %+v", ack)
+	}
+	return readMessage(t, conn)
+}
+
+func TestFundingHub_SnapshotAndUpdates(t *testing.T) {
+	hub := newTestHub()
+	now := time.Now()
+	hub.OnFundingSnapshot(domain.FundingSnapshot{
+		Timestamp: now,
+		Rates: []domain.FundingRate{
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001},
+			{Symbol: "ETHUSDT", Exchange: "binance", FundingRate: 0.0002},
+		},
+	})
+
+	conn := dialHub(t, hub)
+	snapshot := subscribe(t, conn, wsRequest{Action: "subscribe"})
+	if snapshot.Type != "snapshot" || len(snapshot.Rates) != 2 {
+		t.Fatalf("Expected snapshot with 2 rates, got %+v", snapshot)
+	}
+
+	// BTC changes, ETH is unchanged, SOL appears
+	hub.OnFundingSnapshot(domain.FundingSnapshot{
+		Timestamp: now.Add(time.Minute),
+		Rates: []domain.FundingRate{
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0003},
+			{Symbol: "ETHUSDT", Exchange: "binance", FundingRate: 0.0002},
+			{Symbol: "SOLUSDT", Exchange: "binance", FundingRate: 0.0004},
+		},
+	})
+
+	update := readMessage(t, conn)
+	if update.Type != "update" || len(update.Updated) != 2 || len(update.Removed) != 0 {
+		t.Fatalf("Expected update with 2 changed rates, got %+v", update)
+	}
+
+	// ETH disappears
+	hub.OnFundingSnapshot(domain.FundingSnapshot{
+		Timestamp: now.Add(2 * time.Minute),
+		Rates: []domain.FundingRate{
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0003},
+			{Symbol: "SOLUSDT", Exchange: "binance", FundingRate: 0.0004},
+		},
+	})
+
+	update = readMessage(t, conn)
+	if len(update.Updated) != 0 || len(update.Removed) != 1 || update.Removed[0].Symbol != "ETHUSDT" {
+		t.Fatalf("Expected ETHUSDT removal, got %+v", update)
+	}
+}
+
+func TestFundingHub_SubscriptionFilters(t *testing.T) {
+	hub := newTestHub()
+	rates := []domain.FundingRate{
+		{Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001},
+		{Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", Exchange: "okx", FundingRate: 0.0002},
+		{Symbol: "ETHUSDT", CanonicalSymbol: "ETHUSDT", Exchange: "binance", FundingRate: 0.0003},
+	}
+	hub.OnFundingSnapshot(domain.FundingSnapshot{Timestamp: time.Now(), Rates: rates})
+
+	conn := dialHub(t, hub)
+
+	// Canonical symbols match across venues
+	snapshot := subscribe(t, conn, wsRequest{Action: "subscribe", Symbols: []string{"btcusdt"}})
+	if len(snapshot.Rates) != 2 {
+		t.Fatalf("Expected 2 BTCUSDT rates, got %+v", snapshot.Rates)
+	}
+
+	if err := conn.WriteJSON(wsRequest{Action: "unsubscribe", Symbols: []string{"BTCUSDT"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ack := readMessage(t, conn); ack.Type != "unsubscribed" {
+		t.Fatalf("Expected unsubscribed ack, got %+v", ack)
+	}
+
+	snapshot = subscribe(t, conn, wsRequest{Action: "subscribe", Exchanges: []string{"OKX"}})
+	if len(snapshot.Rates) != 1 || snapshot.Rates[0].Exchange != "okx" {
+		t.Fatalf("Expected only the okx rate, got %+v", snapshot.Rates)
+	}
+
+	// Updates outside the subscription are not delivered
+	rates[2].FundingRate = 0.0009
+	rates[1].FundingRate = 0.0005
+	hub.OnFundingSnapshot(domain.FundingSnapshot{Timestamp: time.Now(), Rates: rates})
+
+	update := readMessage(t, conn)
+	if len(update.Updated) != 1 || update.Updated[0].Exchange != "okx" {
+		t.Fatalf("Expected only the okx update, got %+v", update)
+	}
+}
+
+func TestFundingHub_UnsubscribeLastFilter(t *testing.T) {
+	hub := newTestHub()
+	rates := []domain.FundingRate{
+		{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001},
+		{Symbol: "BTC-USDT-SWAP", Exchange: "okx", FundingRate: 0.0002},
+		{Symbol: "BTC_USDT", Exchange: "mexc", FundingRate: 0.0003},
+	}
+	hub.OnFundingSnapshot(domain.FundingSnapshot{Timestamp: time.Now(), Rates: rates})
+
+	conn := dialHub(t, hub)
+	subscribe(t, conn, wsRequest{Action: "subscribe", Exchanges: []string{"binance", "okx"}})
+
+	conn.WriteJSON(wsRequest{Action: "unsubscribe", Exchanges: []string{"binance"}})
+	if ack := readMessage(t, conn); ack.Type != "unsubscribed" || len(ack.Exchanges) != 1 || ack.Exchanges[0] != "okx" {
+		t.Fatalf("Expected okx left subscribed, got %+v", ack)
+	}
+
+	// Removing the last exchange ends the subscription rather than widening
+	// it to every exchange
+	conn.WriteJSON(wsRequest{Action: "unsubscribe", Exchanges: []string{"okx"}})
+	if ack := readMessage(t, conn); ack.Type != "unsubscribed" || len(ack.Exchanges) != 0 {
+		t.Fatalf("Expected no exchanges left, got %+v", ack)
+	}
+
+	for i := range rates {
+		rates[i].FundingRate *= 2
+	}
+	hub.OnFundingSnapshot(domain.FundingSnapshot{Timestamp: time.Now(), Rates: rates})
+
+	// Messages are delivered in order, so an update would arrive first
+	conn.WriteJSON(map[string]string{"action": "bogus"})
+	if message := readMessage(t, conn); message.Type != "error" {
+		t.Errorf("Expected no update after unsubscribing, got %+v", message)
+	}
+}
+
+func TestFundingHub_UnsubscribeUnsetFilter(t *testing.T) {
+	hub := newTestHub()
+	rates := []domain.FundingRate{
+		{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001},
+		{Symbol: "ETHUSDT", Exchange: "binance", FundingRate: 0.0002},
+	}
+	hub.OnFundingSnapshot(domain.FundingSnapshot{Timestamp: time.Now(), Rates: rates})
+
+	conn := dialHub(t, hub)
+	subscribe(t, conn, wsRequest{Action: "subscribe", Symbols: []string{"BTCUSDT"}})
+
+	// The exchange filter was never set, so this leaves the symbol filter as is
+	conn.WriteJSON(wsRequest{Action: "unsubscribe", Exchanges: []string{"okx"}})
+	if ack := readMessage(t, conn); ack.Type != "unsubscribed" || len(ack.Symbols) != 1 || ack.Symbols[0] != "BTCUSDT" {
+		t.Fatalf("Expected BTCUSDT left subscribed, got %+v", ack)
+	}
+
+	for i := range rates {
+		rates[i].FundingRate *= 2
+	}
+	hub.OnFundingSnapshot(domain.FundingSnapshot{Timestamp: time.Now(), Rates: rates})
+
+	update := readMessage(t, conn)
+	if update.Type != "update" || len(update.Updated) != 1 || update.Updated[0].Symbol != "BTCUSDT" {
+		t.Errorf("Expected the BTCUSDT update, got %+v", update)
+	}
+}
+
+func TestFundingHub_MarginTypeSubscription(t *testing.T) {
+	hub := newTestHub()
+	hub.OnFundingSnapshot(domain.FundingSnapshot{
+		Timestamp: time.Now(),
+		Rates: []domain.FundingRate{
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, MarginType: domain.MarginTypeLinear},
+			{Symbol: "BTCUSD_PERP", Exchange: "binance", FundingRate: 0.0002, MarginType: domain.MarginTypeInverse},
+		},
+	})
+
+	conn := dialHub(t, hub)
+	snapshot := subscribe(t, conn, wsRequest{Action: "subscribe", MarginTypes: []string{"Inverse"}})
+	if len(snapshot.Rates) != 1 || snapshot.Rates[0].Symbol != "BTCUSD_PERP" {
+		t.Fatalf("Expected only the inverse rate, got %+v", snapshot.Rates)
+	}
+
+	conn.WriteJSON(wsRequest{Action: "subscribe", MarginTypes: []string{"cross"}})
+	if message := readMessage(t, conn); message.Type != "error" || !strings.Contains(message.Error, "cross") {
+		t.Errorf("Expected error for unknown margin type, got %+v", message)
+	}
+}
+
+func TestFundingHub_InvalidRequests(t *testing.T) {
+	hub := newTestHub()
+	conn := dialHub(t, hub)
+
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if message := readMessage(t, conn); message.Type != "error" {
+		t.Errorf("Expected error for invalid JSON, got %+v", message)
+	}
+
+	conn.WriteJSON(map[string]string{"action": "bogus"})
+	if message := readMessage(t, conn); message.Type != "error" || !strings.Contains(message.Error, "bogus") {
+		t.Errorf("Expected error for unknown action, got %+v", message)
+	}
+}
+
+func TestFundingHub_Heartbeat(t *testing.T) {
+	hub := newTestHub()
+	hub.pingPeriod = 50 * time.Millisecond
+	conn := dialHub(t, hub)
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	// Reading drives the control message handlers
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-pinged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a heartbeat ping")
+	}
+}
+
+func TestFundingHub_EvictsSlowConsumer(t *testing.T) {
+	hub := newTestHub()
+
+	// A client whose write pump never drains its buffer
+	slow := &wsClient{
+		hub:        hub,
+		send:       make(chan []byte, 1),
+		subscribed: true,
+		exchanges:  make(map[string]bool),
+		symbols:    make(map[string]bool),
+	}
+	hub.clients[slow] = true
+
+	for i := 1; i <= 3; i++ {
+		hub.OnFundingSnapshot(domain.FundingSnapshot{
+			Timestamp: time.Now(),
+			Rates:     []domain.FundingRate{{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: float64(i) / 10000}},
+		})
+	}
+
+	if hub.ClientCount() != 0 {
+		t.Fatalf("Expected slow consumer to be evicted, %d clients left", hub.ClientCount())
+	}
+
+	// The buffered message is still readable, then the channel is closed
+	<-slow.send
+	if _, ok := <-slow.send; ok {
+		t.Error("Expected send channel to be closed after eviction")
+	}
+}
+
+func TestFundingHub_Close(t *testing.T) {
+	hub := newTestHub()
+	conn := dialHub(t, hub)
+	subscribe(t, conn, wsRequest{Action: "subscribe"})
+
+	hub.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Expected normal closure, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Alert rule types
+const (
+	// AlertThreshold fires when a rate's magnitude exceeds the threshold
+	AlertThreshold = "threshold"
+	// AlertChange fires when a rate moves by more than the threshold between
+	// two consecutive snapshots
+	AlertChange = "change"
+	// AlertSpread fires when the widest cross-exchange spread of a market
+	// exceeds the threshold
+	AlertSpread = "spread"
+	// AlertFlip fires when a rate changes sign, optionally only when the new
+	// rate's magnitude reaches the threshold
+	AlertFlip = "flip"
+)
+
+// DefaultAlertCooldown is how long an alert stays quiet after firing when
+// its rule doesn't set a cooldown
+const DefaultAlertCooldown = 15 * time.Minute
+
+// AlertRule is an alerting rule evaluated after every snapshot refresh
+type AlertRule struct {
+	Name      string   `json:"name" mapstructure:"name"`
+	Type      string   `json:"type" mapstructure:"type"`
+	Symbols   []string `json:"symbols,omitempty" mapstructure:"symbols"`     // venue or canonical symbols, empty means any
+	Exchanges []string `json:"exchanges,omitempty" mapstructure:"exchanges"` // empty means any
+	Threshold float64  `json:"threshold" mapstructure:"threshold"`
+	Basis     string   `json:"basis,omitempty" mapstructure:"basis"`       // raw, 1h, 8h or apr
+	Cooldown  int      `json:"cooldown,omitempty" mapstructure:"cooldown"` // seconds, defaults to DefaultAlertCooldown
+
+	MarginType MarginType `json:"margin_type,omitempty" mapstructure:"margin_type"` // linear or inverse, empty means any
+}
+
+// Validate checks the rule's type, threshold and basis
+func (r AlertRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: rule has no name", ErrInvalidAlertRule)
+	}
+	switch r.Type {
+	case AlertThreshold, AlertChange, AlertSpread, AlertFlip:
+	default:
+		return fmt.Errorf("%w: %s has unknown type %q", ErrInvalidAlertRule, r.Name, r.Type)
+	}
+	if r.Threshold < 0 {
+		return fmt.Errorf("%w: %s has a negative threshold", ErrInvalidAlertRule, r.Name)
+	}
+	if r.Basis != "" && !ValidRateBasis(r.Basis) {
+		return fmt.Errorf("%w: %s has unknown basis %q", ErrInvalidAlertRule, r.Name, r.Basis)
+	}
+	if r.Cooldown < 0 {
+		return fmt.Errorf("%w: %s has a negative cooldown", ErrInvalidAlertRule, r.Name)
+	}
+	switch r.MarginType {
+	case "", MarginTypeLinear, MarginTypeInverse:
+	default:
+		return fmt.Errorf("%w: %s has unknown margin type %q", ErrInvalidAlertRule, r.Name, r.MarginType)
+	}
+	return nil
+}
+
+// RateBasis returns the basis the threshold applies to. Spreads default to
+// 8h like arbitrage opportunities, so venues with different cycles compare.
+func (r AlertRule) RateBasis() string {
+	if r.Basis != "" {
+		return r.Basis
+	}
+	if r.Type == AlertSpread {
+		return RateBasis8h
+	}
+	return RateBasisRaw
+}
+
+// CooldownDuration returns how long an alert of this rule stays quiet after firing
+func (r AlertRule) CooldownDuration() time.Duration {
+	if r.Cooldown > 0 {
+		return time.Duration(r.Cooldown) * time.Second
+	}
+	return DefaultAlertCooldown
+}
+
+// Matches reports whether the rule watches the rate's exchange, symbol and
+// margin type
+func (r AlertRule) Matches(rate FundingRate) bool {
+	if !rate.MatchesMarginType(r.MarginType) {
+		return false
+	}
+	if len(r.Exchanges) > 0 && !containsFold(r.Exchanges, rate.Exchange) {
+		return false
+	}
+	if len(r.Symbols) > 0 && !containsFold(r.Symbols, rate.Symbol) && !containsFold(r.Symbols, rate.MarketSymbol()) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// AlertsConfig holds the alert rules and how many fired alerts are kept
+type AlertsConfig struct {
+	Rules       []AlertRule `mapstructure:"rules"`
+	HistorySize int         `mapstructure:"history_size"`
+}
+
+// Alert is a rule breach raised by the alert engine
+type Alert struct {
+	ID        int64     `json:"id"`
+	Rule      string    `json:"rule"`
+	Type      string    `json:"type"`
+	Symbol    string    `json:"symbol"`    // canonical symbol when known
+	Exchanges []string  `json:"exchanges"` // the low then high exchange for spreads
+	Value     float64   `json:"value"`     // the rate, change or spread on Basis
+	Previous  float64   `json:"previous,omitempty"`
+	Threshold float64   `json:"threshold"`
+	Basis     string    `json:"basis"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Margin type of the rates behind the alert, empty when a spread mixes both
+	MarginType MarginType `json:"margin_type,omitempty"`
+}
+
+// AlertFilter narrows down the alert history
+type AlertFilter struct {
+	Rule     string
+	Symbol   string    // canonical symbol, empty means any
+	Exchange string    // empty means any
+	Since    time.Time // zero means unbounded
+	Limit    int       // 0 means unlimited
+
+	MarginType MarginType // empty means any
+}
+
+// AlertNotifier delivers fired alerts. Notifiers are called from the refresh
+// cycle, so slow ones should hand the work off instead of blocking it.
+type AlertNotifier interface {
+	Notify(alert Alert) error
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// BackfillRequest selects the settled funding history to store in the logs
+type BackfillRequest struct {
+	// Exchanges to read, every exchange serving funding history when empty
+	Exchanges []string `json:"exchanges,omitempty"`
+	// Symbols are venue symbols or canonical ones such as BTCUSDT, which
+	// select the market on every exchange listing it
+	Symbols []string  `json:"symbols"`
+	From    time.Time `json:"from"` // inclusive
+	To      time.Time `json:"to"`   // exclusive
+}
+
+// Validate checks that the request selects symbols and a range
+func (r BackfillRequest) Validate() error {
+	if len(r.Symbols) == 0 {
+		return fmt.Errorf("%w: no symbols", ErrInvalidBackfill)
+	}
+	if !r.From.Before(r.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidBackfill)
+	}
+	return nil
+}
+
+// BackfillStatus is the state of a backfill
+type BackfillStatus string
+
+const (
+	BackfillStatusRunning BackfillStatus = "running"
+	BackfillStatusDone    BackfillStatus = "done"
+	BackfillStatusFailed  BackfillStatus = "failed"
+)
+
+// BackfillTask is the backfill of one market on one exchange, read in
+// windows of its range
+type BackfillTask struct {
+	Exchange  string `json:"exchange"`
+	Symbol    string `json:"symbol"`     // venue symbol
+	LogSymbol string `json:"log_symbol"` // symbol the settlements are logged under
+
+	Windows     int    `json:"windows"`
+	Completed   int    `json:"completed"`   // windows stored
+	Settlements int    `json:"settlements"` // settlements stored
+	Error       string `json:"error,omitempty"`
+}
+
+// Done reports whether the task stored every window or failed
+func (t BackfillTask) Done() bool {
+	return t.Error != "" || t.Completed == t.Windows
+}
+
+// BackfillProgress reports a backfill, updated as each window is stored
+type BackfillProgress struct {
+	Status     BackfillStatus  `json:"status"`
+	Request    BackfillRequest `json:"request"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"` // zero while running
+
+	Tasks       []BackfillTask `json:"tasks"`
+	Settlements int            `json:"settlements"` // stored across tasks
+	// Symbols no selected exchange lists
+	Unmatched []string `json:"unmatched,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Windows returns the windows stored and to store across tasks
+func (p BackfillProgress) Windows() (completed, total int) {
+	for _, task := range p.Tasks {
+		completed += task.Completed
+		total += task.Windows
+	}
+	return completed, total
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Digest channel types
+const (
+	DigestTelegram = "telegram"
+	DigestSlack    = "slack"
+	DigestDiscord  = "discord"
+)
+
+const (
+	// DefaultDigestInterval is the digest cadence when a channel doesn't set one
+	DefaultDigestInterval = time.Hour
+	// DefaultDigestLimit is how many rates a digest lists when a channel doesn't set it
+	DefaultDigestLimit = 10
+	// DefaultTelegramBaseURL is the Telegram Bot API endpoint
+	DefaultTelegramBaseURL = "https://api.telegram.org"
+)
+
+// DigestChannel is a chat destination receiving a scheduled digest of the
+// most extreme funding rates. Rates are selected like /api/funding-top:
+// their magnitude on Basis must exceed Top (0 keeps every rate), and the
+// Limit largest are listed.
+type DigestChannel struct {
+	Name     string `mapstructure:"name"`
+	Type     string `mapstructure:"type"`     // telegram, slack or discord
+	Interval int    `mapstructure:"interval"` // minutes between digests
+
+	// Telegram bot API; base_url defaults to DefaultTelegramBaseURL
+	BaseURL  string `mapstructure:"base_url"`
+	BotToken string `mapstructure:"bot_token"`
+	ChatID   string `mapstructure:"chat_id"`
+
+	// Slack incoming webhook or Discord webhook
+	WebhookURL string `mapstructure:"webhook_url"`
+
+	Symbols   []string `mapstructure:"symbols"`   // venue or canonical symbols, empty means any
+	Exchanges []string `mapstructure:"exchanges"` // empty means any
+	Top       float64  `mapstructure:"top"`
+	Basis     string   `mapstructure:"basis"` // raw (default), 1h, 8h or apr
+	Limit     int      `mapstructure:"limit"` // defaults to DefaultDigestLimit
+}
+
+// Validate checks the channel's type, credentials, schedule and filters
+func (c DigestChannel) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: digest channel has no name", ErrInvalidConfig)
+	}
+	switch c.Type {
+	case DigestTelegram:
+		if c.BotToken == "" || c.ChatID == "" {
+			return fmt.Errorf("%w: digest channel %s needs a bot_token and a chat_id", ErrInvalidConfig, c.Name)
+		}
+	case DigestSlack, DigestDiscord:
+		if c.WebhookURL == "" {
+			return fmt.Errorf("%w: digest channel %s needs a webhook_url", ErrInvalidConfig, c.Name)
+		}
+	default:
+		return fmt.Errorf("%w: digest channel %s has unknown type %q", ErrInvalidConfig, c.Name, c.Type)
+	}
+	if c.Interval < 0 || c.Top < 0 || c.Limit < 0 {
+		return fmt.Errorf("%w: digest channel %s has a negative interval, top or limit", ErrInvalidConfig, c.Name)
+	}
+	if c.Basis != "" && !ValidRateBasis(c.Basis) {
+		return fmt.Errorf("%w: digest channel %s has unknown basis %q", ErrInvalidConfig, c.Name, c.Basis)
+	}
+	return nil
+}
+
+// IntervalDuration returns the time between two digests of the channel
+func (c DigestChannel) IntervalDuration() time.Duration {
+	if c.Interval > 0 {
+		return time.Duration(c.Interval) * time.Minute
+	}
+	return DefaultDigestInterval
+}
+
+// RateBasis returns the basis the threshold and ranking apply to
+func (c DigestChannel) RateBasis() string {
+	if c.Basis != "" {
+		return c.Basis
+	}
+	return RateBasisRaw
+}
+
+// SelectRates picks the channel's rates with the /api/funding-top selection
+// and keeps the Limit most extreme, largest magnitude first
+func (c DigestChannel) SelectRates(rates []FundingRate) []FundingRate {
+	basis := c.RateBasis()
+
+	watched := make([]FundingRate, 0, len(rates))
+	for _, rate := range rates {
+		if len(c.Exchanges) > 0 && !containsFold(c.Exchanges, rate.Exchange) {
+			continue
+		}
+		if len(c.Symbols) > 0 && !containsFold(c.Symbols, rate.Symbol) && !containsFold(c.Symbols, rate.MarketSymbol()) {
+			continue
+		}
+		watched = append(watched, rate)
+	}
+
+	selected := SelectTopFundingRates(watched, c.Top, basis)
+	sort.SliceStable(selected, func(i, j int) bool {
+		return math.Abs(selected[i].RateForBasis(basis)) > math.Abs(selected[j].RateForBasis(basis))
+	})
+
+	limit := c.Limit
+	if limit == 0 {
+		limit = DefaultDigestLimit
+	}
+	if len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+// DigestsConfig holds the chat channels receiving funding digests
+type DigestsConfig struct {
+	Channels []DigestChannel `mapstructure:"channels"`
+}
+
+// FundingDigest is the list of extreme funding rates posted to a channel
+type FundingDigest struct {
+	Channel   string
+	Timestamp time.Time
+	Basis     string
+	Threshold float64
+	Rates     []FundingRate
+}
+
+// DigestNotifier posts funding digests to a chat service
+type DigestNotifier interface {
+	SendDigest(ctx context.Context, digest FundingDigest) error
+}
@@ -1,19 +1,255 @@
 package domain
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
-// FundingRate represents a funding rate for a specific trading pair
+// FundingRate represents a funding rate for a specific trading pair.
+// FundingRate is the rate of the running cycle as currently estimated, to be
+// settled at NextFundingTime; LastFundingRate is the one settled last, at
+// LastFundingTime.
 type FundingRate struct {
-	Symbol           string    `json:"symbol"`
-	Exchange         string    `json:"exchange"`
-	FundingRate      float64   `json:"funding_rate"`
-	NextFundingTime  time.Time `json:"next_funding_time"`
-	Timestamp        time.Time `json:"timestamp"`
-	MarkPrice        float64   `json:"mark_price,omitempty"`
-	IndexPrice       float64   `json:"index_price,omitempty"`
-	LastFundingRate  float64   `json:"last_funding_rate,omitempty"`
+	Symbol          string    `json:"symbol"`
+	Exchange        string    `json:"exchange"`
+	FundingRate     float64   `json:"funding_rate"`
+	NextFundingTime time.Time `json:"next_funding_time"`
+	Timestamp       time.Time `json:"timestamp"`
+	MarkPrice       float64   `json:"mark_price,omitempty"`
+	IndexPrice      float64   `json:"index_price,omitempty"`
+	LastFundingRate float64   `json:"last_funding_rate,omitempty"`
+
+	// Settlement time of LastFundingRate; nil until the venue reports one
+	LastFundingTime *time.Time `json:"last_funding_time,omitempty"`
+
+	// Rate the venue projects for the next settlement when it publishes one
+	// apart from the current rate
+	PredictedFundingRate float64 `json:"predicted_funding_rate,omitempty"`
+
+	// Funding cycle length; 0 when the venue doesn't report it
+	FundingIntervalHours int `json:"funding_interval_hours,omitempty"`
+
+	// Collateral and settlement of the contract, linear or inverse
+	MarginType MarginType `json:"margin_type,omitempty"`
+
+	// Rates normalized by the funding interval so venues are comparable
+	FundingRate1h  float64 `json:"funding_rate_1h"`
+	FundingRate8h  float64 `json:"funding_rate_8h"`
+	FundingRateAPR float64 `json:"funding_rate_apr"`
+
+	// Canonical identity of the market, shared by all venues listing it
+	CanonicalSymbol string       `json:"canonical_symbol,omitempty"`
+	Base            string       `json:"base,omitempty"`
+	Quote           string       `json:"quote,omitempty"`
+	ContractType    ContractType `json:"contract_type,omitempty"`
+}
+
+// MarketSymbol returns the canonical symbol when known and the venue symbol otherwise
+func (f FundingRate) MarketSymbol() string {
+	if f.CanonicalSymbol != "" {
+		return f.CanonicalSymbol
+	}
+	return f.Symbol
+}
+
+// DefaultFundingIntervalHours is assumed when a venue doesn't report its funding cycle
+const DefaultFundingIntervalHours = 8
+
+// IntervalHours returns the funding cycle length, falling back to the default
+func (f FundingRate) IntervalHours() int {
+	if f.FundingIntervalHours > 0 {
+		return f.FundingIntervalHours
+	}
+	return DefaultFundingIntervalHours
+}
+
+// HourlyRate returns the funding rate paid per hour
+func (f FundingRate) HourlyRate() float64 {
+	return f.FundingRate / float64(f.IntervalHours())
+}
+
+// EightHourRate returns the funding rate scaled to the common 8h cycle
+func (f FundingRate) EightHourRate() float64 {
+	return f.HourlyRate() * 8
+}
+
+// AnnualizedRate returns the funding rate compounded linearly over a year
+func (f FundingRate) AnnualizedRate() float64 {
+	return f.HourlyRate() * 24 * 365
+}
+
+// Rate bases a funding rate can be expressed on
+const (
+	RateBasisRaw = "raw"
+	RateBasis1h  = "1h"
+	RateBasis8h  = "8h"
+	RateBasisAPR = "apr"
+)
+
+// ValidRateBasis reports whether basis is one of raw, 1h, 8h or apr
+func ValidRateBasis(basis string) bool {
+	switch basis {
+	case RateBasisRaw, RateBasis1h, RateBasis8h, RateBasisAPR:
+		return true
+	}
+	return false
+}
+
+// RateForBasis returns the funding rate expressed on the requested basis,
+// the raw rate for unknown ones
+func (f FundingRate) RateForBasis(basis string) float64 {
+	switch basis {
+	case RateBasis1h:
+		return f.HourlyRate()
+	case RateBasis8h:
+		return f.EightHourRate()
+	case RateBasisAPR:
+		return f.AnnualizedRate()
+	default:
+		return f.FundingRate
+	}
+}
+
+// ExceedsRate reports whether the rate's magnitude on basis is above threshold,
+// the selection applied by /api/funding-top
+func (f FundingRate) ExceedsRate(threshold float64, basis string) bool {
+	return math.Abs(f.RateForBasis(basis)) > threshold
+}
+
+// SelectTopFundingRates returns the rates whose magnitude on basis exceeds
+// threshold, in their original order, as served by /api/funding-top
+func SelectTopFundingRates(rates []FundingRate, threshold float64, basis string) []FundingRate {
+	topRates := make([]FundingRate, 0, len(rates))
+	for _, rate := range rates {
+		if rate.ExceedsRate(threshold, basis) {
+			topRates = append(topRates, rate)
+		}
+	}
+	return topRates
+}
+
+// ContractType describes how a derivative contract expires
+type ContractType string
+
+const (
+	ContractTypePerpetual ContractType = "perpetual"
+	ContractTypeFuture    ContractType = "future"
+)
+
+// MarginType tells which asset margins and settles a contract
+type MarginType string
+
+const (
+	// MarginTypeLinear contracts are margined in the quote asset (USDT, USDC)
+	MarginTypeLinear MarginType = "linear"
+	// MarginTypeInverse contracts are margined in the base coin, e.g. BTC for BTCUSD
+	MarginTypeInverse MarginType = "inverse"
+)
+
+// ParseMarginType parses a margin type filter, empty meaning any
+func ParseMarginType(value string) (MarginType, error) {
+	switch marginType := MarginType(strings.ToLower(strings.TrimSpace(value))); marginType {
+	case "", MarginTypeLinear, MarginTypeInverse:
+		return marginType, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidMarginType, value)
+}
+
+// MatchesMarginType reports whether the rate has the given margin type, any
+// rate matching an empty one
+func (f FundingRate) MatchesMarginType(marginType MarginType) bool {
+	return marginType == "" || f.MarginType == marginType
+}
+
+// FilterByMarginType returns the rates of the given margin type, all of them
+// when it is empty
+func FilterByMarginType(rates []FundingRate, marginType MarginType) []FundingRate {
+	if marginType == "" {
+		return rates
+	}
+	filtered := make([]FundingRate, 0, len(rates))
+	for _, rate := range rates {
+		if rate.MatchesMarginType(marginType) {
+			filtered = append(filtered, rate)
+		}
+	}
+	return filtered
+}
+
+// Instrument is the venue independent identity of a contract
+type Instrument struct {
+	Base         string       `json:"base" mapstructure:"base"`
+	Quote        string       `json:"quote" mapstructure:"quote"`
+	ContractType ContractType `json:"contract_type" mapstructure:"contract_type"`
+	Expiry       string       `json:"expiry,omitempty" mapstructure:"expiry"` // YYYYMMDD, futures only
+}
+
+// Symbol returns the canonical symbol, e.g. BTCUSDT or BTCUSDT-20240628 for futures
+func (i Instrument) Symbol() string {
+	symbol := i.Base + i.Quote
+	if i.ContractType == ContractTypeFuture && i.Expiry != "" {
+		symbol += "-" + i.Expiry
+	}
+	return symbol
+}
+
+// FundingSnapshot is the set of funding rates collected by one polling cycle
+type FundingSnapshot struct {
+	Timestamp time.Time                 `json:"timestamp"`
+	Rates     []FundingRate             `json:"rates"`
+	Exchanges map[string]ExchangeStatus `json:"exchanges"`
+}
+
+// Outcomes of polling a single exchange
+const (
+	ExchangeStatusOK      = "ok"
+	ExchangeStatusTimeout = "timeout"
+	ExchangeStatusError   = "error"
+)
+
+// ExchangeStatus reports how polling one exchange went
+type ExchangeStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Rows      int    `json:"rows"`
+	Error     string `json:"error,omitempty"`
+	AsOf      int64  `json:"as_of,omitempty"` // unix time the rates were fetched
+	Stale     bool   `json:"stale"`           // rates are older than expected or the last refresh failed
+}
+
+// ArbitrageLeg is one side of a cross-exchange funding arbitrage
+type ArbitrageLeg struct {
+	Exchange             string     `json:"exchange"`
+	Symbol               string     `json:"symbol"`
+	FundingRate          float64    `json:"funding_rate"`
+	FundingIntervalHours int        `json:"funding_interval_hours"`
+	NextFundingTime      time.Time  `json:"next_funding_time"`
+	TimeToFunding        int64      `json:"time_to_funding"` // seconds until the next settlement
+	MarkPrice            float64    `json:"mark_price,omitempty"`
+	MarginType           MarginType `json:"margin_type,omitempty"`
+}
+
+// ArbitrageOpportunity pairs the same market on two exchanges: long where
+// funding is lowest, short where it is highest, collecting the spread.
+// Spread is expressed per 8h so legs with different cycles compare.
+type ArbitrageOpportunity struct {
+	Symbol          string       `json:"symbol"`
+	Long            ArbitrageLeg `json:"long"`
+	Short           ArbitrageLeg `json:"short"`
+	Spread          float64      `json:"spread"`
+	AnnualizedYield float64      `json:"annualized_yield"`
+}
+
+// ArbitrageFilter narrows down arbitrage opportunities
+type ArbitrageFilter struct {
+	MinSpread float64
+	Exchanges []string // both legs must be on one of these, empty means any
+	Symbol    string   // canonical symbol, empty means any
+	Limit     int      // 0 means unlimited
+
+	MarginType MarginType // both legs must have this margin type, empty means any
 }
 
 // ExchangeConfig holds configuration for each exchange
@@ -22,6 +258,25 @@ type ExchangeConfig struct {
 	APISecret string `mapstructure:"api_secret"`
 	BaseURL   string `mapstructure:"base_url"`
 	Enabled   bool   `mapstructure:"enabled"`
+	Timeout   int    `mapstructure:"timeout"` // seconds, overrides exchange_timeout
+
+	RefreshInterval int `mapstructure:"refresh_interval"` // seconds, overrides refresh_interval
+
+	// Requests per second and burst allowed by the shared HTTP transport;
+	// zero keeps the exchange's default limit
+	RateLimit float64 `mapstructure:"rate_limit"`
+	Burst     int     `mapstructure:"burst"`
+
+	// Endpoint of the coin-margined contracts on venues serving them from
+	// another host than BaseURL (Binance); empty skips them
+	InverseBaseURL string `mapstructure:"inverse_base_url"`
+}
+
+// HTTPConfig configures the HTTP transport shared by the exchange clients
+type HTTPConfig struct {
+	UserAgent  string `mapstructure:"user_agent"`
+	Proxy      string `mapstructure:"proxy"`       // http, https or socks5 URL; empty uses the environment
+	MaxRetries int    `mapstructure:"max_retries"` // retries of a request failing with 429, 5xx or a network error
 }
 
 // Config represents the main application configuration
@@ -30,10 +285,45 @@ type Config struct {
 	Exchanges       map[string]ExchangeConfig `mapstructure:"exchanges"`
 	LoggingInterval int                       `mapstructure:"logging_interval"` // in minutes
 	LogDirectory    string                    `mapstructure:"log_directory"`
+	ExchangeTimeout int                       `mapstructure:"exchange_timeout"` // seconds per exchange poll
+	RefreshInterval int                       `mapstructure:"refresh_interval"` // seconds between snapshot refreshes
+	LogStorage      string                    `mapstructure:"log_storage"`      // file (default) or sqlite
+	SQLitePath      string                    `mapstructure:"sqlite_path"`      // defaults to <log_directory>/funding.db
+
+	// HTTP configures the transport the exchange clients share
+	HTTP HTTPConfig `mapstructure:"http"`
+
+	// Alerts are evaluated after every snapshot refresh
+	Alerts AlertsConfig `mapstructure:"alerts"`
+
+	// Webhooks receive the rates of every logging cycle
+	Webhooks WebhooksConfig `mapstructure:"webhooks"`
+
+	// Digests post the most extreme rates to chat channels on a schedule
+	Digests DigestsConfig `mapstructure:"digests"`
+
+	// Metrics configures the Prometheus /metrics endpoint
+	Metrics MetricsConfig `mapstructure:"metrics"`
+
+	// Health sets the thresholds deriving exchange health from recent polls
+	Health HealthConfig `mapstructure:"health"`
+
+	// SymbolOverrides maps exchange -> venue symbol -> canonical instrument
+	// for markets the built-in parsers get wrong
+	SymbolOverrides map[string]map[string]Instrument `mapstructure:"symbol_overrides"`
+}
+
+// MetricsConfig controls the Prometheus /metrics endpoint
+type MetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// FundingRateSymbols get a funding rate gauge per exchange; none are
+	// exported when empty, which keeps the label cardinality bounded
+	FundingRateSymbols []string `mapstructure:"funding_rate_symbols"`
 }
 
 // ExchangeInfo represents exchange status information
 type ExchangeInfo struct {
 	Name    string `json:"name"`
 	Healthy bool   `json:"healthy"`
-} 
\ No newline at end of file
+}
@@ -3,7 +3,15 @@ package domain
 import "errors"
 
 var (
-	ErrExchangeNotFound = errors.New("exchange not found")
-	ErrInvalidConfig    = errors.New("invalid configuration")
-	ErrLogFileNotFound  = errors.New("log file not found")
-) 
\ No newline at end of file
+	ErrExchangeNotFound      = errors.New("exchange not found")
+	ErrInvalidConfig         = errors.New("invalid configuration")
+	ErrLogFileNotFound       = errors.New("log file not found")
+	ErrExchangeTimeout       = errors.New("exchange request timed out")
+	ErrInvalidResolution     = errors.New("invalid history resolution")
+	ErrInvalidAlertRule      = errors.New("invalid alert rule")
+	ErrDigestChannelNotFound = errors.New("digest channel not found")
+	ErrInvalidMarginType     = errors.New("invalid margin type")
+	ErrHistoryUnsupported    = errors.New("exchange has no funding history")
+	ErrInvalidBackfill       = errors.New("invalid backfill request")
+	ErrBackfillRunning       = errors.New("a backfill is already running")
+)
@@ -0,0 +1,23 @@
+package domain
+
+// ExchangeCapabilities lists what an exchange client reports besides the
+// current funding rate
+type ExchangeCapabilities struct {
+	MarkPrice       bool `json:"mark_price"`
+	IndexPrice      bool `json:"index_price"`
+	FundingInterval bool `json:"funding_interval"` // funding cycle per market
+	NextFundingTime bool `json:"next_funding_time"`
+	APIKey          bool `json:"api_key"` // sends the configured API key
+
+	PredictedFunding bool `json:"predicted_funding"` // projects the next settlement's rate
+	InverseContracts bool `json:"inverse_contracts"` // lists coin-margined perpetuals too
+}
+
+// ExchangeDescriptor describes a registered exchange and whether it is enabled
+type ExchangeDescriptor struct {
+	Name           string               `json:"name"`
+	DefaultBaseURL string               `json:"default_base_url"`
+	RateLimit      float64              `json:"rate_limit"` // default requests per second
+	Capabilities   ExchangeCapabilities `json:"capabilities"`
+	Enabled        bool                 `json:"enabled"`
+}
@@ -0,0 +1,103 @@
+package domain
+
+import "time"
+
+// Health states of an exchange and of the service as a whole
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+const (
+	// DefaultHealthWindow is how many recent polls the error rate covers
+	DefaultHealthWindow = 20
+	// DefaultDegradedErrorRate is the error rate above which an exchange is degraded
+	DefaultDegradedErrorRate = 0.25
+	// DefaultDownAfterFailures is how many failed polls in a row take an exchange down
+	DefaultDownAfterFailures = 5
+	// DefaultMaxStaleness is how long an exchange may go without a successful poll
+	DefaultMaxStaleness = 5 * time.Minute
+)
+
+// HealthConfig sets the thresholds deriving exchange health from recent polls.
+// Zero values fall back to the defaults.
+type HealthConfig struct {
+	Window            int     `mapstructure:"window"`              // polls kept per exchange
+	DegradedErrorRate float64 `mapstructure:"degraded_error_rate"` // 0-1 over the window
+	DownAfterFailures int     `mapstructure:"down_after_failures"`
+	MaxStaleness      int     `mapstructure:"max_staleness"` // seconds since the last success
+}
+
+// WindowSize returns how many recent polls the error rate covers
+func (c HealthConfig) WindowSize() int {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return DefaultHealthWindow
+}
+
+// ErrorRateThreshold returns the error rate above which an exchange is degraded
+func (c HealthConfig) ErrorRateThreshold() float64 {
+	if c.DegradedErrorRate > 0 {
+		return c.DegradedErrorRate
+	}
+	return DefaultDegradedErrorRate
+}
+
+// FailureLimit returns how many failed polls in a row take an exchange down
+func (c HealthConfig) FailureLimit() int {
+	if c.DownAfterFailures > 0 {
+		return c.DownAfterFailures
+	}
+	return DefaultDownAfterFailures
+}
+
+// StalenessLimit returns how long an exchange may go without a successful poll
+func (c HealthConfig) StalenessLimit() time.Duration {
+	if c.MaxStaleness > 0 {
+		return time.Duration(c.MaxStaleness) * time.Second
+	}
+	return DefaultMaxStaleness
+}
+
+// ExchangeHealth is the health of one exchange derived from its recent polls
+type ExchangeHealth struct {
+	Name                string  `json:"name"`
+	Status              string  `json:"status"` // healthy, degraded or down
+	Healthy             bool    `json:"healthy"`
+	Polls               int     `json:"polls"`      // polls in the window
+	ErrorRate           float64 `json:"error_rate"` // failed share of the window
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	LastSuccess         int64   `json:"last_success,omitempty"` // unix time
+	LastError           string  `json:"last_error,omitempty"`
+	LastErrorAt         int64   `json:"last_error_at,omitempty"` // unix time
+}
+
+// HealthReport is the health of every exchange and the overall status:
+// healthy when all exchanges are, down when none is usable, degraded otherwise
+type HealthReport struct {
+	Status    string                    `json:"status"`
+	Exchanges map[string]ExchangeHealth `json:"exchanges"`
+}
+
+// OverallHealth folds the exchange states into the service status
+func OverallHealth(exchanges map[string]ExchangeHealth) string {
+	healthy, down := 0, 0
+	for _, exchange := range exchanges {
+		switch exchange.Status {
+		case HealthHealthy:
+			healthy++
+		case HealthDown:
+			down++
+		}
+	}
+
+	switch {
+	case down == len(exchanges):
+		return HealthDown
+	case healthy == len(exchanges):
+		return HealthHealthy
+	}
+	return HealthDegraded
+}
@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// HistoryResolution is the bucket size of funding history queries
+type HistoryResolution string
+
+const (
+	ResolutionRaw HistoryResolution = "raw"
+	Resolution1h  HistoryResolution = "1h"
+	Resolution8h  HistoryResolution = "8h"
+	Resolution1d  HistoryResolution = "1d"
+)
+
+// ParseHistoryResolution parses a resolution, defaulting to raw when empty
+func ParseHistoryResolution(resolution string) (HistoryResolution, error) {
+	switch HistoryResolution(resolution) {
+	case "":
+		return ResolutionRaw, nil
+	case ResolutionRaw, Resolution1h, Resolution8h, Resolution1d:
+		return HistoryResolution(resolution), nil
+	}
+	return "", ErrInvalidResolution
+}
+
+// Duration returns the bucket size, 0 for raw points
+func (r HistoryResolution) Duration() time.Duration {
+	switch r {
+	case Resolution1h:
+		return time.Hour
+	case Resolution8h:
+		return 8 * time.Hour
+	case Resolution1d:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// HistoryQuery selects the logged funding rates of a symbol
+type HistoryQuery struct {
+	Symbol     string
+	Exchanges  []string  // all exchanges when empty
+	From       time.Time // inclusive, unbounded when zero
+	To         time.Time // exclusive, unbounded when zero
+	Resolution HistoryResolution
+}
+
+// Includes reports whether a point logged on exchange at timestamp matches the query
+func (q HistoryQuery) Includes(exchange string, timestamp time.Time) bool {
+	if !q.From.IsZero() && timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !timestamp.Before(q.To) {
+		return false
+	}
+	if len(q.Exchanges) == 0 {
+		return true
+	}
+	for _, name := range q.Exchanges {
+		if name == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregateFundingHistory sorts points by exchange and time and, for bucketed
+// resolutions, folds every exchange's points into UTC aligned buckets. A
+// bucket reports the mean rate and prices, the rate's OHLC and its sample
+// count, and is timestamped with its start. Points without a price, such as
+// backfilled settlements, are left out of that price's mean.
+func AggregateFundingHistory(points []FundingRateHistory, resolution HistoryResolution) []FundingRateHistory {
+	sort.SliceStable(points, func(i, j int) bool {
+		if points[i].Exchange != points[j].Exchange {
+			return points[i].Exchange < points[j].Exchange
+		}
+		return points[i].Timestamp < points[j].Timestamp
+	})
+
+	bucketSeconds := int64(resolution.Duration() / time.Second)
+	if bucketSeconds == 0 {
+		return points
+	}
+
+	aggregated := []FundingRateHistory{}
+	var markSamples, indexSamples []int
+	for _, point := range points {
+		start := point.Timestamp - point.Timestamp%bucketSeconds
+
+		last := len(aggregated) - 1
+		if last < 0 || aggregated[last].Exchange != point.Exchange || aggregated[last].Timestamp != start {
+			aggregated = append(aggregated, FundingRateHistory{
+				Exchange:  point.Exchange,
+				Timestamp: start,
+				OHLC: &FundingRateOHLC{
+					Open: point.FundingRate,
+					High: point.FundingRate,
+					Low:  point.FundingRate,
+				},
+			})
+			markSamples = append(markSamples, 0)
+			indexSamples = append(indexSamples, 0)
+			last++
+		}
+
+		// Sums are turned into means once the bucket is complete
+		bucket := &aggregated[last]
+		bucket.Samples++
+		bucket.FundingRate += point.FundingRate
+		if point.MarkPrice > 0 {
+			bucket.MarkPrice += point.MarkPrice
+			markSamples[last]++
+		}
+		if point.IndexPrice > 0 {
+			bucket.IndexPrice += point.IndexPrice
+			indexSamples[last]++
+		}
+		bucket.OHLC.Close = point.FundingRate
+		if point.FundingRate > bucket.OHLC.High {
+			bucket.OHLC.High = point.FundingRate
+		}
+		if point.FundingRate < bucket.OHLC.Low {
+			bucket.OHLC.Low = point.FundingRate
+		}
+	}
+
+	for i := range aggregated {
+		aggregated[i].FundingRate /= float64(aggregated[i].Samples)
+		if markSamples[i] > 0 {
+			aggregated[i].MarkPrice /= float64(markSamples[i])
+		}
+		if indexSamples[i] > 0 {
+			aggregated[i].IndexPrice /= float64(indexSamples[i])
+		}
+	}
+	return aggregated
+}
+
+// FundingSettlement is a funding rate an exchange settled, i.e. paid between
+// longs and shorts at FundingTime
+type FundingSettlement struct {
+	Exchange    string    `json:"exchange"`
+	Symbol      string    `json:"symbol"`
+	FundingRate float64   `json:"funding_rate"`
+	FundingTime time.Time `json:"funding_time"`
+}
+
+// SettlementQuery selects the settlements of a venue symbol
+type SettlementQuery struct {
+	Exchange string
+	Symbol   string
+	From     time.Time // inclusive
+	To       time.Time // exclusive
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestParseHistoryResolution(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected HistoryResolution
+		valid    bool
+	}{
+		{"", ResolutionRaw, true},
+		{"raw", ResolutionRaw, true},
+		{"1h", Resolution1h, true},
+		{"8h", Resolution8h, true},
+		{"1d", Resolution1d, true},
+		{"5m", "", false},
+	}
+
+	for _, tt := range tests {
+		resolution, err := ParseHistoryResolution(tt.value)
+		if tt.valid && (err != nil || resolution != tt.expected) {
+			t.Errorf("Expected %s for %q, got %s, %v", tt.expected, tt.value, resolution, err)
+		}
+		if !tt.valid && err != ErrInvalidResolution {
+			t.Errorf("Expected ErrInvalidResolution for %q, got %v", tt.value, err)
+		}
+	}
+}
+
+func TestAggregateFundingHistory(t *testing.T) {
+	// 2024-01-01 00:00 UTC, points deliberately out of order
+	const start = 1704067200
+	points := []FundingRateHistory{
+		{Exchange: "bybit", Timestamp: start + 60, FundingRate: 0.0005, MarkPrice: 100},
+		{Exchange: "binance", Timestamp: start + 7200, FundingRate: 0.0002, MarkPrice: 102},
+		{Exchange: "binance", Timestamp: start, FundingRate: 0.0001, MarkPrice: 100},
+		{Exchange: "binance", Timestamp: start + 3600, FundingRate: 0.0004, MarkPrice: 101},
+		{Exchange: "binance", Timestamp: start + 9*3600, FundingRate: -0.0001, MarkPrice: 99},
+	}
+
+	raw := AggregateFundingHistory(append([]FundingRateHistory(nil), points...), ResolutionRaw)
+	if len(raw) != len(points) || raw[0].Timestamp != start || raw[4].Exchange != "bybit" || raw[0].OHLC != nil {
+		t.Errorf("Expected raw points sorted by exchange and time, got %+v", raw)
+	}
+
+	buckets := AggregateFundingHistory(points, Resolution8h)
+	if len(buckets) != 3 {
+		t.Fatalf("Expected 3 buckets, got %+v", buckets)
+	}
+
+	first := buckets[0]
+	if first.Exchange != "binance" || first.Timestamp != start || first.Samples != 3 {
+		t.Errorf("Expected the first binance bucket of 3 samples, got %+v", first)
+	}
+	if first.OHLC == nil || *first.OHLC != (FundingRateOHLC{Open: 0.0001, High: 0.0004, Low: 0.0001, Close: 0.0002}) {
+		t.Errorf("Expected OHLC 0.0001/0.0004/0.0001/0.0002, got %+v", first.OHLC)
+	}
+	if diff := first.FundingRate - 0.0007/3; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("Expected mean rate %v, got %v", 0.0007/3, first.FundingRate)
+	}
+	if first.MarkPrice != 101 {
+		t.Errorf("Expected mean mark price 101, got %v", first.MarkPrice)
+	}
+
+	// Buckets align on the 00:00, 08:00 and 16:00 UTC settlements
+	if buckets[1].Exchange != "binance" || buckets[1].Timestamp != start+8*3600 || buckets[1].Samples != 1 {
+		t.Errorf("Expected the 08:00 binance bucket, got %+v", buckets[1])
+	}
+	if buckets[2].Exchange != "bybit" || buckets[2].Timestamp != start {
+		t.Errorf("Expected the bybit bucket, got %+v", buckets[2])
+	}
+}
+
+func TestAggregateFundingHistoryWithoutPrices(t *testing.T) {
+	// A backfilled settlement at 00:00 carries no prices, the polled points do
+	const start = 1704067200
+	points := []FundingRateHistory{
+		{Exchange: "binance", Timestamp: start, FundingRate: 0.0001},
+		{Exchange: "binance", Timestamp: start + 60, FundingRate: 0.0002, MarkPrice: 100, IndexPrice: 99},
+		{Exchange: "binance", Timestamp: start + 120, FundingRate: 0.0003, MarkPrice: 102},
+		{Exchange: "binance", Timestamp: start + 8*3600, FundingRate: 0.0004},
+	}
+
+	buckets := AggregateFundingHistory(points, Resolution8h)
+	if len(buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %+v", buckets)
+	}
+	if buckets[0].Samples != 3 || buckets[0].MarkPrice != 101 || buckets[0].IndexPrice != 99 {
+		t.Errorf("Expected 3 samples with mean prices 101/99 over the priced points, got %+v", buckets[0])
+	}
+	if diff := buckets[0].FundingRate - 0.0002; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("Expected mean rate 0.0002 over every sample, got %v", buckets[0].FundingRate)
+	}
+	if buckets[1].Samples != 1 || buckets[1].MarkPrice != 0 || buckets[1].IndexPrice != 0 {
+		t.Errorf("Expected a bucket of settlements only to have no prices, got %+v", buckets[1])
+	}
+}
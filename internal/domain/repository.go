@@ -1,20 +1,40 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// ExchangeRepository defines the contract for exchange data access
+// ExchangeRepository defines the contract for exchange data access.
+// Implementations must abort in-flight requests when ctx is cancelled.
 type ExchangeRepository interface {
-	GetFundingRates() ([]FundingRate, error)
+	GetFundingRates(ctx context.Context) ([]FundingRate, error)
 	GetName() string
-	IsHealthy() bool
+	IsHealthy(ctx context.Context) bool
 }
 
-// LogRepository defines the contract for logging operations
+// FundingHistoryRepository is implemented by exchanges serving the funding
+// rates they settled. GetFundingHistory returns the settlements of a venue
+// symbol within [start, end), oldest first, paging through the venue's API.
+type FundingHistoryRepository interface {
+	GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]FundingSettlement, error)
+}
+
+// SymbolNormalizer resolves venue specific symbols into canonical instruments
+type SymbolNormalizer interface {
+	Normalize(exchange string, symbol string) (Instrument, bool)
+}
+
+// LogRepository defines the contract for logging operations.
+// LogFundingRates stamps rates with the time they are logged, while
+// BackfillFundingRates keeps their own Timestamp and stores a single entry per
+// exchange, symbol and second, so writing the same rates again is harmless.
 type LogRepository interface {
 	LogFundingRates(symbol string, rates []FundingRate) error
+	BackfillFundingRates(symbol string, rates []FundingRate) error
 	GetSymbolLogs(symbol string, date string) ([]byte, error)
 	GetAllLogs() ([]LogFile, error)
-	GetHistoricalFundingRates(symbol string, exchange string) ([]FundingRateHistory, error)
+	GetHistoricalFundingRates(query HistoryQuery) ([]FundingRateHistory, error)
 }
 
 // LogFile represents a log file entry
@@ -26,8 +46,24 @@ type LogFile struct {
 	Modified time.Time `json:"modified"`
 }
 
-// FundingRateHistory represents a funding rate at a specific time for a symbol and exchange
+// FundingRateHistory represents a logged funding rate of a symbol on one
+// exchange, or a bucket of them for aggregated resolutions
 type FundingRateHistory struct {
-	Timestamp   int64   `json:"timestamp"`
+	Exchange    string  `json:"exchange"`
+	Timestamp   int64   `json:"timestamp"` // unix seconds, the bucket start when aggregated
 	FundingRate float64 `json:"funding_rate"`
+	MarkPrice   float64 `json:"mark_price"`
+	IndexPrice  float64 `json:"index_price"`
+
+	// Set for aggregated resolutions, whose rate and prices are bucket means
+	OHLC    *FundingRateOHLC `json:"ohlc,omitempty"`
+	Samples int              `json:"samples,omitempty"`
+}
+
+// FundingRateOHLC summarizes the funding rates within a history bucket
+type FundingRateOHLC struct {
+	Open  float64 `json:"open"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+	Close float64 `json:"close"`
 }
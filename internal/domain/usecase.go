@@ -1,12 +1,57 @@
 package domain
 
-// MultiExchangeUseCaseInterface defines the contract for multi-exchange use cases
+import "context"
+
+// MultiExchangeUseCaseInterface defines the contract for multi-exchange use cases.
+// Methods taking a context abort any exchange calls once it is cancelled.
 type MultiExchangeUseCaseInterface interface {
-	GetAllFundingRates() ([]FundingRate, error)
-	GetExchangeFundingRates(exchangeName string) ([]FundingRate, error)
-	GetExchangeInfo() map[string]ExchangeInfo
-	LogAllFundingRates() error
+	GetAllFundingRates(ctx context.Context) ([]FundingRate, error)
+	GetFundingSnapshot(ctx context.Context) (FundingSnapshot, error)
+	GetExchangeFundingRates(ctx context.Context, exchangeName string) ([]FundingRate, error)
+	RefreshFundingRates(ctx context.Context, exchangeNames ...string) error
+	GetExchangeInfo(ctx context.Context) map[string]ExchangeInfo
+	LogAllFundingRates(ctx context.Context) error
 	GetSymbolLogs(symbol string, date string) ([]byte, error)
 	GetAllLogs() ([]LogFile, error)
-	GetHistoricalFundingRates(symbol string, exchange string) ([]FundingRateHistory, error)
+	GetHistoricalFundingRates(query HistoryQuery) ([]FundingRateHistory, error)
+}
+
+// ArbitrageUseCaseInterface defines the contract for cross-exchange arbitrage use cases
+type ArbitrageUseCaseInterface interface {
+	GetOpportunities(ctx context.Context, filter ArbitrageFilter) ([]ArbitrageOpportunity, error)
+}
+
+// AlertUseCaseInterface defines the contract for alerting use cases
+type AlertUseCaseInterface interface {
+	GetAlerts(filter AlertFilter) []Alert
+	GetAlertRules() []AlertRule
+}
+
+// SettlementUseCaseInterface defines the contract for reading settled funding
+// rates from the exchanges
+type SettlementUseCaseInterface interface {
+	GetSettlements(ctx context.Context, query SettlementQuery) ([]FundingSettlement, error)
+}
+
+// BackfillUseCaseInterface defines the contract for storing the settled
+// funding history of the exchanges in the background
+type BackfillUseCaseInterface interface {
+	StartBackfill(request BackfillRequest) (BackfillProgress, error)
+	GetBackfillProgress() (BackfillProgress, bool)
+}
+
+// HealthUseCaseInterface defines the contract for service health checks
+type HealthUseCaseInterface interface {
+	GetHealth() HealthReport
+	IsReady() bool
+}
+
+// FundingListener is notified with the snapshot produced by every polling cycle
+type FundingListener interface {
+	OnFundingSnapshot(snapshot FundingSnapshot)
+}
+
+// PollObserver is told the outcome of every exchange poll
+type PollObserver interface {
+	ObservePoll(exchange string, status ExchangeStatus)
 }
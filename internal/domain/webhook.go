@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// WebhookTarget is an endpoint receiving the funding rates of every logging
+// cycle. Rates are selected like /api/funding-top: their magnitude on Basis
+// must exceed Top, and every rate is sent when Top is 0.
+type WebhookTarget struct {
+	URL       string   `mapstructure:"url"`
+	Secret    string   `mapstructure:"secret"`    // HMAC-SHA256 key, unsigned when empty
+	Symbols   []string `mapstructure:"symbols"`   // venue or canonical symbols, empty means any
+	Exchanges []string `mapstructure:"exchanges"` // empty means any
+	Top       float64  `mapstructure:"top"`
+	Basis     string   `mapstructure:"basis"`   // raw (default), 1h, 8h or apr
+	Timeout   int      `mapstructure:"timeout"` // seconds per delivery attempt
+}
+
+// Validate checks the target's URL, threshold and basis
+func (t WebhookTarget) Validate() error {
+	parsed, err := url.Parse(t.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: invalid webhook url %q", ErrInvalidConfig, t.URL)
+	}
+	if t.Top < 0 {
+		return fmt.Errorf("%w: webhook %s has a negative top", ErrInvalidConfig, t.URL)
+	}
+	if t.Basis != "" && !ValidRateBasis(t.Basis) {
+		return fmt.Errorf("%w: webhook %s has unknown basis %q", ErrInvalidConfig, t.URL, t.Basis)
+	}
+	return nil
+}
+
+// Matches reports whether the rate is sent to the target
+func (t WebhookTarget) Matches(rate FundingRate) bool {
+	if len(t.Exchanges) > 0 && !containsFold(t.Exchanges, rate.Exchange) {
+		return false
+	}
+	if len(t.Symbols) > 0 && !containsFold(t.Symbols, rate.Symbol) && !containsFold(t.Symbols, rate.MarketSymbol()) {
+		return false
+	}
+	if t.Top > 0 {
+		basis := t.Basis
+		if basis == "" {
+			basis = RateBasisRaw
+		}
+		return rate.ExceedsRate(t.Top, basis)
+	}
+	return true
+}
+
+// WebhooksConfig holds the webhook targets and their delivery policy
+type WebhooksConfig struct {
+	Targets        []WebhookTarget `mapstructure:"targets"`
+	MaxAttempts    int             `mapstructure:"max_attempts"`     // deliveries tried before dead-lettering
+	DeadLetterPath string          `mapstructure:"dead_letter_path"` // defaults to <log_directory>/webhook_dead_letters.jsonl
+
+	DeadLetterRetryInterval int `mapstructure:"dead_letter_retry_interval"` // seconds between dead letter retries, 300 by default
+}
@@ -0,0 +1,29 @@
+package infrastructure
+
+import (
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogAlertNotifier writes fired alerts to the application log
+type LogAlertNotifier struct {
+	logger *logrus.Logger
+}
+
+func NewLogAlertNotifier(logger *logrus.Logger) *LogAlertNotifier {
+	return &LogAlertNotifier{
+		logger: logger,
+	}
+}
+
+// Notify logs the alert as a warning
+func (n *LogAlertNotifier) Notify(alert domain.Alert) error {
+	n.logger.WithFields(logrus.Fields{
+		"rule":      alert.Rule,
+		"type":      alert.Type,
+		"symbol":    alert.Symbol,
+		"exchanges": alert.Exchanges,
+	}).Warnf("Funding alert: %s", alert.Message)
+	return nil
+}
@@ -1,51 +1,95 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// BinanceClient reads the USDⓈ-M perpetuals, and the COIN-M ones when an
+// inverse base URL is set. The premium index carries the rate of the running
+// cycle, misleadingly named lastFundingRate; settled rates come from the
+// funding rate history.
 type BinanceClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
 }
 
 type BinanceFundingRate struct {
 	Symbol               string `json:"symbol"`
-	MarkPrice           string `json:"markPrice"`
-	IndexPrice          string `json:"indexPrice"`
-	LastFundingRate     string `json:"lastFundingRate"`
-	NextFundingTime     int64  `json:"nextFundingTime"`
-	FundingRate         string `json:"fundingRate"`
+	MarkPrice            string `json:"markPrice"`
+	IndexPrice           string `json:"indexPrice"`
+	LastFundingRate      string `json:"lastFundingRate"`
+	NextFundingTime      int64  `json:"nextFundingTime"`
+	FundingRate          string `json:"fundingRate"`
 	FundingRatePrecision string `json:"fundingRatePrecision"`
-	Time                int64  `json:"time"`
+	Time                 int64  `json:"time"`
+}
+
+// BinanceFundingRateHistory is a settlement of the funding rate history
+type BinanceFundingRateHistory struct {
+	Symbol      string `json:"symbol"`
+	FundingTime int64  `json:"fundingTime"`
+	FundingRate string `json:"fundingRate"`
+	MarkPrice   string `json:"markPrice"`
+}
+
+// binanceHistoryLimit is the most settlements a funding rate history request returns
+const binanceHistoryLimit = 1000
+
+// BinanceFundingInfo lists symbols whose funding cycle differs from the 8h default
+type BinanceFundingInfo struct {
+	Symbol               string `json:"symbol"`
+	FundingIntervalHours int    `json:"fundingIntervalHours"`
 }
 
-func NewBinanceClient(config domain.ExchangeConfig, logger *logrus.Logger) *BinanceClient {
-	return &BinanceClient{
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "binance",
+		DefaultBaseURL: "https://fapi.binance.com",
+		RateLimit:      4, // premiumIndex costs 10 of the 2400 weight allowed per minute
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, APIKey: true, InverseContracts: true},
+		SymbolParser:   parseBinanceSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewBinanceClient(config, client, logger)
+		},
+		DefaultInverseBaseURL: "https://dapi.binance.com",
+	})
+}
+
+func NewBinanceClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *BinanceClient {
+	b := &BinanceClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	b.settlements = newSettlementTracker(b, time.Now, logger)
+	return b
 }
 
 func (b *BinanceClient) GetName() string {
 	return "binance"
 }
 
-func (b *BinanceClient) IsHealthy() bool {
+func (b *BinanceClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/fapi/v1/premiumIndex", b.config.BaseURL)
-	resp, err := b.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := b.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -53,10 +97,10 @@ func (b *BinanceClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (b *BinanceClient) GetFundingRates() ([]domain.FundingRate, error) {
+func (b *BinanceClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
 	url := fmt.Sprintf("%s/fapi/v1/premiumIndex", b.config.BaseURL)
-	
-	req, err := http.NewRequest("GET", url, nil)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -87,6 +131,33 @@ func (b *BinanceClient) GetFundingRates() ([]domain.FundingRate, error) {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	intervals, err := b.getFundingIntervals(ctx)
+	if err != nil {
+		b.logger.Warnf("Failed to get funding intervals from Binance, assuming 8h: %v", err)
+	}
+
+	rates := b.toFundingRates(binanceRates, intervals, domain.MarginTypeLinear)
+
+	// Coin-margined perpetuals are served by the separate COIN-M API
+	if b.config.InverseBaseURL != "" {
+		inverseRates, err := b.getInverseFundingRates(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			b.logger.Warnf("Failed to get coin-margined funding rates from Binance: %v", err)
+		}
+		rates = append(rates, inverseRates...)
+	}
+
+	b.settlements.update(ctx, rates)
+
+	b.logger.Infof("Retrieved %d funding rates from Binance", len(rates))
+	return rates, nil
+}
+
+// toFundingRates converts premium index entries of the given margin type
+func (b *BinanceClient) toFundingRates(binanceRates []BinanceFundingRate, intervals map[string]int, marginType domain.MarginType) []domain.FundingRate {
 	var rates []domain.FundingRate
 	for _, rate := range binanceRates {
 		fundingRate, err := strconv.ParseFloat(rate.LastFundingRate, 64)
@@ -105,23 +176,166 @@ func (b *BinanceClient) GetFundingRates() ([]domain.FundingRate, error) {
 			b.logger.Warnf("Failed to parse index price for %s: %v", rate.Symbol, err)
 		}
 
-		lastFundingRate, err := strconv.ParseFloat(rate.LastFundingRate, 64)
-		if err != nil {
-			b.logger.Warnf("Failed to parse last funding rate for %s: %v", rate.Symbol, err)
+		fundingIntervalHours := domain.DefaultFundingIntervalHours
+		if hours, ok := intervals[rate.Symbol]; ok && hours > 0 {
+			fundingIntervalHours = hours
 		}
 
 		rates = append(rates, domain.FundingRate{
-			Symbol:           rate.Symbol,
-			Exchange:         b.GetName(),
-			FundingRate:      fundingRate,
-			NextFundingTime:  time.Unix(rate.NextFundingTime/1000, 0),
-			Timestamp:        time.Now(),
-			MarkPrice:        markPrice,
-			IndexPrice:       indexPrice,
-			LastFundingRate:  lastFundingRate,
+			Symbol:               rate.Symbol,
+			Exchange:             b.GetName(),
+			FundingRate:          fundingRate,
+			NextFundingTime:      time.Unix(rate.NextFundingTime/1000, 0),
+			Timestamp:            time.Now(),
+			MarkPrice:            markPrice,
+			IndexPrice:           indexPrice,
+			FundingIntervalHours: fundingIntervalHours,
+			MarginType:           marginType,
 		})
 	}
+	return rates
+}
 
-	b.logger.Infof("Retrieved %d funding rates from Binance", len(rates))
-	return rates, nil
-} 
\ No newline at end of file
+// getInverseFundingRates returns the coin-margined perpetuals, settled every
+// 8h. The COIN-M premium index also lists quarterlies, without funding.
+func (b *BinanceClient) getInverseFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	url := fmt.Sprintf("%s/dapi/v1/premiumIndex", b.config.InverseBaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if b.config.APIKey != "" {
+		req.Header.Set("X-MBX-APIKEY", b.config.APIKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var premiumIndex []BinanceFundingRate
+	if err := json.NewDecoder(resp.Body).Decode(&premiumIndex); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	perpetuals := make([]BinanceFundingRate, 0, len(premiumIndex))
+	for _, rate := range premiumIndex {
+		if strings.HasSuffix(rate.Symbol, "_PERP") {
+			perpetuals = append(perpetuals, rate)
+		}
+	}
+	return b.toFundingRates(perpetuals, nil, domain.MarginTypeInverse), nil
+}
+
+// GetFundingHistory reads a symbol's settlements, from the COIN-M API for
+// coin-margined perpetuals. Binance serves them oldest first.
+func (b *BinanceClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	endpoint := b.config.BaseURL + "/fapi/v1/fundingRate"
+	if strings.HasSuffix(symbol, "_PERP") {
+		endpoint = b.config.InverseBaseURL + "/dapi/v1/fundingRate"
+	}
+
+	return pageSettlementsForward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"symbol":    {symbol},
+			"startTime": {strconv.FormatInt(bound.UnixMilli(), 10)},
+			"endTime":   {strconv.FormatInt(end.UnixMilli()-1, 10)},
+			"limit":     {strconv.Itoa(binanceHistoryLimit)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+		if b.config.APIKey != "" {
+			req.Header.Set("X-MBX-APIKEY", b.config.APIKey)
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var history []BinanceFundingRateHistory
+		if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range history {
+			fundingRate, err := strconv.ParseFloat(entry.FundingRate, 64)
+			if err != nil {
+				b.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    b.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(entry.FundingTime),
+			})
+		}
+		return settlements, len(history) >= binanceHistoryLimit, nil
+	})
+}
+
+// getFundingIntervals returns the funding cycle of every symbol not on the 8h default
+func (b *BinanceClient) getFundingIntervals(ctx context.Context) (map[string]int, error) {
+	url := fmt.Sprintf("%s/fapi/v1/fundingInfo", b.config.BaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var fundingInfo []BinanceFundingInfo
+	if err := json.NewDecoder(resp.Body).Decode(&fundingInfo); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	intervals := make(map[string]int, len(fundingInfo))
+	for _, info := range fundingInfo {
+		intervals[info.Symbol] = info.FundingIntervalHours
+	}
+	return intervals, nil
+}
+
+// parseBinanceSymbol handles BTCUSDT perpetuals and BTCUSDT_240628 quarterlies
+func parseBinanceSymbol(symbol string) (domain.Instrument, bool) {
+	name, suffix, dated := strings.Cut(symbol, "_")
+	base, quote, ok := splitConcatenatedSymbol(name)
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	if !dated || suffix == "PERP" {
+		return perpetualInstrument(base, quote), true
+	}
+	expiry, ok := parseExpiry("060102", suffix)
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	return futureInstrument(base, quote, expiry), true
+}
@@ -0,0 +1,275 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fundingmonitor/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBinanceClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			w.Write([]byte(`[
+				{"symbol":"BTCUSDT","markPrice":"50000.10","indexPrice":"49990.00","lastFundingRate":"0.00010000","nextFundingTime":1704096000000,"time":1704090000000},
+				{"symbol":"BLZUSDT","markPrice":"0.25","indexPrice":"0.25","lastFundingRate":"-0.00200000","nextFundingTime":1704081600000,"time":1704090000000}
+			]`))
+		case "/fapi/v1/fundingInfo":
+			w.Write([]byte(`[{"symbol":"BLZUSDT","adjustedFundingRateCap":"0.02","adjustedFundingRateFloor":"-0.02","fundingIntervalHours":4}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(rates) != 2 {
+		t.Fatalf("Expected 2 rates, got %d", len(rates))
+	}
+
+	intervals := map[string]int{}
+	for _, rate := range rates {
+		intervals[rate.Symbol] = rate.FundingIntervalHours
+	}
+	if intervals["BTCUSDT"] != 8 {
+		t.Errorf("Expected default 8h interval for BTCUSDT, got %d", intervals["BTCUSDT"])
+	}
+	if intervals["BLZUSDT"] != 4 {
+		t.Errorf("Expected 4h interval for BLZUSDT, got %d", intervals["BLZUSDT"])
+	}
+}
+
+func TestBinanceClient_GetFundingRatesWithCoinMargined(t *testing.T) {
+	var inverseFailing bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","markPrice":"50000.10","indexPrice":"49990.00","lastFundingRate":"0.00010000","nextFundingTime":1704096000000,"time":1704090000000}]`))
+		case "/dapi/v1/premiumIndex":
+			if inverseFailing {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`[
+				{"symbol":"BTCUSD_PERP","pair":"BTCUSD","markPrice":"50003.2","indexPrice":"49995.1","estimatedSettlePrice":"49980.3","lastFundingRate":"0.00008312","interestRate":"0.00010000","nextFundingTime":1704096000000,"time":1704090000000},
+				{"symbol":"BTCUSD_240628","pair":"BTCUSD","markPrice":"52110.5","indexPrice":"49995.1","estimatedSettlePrice":"49980.3","lastFundingRate":"","interestRate":"","nextFundingTime":0,"time":1704090000000}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL, InverseBaseURL: server.URL}, http.DefaultClient, logger)
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The BTCUSD_240628 quarterly has no funding and is skipped
+	if len(rates) != 2 {
+		t.Fatalf("Expected 2 rates, got %d", len(rates))
+	}
+	marginTypes := map[string]domain.MarginType{}
+	for _, rate := range rates {
+		marginTypes[rate.Symbol] = rate.MarginType
+	}
+	if marginTypes["BTCUSDT"] != domain.MarginTypeLinear || marginTypes["BTCUSD_PERP"] != domain.MarginTypeInverse {
+		t.Errorf("Expected BTCUSDT linear and BTCUSD_PERP inverse, got %v", marginTypes)
+	}
+
+	// The USDT-M rates are still served when the COIN-M API fails
+	inverseFailing = true
+	rates, err = client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 1 || rates[0].Symbol != "BTCUSDT" {
+		t.Errorf("Expected only BTCUSDT, got %+v", rates)
+	}
+}
+
+func TestBinanceClient_GetFundingRatesSettledRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","markPrice":"50000.10","indexPrice":"49990.00","lastFundingRate":"0.00010000","nextFundingTime":1704096000000,"time":1704090000000}]`))
+		case "/fapi/v1/fundingRate":
+			if r.URL.Query().Get("symbol") != "BTCUSDT" || r.URL.Query().Get("startTime") == "" {
+				t.Errorf("Expected a bounded BTCUSDT history request, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[
+				{"symbol":"BTCUSDT","fundingTime":1704038400000,"fundingRate":"0.00020000","markPrice":"42310.2"},
+				{"symbol":"BTCUSDT","fundingTime":1704067200000,"fundingRate":"0.00023000","markPrice":"42580.9"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 1 {
+		t.Fatalf("Expected 1 rate, got %d", len(rates))
+	}
+
+	// The premium index lastFundingRate is the running rate, not the settled one
+	rate := rates[0]
+	if rate.FundingRate != 0.0001 {
+		t.Errorf("Expected the running rate 0.0001, got %v", rate.FundingRate)
+	}
+	if rate.LastFundingRate != 0.00023 || rate.LastFundingTime == nil || !rate.LastFundingTime.Equal(time.UnixMilli(1704067200000)) {
+		t.Errorf("Expected the last settlement 0.00023 at %v, got %v at %v", time.UnixMilli(1704067200000), rate.LastFundingRate, rate.LastFundingTime)
+	}
+}
+
+// newBinanceHistoryServer serves a settlement every 8h since 2023, oldest
+// first and at most limit per request like Binance, and counts the requests
+func newBinanceHistoryServer(t *testing.T, path string, requests *int) *httptest.Server {
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		*requests++
+		mu.Unlock()
+
+		query := r.URL.Query()
+		startTime, _ := strconv.ParseInt(query.Get("startTime"), 10, 64)
+		endTime, _ := strconv.ParseInt(query.Get("endTime"), 10, 64)
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		var entries []string
+		for at := first; at.UnixMilli() <= endTime && len(entries) < limit; at = at.Add(8 * time.Hour) {
+			if at.UnixMilli() < startTime {
+				continue
+			}
+			entries = append(entries, fmt.Sprintf(`{"symbol":%q,"fundingTime":%d,"fundingRate":"0.00010000"}`, query.Get("symbol"), at.UnixMilli()))
+		}
+		w.Write([]byte("[" + strings.Join(entries, ",") + "]"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBinanceClient_GetFundingHistory(t *testing.T) {
+	var requests int
+	server := newBinanceHistoryServer(t, "/fapi/v1/fundingRate", &requests)
+
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "BTCUSDT", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 396 days of 8h settlements take two pages of 1000
+	if len(settlements) != 396*3 {
+		t.Fatalf("Expected %d settlements, got %d", 396*3, len(settlements))
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if !settlements[0].FundingTime.Equal(start) || !settlements[len(settlements)-1].FundingTime.Equal(end.Add(-8*time.Hour)) {
+		t.Errorf("Expected settlements from %v until before %v, got %v to %v", start, end, settlements[0].FundingTime, settlements[len(settlements)-1].FundingTime)
+	}
+	for i, settlement := range settlements {
+		if i > 0 && !settlement.FundingTime.Equal(settlements[i-1].FundingTime.Add(8*time.Hour)) {
+			t.Fatalf("Expected consecutive settlements, got %v after %v", settlement.FundingTime, settlements[i-1].FundingTime)
+		}
+		if settlement.Exchange != "binance" || settlement.Symbol != "BTCUSDT" || settlement.FundingRate != 0.0001 {
+			t.Fatalf("Unexpected settlement %+v", settlement)
+		}
+	}
+}
+
+func TestBinanceClient_GetFundingHistoryCoinMargined(t *testing.T) {
+	var requests int
+	server := newBinanceHistoryServer(t, "/dapi/v1/fundingRate", &requests)
+
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: "http://127.0.0.1:0", InverseBaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "BTCUSD_PERP", start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(settlements) != 3 || requests != 1 {
+		t.Errorf("Expected 3 settlements in 1 request from the COIN-M API, got %d in %d", len(settlements), requests)
+	}
+}
+
+func TestBinanceClient_GetFundingRatesWithoutFundingInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/premiumIndex" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"symbol":"BTCUSDT","markPrice":"50000","indexPrice":"50000","lastFundingRate":"0.0001","nextFundingTime":1704096000000}]`))
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logger)
+
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected rates despite missing funding info, got %v", err)
+	}
+	if len(rates) != 1 || rates[0].FundingIntervalHours != 8 {
+		t.Errorf("Expected one rate with the 8h default, got %+v", rates)
+	}
+}
+
+func TestBinanceClient_GetFundingRatesCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	client := NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Now()
+	if _, err := client.GetFundingRates(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to be aborted, took %v", elapsed)
+	}
+}
@@ -0,0 +1,218 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BingXClient reads the BingX USDT perpetuals. The premium index only
+// carries the running rate, settled rates come from the funding rate history.
+type BingXClient struct {
+	config domain.ExchangeConfig
+	logger *logrus.Logger
+	client *http.Client
+
+	settlements *settlementTracker
+}
+
+// BingXPremiumIndex is an entry of the premium index endpoint, which carries
+// the funding rate of the current cycle next to mark and index price
+type BingXPremiumIndex struct {
+	Symbol          string `json:"symbol"`
+	MarkPrice       string `json:"markPrice"`
+	IndexPrice      string `json:"indexPrice"`
+	LastFundingRate string `json:"lastFundingRate"`
+	NextFundingTime int64  `json:"nextFundingTime"`
+}
+
+type BingXPremiumIndexResponse struct {
+	Code int                 `json:"code"`
+	Msg  string              `json:"msg"`
+	Data []BingXPremiumIndex `json:"data"`
+}
+
+// BingXFundingRateHistory is a settlement of the funding rate history
+type BingXFundingRateHistory struct {
+	Symbol      string `json:"symbol"`
+	FundingRate string `json:"fundingRate"`
+	FundingTime int64  `json:"fundingTime"`
+}
+
+type BingXFundingRateHistoryResponse struct {
+	Code int                       `json:"code"`
+	Msg  string                    `json:"msg"`
+	Data []BingXFundingRateHistory `json:"data"`
+}
+
+// bingxHistoryLimit is the most settlements a funding rate history request returns
+const bingxHistoryLimit = 1000
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "bingx",
+		DefaultBaseURL: "https://open-api.bingx.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, NextFundingTime: true},
+		SymbolParser:   parseBingXSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewBingXClient(config, client, logger)
+		},
+	})
+}
+
+func NewBingXClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *BingXClient {
+	b := &BingXClient{
+		config: config,
+		logger: logger,
+		client: client,
+	}
+	b.settlements = newSettlementTracker(b, time.Now, logger)
+	return b
+}
+
+func (b *BingXClient) GetName() string {
+	return "bingx"
+}
+
+func (b *BingXClient) IsHealthy(ctx context.Context) bool {
+	url := fmt.Sprintf("%s/openApi/swap/v2/server/time", b.config.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (b *BingXClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	url := fmt.Sprintf("%s/openApi/swap/v2/quote/premiumIndex", b.config.BaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response BingXPremiumIndexResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.Code != 0 {
+		return nil, fmt.Errorf("API returned error: %s", response.Msg)
+	}
+
+	var rates []domain.FundingRate
+	for _, rate := range response.Data {
+		fundingRate, err := strconv.ParseFloat(rate.LastFundingRate, 64)
+		if err != nil {
+			b.logger.Warnf("Failed to parse funding rate for %s: %v", rate.Symbol, err)
+			continue
+		}
+
+		markPrice, err := strconv.ParseFloat(rate.MarkPrice, 64)
+		if err != nil {
+			b.logger.Warnf("Failed to parse mark price for %s: %v", rate.Symbol, err)
+		}
+
+		indexPrice, err := strconv.ParseFloat(rate.IndexPrice, 64)
+		if err != nil {
+			b.logger.Warnf("Failed to parse index price for %s: %v", rate.Symbol, err)
+		}
+
+		rates = append(rates, domain.FundingRate{
+			Symbol:          rate.Symbol,
+			Exchange:        b.GetName(),
+			FundingRate:     fundingRate,
+			NextFundingTime: time.UnixMilli(rate.NextFundingTime),
+			Timestamp:       time.Now(),
+			MarkPrice:       markPrice,
+			IndexPrice:      indexPrice,
+			MarginType:      domain.MarginTypeLinear,
+		})
+	}
+
+	b.settlements.update(ctx, rates)
+
+	b.logger.Infof("Retrieved %d funding rates from BingX", len(rates))
+	return rates, nil
+}
+
+// GetFundingHistory reads a perpetual's settlements, asking for ever earlier
+// ones while BingX returns full pages
+func (b *BingXClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsBackward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"symbol":    {symbol},
+			"startTime": {strconv.FormatInt(start.UnixMilli(), 10)},
+			"endTime":   {strconv.FormatInt(bound.UnixMilli(), 10)},
+			"limit":     {strconv.Itoa(bingxHistoryLimit)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", b.config.BaseURL+"/openApi/swap/v2/quote/fundingRate?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var response BingXFundingRateHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if response.Code != 0 {
+			return nil, false, fmt.Errorf("API returned error: %s", response.Msg)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range response.Data {
+			fundingRate, err := strconv.ParseFloat(entry.FundingRate, 64)
+			if err != nil {
+				b.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    b.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(entry.FundingTime),
+			})
+		}
+		return settlements, len(response.Data) >= bingxHistoryLimit, nil
+	})
+}
+
+// parseBingXSymbol handles BTC-USDT perpetuals
+func parseBingXSymbol(symbol string) (domain.Instrument, bool) {
+	return parseDashSymbol(symbol)
+}
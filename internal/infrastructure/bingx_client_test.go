@@ -0,0 +1,104 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBingXClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openApi/swap/v2/quote/premiumIndex" {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "bingx", "premium_index.json"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	client := NewBingXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The delisted NCSKGME2USD-USDT has no funding rate and is skipped
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		markPrice   float64
+		indexPrice  float64
+		nextFunding int64
+	}{
+		{"BTC-USDT", 0.0001, 42652.3, 42648.9, 1704096000000},
+		{"ETH-USDT", -0.00003125, 2250.51, 2249.95, 1704096000000},
+		{"1000PEPE-USDT", 0.00052, 0.0013021, 0.001301, 1704081600000},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.Exchange != "bingx" {
+			t.Errorf("Expected exchange bingx for %s, got %s", tt.symbol, rate.Exchange)
+		}
+		if rate.FundingRate != tt.fundingRate {
+			t.Errorf("Expected funding rate %v for %s, got %v", tt.fundingRate, tt.symbol, rate.FundingRate)
+		}
+		if rate.MarkPrice != tt.markPrice || rate.IndexPrice != tt.indexPrice {
+			t.Errorf("Expected mark/index %v/%v for %s, got %v/%v", tt.markPrice, tt.indexPrice, tt.symbol, rate.MarkPrice, rate.IndexPrice)
+		}
+		if !rate.NextFundingTime.Equal(time.UnixMilli(tt.nextFunding)) {
+			t.Errorf("Expected next funding time %v for %s, got %v", time.UnixMilli(tt.nextFunding), tt.symbol, rate.NextFundingTime)
+		}
+	}
+}
+
+func TestBingXClient_GetFundingRatesError(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"http error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}},
+		{"api error", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"code":100410,"msg":"rate limitation","data":[]}`))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := NewBingXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+			if _, err := client.GetFundingRates(context.Background()); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -1,21 +1,28 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// BitgetClient reads the USDT margined Bitget perpetuals. Tickers only carry
+// the running rate, settled rates come from the funding history.
 type BitgetClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
 }
 
 type BitgetTicker struct {
@@ -49,23 +56,59 @@ type BitgetTickersResponse struct {
 	Data        []BitgetTicker `json:"data"`
 }
 
-func NewBitgetClient(config domain.ExchangeConfig, logger *logrus.Logger) *BitgetClient {
-	return &BitgetClient{
+// BitgetFundingRateHistory is a settlement of the funding rate history
+type BitgetFundingRateHistory struct {
+	Symbol      string `json:"symbol"`
+	FundingRate string `json:"fundingRate"`
+	SettleTime  string `json:"settleTime"`
+}
+
+type BitgetFundingRateHistoryResponse struct {
+	Code string                     `json:"code"`
+	Msg  string                     `json:"msg"`
+	Data []BitgetFundingRateHistory `json:"data"`
+}
+
+// bitgetHistoryPageSize is the settlements asked per funding rate history page
+const bitgetHistoryPageSize = 100
+
+// Bitget settles most perpetuals every 8h at 00:00, 08:00 and 16:00 UTC; the
+// tickers don't tell the few on shorter cycles apart
+const bitgetFundingIntervalHours = 8
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "bitget",
+		DefaultBaseURL: "https://api.bitget.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, NextFundingTime: true},
+		SymbolParser:   parseBitgetSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewBitgetClient(config, client, logger)
+		},
+	})
+}
+
+func NewBitgetClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *BitgetClient {
+	b := &BitgetClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	b.settlements = newSettlementTracker(b, time.Now, logger)
+	return b
 }
 
 func (b *BitgetClient) GetName() string {
 	return "bitget"
 }
 
-func (b *BitgetClient) IsHealthy() bool {
+func (b *BitgetClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/api/mix/v1/market/contracts?productType=umcbl", b.config.BaseURL)
-	resp, err := b.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := b.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -73,11 +116,11 @@ func (b *BitgetClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (b *BitgetClient) GetFundingRates() ([]domain.FundingRate, error) {
+func (b *BitgetClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
 	// Use the bulk tickers endpoint instead of individual calls
 	tickersURL := fmt.Sprintf("%s/api/mix/v1/market/tickers?productType=umcbl", b.config.BaseURL)
 
-	req, err := http.NewRequest("GET", tickersURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", tickersURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tickers request: %w", err)
 	}
@@ -123,19 +166,101 @@ func (b *BitgetClient) GetFundingRates() ([]domain.FundingRate, error) {
 
 		indexPrice, _ := strconv.ParseFloat(ticker.IndexPrice, 64)
 		timestamp, _ := strconv.ParseInt(ticker.Timestamp, 10, 64)
+		updated := time.Now()
+		if timestamp > 0 {
+			updated = time.UnixMilli(timestamp)
+		}
 
 		rates = append(rates, domain.FundingRate{
 			Symbol:          ticker.Symbol,
 			Exchange:        b.GetName(),
 			FundingRate:     fundingRate,
-			NextFundingTime: time.Now().Add(8 * time.Hour), // Bitget funding occurs every 8 hours
+			NextFundingTime: nextBitgetFundingTime(updated),
 			Timestamp:       time.Unix(timestamp/1000, 0),
 			MarkPrice:       0, // Not provided in ticker endpoint
 			IndexPrice:      indexPrice,
-			LastFundingRate: 0, // Not provided in ticker endpoint
+			MarginType:      domain.MarginTypeLinear, // umcbl lists the USDT margined contracts
 		})
 	}
 
+	b.settlements.update(ctx, rates)
+
 	b.logger.Infof("Retrieved %d funding rates from Bitget", len(rates))
 	return rates, nil
 }
+
+// GetFundingHistory reads a contract's settlements, which Bitget serves
+// newest first in numbered pages
+func (b *BitgetClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsByNumber(start, end, func(number int) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"symbol":   {symbol},
+			"pageSize": {strconv.Itoa(bitgetHistoryPageSize)},
+			"pageNo":   {strconv.Itoa(number)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", b.config.BaseURL+"/api/mix/v1/market/history-fundRate?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var response BitgetFundingRateHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if response.Code != "00000" {
+			return nil, false, fmt.Errorf("Bitget API error: %s", response.Msg)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range response.Data {
+			fundingRate, err := strconv.ParseFloat(entry.FundingRate, 64)
+			if err != nil {
+				b.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			settleTime, err := strconv.ParseInt(entry.SettleTime, 10, 64)
+			if err != nil {
+				b.logger.Warnf("Failed to parse funding time for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    b.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(settleTime),
+			})
+		}
+		return settlements, len(response.Data) >= bitgetHistoryPageSize, nil
+	})
+}
+
+// nextBitgetFundingTime returns the next 8h settlement boundary after now
+func nextBitgetFundingTime(now time.Time) time.Time {
+	interval := bitgetFundingIntervalHours * time.Hour
+	return now.UTC().Truncate(interval).Add(interval)
+}
+
+// parseBitgetSymbol handles product-suffixed symbols such as BTCUSDT_UMCBL,
+// BTCUSD_DMCBL and BTCPERP_CMCBL (USDC margined)
+func parseBitgetSymbol(symbol string) (domain.Instrument, bool) {
+	name, _, _ := strings.Cut(strings.ToUpper(symbol), "_")
+	if strings.HasSuffix(name, "PERP") && len(name) > len("PERP") {
+		return perpetualInstrument(strings.TrimSuffix(name, "PERP"), "USDC"), true
+	}
+	base, quote, ok := splitConcatenatedSymbol(name)
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	return perpetualInstrument(base, quote), true
+}
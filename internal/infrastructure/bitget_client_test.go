@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBitgetClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/mix/v1/market/tickers":
+			w.Write([]byte(`{"code":"00000","msg":"success","requestTime":1704090000000,"data":[
+				{"symbol":"BTCUSDT_UMCBL","last":"42510.5","indexPrice":"42490.5","fundingRate":"0.0001","timestamp":"1704090000000"},
+				{"symbol":"ETHUSDT_UMCBL","last":"2280.4","indexPrice":"2280.1","fundingRate":"","timestamp":"1704090000000"}
+			]}`))
+		case "/api/mix/v1/market/history-fundRate":
+			if r.URL.Query().Get("symbol") != "BTCUSDT_UMCBL" || r.URL.Query().Get("pageNo") != "1" {
+				t.Errorf("Expected the first BTCUSDT_UMCBL history page, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"code":"00000","msg":"success","data":[
+				{"symbol":"BTCUSDT","fundingRate":"0.00008","settleTime":"1704067200000"},
+				{"symbol":"BTCUSDT","fundingRate":"0.0001","settleTime":"1704038400000"}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewBitgetClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// ETHUSDT_UMCBL has no funding rate and is skipped
+	if len(rates) != 1 {
+		t.Fatalf("Expected 1 rate, got %d", len(rates))
+	}
+
+	// The ticker fundingRate is the running rate, not the settled one
+	rate := rates[0]
+	if rate.FundingRate != 0.0001 || rate.IndexPrice != 42490.5 {
+		t.Errorf("Expected the running rate 0.0001 at index 42490.5, got %v at %v", rate.FundingRate, rate.IndexPrice)
+	}
+	if !rate.NextFundingTime.Equal(time.UnixMilli(1704096000000)) {
+		t.Errorf("Expected the next settlement at %v, got %v", time.UnixMilli(1704096000000), rate.NextFundingTime)
+	}
+	if rate.LastFundingRate != 0.00008 || rate.LastFundingTime == nil || !rate.LastFundingTime.Equal(time.UnixMilli(1704067200000)) {
+		t.Errorf("Expected the last settlement 0.00008 at %v, got %v at %v", time.UnixMilli(1704067200000), rate.LastFundingRate, rate.LastFundingTime)
+	}
+}
+
+func TestBitgetClient_GetFundingHistory(t *testing.T) {
+	// Settlements every 8h since 2023, served newest first in numbered pages
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/mix/v1/market/history-fundRate" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		number, _ := strconv.Atoi(r.URL.Query().Get("pageNo"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+
+		var entries []string
+		at := last.Add(-time.Duration((number-1)*size) * 8 * time.Hour)
+		for ; !at.Before(first) && len(entries) < size; at = at.Add(-8 * time.Hour) {
+			entries = append(entries, fmt.Sprintf(`{"symbol":"BTCUSDT","fundingRate":"0.0001","settleTime":"%d"}`, at.UnixMilli()))
+		}
+		w.Write([]byte(`{"code":"00000","msg":"success","data":[` + strings.Join(entries, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client := NewBitgetClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "BTCUSDT_UMCBL", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Reaching back 92 days from the newest settlement takes three pages of 100
+	if len(settlements) != 61*3 {
+		t.Fatalf("Expected %d settlements, got %d", 61*3, len(settlements))
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if !settlements[0].FundingTime.Equal(start) || !settlements[len(settlements)-1].FundingTime.Equal(end.Add(-8*time.Hour)) {
+		t.Errorf("Expected settlements from %v until before %v oldest first, got %v to %v", start, end, settlements[0].FundingTime, settlements[len(settlements)-1].FundingTime)
+	}
+	for i, settlement := range settlements {
+		if i > 0 && !settlement.FundingTime.Equal(settlements[i-1].FundingTime.Add(8*time.Hour)) {
+			t.Fatalf("Expected consecutive settlements, got %v after %v", settlement.FundingTime, settlements[i-1].FundingTime)
+		}
+		if settlement.Exchange != "bitget" || settlement.Symbol != "BTCUSDT_UMCBL" || settlement.FundingRate != 0.0001 {
+			t.Fatalf("Unexpected settlement %+v", settlement)
+		}
+	}
+}
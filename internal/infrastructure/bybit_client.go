@@ -1,25 +1,32 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// BybitClient reads the linear and inverse perpetuals of Bybit. Tickers only
+// carry the running rate, settled rates come from the funding history.
 type BybitClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
 }
 
 type BybitTicker struct {
-	Symbol           string `json:"symbol"`
+	Symbol          string `json:"symbol"`
 	FundingRate     string `json:"fundingRate"`
 	MarkPrice       string `json:"markPrice"`
 	IndexPrice      string `json:"indexPrice"`
@@ -27,30 +34,88 @@ type BybitTicker struct {
 }
 
 type BybitTickerResponse struct {
-	RetCode int                    `json:"retCode"`
-	RetMsg  string                 `json:"retMsg"`
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
 	Result  struct {
 		List []BybitTicker `json:"list"`
 	} `json:"result"`
 }
 
-func NewBybitClient(config domain.ExchangeConfig, logger *logrus.Logger) *BybitClient {
-	return &BybitClient{
+type BybitInstrument struct {
+	Symbol          string `json:"symbol"`
+	FundingInterval int    `json:"fundingInterval"` // minutes
+}
+
+type BybitInstrumentsResponse struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  struct {
+		List           []BybitInstrument `json:"list"`
+		NextPageCursor string            `json:"nextPageCursor"`
+	} `json:"result"`
+}
+
+// BybitFundingHistory is a settlement of the funding rate history
+type BybitFundingHistory struct {
+	Symbol               string `json:"symbol"`
+	FundingRate          string `json:"fundingRate"`
+	FundingRateTimestamp string `json:"fundingRateTimestamp"`
+}
+
+type BybitFundingHistoryResponse struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  struct {
+		List []BybitFundingHistory `json:"list"`
+	} `json:"result"`
+}
+
+// bybitHistoryLimit is the most settlements a funding history request returns
+const bybitHistoryLimit = 200
+
+// bybitCategories are the instrument categories listing perpetuals: USDT and
+// USDC contracts are linear, BTCUSD-style ones coin-margined
+var bybitCategories = []struct {
+	name       string
+	marginType domain.MarginType
+}{
+	{"linear", domain.MarginTypeLinear},
+	{"inverse", domain.MarginTypeInverse},
+}
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "bybit",
+		DefaultBaseURL: "https://api.bybit.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, APIKey: true, InverseContracts: true},
+		SymbolParser:   parseBybitSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewBybitClient(config, client, logger)
+		},
+	})
+}
+
+func NewBybitClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *BybitClient {
+	b := &BybitClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	b.settlements = newSettlementTracker(b, time.Now, logger)
+	return b
 }
 
 func (b *BybitClient) GetName() string {
 	return "bybit"
 }
 
-func (b *BybitClient) IsHealthy() bool {
+func (b *BybitClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/v5/market/tickers", b.config.BaseURL)
-	resp, err := b.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := b.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -58,10 +123,43 @@ func (b *BybitClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (b *BybitClient) GetFundingRates() ([]domain.FundingRate, error) {
+// GetFundingRates reads the perpetuals of every category, failing only when
+// none of them could be read
+func (b *BybitClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	var rates []domain.FundingRate
+	var lastErr error
+	failed := 0
+
+	for _, category := range bybitCategories {
+		categoryRates, err := b.getCategoryFundingRates(ctx, category.name, category.marginType)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			b.logger.Warnf("Failed to get %s funding rates from Bybit: %v", category.name, err)
+			lastErr = err
+			failed++
+			continue
+		}
+		rates = append(rates, categoryRates...)
+	}
+
+	if failed == len(bybitCategories) {
+		return nil, lastErr
+	}
+
+	b.settlements.update(ctx, rates)
+
+	b.logger.Infof("Retrieved %d funding rates from Bybit", len(rates))
+	return rates, nil
+}
+
+// getCategoryFundingRates reads the tickers of one category. Inverse
+// tickers include dated futures, which have no funding rate.
+func (b *BybitClient) getCategoryFundingRates(ctx context.Context, category string, marginType domain.MarginType) ([]domain.FundingRate, error) {
 	url := fmt.Sprintf("%s/v5/market/tickers", b.config.BaseURL)
-	
-	req, err := http.NewRequest("GET", url, nil)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -73,7 +171,7 @@ func (b *BybitClient) GetFundingRates() ([]domain.FundingRate, error) {
 
 	// Add query parameters for current funding rates
 	q := req.URL.Query()
-	q.Add("category", "linear")
+	q.Add("category", category)
 	req.URL.RawQuery = q.Encode()
 
 	resp, err := b.client.Do(req)
@@ -101,8 +199,17 @@ func (b *BybitClient) GetFundingRates() ([]domain.FundingRate, error) {
 		return nil, fmt.Errorf("Bybit API error: %s", bybitResponse.RetMsg)
 	}
 
+	intervals, err := b.getFundingIntervals(ctx, category)
+	if err != nil {
+		b.logger.Warnf("Failed to get %s funding intervals from Bybit, assuming 8h: %v", category, err)
+	}
+
 	var rates []domain.FundingRate
 	for _, ticker := range bybitResponse.Result.List {
+		if ticker.FundingRate == "" {
+			continue
+		}
+
 		fundingRate, err := strconv.ParseFloat(ticker.FundingRate, 64)
 		if err != nil {
 			b.logger.Warnf("Failed to parse funding rate for %s: %v", ticker.Symbol, err)
@@ -125,17 +232,165 @@ func (b *BybitClient) GetFundingRates() ([]domain.FundingRate, error) {
 		}
 
 		rates = append(rates, domain.FundingRate{
-			Symbol:          ticker.Symbol,
-			Exchange:        b.GetName(),
-			FundingRate:     fundingRate,
-			NextFundingTime: time.Unix(nextFundingTime/1000, 0),
-			Timestamp:       time.Now(),
-			MarkPrice:       markPrice,
-			IndexPrice:      indexPrice,
-			LastFundingRate: 0, // Not provided in this endpoint
+			Symbol:               ticker.Symbol,
+			Exchange:             b.GetName(),
+			FundingRate:          fundingRate,
+			NextFundingTime:      time.Unix(nextFundingTime/1000, 0),
+			Timestamp:            time.Now(),
+			MarkPrice:            markPrice,
+			IndexPrice:           indexPrice,
+			FundingIntervalHours: intervals[ticker.Symbol],
+			MarginType:           marginType,
 		})
 	}
-
-	b.logger.Infof("Retrieved %d funding rates from Bybit", len(rates))
 	return rates, nil
-} 
\ No newline at end of file
+}
+
+// GetFundingHistory reads a perpetual's settlements, which Bybit serves
+// newest first. USD quoted symbols are looked up among the inverse contracts.
+func (b *BybitClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	category := "linear"
+	if instrument, ok := parseBybitSymbol(symbol); ok && instrument.Quote == "USD" {
+		category = "inverse"
+	}
+
+	return pageSettlementsBackward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"category": {category},
+			"symbol":   {symbol},
+			"endTime":  {strconv.FormatInt(bound.UnixMilli(), 10)},
+			"limit":    {strconv.Itoa(bybitHistoryLimit)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", b.config.BaseURL+"/v5/market/funding/history?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+		if b.config.APIKey != "" {
+			req.Header.Set("X-BAPI-API-KEY", b.config.APIKey)
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var response BybitFundingHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if response.RetCode != 0 {
+			return nil, false, fmt.Errorf("Bybit API error: %s", response.RetMsg)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range response.Result.List {
+			fundingRate, err := strconv.ParseFloat(entry.FundingRate, 64)
+			if err != nil {
+				b.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			fundingTime, err := strconv.ParseInt(entry.FundingRateTimestamp, 10, 64)
+			if err != nil {
+				b.logger.Warnf("Failed to parse funding time for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    b.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(fundingTime),
+			})
+		}
+		return settlements, len(response.Result.List) >= bybitHistoryLimit, nil
+	})
+}
+
+// getFundingIntervals pages through the instruments of a category and
+// returns each symbol's funding cycle in hours
+func (b *BybitClient) getFundingIntervals(ctx context.Context, category string) (map[string]int, error) {
+	intervals := make(map[string]int)
+	cursor := ""
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/v5/market/instruments-info", b.config.BaseURL), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		q := req.URL.Query()
+		q.Add("category", category)
+		q.Add("limit", "1000")
+		if cursor != "" {
+			q.Add("cursor", cursor)
+		}
+		req.URL.RawQuery = q.Encode()
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make request: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var instrumentsResponse BybitInstrumentsResponse
+		err = json.NewDecoder(resp.Body).Decode(&instrumentsResponse)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		if instrumentsResponse.RetCode != 0 {
+			return nil, fmt.Errorf("Bybit API error: %s", instrumentsResponse.RetMsg)
+		}
+
+		for _, instrument := range instrumentsResponse.Result.List {
+			if instrument.FundingInterval > 0 {
+				intervals[instrument.Symbol] = instrument.FundingInterval / 60
+			}
+		}
+
+		cursor = instrumentsResponse.Result.NextPageCursor
+		if cursor == "" {
+			return intervals, nil
+		}
+	}
+}
+
+// parseBybitSymbol handles BTCUSDT, BTCPERP (USDC perpetual) and dated
+// contracts such as BTC-26JUL24 (USDC) or BTCUSDT-26JUL24
+func parseBybitSymbol(symbol string) (domain.Instrument, bool) {
+	name, suffix, dated := strings.Cut(strings.ToUpper(symbol), "-")
+
+	var base, quote string
+	if strings.HasSuffix(name, "PERP") {
+		base, quote = strings.TrimSuffix(name, "PERP"), "USDC"
+	} else if b, q, ok := splitConcatenatedSymbol(name); ok {
+		base, quote = b, q
+	} else if dated {
+		base, quote = name, "USDC"
+	} else {
+		return domain.Instrument{}, false
+	}
+	if base == "" {
+		return domain.Instrument{}, false
+	}
+
+	if !dated {
+		return perpetualInstrument(base, quote), true
+	}
+	expiry, ok := parseExpiry("2Jan06", suffix)
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	return futureInstrument(base, quote, expiry), true
+}
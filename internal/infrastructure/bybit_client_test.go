@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"fundingmonitor/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBybitClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v5/market/tickers":
+			if r.URL.Query().Get("category") == "inverse" {
+				// Inverse futures such as BTCUSDH24 carry no funding rate
+				w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"category":"inverse","list":[
+					{"symbol":"BTCUSD","fundingRate":"0.00005","markPrice":"50001","indexPrice":"49999","nextFundingTime":"1704096000000"},
+					{"symbol":"BTCUSDH24","fundingRate":"","markPrice":"51200","indexPrice":"49999","nextFundingTime":"0"}
+				]}}`))
+				return
+			}
+			w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"category":"linear","list":[
+				{"symbol":"BTCUSDT","fundingRate":"0.0001","markPrice":"50000","indexPrice":"49999","nextFundingTime":"1704096000000"},
+				{"symbol":"ORDIUSDT","fundingRate":"0.0005","markPrice":"40","indexPrice":"40","nextFundingTime":"1704085200000"}
+			]}}`))
+		case "/v5/market/instruments-info":
+			if r.URL.Query().Get("category") == "inverse" {
+				w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[{"symbol":"BTCUSD","fundingInterval":480}],"nextPageCursor":""}}`))
+				return
+			}
+			// Two pages to exercise cursor pagination
+			if r.URL.Query().Get("cursor") == "" {
+				w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[{"symbol":"BTCUSDT","fundingInterval":480}],"nextPageCursor":"page2"}}`))
+				return
+			}
+			w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[{"symbol":"ORDIUSDT","fundingInterval":60}],"nextPageCursor":""}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewBybitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	intervals := map[string]int{}
+	marginTypes := map[string]domain.MarginType{}
+	for _, rate := range rates {
+		intervals[rate.Symbol] = rate.FundingIntervalHours
+		marginTypes[rate.Symbol] = rate.MarginType
+	}
+	if intervals["BTCUSDT"] != 8 || intervals["ORDIUSDT"] != 1 || intervals["BTCUSD"] != 8 {
+		t.Errorf("Expected 8h, 1h and 8h intervals, got %v", intervals)
+	}
+	if marginTypes["BTCUSDT"] != domain.MarginTypeLinear || marginTypes["BTCUSD"] != domain.MarginTypeInverse {
+		t.Errorf("Expected BTCUSDT linear and BTCUSD inverse, got %v", marginTypes)
+	}
+}
+
+func TestBybitClient_GetFundingRatesCategoryFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("category") == "inverse" {
+			w.Write([]byte(`{"retCode":10001,"retMsg":"params error","result":{}}`))
+			return
+		}
+		w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[
+			{"symbol":"BTCUSDT","fundingRate":"0.0001","markPrice":"50000","indexPrice":"49999","nextFundingTime":"1704096000000"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client := NewBybitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the linear rates despite the inverse failure, got %v", err)
+	}
+	if len(rates) != 1 || rates[0].Symbol != "BTCUSDT" {
+		t.Errorf("Expected only BTCUSDT, got %+v", rates)
+	}
+}
+
+func TestBybitClient_GetFundingHistory(t *testing.T) {
+	// Hourly settlements since 2024, served newest first and at most limit
+	// per request up to endTime
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	categories := map[string]string{}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v5/market/funding/history" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		query := r.URL.Query()
+		categories[query.Get("symbol")] = query.Get("category")
+		endTime, _ := strconv.ParseInt(query.Get("endTime"), 10, 64)
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		var entries []string
+		for at := last; !at.Before(first) && len(entries) < limit; at = at.Add(-time.Hour) {
+			if at.UnixMilli() > endTime {
+				continue
+			}
+			entries = append(entries, fmt.Sprintf(`{"symbol":%q,"fundingRate":"0.0005","fundingRateTimestamp":"%d"}`, query.Get("symbol"), at.UnixMilli()))
+		}
+		fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"category":%q,"list":[%s]}}`, query.Get("category"), strings.Join(entries, ","))
+	}))
+	defer server.Close()
+
+	client := NewBybitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "ORDIUSDT", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 10 days of hourly settlements take two pages of 200
+	if len(settlements) != 240 || requests != 2 {
+		t.Fatalf("Expected 240 settlements in 2 requests, got %d in %d", len(settlements), requests)
+	}
+	if !settlements[0].FundingTime.Equal(start) || !settlements[239].FundingTime.Equal(end.Add(-time.Hour)) {
+		t.Errorf("Expected settlements from %v until before %v oldest first, got %v to %v", start, end, settlements[0].FundingTime, settlements[239].FundingTime)
+	}
+
+	if _, err := client.GetFundingHistory(context.Background(), "BTCUSD", start, start.Add(time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if categories["ORDIUSDT"] != "linear" || categories["BTCUSD"] != "inverse" {
+		t.Errorf("Expected ORDIUSDT linear and BTCUSD inverse, got %v", categories)
+	}
+}
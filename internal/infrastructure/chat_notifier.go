@@ -0,0 +1,181 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// chatTimeout bounds a single post to a chat service
+const chatTimeout = 10 * time.Second
+
+// TelegramNotifier posts digests through the Telegram Bot API sendMessage method
+type TelegramNotifier struct {
+	baseURL string
+	token   string
+	chatID  string
+	client  *http.Client
+}
+
+func NewTelegramNotifier(channel domain.DigestChannel) *TelegramNotifier {
+	baseURL := channel.BaseURL
+	if baseURL == "" {
+		baseURL = domain.DefaultTelegramBaseURL
+	}
+	return &TelegramNotifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   channel.BotToken,
+		chatID:  channel.ChatID,
+		client:  &http.Client{Timeout: chatTimeout},
+	}
+}
+
+// SendDigest posts the digest as an HTML message with a preformatted table
+func (n *TelegramNotifier) SendDigest(ctx context.Context, digest domain.FundingDigest) error {
+	request := map[string]interface{}{
+		"chat_id":                  n.chatID,
+		"text":                     "<b>" + html.EscapeString(digestTitle(digest)) + "</b>\n<pre>" + html.EscapeString(digestTable(digest)) + "</pre>",
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+
+	var response struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := postChatMessage(ctx, n.client, n.baseURL+"/bot"+n.token+"/sendMessage", request, &response); err != nil {
+		return err
+	}
+	if !response.OK {
+		return fmt.Errorf("telegram API error: %s", response.Description)
+	}
+	return nil
+}
+
+// SlackNotifier posts digests to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func NewSlackNotifier(channel domain.DigestChannel) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: channel.WebhookURL,
+		client:     &http.Client{Timeout: chatTimeout},
+	}
+}
+
+// SendDigest posts the digest as mrkdwn with the table in a code block
+func (n *SlackNotifier) SendDigest(ctx context.Context, digest domain.FundingDigest) error {
+	request := map[string]interface{}{
+		"text": "*" + digestTitle(digest) + "*\n```\n" + digestTable(digest) + "```",
+	}
+	return postChatMessage(ctx, n.client, n.webhookURL, request, nil)
+}
+
+// DiscordNotifier posts digests to a Discord webhook
+type DiscordNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func NewDiscordNotifier(channel domain.DigestChannel) *DiscordNotifier {
+	return &DiscordNotifier{
+		webhookURL: channel.WebhookURL,
+		client:     &http.Client{Timeout: chatTimeout},
+	}
+}
+
+// SendDigest posts the digest as markdown with the table in a code block
+func (n *DiscordNotifier) SendDigest(ctx context.Context, digest domain.FundingDigest) error {
+	request := map[string]interface{}{
+		"content": "**" + digestTitle(digest) + "**\n```\n" + digestTable(digest) + "```",
+	}
+	return postChatMessage(ctx, n.client, n.webhookURL, request, nil)
+}
+
+// postChatMessage posts a JSON message and decodes the reply into response when given
+func postChatMessage(ctx context.Context, client *http.Client, url string, message interface{}, response interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if response != nil {
+		if err := json.Unmarshal(respBody, response); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return nil
+}
+
+// digestTitle describes the digest's selection and time
+func digestTitle(digest domain.FundingDigest) string {
+	title := fmt.Sprintf("Top %d funding rates (%s)", len(digest.Rates), digest.Basis)
+	if digest.Threshold > 0 {
+		title += fmt.Sprintf(" beyond %.4f%%", digest.Threshold*100)
+	}
+	return title + " - " + digest.Timestamp.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// digestTable renders the rates as a plain text table for monospace display
+func digestTable(digest domain.FundingDigest) string {
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(writer, "#\tSYMBOL\tEXCHANGE\tRATE\tNEXT\n")
+	for i, rate := range digest.Rates {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\n", i+1, rate.MarketSymbol(), rate.Exchange,
+			formatDigestRate(rate.RateForBasis(digest.Basis)), timeToFunding(rate, digest.Timestamp))
+	}
+
+	writer.Flush()
+	return table.String()
+}
+
+// timeToFunding renders the time left until the next settlement, e.g. 1h20m
+func timeToFunding(rate domain.FundingRate, now time.Time) string {
+	if rate.NextFundingTime.IsZero() || !rate.NextFundingTime.After(now) {
+		return "-"
+	}
+	left := rate.NextFundingTime.Sub(now).Round(time.Minute)
+	if left == 0 {
+		return "<1m"
+	}
+	return strings.TrimSuffix(left.String(), "0s")
+}
+
+// formatDigestRate renders a rate as a signed percentage
+func formatDigestRate(rate float64) string {
+	return fmt.Sprintf("%+.4f%%", rate*100)
+}
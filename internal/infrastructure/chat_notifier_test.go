@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// chatStandIn is a local stand-in for a chat service recording the last post
+type chatStandIn struct {
+	*httptest.Server
+	path    string
+	message map[string]interface{}
+}
+
+func newChatStandIn(t *testing.T, status int, reply string) *chatStandIn {
+	standIn := &chatStandIn{}
+	standIn.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		standIn.path = r.URL.Path
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &standIn.message); err != nil {
+			t.Errorf("Invalid message %s: %v", body, err)
+		}
+
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(standIn.Close)
+	return standIn
+}
+
+var testDigest = domain.FundingDigest{
+	Channel:   "desk",
+	Timestamp: time.Date(2024, 1, 1, 6, 40, 0, 0, time.UTC),
+	Basis:     domain.RateBasis8h,
+	Threshold: 0.001,
+	Rates: []domain.FundingRate{
+		{Exchange: "okx", Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.005, NextFundingTime: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{Exchange: "binance", Symbol: "ETHUSDT", FundingRate: -0.003},
+	},
+}
+
+func TestDigestTable(t *testing.T) {
+	expectedTitle := "Top 2 funding rates (8h) beyond 0.1000% - 2024-01-01 06:40 UTC"
+	if title := digestTitle(testDigest); title != expectedTitle {
+		t.Errorf("Expected title %q, got %q", expectedTitle, title)
+	}
+
+	expectedTable := "#  SYMBOL   EXCHANGE  RATE      NEXT\n" +
+		"1  BTCUSDT  okx       +0.5000%  1h20m\n" +
+		"2  ETHUSDT  binance   -0.3000%  -\n"
+	if table := digestTable(testDigest); table != expectedTable {
+		t.Errorf("Expected table:\n%s\ngot:\n%s", expectedTable, table)
+	}
+}
+
+func TestTelegramNotifier_SendDigest(t *testing.T) {
+	standIn := newChatStandIn(t, http.StatusOK, `{"ok":true,"result":{"message_id":1}}`)
+	notifier := NewTelegramNotifier(domain.DigestChannel{BaseURL: standIn.URL + "/", BotToken: "123:abc", ChatID: "-100"})
+
+	if err := notifier.SendDigest(context.Background(), testDigest); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if standIn.path != "/bot123:abc/sendMessage" {
+		t.Errorf("Expected the sendMessage method, got %s", standIn.path)
+	}
+	if standIn.message["chat_id"] != "-100" || standIn.message["parse_mode"] != "HTML" {
+		t.Errorf("Expected chat -100 in HTML mode, got %v", standIn.message)
+	}
+	text, _ := standIn.message["text"].(string)
+	if !strings.HasPrefix(text, "<b>Top 2 funding rates") || !strings.Contains(text, "<pre>#  SYMBOL") || !strings.HasSuffix(text, "</pre>") {
+		t.Errorf("Expected a bold title and a preformatted table, got %q", text)
+	}
+}
+
+func TestTelegramNotifier_SendDigestError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reply  string
+		err    string
+	}{
+		{"api error", http.StatusOK, `{"ok":false,"description":"Bad Request: chat not found"}`, "chat not found"},
+		{"http error", http.StatusUnauthorized, `{"ok":false,"description":"Unauthorized"}`, "status 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := newChatStandIn(t, tt.status, tt.reply)
+			notifier := NewTelegramNotifier(domain.DigestChannel{BaseURL: standIn.URL, BotToken: "123:abc", ChatID: "-100"})
+
+			err := notifier.SendDigest(context.Background(), testDigest)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSlackNotifier_SendDigest(t *testing.T) {
+	standIn := newChatStandIn(t, http.StatusOK, "ok")
+	notifier := NewSlackNotifier(domain.DigestChannel{WebhookURL: standIn.URL + "/services/T000/B000/XXXX"})
+
+	if err := notifier.SendDigest(context.Background(), testDigest); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if standIn.path != "/services/T000/B000/XXXX" {
+		t.Errorf("Expected the webhook path, got %s", standIn.path)
+	}
+	expected := "*" + digestTitle(testDigest) + "*\n```\n" + digestTable(testDigest) + "```"
+	if standIn.message["text"] != expected {
+		t.Errorf("Expected %q, got %v", expected, standIn.message["text"])
+	}
+}
+
+func TestDiscordNotifier_SendDigest(t *testing.T) {
+	standIn := newChatStandIn(t, http.StatusNoContent, "")
+	notifier := NewDiscordNotifier(domain.DigestChannel{WebhookURL: standIn.URL + "/api/webhooks/1/token"})
+
+	if err := notifier.SendDigest(context.Background(), testDigest); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "**" + digestTitle(testDigest) + "**\n```\n" + digestTable(testDigest) + "```"
+	if standIn.message["content"] != expected {
+		t.Errorf("Expected %q, got %v", expected, standIn.message["content"])
+	}
+
+	failing := newChatStandIn(t, http.StatusTooManyRequests, `{"message":"You are being rate limited."}`)
+	notifier = NewDiscordNotifier(domain.DigestChannel{WebhookURL: failing.URL})
+	if err := notifier.SendDigest(context.Background(), testDigest); err == nil || !strings.Contains(err.Error(), "status 429") {
+		t.Errorf("Expected a status 429 error, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package infrastructure
 
 import (
 	"fundingmonitor/internal/domain"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -13,62 +15,27 @@ func LoadConfig() (*domain.Config, error) {
 
 	// Set defaults
 	viper.SetDefault("port", "8080")
-	viper.SetDefault("exchanges", map[string]interface{}{
-		"binance": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api.binance.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"bybit": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api.bybit.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"okx": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://www.okx.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"mexc": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api.mexc.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"bitget": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api.bitget.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"gate": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api.gateio.ws",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"deribit": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://www.deribit.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"xt": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api.xt.com",
-			"api_key":   "",
-			"api_secret": "",
-		},
-		"kucoin": map[string]interface{}{
-			"enabled":   true,
-			"base_url":  "https://api-futures.kucoin.com",
-			"api_key":   "",
+	viper.SetDefault("exchange_timeout", 15)
+	viper.SetDefault("refresh_interval", 30)
+	viper.SetDefault("log_storage", "file")
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("http.max_retries", 3)
+
+	// Every registered exchange is enabled on its default endpoint
+	exchangeDefaults := make(map[string]interface{})
+	for _, registration := range RegisteredExchanges() {
+		defaults := map[string]interface{}{
+			"enabled":    true,
+			"base_url":   registration.DefaultBaseURL,
+			"api_key":    "",
 			"api_secret": "",
-		},
-	})
+		}
+		if registration.DefaultInverseBaseURL != "" {
+			defaults["inverse_base_url"] = registration.DefaultInverseBaseURL
+		}
+		exchangeDefaults[registration.Name] = defaults
+	}
+	viper.SetDefault("exchanges", exchangeDefaults)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -82,4 +49,33 @@ func LoadConfig() (*domain.Config, error) {
 	}
 
 	return &config, nil
-} 
\ No newline at end of file
+}
+
+// ExchangeTimeouts returns the default poll deadline and the per-exchange
+// overrides. An exchange without a configured timeout gets its registered
+// one when that is longer than the default.
+func ExchangeTimeouts(config *domain.Config) (time.Duration, map[string]time.Duration) {
+	defaultTimeout := time.Duration(config.ExchangeTimeout) * time.Second
+	overrides := make(map[string]time.Duration)
+	for name, exchangeConfig := range config.Exchanges {
+		if exchangeConfig.Timeout > 0 {
+			overrides[name] = time.Duration(exchangeConfig.Timeout) * time.Second
+			continue
+		}
+		if registration, ok := LookupExchange(name); ok && registration.Timeout > defaultTimeout {
+			overrides[name] = registration.Timeout
+		}
+	}
+	return defaultTimeout, overrides
+}
+
+// RefreshIntervals returns the default snapshot refresh cadence and the per-exchange overrides
+func RefreshIntervals(config *domain.Config) (time.Duration, map[string]time.Duration) {
+	overrides := make(map[string]time.Duration)
+	for name, exchangeConfig := range config.Exchanges {
+		if exchangeConfig.RefreshInterval > 0 {
+			overrides[name] = time.Duration(exchangeConfig.RefreshInterval) * time.Second
+		}
+	}
+	return time.Duration(config.RefreshInterval) * time.Second, overrides
+}
@@ -1,66 +1,110 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// DeribitClient reads the Deribit perpetuals. Book summaries carry the
+// running rate only; funding_8h is a rolling 8h rate, not the last settled
+// one, which comes from the funding rate history.
 type DeribitClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
+}
+
+// DeribitBookSummary is an entry of get_book_summary_by_currency; the funding
+// fields are only set on perpetuals
+type DeribitBookSummary struct {
+	InstrumentName         string  `json:"instrument_name"`
+	BaseCurrency           string  `json:"base_currency"`
+	QuoteCurrency          string  `json:"quote_currency"`
+	MarkPrice              float64 `json:"mark_price"`
+	EstimatedDeliveryPrice float64 `json:"estimated_delivery_price"` // the index price for perpetuals
+	CurrentFunding         float64 `json:"current_funding"`
+	Funding8h              float64 `json:"funding_8h"`
+	CreationTimestamp      int64   `json:"creation_timestamp"`
 }
 
-type DeribitInstrument struct {
-	InstrumentName string `json:"instrument_name"`
-	BaseCurrency   string `json:"base_currency"`
-	QuoteCurrency  string `json:"quote_currency"`
-	IsActive       bool   `json:"is_active"`
+type DeribitBookSummaryResponse struct {
+	JsonRPC string               `json:"jsonrpc"`
+	Result  []DeribitBookSummary `json:"result"`
 }
 
-type DeribitInstrumentsResponse struct {
-	JsonRPC string              `json:"jsonrpc"`
-	Result  []DeribitInstrument `json:"result"`
+// DeribitFundingRateHistory is an hourly entry of the funding rate history;
+// Interest8h is the funding accrued over the 8h up to Timestamp
+type DeribitFundingRateHistory struct {
+	Timestamp  int64   `json:"timestamp"`
+	IndexPrice float64 `json:"index_price"`
+	Interest1h float64 `json:"interest_1h"`
+	Interest8h float64 `json:"interest_8h"`
 }
 
-type DeribitTicker struct {
-	InstrumentName string  `json:"instrument_name"`
-	CurrentFunding float64 `json:"current_funding"`
-	Funding8h      float64 `json:"funding_8h"`
-	MarkPrice      float64 `json:"mark_price"`
-	IndexPrice     float64 `json:"index_price"`
-	Timestamp      int64   `json:"timestamp"`
-	State          string  `json:"state"`
+type DeribitFundingRateHistoryResponse struct {
+	JsonRPC string                      `json:"jsonrpc"`
+	Result  []DeribitFundingRateHistory `json:"result"`
 }
 
-type DeribitTickerResponse struct {
-	JsonRPC string        `json:"jsonrpc"`
-	Result  DeribitTicker `json:"result"`
+// deribitHistoryWindow is the span asked per funding rate history request,
+// Deribit returning a month of hourly entries at most
+const deribitHistoryWindow = 30 * 24 * time.Hour
+
+// deribitSettlementCurrencies covers the inverse (BTC, ETH) and linear
+// (USDC, USDT) perpetuals
+var deribitSettlementCurrencies = []string{"BTC", "ETH", "USDC", "USDT"}
+
+// deribitInverseCurrencies settle the coin-margined perpetuals
+var deribitInverseCurrencies = map[string]bool{"BTC": true, "ETH": true}
+
+// Deribit settles perpetual funding every 8h at 00:00, 08:00 and 16:00 UTC
+const deribitFundingIntervalHours = 8
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "deribit",
+		DefaultBaseURL: "https://www.deribit.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, InverseContracts: true},
+		SymbolParser:   parseDeribitSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewDeribitClient(config, client, logger)
+		},
+	})
 }
 
-func NewDeribitClient(config domain.ExchangeConfig, logger *logrus.Logger) *DeribitClient {
-	return &DeribitClient{
+func NewDeribitClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *DeribitClient {
+	d := &DeribitClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	d.settlements = newSettlementTracker(d, time.Now, logger)
+	return d
 }
 
 func (d *DeribitClient) GetName() string {
 	return "deribit"
 }
 
-func (d *DeribitClient) IsHealthy() bool {
-	url := fmt.Sprintf("%s/api/v2/public/get_instruments?currency=USDC&kind=future&expired=false", d.config.BaseURL)
-	resp, err := d.client.Get(url)
+func (d *DeribitClient) IsHealthy(ctx context.Context) bool {
+	url := fmt.Sprintf("%s/api/v2/public/test", d.config.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := d.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -68,131 +112,179 @@ func (d *DeribitClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (d *DeribitClient) GetFundingRates() ([]domain.FundingRate, error) {
-	// Get all perpetual instruments for both USDC and BTC
-	var allInstruments []DeribitInstrument
+// GetFundingRates reads every perpetual from one book summary request per
+// settlement currency
+func (d *DeribitClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	var rates []domain.FundingRate
+	var lastErr error
+	failed := 0
 
-	// Get USDC perpetual instruments
-	usdcURL := fmt.Sprintf("%s/api/v2/public/get_instruments?currency=USDC&kind=future&expired=false", d.config.BaseURL)
-	usdcResp, err := d.client.Get(usdcURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get USDC instruments: %w", err)
-	}
-	defer usdcResp.Body.Close()
+	for _, currency := range deribitSettlementCurrencies {
+		summaries, err := d.getBookSummaries(ctx, currency)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			d.logger.Warnf("Failed to get %s book summaries from Deribit: %v", currency, err)
+			lastErr = err
+			failed++
+			continue
+		}
 
-	if usdcResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(usdcResp.Body)
-		return nil, fmt.Errorf("USDC instruments API request failed with status %d: %s", usdcResp.StatusCode, string(body))
-	}
+		marginType := domain.MarginTypeLinear
+		if deribitInverseCurrencies[currency] {
+			marginType = domain.MarginTypeInverse
+		}
 
-	usdcBody, err := io.ReadAll(usdcResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read USDC instruments response: %w", err)
+		for _, summary := range summaries {
+			if !strings.HasSuffix(summary.InstrumentName, "-PERPETUAL") {
+				continue
+			}
+
+			timestamp := time.Now()
+			if summary.CreationTimestamp > 0 {
+				timestamp = time.UnixMilli(summary.CreationTimestamp)
+			}
+			rates = append(rates, domain.FundingRate{
+				Symbol:               summary.InstrumentName,
+				Exchange:             d.GetName(),
+				FundingRate:          summary.CurrentFunding,
+				NextFundingTime:      nextDeribitFundingTime(timestamp),
+				Timestamp:            timestamp,
+				MarkPrice:            summary.MarkPrice,
+				IndexPrice:           summary.EstimatedDeliveryPrice,
+				FundingIntervalHours: deribitFundingIntervalHours,
+				MarginType:           marginType,
+			})
+		}
 	}
 
-	var usdcResponse DeribitInstrumentsResponse
-	if err := json.Unmarshal(usdcBody, &usdcResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal USDC instruments response: %w", err)
+	if failed == len(deribitSettlementCurrencies) {
+		return nil, lastErr
 	}
 
-	// Get BTC perpetual instruments
-	btcURL := fmt.Sprintf("%s/api/v2/public/get_instruments?currency=BTC&kind=future&expired=false", d.config.BaseURL)
-	btcResp, err := d.client.Get(btcURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get BTC instruments: %w", err)
-	}
-	defer btcResp.Body.Close()
+	d.settlements.update(ctx, rates)
 
-	if btcResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(btcResp.Body)
-		return nil, fmt.Errorf("BTC instruments API request failed with status %d: %s", btcResp.StatusCode, string(body))
+	d.logger.Infof("Retrieved %d funding rates from Deribit", len(rates))
+	return rates, nil
+}
+
+// getBookSummaries returns the futures book summaries settled in currency
+func (d *DeribitClient) getBookSummaries(ctx context.Context, currency string) ([]DeribitBookSummary, error) {
+	url := fmt.Sprintf("%s/api/v2/public/get_book_summary_by_currency?currency=%s&kind=future", d.config.BaseURL, currency)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	btcBody, err := io.ReadAll(btcResp.Body)
+	resp, err := d.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read BTC instruments response: %w", err)
+		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
+	defer resp.Body.Close()
 
-	var btcResponse DeribitInstrumentsResponse
-	if err := json.Unmarshal(btcBody, &btcResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal BTC instruments response: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	// Combine all instruments and filter for perpetual contracts
-	allInstruments = append(allInstruments, usdcResponse.Result...)
-	allInstruments = append(allInstruments, btcResponse.Result...)
+	var response DeribitBookSummaryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return response.Result, nil
+}
 
-	var rates []domain.FundingRate
+// GetFundingHistory reads a perpetual's settlements a month at a time. The
+// history is hourly, a settlement being the 8h interest at each 8h boundary.
+func (d *DeribitClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	interval := deribitFundingIntervalHours * time.Hour
 
-	// Get ticker data for each perpetual instrument
-	for _, instrument := range allInstruments {
-		// Only process perpetual contracts
-		if !instrument.IsActive || !contains(instrument.InstrumentName, "PERPETUAL") {
-			continue
+	var settlements []domain.FundingSettlement
+	for from := start; from.Before(end); from = from.Add(deribitHistoryWindow) {
+		to := from.Add(deribitHistoryWindow)
+		if to.After(end) {
+			to = end
 		}
 
-		// Get ticker data for this instrument
-		tickerURL := fmt.Sprintf("%s/api/v2/public/ticker?instrument_name=%s", d.config.BaseURL, instrument.InstrumentName)
-
-		tickerResp, err := d.client.Get(tickerURL)
+		entries, err := d.getFundingRateHistory(ctx, symbol, from, to)
 		if err != nil {
-			d.logger.Warnf("Failed to get ticker for %s: %v", instrument.InstrumentName, err)
-			continue
+			return nil, err
 		}
-
-		if tickerResp.StatusCode != http.StatusOK {
-			tickerResp.Body.Close()
-			d.logger.Warnf("Ticker request failed for %s with status %d", instrument.InstrumentName, tickerResp.StatusCode)
-			continue
+		for _, entry := range entries {
+			fundingTime := time.UnixMilli(entry.Timestamp)
+			if !fundingTime.Truncate(interval).Equal(fundingTime) {
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    d.GetName(),
+				Symbol:      symbol,
+				FundingRate: entry.Interest8h,
+				FundingTime: fundingTime,
+			})
 		}
+	}
+	return finishSettlements(settlements, start, end), nil
+}
 
-		tickerBody, err := io.ReadAll(tickerResp.Body)
-		tickerResp.Body.Close()
-		if err != nil {
-			d.logger.Warnf("Failed to read ticker response for %s: %v", instrument.InstrumentName, err)
-			continue
-		}
+// getFundingRateHistory returns the hourly funding entries of a perpetual
+// between from and to, both inclusive
+func (d *DeribitClient) getFundingRateHistory(ctx context.Context, symbol string, from, to time.Time) ([]DeribitFundingRateHistory, error) {
+	query := url.Values{
+		"instrument_name": {symbol},
+		"start_timestamp": {strconv.FormatInt(from.UnixMilli(), 10)},
+		"end_timestamp":   {strconv.FormatInt(to.UnixMilli(), 10)},
+	}
+	url := fmt.Sprintf("%s/api/v2/public/get_funding_rate_history?%s", d.config.BaseURL, query.Encode())
 
-		var tickerResponse DeribitTickerResponse
-		if err := json.Unmarshal(tickerBody, &tickerResponse); err != nil {
-			d.logger.Warnf("Failed to unmarshal ticker response for %s: %v", instrument.InstrumentName, err)
-			continue
-		}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-		// Skip if instrument is not active
-		if tickerResponse.Result.State != "open" {
-			continue
-		}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
 
-		rates = append(rates, domain.FundingRate{
-			Symbol:          tickerResponse.Result.InstrumentName,
-			Exchange:        d.GetName(),
-			FundingRate:     tickerResponse.Result.CurrentFunding,
-			NextFundingTime: time.Now().Add(8 * time.Hour), // Deribit funding occurs every 8 hours
-			Timestamp:       time.Unix(tickerResponse.Result.Timestamp/1000, 0),
-			MarkPrice:       tickerResponse.Result.MarkPrice,
-			IndexPrice:      tickerResponse.Result.IndexPrice,
-			LastFundingRate: tickerResponse.Result.Funding8h,
-		})
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	d.logger.Infof("Retrieved %d funding rates from Deribit", len(rates))
-	return rates, nil
+	var response DeribitFundingRateHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return response.Result, nil
 }
 
-// Helper function to check if string contains substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr ||
-		(len(s) > len(substr) && (s[:len(substr)] == substr ||
-			s[len(s)-len(substr):] == substr ||
-			containsSubstring(s, substr))))
+// nextDeribitFundingTime returns the next 8h settlement boundary after now
+func nextDeribitFundingTime(now time.Time) time.Time {
+	interval := deribitFundingIntervalHours * time.Hour
+	return now.UTC().Truncate(interval).Add(interval)
 }
 
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+// parseDeribitSymbol handles inverse BTC-PERPETUAL, linear SOL_USDC-PERPETUAL
+// and dated BTC-28JUN24 instruments
+func parseDeribitSymbol(symbol string) (domain.Instrument, bool) {
+	name, suffix, ok := strings.Cut(strings.ToUpper(symbol), "-")
+	if !ok || name == "" {
+		return domain.Instrument{}, false
+	}
+
+	base, quote, linear := strings.Cut(name, "_")
+	if !linear {
+		quote = "USD"
+	}
+	if suffix == "PERPETUAL" {
+		return perpetualInstrument(base, quote), true
+	}
+	expiry, ok := parseExpiry("2Jan06", suffix)
+	if !ok {
+		return domain.Instrument{}, false
 	}
-	return false
+	return futureInstrument(base, quote, expiry), true
 }
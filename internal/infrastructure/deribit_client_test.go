@@ -0,0 +1,214 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"fundingmonitor/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newDeribitServer serves the recorded book summaries in testdata/deribit,
+// failing the currencies listed in failing, and counts their requests. The
+// funding history of BTC-PERPETUAL is served as well.
+func newDeribitServer(t *testing.T, requests *int32, failing ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/public/get_funding_rate_history" && r.URL.Query().Get("instrument_name") == "BTC-PERPETUAL" {
+			serveDeribitFundingHistory(w, r)
+			return
+		}
+		if r.URL.Path != "/api/v2/public/get_book_summary_by_currency" || r.URL.Query().Get("kind") != "future" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(requests, 1)
+
+		currency := r.URL.Query().Get("currency")
+		for _, failed := range failing {
+			if currency == failed {
+				http.Error(w, `{"jsonrpc":"2.0","error":{"message":"Internal error","code":11094}}`, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join("testdata", "deribit", "book_summary_"+currency+".json"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serveDeribitFundingHistory answers with an hourly entry for every hour
+// between start_timestamp and end_timestamp, at most 744 of them. The 8h
+// interest is 0.00011 on the 8h boundaries and 0.00005 elsewhere.
+func serveDeribitFundingHistory(w http.ResponseWriter, r *http.Request) {
+	start, _ := strconv.ParseInt(r.URL.Query().Get("start_timestamp"), 10, 64)
+	end, _ := strconv.ParseInt(r.URL.Query().Get("end_timestamp"), 10, 64)
+
+	var entries []string
+	for at := time.UnixMilli(start).Truncate(time.Hour); !at.After(time.UnixMilli(end)) && len(entries) < 744; at = at.Add(time.Hour) {
+		if at.Before(time.UnixMilli(start)) {
+			continue
+		}
+		interest8h := 0.00005
+		if at.UTC().Hour()%8 == 0 {
+			interest8h = 0.00011
+		}
+		entries = append(entries, fmt.Sprintf(`{"timestamp":%d,"index_price":42650.1,"prev_index_price":42648.9,"interest_8h":%v,"interest_1h":0.00001}`, at.UnixMilli(), interest8h))
+	}
+	w.Write([]byte(`{"jsonrpc":"2.0","result":[` + strings.Join(entries, ",") + `]}`))
+}
+
+func TestDeribitClient_GetFundingRates(t *testing.T) {
+	var requests int32
+	server := newDeribitServer(t, &requests)
+
+	client := NewDeribitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// One request per settlement currency, however many instruments are listed
+	if requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests)
+	}
+
+	// The dated BTC-28JUN24 future is skipped
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 perpetual rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	// The rolling funding_8h is not the settled rate, which is the 8h
+	// interest of the last settlement at 00:00
+	btc := bySymbol["BTC-PERPETUAL"]
+	if btc.FundingRate != 0.00004 || btc.LastFundingRate != 0.00011 {
+		t.Errorf("Expected BTC funding 0.00004/0.00011, got %v/%v", btc.FundingRate, btc.LastFundingRate)
+	}
+	if btc.LastFundingTime == nil || !btc.LastFundingTime.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the last BTC settlement at 00:00, got %v", btc.LastFundingTime)
+	}
+	if eth := bySymbol["ETH-PERPETUAL"]; eth.LastFundingRate != 0 || eth.LastFundingTime != nil {
+		t.Errorf("Expected no ETH settlement without history, got %v at %v", eth.LastFundingRate, eth.LastFundingTime)
+	}
+	if btc.MarkPrice != 42651.02 || btc.IndexPrice != 42648.21 {
+		t.Errorf("Expected BTC mark/index 42651.02/42648.21, got %v/%v", btc.MarkPrice, btc.IndexPrice)
+	}
+	if btc.FundingIntervalHours != 8 {
+		t.Errorf("Expected 8h interval, got %d", btc.FundingIntervalHours)
+	}
+
+	// Settlements fall on the 00:00, 08:00 and 16:00 UTC boundaries
+	nextFunding := map[string]time.Time{
+		"BTC-PERPETUAL":      time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+		"ETH-PERPETUAL":      time.Date(2024, 1, 1, 16, 0, 0, 0, time.UTC),
+		"SOL_USDC-PERPETUAL": time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for symbol, expected := range nextFunding {
+		if rate, ok := bySymbol[symbol]; !ok || !rate.NextFundingTime.Equal(expected) {
+			t.Errorf("Expected next funding %v for %s, got %v", expected, symbol, rate.NextFundingTime)
+		}
+	}
+
+	if !btc.Timestamp.Equal(time.UnixMilli(1704092400123)) {
+		t.Errorf("Expected the summary timestamp, got %v", btc.Timestamp)
+	}
+
+	// BTC and ETH settled perpetuals are coin-margined, USDC settled ones linear
+	marginTypes := map[string]domain.MarginType{
+		"BTC-PERPETUAL":      domain.MarginTypeInverse,
+		"ETH-PERPETUAL":      domain.MarginTypeInverse,
+		"SOL_USDC-PERPETUAL": domain.MarginTypeLinear,
+	}
+	for symbol, expected := range marginTypes {
+		if rate := bySymbol[symbol]; rate.MarginType != expected {
+			t.Errorf("Expected margin type %s for %s, got %s", expected, symbol, rate.MarginType)
+		}
+	}
+}
+
+func TestDeribitClient_GetFundingRatesPartialFailure(t *testing.T) {
+	var requests int32
+	server := newDeribitServer(t, &requests, "ETH")
+
+	client := NewDeribitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 2 {
+		t.Errorf("Expected the 2 rates of the healthy currencies, got %d", len(rates))
+	}
+}
+
+func TestDeribitClient_GetFundingRatesAllFailed(t *testing.T) {
+	var requests int32
+	server := newDeribitServer(t, &requests, deribitSettlementCurrencies...)
+
+	client := NewDeribitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	if _, err := client.GetFundingRates(context.Background()); err == nil {
+		t.Error("Expected an error when every currency fails")
+	}
+}
+
+func TestDeribitClient_GetFundingHistory(t *testing.T) {
+	var requests int32
+	server := newDeribitServer(t, &requests)
+
+	client := NewDeribitClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(45 * 24 * time.Hour)
+	settlements, err := client.GetFundingHistory(context.Background(), "BTC-PERPETUAL", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Only the 8h boundaries of the two monthly windows are settlements
+	if len(settlements) != 45*3 {
+		t.Fatalf("Expected %d settlements, got %d", 45*3, len(settlements))
+	}
+	for i, settlement := range settlements {
+		expected := start.Add(time.Duration(i) * 8 * time.Hour)
+		if !settlement.FundingTime.Equal(expected) || settlement.FundingRate != 0.00011 {
+			t.Fatalf("Expected 0.00011 settled at %v, got %v at %v", expected, settlement.FundingRate, settlement.FundingTime)
+		}
+	}
+}
+
+func TestNextDeribitFundingTime(t *testing.T) {
+	tests := []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 1, 1, 7, 59, 59, 0, time.UTC), time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 16, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		// Local times are placed on UTC boundaries
+		{time.Date(2024, 1, 1, 9, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)), time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if next := nextDeribitFundingTime(tt.now); !next.Equal(tt.expected) {
+			t.Errorf("Expected %v after %v, got %v", tt.expected, tt.now, next)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dydxHistoryPageSize is the settlements asked per funding history request
+const dydxHistoryPageSize = 100
+
+// dYdX settles funding every hour
+const dydxFundingIntervalHours = 1
+
+// dydxActiveStatus marks the markets open for trading
+const dydxActiveStatus = "ACTIVE"
+
+// DydxClient reads the dYdX v4 chain through its public indexer. The
+// markets endpoint carries the funding rate accruing for the next hourly
+// settlement, the last settled rate comes from each market's funding history.
+type DydxClient struct {
+	config domain.ExchangeConfig
+	logger *logrus.Logger
+	client *http.Client
+	now    func() time.Time
+
+	settlements *settlementTracker
+}
+
+type DydxPerpetualMarket struct {
+	Ticker          string `json:"ticker"`
+	Status          string `json:"status"`
+	OraclePrice     string `json:"oraclePrice"`
+	NextFundingRate string `json:"nextFundingRate"`
+	OpenInterest    string `json:"openInterest"`
+	MarketType      string `json:"marketType"`
+}
+
+type DydxPerpetualMarketsResponse struct {
+	Markets map[string]DydxPerpetualMarket `json:"markets"`
+}
+
+// DydxHistoricalFunding is one hourly settlement; price is the oracle price
+// it settled at
+type DydxHistoricalFunding struct {
+	Ticker            string    `json:"ticker"`
+	Rate              string    `json:"rate"`
+	Price             string    `json:"price"`
+	EffectiveAt       time.Time `json:"effectiveAt"`
+	EffectiveAtHeight string    `json:"effectiveAtHeight"`
+}
+
+type DydxHistoricalFundingResponse struct {
+	HistoricalFunding []DydxHistoricalFunding `json:"historicalFunding"`
+}
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "dydx",
+		DefaultBaseURL: "https://indexer.dydx.trade",
+		Capabilities:   domain.ExchangeCapabilities{IndexPrice: true, FundingInterval: true, NextFundingTime: true},
+		SymbolParser:   parseDydxSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewDydxClient(config, client, logger)
+		},
+	})
+}
+
+func NewDydxClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *DydxClient {
+	d := &DydxClient{
+		config: config,
+		logger: logger,
+		client: client,
+		now:    time.Now,
+	}
+	d.settlements = newSettlementTracker(d, func() time.Time { return d.now() }, logger)
+	return d
+}
+
+func (d *DydxClient) GetName() string {
+	return "dydx"
+}
+
+func (d *DydxClient) IsHealthy(ctx context.Context) bool {
+	url := fmt.Sprintf("%s/v4/time", d.config.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// GetFundingRates reads every active perpetual market. A market whose
+// funding history can't be read is still reported, without last settlement.
+func (d *DydxClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	var response DydxPerpetualMarketsResponse
+	if err := d.get(ctx, "/v4/perpetualMarkets", nil, &response); err != nil {
+		return nil, err
+	}
+
+	now := d.now()
+	var markets []DydxPerpetualMarket
+	for _, market := range response.Markets {
+		if market.Status == dydxActiveStatus {
+			markets = append(markets, market)
+		}
+	}
+	sort.Slice(markets, func(i, j int) bool { return markets[i].Ticker < markets[j].Ticker })
+
+	var rates []domain.FundingRate
+	for _, market := range markets {
+		fundingRate, err := strconv.ParseFloat(market.NextFundingRate, 64)
+		if err != nil {
+			d.logger.Warnf("Failed to parse funding rate for %s: %v", market.Ticker, err)
+			continue
+		}
+
+		oraclePrice, err := strconv.ParseFloat(market.OraclePrice, 64)
+		if err != nil {
+			d.logger.Warnf("Failed to parse oracle price for %s: %v", market.Ticker, err)
+		}
+
+		rates = append(rates, domain.FundingRate{
+			Symbol:               market.Ticker,
+			Exchange:             d.GetName(),
+			FundingRate:          fundingRate,
+			NextFundingTime:      nextDydxFundingTime(now),
+			Timestamp:            now,
+			IndexPrice:           oraclePrice,
+			FundingIntervalHours: dydxFundingIntervalHours,
+			MarginType:           domain.MarginTypeLinear,
+		})
+	}
+
+	d.settlements.update(ctx, rates)
+
+	d.logger.Infof("Retrieved %d funding rates from dYdX", len(rates))
+	return rates, nil
+}
+
+// GetFundingHistory reads a market's hourly settlements, which the indexer
+// serves newest first
+func (d *DydxClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsBackward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		var response DydxHistoricalFundingResponse
+		query := url.Values{
+			"limit":               {strconv.Itoa(dydxHistoryPageSize)},
+			"effectiveBeforeOrAt": {bound.UTC().Format(time.RFC3339Nano)},
+		}
+		if err := d.get(ctx, "/v4/historicalFunding/"+url.PathEscape(symbol), query, &response); err != nil {
+			return nil, false, err
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, funding := range response.HistoricalFunding {
+			rate, err := strconv.ParseFloat(funding.Rate, 64)
+			if err != nil {
+				d.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    d.GetName(),
+				Symbol:      symbol,
+				FundingRate: rate,
+				FundingTime: funding.EffectiveAt,
+			})
+		}
+		return settlements, len(response.HistoricalFunding) >= dydxHistoryPageSize, nil
+	})
+}
+
+// get requests an indexer endpoint and decodes its JSON body into out
+func (d *DydxClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", d.config.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// nextDydxFundingTime returns the next hourly settlement after now
+func nextDydxFundingTime(now time.Time) time.Time {
+	return now.UTC().Truncate(time.Hour).Add(time.Hour)
+}
+
+// parseDydxSymbol handles BTC-USD tickers. Markets are quoted in USD but
+// margined and settled in USDC.
+func parseDydxSymbol(symbol string) (domain.Instrument, bool) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(symbol)), "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "USD" {
+		return domain.Instrument{}, false
+	}
+	return perpetualInstrument(parts[0], "USDC"), true
+}
@@ -0,0 +1,178 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dydxServer serves the recorded indexer responses in testdata/dydx and
+// records which funding histories were requested
+type dydxServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	histories []string
+}
+
+func newDydxServer(t *testing.T) *dydxServer {
+	serveFixture := func(w http.ResponseWriter, r *http.Request, name string) {
+		data, err := os.ReadFile(filepath.Join("testdata", "dydx", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}
+
+	server := &dydxServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v4/perpetualMarkets":
+			serveFixture(w, r, "perpetual_markets.json")
+		case r.URL.Path == "/v4/time":
+			w.Write([]byte(`{"iso":"2024-01-01T00:30:00.000Z","epoch":1704069000}`))
+		case strings.HasPrefix(r.URL.Path, "/v4/historicalFunding/"):
+			if _, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("effectiveBeforeOrAt")); err != nil {
+				t.Errorf("Expected the history to be bounded by time, got %q", r.URL.Query().Get("effectiveBeforeOrAt"))
+			}
+			ticker := strings.TrimPrefix(r.URL.Path, "/v4/historicalFunding/")
+			server.mu.Lock()
+			server.histories = append(server.histories, ticker)
+			server.mu.Unlock()
+			serveFixture(w, r, "historical_funding_"+ticker+".json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// requestedHistories returns and resets the funding histories requested so far
+func (s *dydxServer) requestedHistories() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	histories := s.histories
+	s.histories = nil
+	sort.Strings(histories)
+	return histories
+}
+
+func TestDydxClient_GetFundingRates(t *testing.T) {
+	server := newDydxServer(t)
+
+	client := NewDydxClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	client.now = func() time.Time { return time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC) }
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// LUNA-USD is in final settlement and skipped
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		lastRate    float64
+		indexPrice  float64
+	}{
+		{"BTC-USD", 0.00001245, 0.00001125, 42651.12},
+		{"ETH-USD", -0.0000031, -0.0000052, 2250.46},
+		// SOL-USD has no recorded funding history
+		{"SOL-USD", 0.0000125, 0, 101.2345},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.Exchange != "dydx" {
+			t.Errorf("Expected exchange dydx for %s, got %s", tt.symbol, rate.Exchange)
+		}
+		if rate.FundingRate != tt.fundingRate || rate.LastFundingRate != tt.lastRate {
+			t.Errorf("Expected funding/last rate %v/%v for %s, got %v/%v", tt.fundingRate, tt.lastRate, tt.symbol, rate.FundingRate, rate.LastFundingRate)
+		}
+		if rate.IndexPrice != tt.indexPrice || rate.MarkPrice != 0 {
+			t.Errorf("Expected oracle price %v as index price for %s, got %v (mark %v)", tt.indexPrice, tt.symbol, rate.IndexPrice, rate.MarkPrice)
+		}
+		if rate.FundingIntervalHours != 1 || !rate.NextFundingTime.Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected %s to settle hourly at 01:00, got %d/%v", tt.symbol, rate.FundingIntervalHours, rate.NextFundingTime)
+		}
+	}
+
+	if btc := bySymbol["BTC-USD"]; btc.LastFundingTime == nil || !btc.LastFundingTime.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the last settlement at 00:00, got %v", btc.LastFundingTime)
+	}
+	if sol := bySymbol["SOL-USD"]; sol.LastFundingTime != nil {
+		t.Errorf("Expected no last settlement for SOL-USD, got %v", sol.LastFundingTime)
+	}
+
+	// Hourly rates normalize to eight times their value
+	if btc := bySymbol["BTC-USD"]; btc.EightHourRate() != btc.FundingRate*8 {
+		t.Errorf("Expected the 8h rate to be 8 hourly rates, got %v", btc.EightHourRate())
+	}
+}
+
+func TestDydxClient_CachesSettlementsUntilTheNextFunding(t *testing.T) {
+	server := newDydxServer(t)
+
+	now := time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)
+	client := NewDydxClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	client.now = func() time.Time { return now }
+
+	polls := []struct {
+		name      string
+		at        time.Time
+		histories string
+	}{
+		{"first poll", now, "BTC-USD,ETH-USD,SOL-USD"},
+		// Only the market without settlement is asked again
+		{"same hour", now.Add(20 * time.Minute), "SOL-USD"},
+		{"next settlement", time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), "BTC-USD,ETH-USD,SOL-USD"},
+	}
+	for _, poll := range polls {
+		now = poll.at
+		if _, err := client.GetFundingRates(context.Background()); err != nil {
+			t.Fatalf("Expected no error on the %s, got %v", poll.name, err)
+		}
+		if histories := strings.Join(server.requestedHistories(), ","); histories != poll.histories {
+			t.Errorf("Expected histories %s on the %s, got %s", poll.histories, poll.name, histories)
+		}
+	}
+}
+
+func TestDydxClient_GetFundingRatesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":[{"msg":"Internal Server Error"}]}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewDydxClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	if _, err := client.GetFundingRates(context.Background()); err == nil {
+		t.Error("Expected an error when the markets fail")
+	}
+	if client.IsHealthy(context.Background()) {
+		t.Error("Expected the client to be unhealthy")
+	}
+}
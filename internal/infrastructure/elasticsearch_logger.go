@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"fundingmonitor/internal/domain"
@@ -12,11 +16,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// esPageSize is the number of hits fetched per search_after page, below the
+// default index.max_result_window of 10000
+const esPageSize = 5000
+
 type ElasticsearchLogger struct {
 	client    *http.Client
 	baseURL   string
 	logger    *logrus.Logger
 	indexName string
+	now       func() time.Time
 }
 
 type FundingRateDocument struct {
@@ -29,13 +38,51 @@ type FundingRateDocument struct {
 	DataType    string    `json:"data_type"`
 }
 
+// esSearchResponse is the part of a search response the logger reads
+type esSearchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source FundingRateDocument `json:"_source"`
+			Sort   []json.RawMessage   `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
 func NewElasticsearchLogger(baseURL string, logger *logrus.Logger) *ElasticsearchLogger {
 	return &ElasticsearchLogger{
 		client:    &http.Client{Timeout: 10 * time.Second},
 		baseURL:   baseURL,
 		logger:    logger,
 		indexName: "funding-monitor",
+		now:       time.Now,
+	}
+}
+
+// EnsureIndexTemplate installs the index template mapping the daily indices,
+// so symbols and exchanges are exact match keywords and timestamps are dates
+func (e *ElasticsearchLogger) EnsureIndexTemplate() error {
+	template := map[string]interface{}{
+		"index_patterns": []string{e.indexName + "-*"},
+		"template": map[string]interface{}{
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"symbol":       map[string]interface{}{"type": "keyword"},
+					"exchange":     map[string]interface{}{"type": "keyword"},
+					"data_type":    map[string]interface{}{"type": "keyword"},
+					"funding_rate": map[string]interface{}{"type": "double"},
+					"mark_price":   map[string]interface{}{"type": "double"},
+					"index_price":  map[string]interface{}{"type": "double"},
+					"timestamp":    map[string]interface{}{"type": "date"},
+				},
+			},
+		},
+	}
+
+	if err := e.do("PUT", "/_index_template/"+e.indexName, template, nil); err != nil {
+		return fmt.Errorf("failed to install index template: %w", err)
 	}
+	e.logger.Infof("Installed Elasticsearch index template for %s-*", e.indexName)
+	return nil
 }
 
 func (e *ElasticsearchLogger) LogFundingRates(symbol string, rates []domain.FundingRate) error {
@@ -43,30 +90,65 @@ func (e *ElasticsearchLogger) LogFundingRates(symbol string, rates []domain.Fund
 		return nil
 	}
 
+	// Documents are timestamped with when they were logged, like the lines of FileLogger
+	timestamp := e.now()
+	docs := make([]FundingRateDocument, len(rates))
+	for i, rate := range rates {
+		docs[i] = newFundingRateDocument(symbol, rate, timestamp)
+	}
+	if err := e.bulkIndex(symbol, docs, false); err != nil {
+		return err
+	}
+
+	e.logger.Infof("Successfully logged %d funding rates for %s to Elasticsearch", len(rates), symbol)
+	return nil
+}
+
+// BackfillFundingRates indexes rates at their own timestamps, under ids made
+// of exchange, symbol and second so writing them again replaces the documents
+func (e *ElasticsearchLogger) BackfillFundingRates(symbol string, rates []domain.FundingRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	docs := make([]FundingRateDocument, len(rates))
+	for i, rate := range rates {
+		docs[i] = newFundingRateDocument(symbol, rate, rate.Timestamp)
+	}
+	return e.bulkIndex(symbol, docs, true)
+}
+
+func newFundingRateDocument(symbol string, rate domain.FundingRate, timestamp time.Time) FundingRateDocument {
+	return FundingRateDocument{
+		Symbol:      symbol,
+		Exchange:    rate.Exchange,
+		FundingRate: rate.FundingRate,
+		MarkPrice:   rate.MarkPrice,
+		IndexPrice:  rate.IndexPrice,
+		Timestamp:   timestamp,
+		DataType:    "funding_rate",
+	}
+}
+
+// bulkIndex writes documents to the daily indices of their timestamps, keyed
+// on exchange, symbol and second when keyed and under generated ids otherwise
+func (e *ElasticsearchLogger) bulkIndex(symbol string, docs []FundingRateDocument, keyed bool) error {
 	// Create bulk request
 	var bulkBody bytes.Buffer
 
-	for _, rate := range rates {
+	for _, doc := range docs {
 		// Index action
-		indexAction := map[string]interface{}{
-			"index": map[string]interface{}{
-				"_index": fmt.Sprintf("%s-%s", e.indexName, time.Now().Format("2006.01.02")),
-			},
+		action := map[string]interface{}{
+			"_index": e.dailyIndex(doc.Timestamp),
+		}
+		if keyed {
+			action["_id"] = fmt.Sprintf("%s-%s-%d", doc.Exchange, doc.Symbol, doc.Timestamp.Unix())
 		}
-		indexJSON, _ := json.Marshal(indexAction)
+		indexJSON, _ := json.Marshal(map[string]interface{}{"index": action})
 		bulkBody.Write(indexJSON)
 		bulkBody.WriteString("\n")
 
 		// Document
-		doc := FundingRateDocument{
-			Symbol:      symbol,
-			Exchange:    rate.Exchange,
-			FundingRate: rate.FundingRate,
-			MarkPrice:   rate.MarkPrice,
-			IndexPrice:  rate.IndexPrice,
-			Timestamp:   rate.Timestamp,
-			DataType:    "funding_rate",
-		}
 		docJSON, _ := json.Marshal(doc)
 		bulkBody.Write(docJSON)
 		bulkBody.WriteString("\n")
@@ -74,7 +156,7 @@ func (e *ElasticsearchLogger) LogFundingRates(symbol string, rates []domain.Fund
 
 	// Send bulk request
 	url := fmt.Sprintf("%s/_bulk", e.baseURL)
-	resp, err := e.client.Post(url, "application/json", &bulkBody)
+	resp, err := e.client.Post(url, "application/x-ndjson", &bulkBody)
 	if err != nil {
 		return fmt.Errorf("failed to send bulk request: %w", err)
 	}
@@ -84,63 +166,380 @@ func (e *ElasticsearchLogger) LogFundingRates(symbol string, rates []domain.Fund
 		return fmt.Errorf("elasticsearch bulk request failed with status: %d", resp.StatusCode)
 	}
 
-	e.logger.Infof("Successfully logged %d funding rates for %s to Elasticsearch", len(rates), symbol)
+	// A bulk request succeeds as a whole even when some documents are rejected
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.Errors {
+		return fmt.Errorf("elasticsearch rejected some funding rates for %s", symbol)
+	}
 	return nil
 }
 
+// GetSymbolLogs renders the documents of one local day in the FileLogger
+// line format, so existing consumers of the log endpoint keep working
 func (e *ElasticsearchLogger) GetSymbolLogs(symbol string, date string) ([]byte, error) {
-	// Query Elasticsearch for symbol logs
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": []map[string]interface{}{
-					{"term": map[string]interface{}{"symbol": symbol}},
-					{"term": map[string]interface{}{"data_type": "funding_rate"}},
+	day, ok := parseLogDate(date)
+	if !ok {
+		return nil, domain.ErrLogFileNotFound
+	}
+
+	// The day is selected by timestamp rather than index name, so documents
+	// shipped into UTC dated indices by Logstash are found too
+	filters := e.filters(domain.HistoryQuery{Symbol: symbol, From: day, To: day.AddDate(0, 0, 1)})
+
+	var content strings.Builder
+	err := e.searchAll(filters, func(doc FundingRateDocument) {
+		rate := domain.FundingRate{
+			Exchange:    doc.Exchange,
+			FundingRate: doc.FundingRate,
+			MarkPrice:   doc.MarkPrice,
+			IndexPrice:  doc.IndexPrice,
+		}
+		content.WriteString(formatLogLine(doc.Timestamp.Local(), symbol, rate))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if content.Len() == 0 {
+		return nil, domain.ErrLogFileNotFound
+	}
+	return []byte(content.String()), nil
+}
+
+// GetAllLogs lists one entry per symbol and daily index. Indices come from
+// _cat/indices, and each symbol is credited its share of the index size.
+func (e *ElasticsearchLogger) GetAllLogs() ([]domain.LogFile, error) {
+	var indices []struct {
+		Index     string `json:"index"`
+		DocsCount string `json:"docs.count"`
+		StoreSize string `json:"store.size"`
+	}
+	path := fmt.Sprintf("/_cat/indices/%s-*?format=json&bytes=b&h=index,docs.count,store.size", e.indexName)
+	if err := e.do("GET", path, nil, &indices); err != nil {
+		return nil, fmt.Errorf("failed to list indices: %w", err)
+	}
+	if len(indices) == 0 {
+		return []domain.LogFile{}, nil
+	}
+
+	search := map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": e.filters(domain.HistoryQuery{})}},
+		"aggs": map[string]interface{}{
+			"indices": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "_index", "size": len(indices)},
+				"aggs": map[string]interface{}{
+					"symbols": map[string]interface{}{
+						"terms": map[string]interface{}{"field": "symbol", "size": 10000},
+						"aggs": map[string]interface{}{
+							"modified": map[string]interface{}{"max": map[string]interface{}{"field": "timestamp"}},
+						},
+					},
 				},
 			},
 		},
-		"sort": []map[string]interface{}{
-			{"timestamp": map[string]interface{}{"order": "desc"}},
+	}
+
+	var response struct {
+		Aggregations struct {
+			Indices struct {
+				Buckets []struct {
+					Key     string `json:"key"`
+					Symbols struct {
+						Buckets []struct {
+							Key      string `json:"key"`
+							DocCount int64  `json:"doc_count"`
+							Modified struct {
+								Value float64 `json:"value"`
+							} `json:"modified"`
+						} `json:"buckets"`
+					} `json:"symbols"`
+				} `json:"buckets"`
+			} `json:"indices"`
+		} `json:"aggregations"`
+	}
+	if err := e.do("POST", fmt.Sprintf("/%s-*/_search", e.indexName), search, &response); err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+
+	type indexSize struct {
+		docs  int64
+		bytes int64
+	}
+	indexSizes := make(map[string]indexSize)
+	for _, index := range indices {
+		docs, _ := strconv.ParseInt(index.DocsCount, 10, 64)
+		size, _ := strconv.ParseInt(index.StoreSize, 10, 64)
+		indexSizes[index.Index] = indexSize{docs: docs, bytes: size}
+	}
+
+	logFiles := []domain.LogFile{}
+	for _, index := range response.Aggregations.Indices.Buckets {
+		date := strings.TrimPrefix(index.Key, e.indexName+"-")
+		if day, err := time.Parse("2006.01.02", date); err == nil {
+			date = day.Format("02-01-2006")
+		}
+
+		size := indexSizes[index.Key]
+		for _, symbol := range index.Symbols.Buckets {
+			var share int64
+			if size.docs > 0 {
+				share = size.bytes * symbol.DocCount / size.docs
+			}
+			logFiles = append(logFiles, domain.LogFile{
+				Symbol:   symbol.Key,
+				Date:     date,
+				Path:     index.Key,
+				Size:     share,
+				Modified: time.UnixMilli(int64(symbol.Modified.Value)),
+			})
+		}
+	}
+
+	sort.Slice(logFiles, func(i, j int) bool {
+		if logFiles[i].Symbol != logFiles[j].Symbol {
+			return logFiles[i].Symbol < logFiles[j].Symbol
+		}
+		return logFiles[i].Path < logFiles[j].Path
+	})
+	return logFiles, nil
+}
+
+// GetHistoricalFundingRates searches the daily indices for the query range.
+// Raw points are paged through with search_after, aggregated resolutions are
+// computed by Elasticsearch with a date histogram per exchange.
+func (e *ElasticsearchLogger) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
+	filters := e.filters(query)
+
+	if query.Resolution.Duration() == 0 {
+		history := []domain.FundingRateHistory{}
+		err := e.searchAll(filters, func(doc FundingRateDocument) {
+			history = append(history, domain.FundingRateHistory{
+				Exchange:    doc.Exchange,
+				Timestamp:   doc.Timestamp.Unix(),
+				FundingRate: doc.FundingRate,
+				MarkPrice:   doc.MarkPrice,
+				IndexPrice:  doc.IndexPrice,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return domain.AggregateFundingHistory(history, query.Resolution), nil
+	}
+
+	return e.aggregateHistory(filters, query.Resolution)
+}
+
+// aggregateHistory buckets the matching documents with date histograms, which
+// align on the epoch like domain.AggregateFundingHistory
+func (e *ElasticsearchLogger) aggregateHistory(filters []map[string]interface{}, resolution domain.HistoryResolution) ([]domain.FundingRateHistory, error) {
+	edgeHit := func(order string) map[string]interface{} {
+		return map[string]interface{}{"top_hits": map[string]interface{}{
+			"size":    1,
+			"sort":    []map[string]interface{}{{"timestamp": map[string]interface{}{"order": order}}},
+			"_source": []string{"funding_rate"},
+		}}
+	}
+	// Backfilled settlements carry no prices, the price means leave them out
+	pricedMean := func(field string) map[string]interface{} {
+		return map[string]interface{}{
+			"filter": map[string]interface{}{"range": map[string]interface{}{field: map[string]interface{}{"gt": 0}}},
+			"aggs":   map[string]interface{}{"mean": map[string]interface{}{"avg": map[string]interface{}{"field": field}}},
+		}
+	}
+
+	search := map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filters}},
+		"aggs": map[string]interface{}{
+			"exchanges": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "exchange", "size": 1000},
+				"aggs": map[string]interface{}{
+					"history": map[string]interface{}{
+						"date_histogram": map[string]interface{}{
+							"field":          "timestamp",
+							"fixed_interval": string(resolution),
+							"min_doc_count":  1,
+						},
+						"aggs": map[string]interface{}{
+							"funding_rate": map[string]interface{}{"avg": map[string]interface{}{"field": "funding_rate"}},
+							"mark_price":   pricedMean("mark_price"),
+							"index_price":  pricedMean("index_price"),
+							"high":         map[string]interface{}{"max": map[string]interface{}{"field": "funding_rate"}},
+							"low":          map[string]interface{}{"min": map[string]interface{}{"field": "funding_rate"}},
+							"open":         edgeHit("asc"),
+							"close":        edgeHit("desc"),
+						},
+					},
+				},
+			},
 		},
-		"size": 1000,
 	}
 
-	queryJSON, _ := json.Marshal(query)
-	url := fmt.Sprintf("%s/%s-%s/_search", e.baseURL, e.indexName, date)
+	type metric struct {
+		Value float64 `json:"value"`
+	}
+	type pricedMetric struct {
+		Mean metric `json:"mean"`
+	}
+	type edge struct {
+		Hits struct {
+			Hits []struct {
+				Source FundingRateDocument `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	edgeRate := func(hit edge) float64 {
+		if len(hit.Hits.Hits) == 0 {
+			return 0
+		}
+		return hit.Hits.Hits[0].Source.FundingRate
+	}
 
-	resp, err := e.client.Post(url, "application/json", bytes.NewBuffer(queryJSON))
-	if err != nil {
+	var response struct {
+		Aggregations struct {
+			Exchanges struct {
+				Buckets []struct {
+					Key     string `json:"key"`
+					History struct {
+						Buckets []struct {
+							Key         int64        `json:"key"` // epoch millis of the bucket start
+							DocCount    int          `json:"doc_count"`
+							FundingRate metric       `json:"funding_rate"`
+							MarkPrice   pricedMetric `json:"mark_price"`
+							IndexPrice  pricedMetric `json:"index_price"`
+							High        metric       `json:"high"`
+							Low         metric       `json:"low"`
+							Open        edge         `json:"open"`
+							Close       edge         `json:"close"`
+						} `json:"buckets"`
+					} `json:"history"`
+				} `json:"buckets"`
+			} `json:"exchanges"`
+		} `json:"aggregations"`
+	}
+	if err := e.do("POST", fmt.Sprintf("/%s-*/_search", e.indexName), search, &response); err != nil {
 		return nil, fmt.Errorf("failed to query elasticsearch: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("elasticsearch query failed with status: %d", resp.StatusCode)
+	history := []domain.FundingRateHistory{}
+	for _, exchange := range response.Aggregations.Exchanges.Buckets {
+		for _, bucket := range exchange.History.Buckets {
+			history = append(history, domain.FundingRateHistory{
+				Exchange:    exchange.Key,
+				Timestamp:   bucket.Key / 1000,
+				FundingRate: bucket.FundingRate.Value,
+				MarkPrice:   bucket.MarkPrice.Mean.Value,
+				IndexPrice:  bucket.IndexPrice.Mean.Value,
+				OHLC: &domain.FundingRateOHLC{
+					Open:  edgeRate(bucket.Open),
+					High:  bucket.High.Value,
+					Low:   bucket.Low.Value,
+					Close: edgeRate(bucket.Close),
+				},
+				Samples: bucket.DocCount,
+			})
+		}
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode elasticsearch response: %w", err)
+	// Already bucketed, this only orders exchanges like the other backends
+	return domain.AggregateFundingHistory(history, domain.ResolutionRaw), nil
+}
+
+// filters builds the bool filter clauses selecting the funding rate documents
+// of a query. An empty symbol matches every symbol.
+func (e *ElasticsearchLogger) filters(query domain.HistoryQuery) []map[string]interface{} {
+	filters := []map[string]interface{}{
+		{"term": map[string]interface{}{"data_type": "funding_rate"}},
+	}
+	if query.Symbol != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"symbol": query.Symbol}})
+	}
+	if len(query.Exchanges) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"exchange": query.Exchanges}})
 	}
 
-	return json.MarshalIndent(result, "", "  ")
+	timeRange := map[string]interface{}{"format": "epoch_second"}
+	if !query.From.IsZero() {
+		timeRange["gte"] = query.From.Unix()
+	}
+	if !query.To.IsZero() {
+		timeRange["lt"] = query.To.Unix()
+	}
+	if len(timeRange) > 1 {
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"timestamp": timeRange}})
+	}
+	return filters
 }
 
-func (e *ElasticsearchLogger) GetAllLogs() ([]domain.LogFile, error) {
-	// For Elasticsearch, we return a single log file representing the index
-	today := time.Now().Format("2006.01.02")
-	return []domain.LogFile{
-		{
-			Symbol:   "elasticsearch",
-			Date:     today,
-			Path:     fmt.Sprintf("%s-%s", e.indexName, today),
-			Size:     0, // Size not applicable for Elasticsearch
-			Modified: time.Now(),
+// searchAll visits every matching document oldest first, paging with search_after
+func (e *ElasticsearchLogger) searchAll(filters []map[string]interface{}, visit func(FundingRateDocument)) error {
+	search := map[string]interface{}{
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filters}},
+		"sort": []map[string]interface{}{
+			{"timestamp": map[string]interface{}{"order": "asc"}},
+			{"exchange": map[string]interface{}{"order": "asc"}},
 		},
-	}, nil
+		"size": esPageSize,
+	}
+
+	for {
+		var response esSearchResponse
+		if err := e.do("POST", fmt.Sprintf("/%s-*/_search", e.indexName), search, &response); err != nil {
+			return fmt.Errorf("failed to query elasticsearch: %w", err)
+		}
+
+		hits := response.Hits.Hits
+		for _, hit := range hits {
+			visit(hit.Source)
+		}
+		if len(hits) < esPageSize {
+			return nil
+		}
+		search["search_after"] = hits[len(hits)-1].Sort
+	}
+}
+
+// do sends a JSON request and decodes the response into out when given
+func (e *ElasticsearchLogger) do(method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, e.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("elasticsearch request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode elasticsearch response: %w", err)
+		}
+	}
+	return nil
 }
 
-func (e *ElasticsearchLogger) GetHistoricalFundingRates(symbol string, exchange string) ([]domain.FundingRateHistory, error) {
-	// Not implemented for ElasticsearchLogger
-	return []domain.FundingRateHistory{}, nil
+// dailyIndex returns the index documents logged at timestamp are written to
+func (e *ElasticsearchLogger) dailyIndex(timestamp time.Time) string {
+	return fmt.Sprintf("%s-%s", e.indexName, timestamp.Format("2006.01.02"))
 }
@@ -0,0 +1,400 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"fundingmonitor/internal/domain"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// esRequest is a request received by the fake Elasticsearch
+type esRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   string
+}
+
+// fakeElasticsearch records every request and answers with the handler
+// registered for its path
+type fakeElasticsearch struct {
+	mu       sync.Mutex
+	requests []esRequest
+	handlers map[string]func(req esRequest) string
+}
+
+func newFakeElasticsearch(t *testing.T, handlers map[string]func(req esRequest) string) (*fakeElasticsearch, *ElasticsearchLogger) {
+	fake := &fakeElasticsearch{handlers: handlers}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := esRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Body: string(body)}
+
+		fake.mu.Lock()
+		fake.requests = append(fake.requests, req)
+		fake.mu.Unlock()
+
+		handler, ok := fake.handlers[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"error":"no handler"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(handler(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	return fake, NewElasticsearchLogger(server.URL, logrus.New())
+}
+
+// searchBodies decodes the bodies of the search requests received so far
+func (f *fakeElasticsearch) searchBodies(t *testing.T) []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var bodies []map[string]interface{}
+	for _, req := range f.requests {
+		if strings.HasSuffix(req.Path, "/_search") {
+			var body map[string]interface{}
+			if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+				t.Fatalf("Failed to decode search body: %v", err)
+			}
+			bodies = append(bodies, body)
+		}
+	}
+	return bodies
+}
+
+// esHits renders a search response with one hit per document
+func esHits(docs ...FundingRateDocument) string {
+	type hit struct {
+		Source FundingRateDocument `json:"_source"`
+		Sort   []interface{}       `json:"sort"`
+	}
+	hits := make([]hit, len(docs))
+	for i, doc := range docs {
+		hits[i] = hit{Source: doc, Sort: []interface{}{doc.Timestamp.UnixMilli(), doc.Exchange}}
+	}
+
+	response := map[string]interface{}{"hits": map[string]interface{}{"hits": hits}}
+	data, _ := json.Marshal(response)
+	return string(data)
+}
+
+func TestElasticsearchLogger_EnsureIndexTemplate(t *testing.T) {
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/_index_template/funding-monitor": func(esRequest) string { return `{"acknowledged":true}` },
+	})
+
+	if err := esLogger.EnsureIndexTemplate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(fake.requests) != 1 || fake.requests[0].Method != "PUT" {
+		t.Fatalf("Expected a single PUT, got %+v", fake.requests)
+	}
+
+	var template struct {
+		IndexPatterns []string `json:"index_patterns"`
+		Template      struct {
+			Mappings struct {
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+			} `json:"mappings"`
+		} `json:"template"`
+	}
+	if err := json.Unmarshal([]byte(fake.requests[0].Body), &template); err != nil {
+		t.Fatalf("Failed to decode template: %v", err)
+	}
+	if len(template.IndexPatterns) != 1 || template.IndexPatterns[0] != "funding-monitor-*" {
+		t.Errorf("Expected the funding-monitor-* pattern, got %v", template.IndexPatterns)
+	}
+
+	expected := map[string]string{"symbol": "keyword", "exchange": "keyword", "data_type": "keyword", "timestamp": "date", "funding_rate": "double"}
+	for field, fieldType := range expected {
+		if property := template.Template.Mappings.Properties[field]; property.Type != fieldType {
+			t.Errorf("Expected %s mapped as %s, got %q", field, fieldType, property.Type)
+		}
+	}
+}
+
+func TestElasticsearchLogger_EnsureIndexTemplateError(t *testing.T) {
+	_, esLogger := newFakeElasticsearch(t, nil)
+
+	if err := esLogger.EnsureIndexTemplate(); err == nil {
+		t.Error("Expected an error when the template is rejected")
+	}
+}
+
+func TestElasticsearchLogger_LogFundingRates(t *testing.T) {
+	bulkResponse := `{"errors":false,"items":[]}`
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/_bulk": func(esRequest) string { return bulkResponse },
+	})
+	loggedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	esLogger.now = func() time.Time { return loggedAt }
+
+	rates := []domain.FundingRate{
+		{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: loggedAt.Add(-time.Hour)},
+		{Symbol: "BTC-USDT-SWAP", Exchange: "okx", FundingRate: 0.0002},
+	}
+	if err := esLogger.LogFundingRates("BTCUSDT", rates); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(fake.requests[0].Body), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected an action and a document per rate, got %d lines", len(lines))
+	}
+	if !strings.Contains(lines[0], `"_index":"funding-monitor-2024.01.02"`) {
+		t.Errorf("Expected the daily index of the log time, got %s", lines[0])
+	}
+
+	var doc FundingRateDocument
+	if err := json.Unmarshal([]byte(lines[3]), &doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if doc.Symbol != "BTCUSDT" || doc.Exchange != "okx" || doc.DataType != "funding_rate" || !doc.Timestamp.Equal(loggedAt) {
+		t.Errorf("Expected the okx document logged under BTCUSDT at the log time, got %+v", doc)
+	}
+
+	// Rejected documents fail the write
+	bulkResponse = `{"errors":true,"items":[]}`
+	if err := esLogger.LogFundingRates("BTCUSDT", rates); err == nil {
+		t.Error("Expected an error when documents are rejected")
+	}
+}
+
+func TestElasticsearchLogger_GetSymbolLogs(t *testing.T) {
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)
+
+	// The first page is full, so a second one is requested after its last hit
+	firstPage := make([]FundingRateDocument, esPageSize)
+	for i := range firstPage {
+		firstPage[i] = FundingRateDocument{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: day.Add(time.Duration(i) * time.Second)}
+	}
+	last := FundingRateDocument{Symbol: "BTCUSDT", Exchange: "bybit", FundingRate: 0.0002, MarkPrice: 42001, IndexPrice: 41999.5, Timestamp: day.Add(12 * time.Hour)}
+
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/funding-monitor-*/_search": func(req esRequest) string {
+			if strings.Contains(req.Body, "search_after") {
+				return esHits(last)
+			}
+			return esHits(firstPage...)
+		},
+	})
+
+	content, err := esLogger.GetSymbolLogs("BTCUSDT", "2024-03-05")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != esPageSize+1 {
+		t.Fatalf("Expected %d lines, got %d", esPageSize+1, len(lines))
+	}
+	expected := "[2024-03-05 12:00:00] Symbol: BTCUSDT, Exchange: bybit, Funding Rate: 0.000200, Mark Price: 42001.00, Index Price: 41999.50"
+	if lines[len(lines)-1] != expected {
+		t.Errorf("Expected FileLogger line %q, got %q", expected, lines[len(lines)-1])
+	}
+
+	bodies := fake.searchBodies(t)
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(bodies))
+	}
+	searchAfter, _ := bodies[1]["search_after"].([]interface{})
+	if len(searchAfter) != 2 || searchAfter[1] != "binance" {
+		t.Errorf("Expected search_after from the last hit, got %v", bodies[1]["search_after"])
+	}
+
+	// Whichever spelling, the query covers the local day
+	filter, _ := json.Marshal(bodies[0]["query"])
+	for _, expected := range []string{`"symbol":"BTCUSDT"`, `"data_type":"funding_rate"`, `"gte":` + jsonInt(day.Unix()), `"lt":` + jsonInt(day.AddDate(0, 0, 1).Unix())} {
+		if !strings.Contains(string(filter), expected) {
+			t.Errorf("Expected query to contain %s, got %s", expected, filter)
+		}
+	}
+}
+
+func TestElasticsearchLogger_GetSymbolLogsNotFound(t *testing.T) {
+	_, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/funding-monitor-*/_search": func(esRequest) string { return esHits() },
+	})
+
+	for _, date := range []string{"05-03-2024", "not-a-date"} {
+		if _, err := esLogger.GetSymbolLogs("BTCUSDT", date); err != domain.ErrLogFileNotFound {
+			t.Errorf("Expected ErrLogFileNotFound for %s, got %v", date, err)
+		}
+	}
+}
+
+func TestElasticsearchLogger_GetAllLogs(t *testing.T) {
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/_cat/indices/funding-monitor-*": func(esRequest) string {
+			return `[{"index":"funding-monitor-2024.01.01","docs.count":"300","store.size":"3000"},
+				{"index":"funding-monitor-2024.01.02","docs.count":"100","store.size":"1500"}]`
+		},
+		"/funding-monitor-*/_search": func(esRequest) string {
+			return `{"aggregations":{"indices":{"buckets":[
+				{"key":"funding-monitor-2024.01.01","symbols":{"buckets":[
+					{"key":"ETHUSDT","doc_count":100,"modified":{"value":1704112000000}},
+					{"key":"BTCUSDT","doc_count":200,"modified":{"value":1704113000000}}]}},
+				{"key":"funding-monitor-2024.01.02","symbols":{"buckets":[
+					{"key":"BTCUSDT","doc_count":100,"modified":{"value":1704199000000}}]}}]}}}`
+		},
+	})
+
+	logFiles, err := esLogger.GetAllLogs()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(logFiles) != 3 {
+		t.Fatalf("Expected one entry per symbol and day, got %+v", logFiles)
+	}
+
+	first := logFiles[0]
+	if first.Symbol != "BTCUSDT" || first.Date != "01-01-2024" || first.Path != "funding-monitor-2024.01.01" {
+		t.Errorf("Expected BTCUSDT on 01-01-2024 first, got %+v", first)
+	}
+	if first.Size != 2000 {
+		t.Errorf("Expected two thirds of the index size, got %d", first.Size)
+	}
+	if !first.Modified.Equal(time.UnixMilli(1704113000000)) {
+		t.Errorf("Expected the latest document time, got %v", first.Modified)
+	}
+	if logFiles[2].Symbol != "ETHUSDT" {
+		t.Errorf("Expected ETHUSDT last, got %+v", logFiles[2])
+	}
+
+	if !strings.Contains(fake.requests[0].Query, "format=json") {
+		t.Errorf("Expected JSON index listing, got %s", fake.requests[0].Query)
+	}
+}
+
+func TestElasticsearchLogger_GetHistoricalFundingRates(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/funding-monitor-*/_search": func(esRequest) string {
+			return esHits(
+				FundingRateDocument{Exchange: "binance", FundingRate: 0.0001, MarkPrice: 42000, Timestamp: from},
+				FundingRateDocument{Exchange: "binance", FundingRate: 0.0002, MarkPrice: 42100, Timestamp: from.Add(time.Minute)},
+			)
+		},
+	})
+
+	history, err := esLogger.GetHistoricalFundingRates(domain.HistoryQuery{
+		Symbol:    "BTCUSDT",
+		Exchanges: []string{"binance"},
+		From:      from,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(history) != 2 || history[1].Timestamp != from.Add(time.Minute).Unix() || history[1].MarkPrice != 42100 {
+		t.Errorf("Expected the raw points with prices, got %+v", history)
+	}
+
+	filter, _ := json.Marshal(fake.searchBodies(t)[0]["query"])
+	for _, expected := range []string{`"exchange":["binance"]`, `"gte":` + jsonInt(from.Unix())} {
+		if !strings.Contains(string(filter), expected) {
+			t.Errorf("Expected query to contain %s, got %s", expected, filter)
+		}
+	}
+	if strings.Contains(string(filter), `"lt"`) {
+		t.Errorf("Expected no upper bound, got %s", filter)
+	}
+}
+
+func TestElasticsearchLogger_GetHistoricalFundingRatesAggregated(t *testing.T) {
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/funding-monitor-*/_search": func(esRequest) string {
+			return `{"aggregations":{"exchanges":{"buckets":[
+				{"key":"bybit","history":{"buckets":[
+					{"key":1704067200000,"doc_count":2,
+					 "funding_rate":{"value":0.00015},"mark_price":{"doc_count":2,"mean":{"value":42050}},"index_price":{"doc_count":2,"mean":{"value":42040}},
+					 "high":{"value":0.0002},"low":{"value":0.0001},
+					 "open":{"hits":{"hits":[{"_source":{"funding_rate":0.0001}}]}},
+					 "close":{"hits":{"hits":[{"_source":{"funding_rate":0.0002}}]}}}]}},
+				{"key":"binance","history":{"buckets":[
+					{"key":1704096000000,"doc_count":1,
+					 "funding_rate":{"value":0.0003},"mark_price":{"doc_count":0,"mean":{"value":null}},"index_price":{"doc_count":0,"mean":{"value":null}},
+					 "high":{"value":0.0003},"low":{"value":0.0003},
+					 "open":{"hits":{"hits":[{"_source":{"funding_rate":0.0003}}]}},
+					 "close":{"hits":{"hits":[{"_source":{"funding_rate":0.0003}}]}}}]}}]}}}`
+		},
+	})
+
+	history, err := esLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT", Resolution: domain.Resolution8h})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(history) != 2 || history[0].Exchange != "binance" {
+		t.Fatalf("Expected a bucket per exchange, binance first, got %+v", history)
+	}
+	bybit := history[1]
+	if bybit.Timestamp != 1704067200 || bybit.Samples != 2 || bybit.FundingRate != 0.00015 || bybit.MarkPrice != 42050 {
+		t.Errorf("Expected the bybit bucket means, got %+v", bybit)
+	}
+	if bybit.OHLC == nil || *bybit.OHLC != (domain.FundingRateOHLC{Open: 0.0001, High: 0.0002, Low: 0.0001, Close: 0.0002}) {
+		t.Errorf("Expected OHLC 0.0001/0.0002/0.0001/0.0002, got %+v", bybit.OHLC)
+	}
+
+	// The binance bucket only holds a backfilled settlement without prices
+	if history[0].MarkPrice != 0 || history[0].IndexPrice != 0 {
+		t.Errorf("Expected no prices for the binance bucket, got %+v", history[0])
+	}
+
+	body, _ := json.Marshal(fake.searchBodies(t)[0]["aggs"])
+	for _, expected := range []string{`"fixed_interval":"8h"`, `"range":{"mark_price":{"gt":0}}`} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("Expected aggregations to contain %s, got %s", expected, body)
+		}
+	}
+}
+
+func jsonInt(value int64) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func TestElasticsearchLogger_BackfillFundingRates(t *testing.T) {
+	fake, esLogger := newFakeElasticsearch(t, map[string]func(esRequest) string{
+		"/_bulk": func(esRequest) string { return `{"errors":false,"items":[]}` },
+	})
+	settledAt := time.Date(2024, 1, 2, 8, 0, 0, 0, time.Local)
+
+	rates := []domain.FundingRate{
+		{Symbol: "BTC-USDT-SWAP", Exchange: "okx", FundingRate: 0.0001, Timestamp: settledAt},
+	}
+	if err := esLogger.BackfillFundingRates("BTCUSDT", rates); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(fake.requests[0].Body), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected an action and a document, got %d lines", len(lines))
+	}
+	// The id makes writing the settlement again replace it
+	expectedID := fmt.Sprintf(`"_id":"okx-BTCUSDT-%d"`, settledAt.Unix())
+	if !strings.Contains(lines[0], expectedID) || !strings.Contains(lines[0], `"_index":"funding-monitor-2024.01.02"`) {
+		t.Errorf("Expected the settlement keyed in the index of its day, got %s", lines[0])
+	}
+
+	var doc FundingRateDocument
+	if err := json.Unmarshal([]byte(lines[1]), &doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if !doc.Timestamp.Equal(settledAt) || doc.Symbol != "BTCUSDT" {
+		t.Errorf("Expected the document at the settlement time under BTCUSDT, got %+v", doc)
+	}
+}
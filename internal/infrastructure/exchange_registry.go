@@ -0,0 +1,87 @@
+package infrastructure
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExchangeConstructor creates an exchange client on the shared HTTP transport
+type ExchangeConstructor func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository
+
+// ExchangeRegistration is what an exchange client registers about itself
+type ExchangeRegistration struct {
+	Name           string
+	DefaultBaseURL string
+	RateLimit      float64       // default requests per second, 0 uses defaultRateLimit
+	Burst          int           // default burst, 0 allows one second of requests
+	Timeout        time.Duration // default poll deadline when longer than exchange_timeout
+	Capabilities   domain.ExchangeCapabilities
+	SymbolParser   SymbolParser // maps venue symbols onto canonical instruments
+	New            ExchangeConstructor
+
+	// DefaultInverseBaseURL is the host of the coin-margined contracts when
+	// they aren't served from DefaultBaseURL
+	DefaultInverseBaseURL string
+}
+
+var (
+	registryMu       sync.RWMutex
+	exchangeRegistry = make(map[string]ExchangeRegistration)
+)
+
+// RegisterExchange makes an exchange available to the factory and the
+// config defaults. Clients call it from init; it panics when a name is
+// registered twice or the registration is incomplete.
+func RegisterExchange(registration ExchangeRegistration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if registration.Name == "" || registration.New == nil {
+		panic("infrastructure: exchange registration needs a name and a constructor")
+	}
+	if _, exists := exchangeRegistry[registration.Name]; exists {
+		panic(fmt.Sprintf("infrastructure: exchange %s registered twice", registration.Name))
+	}
+	exchangeRegistry[registration.Name] = registration
+}
+
+// LookupExchange returns the registration of an exchange
+func LookupExchange(name string) (ExchangeRegistration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	registration, ok := exchangeRegistry[name]
+	return registration, ok
+}
+
+// RegisteredExchanges returns every registered exchange in alphabetical order
+func RegisteredExchanges() []ExchangeRegistration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	registrations := make([]ExchangeRegistration, 0, len(exchangeRegistry))
+	for _, registration := range exchangeRegistry {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
+
+// registeredExchangeNames returns the names of the registered exchanges
+func registeredExchangeNames() []string {
+	registrations := RegisteredExchanges()
+	names := make([]string, 0, len(registrations))
+	for _, registration := range registrations {
+		names = append(names, registration.Name)
+	}
+	return names
+}
@@ -0,0 +1,120 @@
+package infrastructure
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRegisteredExchanges(t *testing.T) {
+	expected := []string{"binance", "bingx", "bitget", "bybit", "deribit", "dydx", "gate", "htx", "hyperliquid", "kucoin", "mexc", "okx", "xt"}
+	names := registeredExchangeNames()
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+
+	for _, registration := range RegisteredExchanges() {
+		if registration.DefaultBaseURL == "" || registration.SymbolParser == nil {
+			t.Errorf("Expected a default base URL and a symbol parser for %s", registration.Name)
+		}
+		// Clients report the name they were registered under
+		if name := registration.New(domain.ExchangeConfig{}, nil, logrus.New()).GetName(); name != registration.Name {
+			t.Errorf("Expected %s, got %s", registration.Name, name)
+		}
+	}
+}
+
+func TestRegisterExchange_PanicsOnDuplicates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering binance twice to panic")
+		}
+	}()
+
+	registration, _ := LookupExchange("binance")
+	RegisterExchange(registration)
+}
+
+func TestExchangeFactory_CreateExchanges(t *testing.T) {
+	factory := NewExchangeFactory(logrus.New())
+
+	exchanges, err := factory.CreateExchanges(&domain.Config{Exchanges: map[string]domain.ExchangeConfig{
+		"binance": {Enabled: true},
+		"okx":     {Enabled: true, BaseURL: "http://okx.local"},
+		"xt":      {Enabled: true},
+		"gate":    {Enabled: false},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(exchanges) != 3 {
+		t.Fatalf("Expected 3 enabled exchanges, got %d", len(exchanges))
+	}
+	if binance := exchanges["binance"].(*BinanceClient); binance.config.BaseURL != "https://fapi.binance.com" || binance.config.InverseBaseURL != "https://dapi.binance.com" || binance.config.RateLimit != 4 {
+		t.Errorf("Expected the registered defaults, got %+v", binance.config)
+	}
+	if okx := exchanges["okx"].(*OKXClient); okx.config.BaseURL != "http://okx.local" {
+		t.Errorf("Expected the configured base URL, got %s", okx.config.BaseURL)
+	}
+	if xt := exchanges["xt"].(*XTClient); xt.config.RateLimit != 40 || xt.config.Burst != 80 {
+		t.Errorf("Expected the registered XT rate limit, got %+v", xt.config)
+	}
+
+	_, err = factory.CreateExchanges(&domain.Config{Exchanges: map[string]domain.ExchangeConfig{
+		"binanse": {Enabled: false},
+	}})
+	if !errors.Is(err, domain.ErrInvalidConfig) || !strings.Contains(err.Error(), `"binanse"`) {
+		t.Errorf("Expected an unknown exchange error, got %v", err)
+	}
+}
+
+func TestExchangeTimeouts(t *testing.T) {
+	defaultTimeout, overrides := ExchangeTimeouts(&domain.Config{ExchangeTimeout: 15, Exchanges: map[string]domain.ExchangeConfig{
+		"binance": {Enabled: true},
+		"okx":     {Enabled: true, Timeout: 5},
+		"xt":      {Enabled: true},
+	}})
+	if defaultTimeout != 15*time.Second {
+		t.Errorf("Expected the 15s default, got %v", defaultTimeout)
+	}
+	if len(overrides) != 2 || overrides["okx"] != 5*time.Second || overrides["xt"] != 30*time.Second {
+		t.Errorf("Expected the configured okx and registered xt timeouts, got %v", overrides)
+	}
+
+	// The registered timeout only ever lengthens the default
+	_, overrides = ExchangeTimeouts(&domain.Config{ExchangeTimeout: 60, Exchanges: map[string]domain.ExchangeConfig{"xt": {Enabled: true}}})
+	if len(overrides) != 0 {
+		t.Errorf("Expected the 60s default for xt, got %v", overrides)
+	}
+}
+
+func TestExchangeFactory_DescribeExchanges(t *testing.T) {
+	factory := NewExchangeFactory(logrus.New())
+	descriptors := factory.DescribeExchanges(&domain.Config{Exchanges: map[string]domain.ExchangeConfig{
+		"bitget": {Enabled: true},
+	}})
+
+	if len(descriptors) != len(RegisteredExchanges()) {
+		t.Fatalf("Expected every registered exchange, got %d", len(descriptors))
+	}
+	var bitget domain.ExchangeDescriptor
+	for _, descriptor := range descriptors {
+		if descriptor.Enabled != (descriptor.Name == "bitget") {
+			t.Errorf("Expected only bitget enabled, got %+v", descriptor)
+		}
+		if descriptor.RateLimit <= 0 {
+			t.Errorf("Expected a rate limit for %s", descriptor.Name)
+		}
+		if descriptor.Name == "bitget" {
+			bitget = descriptor
+		}
+	}
+	if bitget.Name != "bitget" || bitget.Capabilities.FundingInterval || !bitget.Capabilities.MarkPrice {
+		t.Errorf("Expected bitget without funding intervals, got %+v", bitget)
+	}
+}
@@ -1,9 +1,12 @@
 package infrastructure
 
 import (
+	"fmt"
 	"fundingmonitor/internal/domain"
 	"fundingmonitor/internal/usecase"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -19,61 +22,130 @@ func NewExchangeFactory(logger *logrus.Logger) *ExchangeFactory {
 	}
 }
 
-// CreateExchanges creates all enabled exchanges
+// CreateExchanges creates all enabled exchanges on a shared HTTP transport.
+// Configuring an exchange that isn't registered is an error.
 func (f *ExchangeFactory) CreateExchanges(config *domain.Config) (map[string]domain.ExchangeRepository, error) {
+	transport, err := NewHTTPTransport(config.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
 	exchanges := make(map[string]domain.ExchangeRepository)
 
 	for name, exchangeConfig := range config.Exchanges {
+		registration, ok := LookupExchange(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown exchange %q, registered exchanges are %s",
+				domain.ErrInvalidConfig, name, strings.Join(registeredExchangeNames(), ", "))
+		}
 		if !exchangeConfig.Enabled {
 			continue
 		}
 
-		var exchange domain.ExchangeRepository
-		switch name {
-		case "binance":
-			exchange = NewBinanceClient(exchangeConfig, f.logger)
-		case "bybit":
-			exchange = NewBybitClient(exchangeConfig, f.logger)
-		case "okx":
-			exchange = NewOKXClient(exchangeConfig, f.logger)
-		case "mexc":
-			exchange = NewMEXCClient(exchangeConfig, f.logger)
-		case "bitget":
-			exchange = NewBitgetClient(exchangeConfig, f.logger)
-		case "gate":
-			exchange = NewGateClient(exchangeConfig, f.logger)
-		case "deribit":
-			exchange = NewDeribitClient(exchangeConfig, f.logger)
-		case "xt":
-			exchange = NewXTClient(exchangeConfig, f.logger)
-		case "kucoin":
-			exchange = NewKuCoinClient(exchangeConfig, f.logger)
-		default:
-			f.logger.Warnf("Unknown exchange: %s", name)
-			continue
+		if exchangeConfig.BaseURL == "" {
+			exchangeConfig.BaseURL = registration.DefaultBaseURL
+		}
+		if exchangeConfig.InverseBaseURL == "" {
+			exchangeConfig.InverseBaseURL = registration.DefaultInverseBaseURL
+		}
+		if exchangeConfig.RateLimit <= 0 {
+			exchangeConfig.RateLimit = registration.RateLimit
+		}
+		if exchangeConfig.Burst <= 0 {
+			exchangeConfig.Burst = registration.Burst
 		}
 
-		exchanges[name] = exchange
+		exchanges[name] = registration.New(exchangeConfig, transport.Client(exchangeConfig), f.logger)
 		f.logger.Infof("Initialized exchange: %s", name)
 	}
 
 	return exchanges, nil
 }
 
+// DescribeExchanges lists every registered exchange and whether it is enabled
+func (f *ExchangeFactory) DescribeExchanges(config *domain.Config) []domain.ExchangeDescriptor {
+	registrations := RegisteredExchanges()
+	descriptors := make([]domain.ExchangeDescriptor, 0, len(registrations))
+	for _, registration := range registrations {
+		rateLimit := registration.RateLimit
+		if rateLimit <= 0 {
+			rateLimit = defaultRateLimit
+		}
+		descriptors = append(descriptors, domain.ExchangeDescriptor{
+			Name:           registration.Name,
+			DefaultBaseURL: registration.DefaultBaseURL,
+			RateLimit:      rateLimit,
+			Capabilities:   registration.Capabilities,
+			Enabled:        config.Exchanges[registration.Name].Enabled,
+		})
+	}
+	return descriptors
+}
+
 // CreateUseCases creates all use cases
 func (f *ExchangeFactory) CreateUseCases(exchanges map[string]domain.ExchangeRepository, logRepo domain.LogRepository) *usecase.MultiExchangeUseCase {
 	return usecase.NewMultiExchangeUseCase(exchanges, logRepo)
 }
 
+// CreateSymbolMapper creates the canonical symbol mapper with config overrides
+func (f *ExchangeFactory) CreateSymbolMapper(config *domain.Config) *SymbolMapper {
+	return NewSymbolMapper(config.SymbolOverrides)
+}
+
 // CreateLogRepository creates the appropriate log repository
-func (f *ExchangeFactory) CreateLogRepository(logDir string, logger *logrus.Logger) domain.LogRepository {
+func (f *ExchangeFactory) CreateLogRepository(config *domain.Config, logDir string, logger *logrus.Logger) (domain.LogRepository, error) {
 	// Check if Elasticsearch is available
 	elasticsearchURL := os.Getenv("ELASTICSEARCH_URL")
 	if elasticsearchURL != "" {
 		logger.Info("Using Elasticsearch for logging")
-		return NewElasticsearchLogger(elasticsearchURL, logger)
+		esLogger := NewElasticsearchLogger(elasticsearchURL, logger)
+		if err := esLogger.EnsureIndexTemplate(); err != nil {
+			// Indices created before the template is installed get dynamic mappings
+			logger.Warnf("Elasticsearch index template not installed: %v", err)
+		}
+		return esLogger, nil
+	}
+
+	switch config.LogStorage {
+	case "", "file":
+		logger.Info("Using file-based logging")
+		return NewFileLogger(logDir, logger), nil
+	case "sqlite":
+		path := config.SQLitePath
+		if path == "" {
+			path = filepath.Join(logDir, "funding.db")
+		}
+		logger.Infof("Using SQLite logging at %s", path)
+		return NewSQLiteLogger(path, logger)
+	default:
+		return nil, fmt.Errorf("unknown log storage: %s", config.LogStorage)
+	}
+}
+
+// CreateWebhookNotifier creates the webhook notifier, keeping its dead letters
+// in the log directory unless configured otherwise
+func (f *ExchangeFactory) CreateWebhookNotifier(config *domain.Config, logDir string) (*WebhookNotifier, error) {
+	webhooks := config.Webhooks
+	if webhooks.DeadLetterPath == "" {
+		webhooks.DeadLetterPath = filepath.Join(logDir, "webhook_dead_letters.jsonl")
+	}
+	return NewWebhookNotifier(webhooks, f.logger)
+}
+
+// CreateDigestNotifier creates the chat notifier of a digest channel
+func (f *ExchangeFactory) CreateDigestNotifier(channel domain.DigestChannel) (domain.DigestNotifier, error) {
+	if err := channel.Validate(); err != nil {
+		return nil, err
 	}
 
-	logger.Info("Using file-based logging")
-	return NewFileLogger(logDir, logger)
+	switch channel.Type {
+	case domain.DigestTelegram:
+		return NewTelegramNotifier(channel), nil
+	case domain.DigestSlack:
+		return NewSlackNotifier(channel), nil
+	case domain.DigestDiscord:
+		return NewDiscordNotifier(channel), nil
+	default:
+		return nil, fmt.Errorf("unknown digest channel type: %s", channel.Type)
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"fundingmonitor/internal/domain"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,11 +43,9 @@ func (f *FileLogger) LogFundingRates(symbol string, rates []domain.FundingRate)
 	defer file.Close()
 
 	// Write each rate on a single line with timestamp
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	timestamp := time.Now()
 	for _, rate := range rates {
-		rateLine := fmt.Sprintf("[%s] Symbol: %s, Exchange: %s, Funding Rate: %.6f, Mark Price: %.2f, Index Price: %.2f\n",
-			timestamp, symbol, rate.Exchange, rate.FundingRate, rate.MarkPrice, rate.IndexPrice)
-		if _, err := file.WriteString(rateLine); err != nil {
+		if _, err := file.WriteString(formatLogLine(timestamp, symbol, rate)); err != nil {
 			return fmt.Errorf("failed to write rate to log file for %s: %w", symbol, err)
 		}
 	}
@@ -54,6 +53,143 @@ func (f *FileLogger) LogFundingRates(symbol string, rates []domain.FundingRate)
 	return nil
 }
 
+// BackfillFundingRates appends rates to the daily files of their own local
+// timestamps, skipping those whose exchange already has a line at the same
+// second in the file
+func (f *FileLogger) BackfillFundingRates(symbol string, rates []domain.FundingRate) error {
+	pairDir := filepath.Join(f.logDir, symbol)
+	if err := os.MkdirAll(pairDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", symbol, err)
+	}
+
+	days := make(map[string][]domain.FundingRate)
+	var order []string
+	for _, rate := range rates {
+		rate.Timestamp = rate.Timestamp.Local()
+		day := rate.Timestamp.Format("02-01-2006")
+		if _, ok := days[day]; !ok {
+			order = append(order, day)
+		}
+		days[day] = append(days[day], rate)
+	}
+
+	for _, day := range order {
+		if err := f.backfillDay(symbol, filepath.Join(pairDir, fmt.Sprintf("%s.log", day)), days[day]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillDay appends the rates not yet logged to one daily file
+func (f *FileLogger) backfillDay(symbol string, filename string, rates []domain.FundingRate) error {
+	logged := make(map[string]bool)
+	content, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read log file for %s: %w", symbol, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if _, rate, ok := parseLogLine(line); ok {
+			logged[logLineKey(rate)] = true
+		}
+	}
+
+	var lines strings.Builder
+	for _, rate := range rates {
+		if key := logLineKey(rate); !logged[key] {
+			logged[key] = true
+			lines.WriteString(formatLogLine(rate.Timestamp, symbol, rate))
+		}
+	}
+	if lines.Len() == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file for %s: %w", symbol, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(lines.String()); err != nil {
+		return fmt.Errorf("failed to write rates to log file for %s: %w", symbol, err)
+	}
+	return nil
+}
+
+// logLineKey identifies the line of a rate within a daily file
+func logLineKey(rate domain.FundingRate) string {
+	return fmt.Sprintf("%s@%d", rate.Exchange, rate.Timestamp.Unix())
+}
+
+// logTimestampFormat is the local time prefix of every log line
+const logTimestampFormat = "2006-01-02 15:04:05"
+
+// formatLogLine renders a rate as a single log line with timestamp
+func formatLogLine(timestamp time.Time, symbol string, rate domain.FundingRate) string {
+	return fmt.Sprintf("[%s] Symbol: %s, Exchange: %s, Funding Rate: %.6f, Mark Price: %.2f, Index Price: %.2f\n",
+		timestamp.Format(logTimestampFormat), symbol, rate.Exchange, rate.FundingRate, rate.MarkPrice, rate.IndexPrice)
+}
+
+// parseLogLine reads back the symbol and rate of a line written by
+// formatLogLine. The timestamp, in local time as written, becomes the rate's.
+func parseLogLine(line string) (string, domain.FundingRate, bool) {
+	line = strings.TrimSpace(line)
+	end := strings.Index(line, "] ")
+	if !strings.HasPrefix(line, "[") || end == -1 {
+		return "", domain.FundingRate{}, false
+	}
+
+	timestamp, err := time.ParseInLocation(logTimestampFormat, line[1:end], time.Local)
+	if err != nil {
+		return "", domain.FundingRate{}, false
+	}
+
+	var symbol string
+	var rate domain.FundingRate
+	var hasRate bool
+	for _, field := range strings.Split(line[end+2:], ", ") {
+		key, value, ok := strings.Cut(field, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Symbol":
+			symbol = value
+		case "Exchange":
+			rate.Exchange = value
+		case "Funding Rate":
+			rate.FundingRate, err = strconv.ParseFloat(value, 64)
+			hasRate = err == nil
+		case "Mark Price":
+			rate.MarkPrice, _ = strconv.ParseFloat(value, 64)
+		case "Index Price":
+			rate.IndexPrice, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+	if rate.Exchange == "" || !hasRate {
+		return "", domain.FundingRate{}, false
+	}
+
+	rate.Symbol = symbol
+	rate.Timestamp = timestamp
+	return symbol, rate, true
+}
+
+// logDateFormats are the accepted spellings of a log date, DD-MM-YYYY being
+// the one used in file names
+var logDateFormats = []string{"02-01-2006", "2006-01-02"}
+
+// parseLogDate parses a log date in local time
+func parseLogDate(date string) (time.Time, bool) {
+	for _, layout := range logDateFormats {
+		if day, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
 func (f *FileLogger) GetSymbolLogs(symbol string, date string) ([]byte, error) {
 	// Convert from YYYY-MM-DD to DD-MM-YYYY if needed
 	if len(date) == 10 && date[4] == '-' && date[7] == '-' {
@@ -121,52 +257,49 @@ func (f *FileLogger) GetAllLogs() ([]domain.LogFile, error) {
 	return logFiles, nil
 }
 
-func (f *FileLogger) GetHistoricalFundingRates(symbol string, exchange string) ([]domain.FundingRateHistory, error) {
-	var history []domain.FundingRateHistory
-	pairDir := filepath.Join(f.logDir, symbol)
+// GetHistoricalFundingRates reads the daily files overlapping the query range
+func (f *FileLogger) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
+	history := []domain.FundingRateHistory{}
+	pairDir := filepath.Join(f.logDir, query.Symbol)
 	files, err := os.ReadDir(pairDir)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
 		return nil, err
 	}
 	for _, file := range files {
 		if file.IsDir() || !strings.HasSuffix(file.Name(), ".log") {
 			continue
 		}
+
+		// Skip days entirely outside the range without reading them
+		if day, ok := parseLogDate(strings.TrimSuffix(file.Name(), ".log")); ok {
+			if (!query.To.IsZero() && !day.Before(query.To)) ||
+				(!query.From.IsZero() && !day.AddDate(0, 0, 1).After(query.From)) {
+				continue
+			}
+		}
+
 		filename := filepath.Join(pairDir, file.Name())
 		content, err := os.ReadFile(filename)
 		if err != nil {
+			f.logger.Warnf("Failed to read log file %s: %v", filename, err)
 			continue
 		}
-		lines := strings.Split(string(content), "\n")
-		var currentTimestamp int64
-		for _, line := range lines {
-			if strings.HasPrefix(line, "[") && strings.Contains(line, "] Symbol: ") {
-				// Parse timestamp
-				endIdx := strings.Index(line, "]")
-				if endIdx > 1 {
-					tsStr := line[1:endIdx]
-					ts, err := time.Parse("2006-01-02 15:04:05", tsStr)
-					if err == nil {
-						currentTimestamp = ts.Unix()
-					}
-				}
-			} else if strings.Contains(line, "Exchange: "+exchange+",") {
-				// Parse funding rate
-				parts := strings.Split(line, ",")
-				for _, part := range parts {
-					part = strings.TrimSpace(part)
-					if strings.HasPrefix(part, "Funding Rate: ") {
-						frStr := strings.TrimPrefix(part, "Funding Rate: ")
-						var fr float64
-						fmt.Sscanf(frStr, "%f", &fr)
-						history = append(history, domain.FundingRateHistory{
-							Timestamp:   currentTimestamp,
-							FundingRate: fr,
-						})
-					}
-				}
+		for _, line := range strings.Split(string(content), "\n") {
+			_, rate, ok := parseLogLine(line)
+			if !ok || !query.Includes(rate.Exchange, rate.Timestamp) {
+				continue
 			}
+			history = append(history, domain.FundingRateHistory{
+				Exchange:    rate.Exchange,
+				Timestamp:   rate.Timestamp.Unix(),
+				FundingRate: rate.FundingRate,
+				MarkPrice:   rate.MarkPrice,
+				IndexPrice:  rate.IndexPrice,
+			})
 		}
 	}
-	return history, nil
+	return domain.AggregateFundingHistory(history, query.Resolution), nil
 }
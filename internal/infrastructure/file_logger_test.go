@@ -146,3 +146,120 @@ func TestFileLogger_GetAllLogs(t *testing.T) {
 		}
 	}
 }
+
+func TestFileLogger_GetHistoricalFundingRates(t *testing.T) {
+	tempDir := t.TempDir()
+	fileLogger := NewFileLogger(tempDir, logrus.New())
+
+	symbolDir := filepath.Join(tempDir, "BTCUSDT")
+	if err := os.MkdirAll(symbolDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"01-01-2024.log": `[2024-01-01 10:00:00] Symbol: BTCUSDT, Exchange: binance, Funding Rate: 0.000100, Mark Price: 42000.00, Index Price: 41990.00
+[2024-01-01 10:00:00] Symbol: BTCUSDT, Exchange: bybit, Funding Rate: 0.000200, Mark Price: 42001.00, Index Price: 41991.00
+[2024-01-01 10:30:00] Symbol: BTCUSDT, Exchange: binance, Funding Rate: 0.000300, Mark Price: 42100.00, Index Price: 42090.00
+`,
+		"02-01-2024.log": `[2024-01-02 10:00:00] Symbol: BTCUSDT, Exchange: binance, Funding Rate: -0.000100, Mark Price: 41000.00, Index Price: 40990.00
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(symbolDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		name      string
+		query     domain.HistoryQuery
+		expected  int
+		exchanges []string
+	}{
+		{"one exchange", domain.HistoryQuery{Symbol: "BTCUSDT", Exchanges: []string{"binance"}}, 3, []string{"binance", "binance", "binance"}},
+		{"all exchanges", domain.HistoryQuery{Symbol: "BTCUSDT"}, 4, []string{"binance", "binance", "binance", "bybit"}},
+		{"range", domain.HistoryQuery{Symbol: "BTCUSDT", From: day(2), To: day(3)}, 1, []string{"binance"}},
+		{"unknown symbol", domain.HistoryQuery{Symbol: "ETHUSDT"}, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := fileLogger.GetHistoricalFundingRates(tt.query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(history) != tt.expected {
+				t.Fatalf("Expected %d points, got %d", tt.expected, len(history))
+			}
+			for i, exchange := range tt.exchanges {
+				if history[i].Exchange != exchange {
+					t.Errorf("Expected point %d on %s, got %s", i, exchange, history[i].Exchange)
+				}
+			}
+		})
+	}
+
+	// Points carry their prices and aggregate into OHLC buckets
+	history, err := fileLogger.GetHistoricalFundingRates(domain.HistoryQuery{
+		Symbol:     "BTCUSDT",
+		Exchanges:  []string{"binance"},
+		To:         day(2),
+		Resolution: domain.Resolution1d,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 1 || history[0].Samples != 2 || history[0].OHLC == nil {
+		t.Fatalf("Expected one bucket of 2 samples, got %+v", history)
+	}
+	if history[0].MarkPrice != 42050 {
+		t.Errorf("Expected mean mark price 42050, got %v", history[0].MarkPrice)
+	}
+	if ohlc := history[0].OHLC; ohlc.Open != 0.0001 || ohlc.Close != 0.0003 {
+		t.Errorf("Expected open 0.0001 and close 0.0003, got %+v", ohlc)
+	}
+}
+
+func TestFileLogger_BackfillFundingRates(t *testing.T) {
+	tempDir := t.TempDir()
+	fileLogger := NewFileLogger(tempDir, logrus.New())
+
+	// Settlements of two local days, one of them already logged
+	first := time.Date(2024, 1, 1, 16, 0, 0, 0, time.Local)
+	rates := []domain.FundingRate{
+		{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0001, Timestamp: first.UTC()},
+		{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: 0.0002, Timestamp: first.Add(8 * time.Hour)},
+		{Symbol: "BTC-USDT-SWAP", Exchange: "okx", FundingRate: 0.0003, Timestamp: first.Add(8 * time.Hour)},
+	}
+	if err := fileLogger.BackfillFundingRates("BTCUSDT", rates[:1]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := fileLogger.BackfillFundingRates("BTCUSDT", rates); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Writing them again adds nothing
+	if err := fileLogger.BackfillFundingRates("BTCUSDT", rates); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "BTCUSDT", "01-01-2024.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 1 || !strings.HasPrefix(string(content), "[2024-01-01 16:00:00]") {
+		t.Errorf("Expected the 16:00 settlement alone on 1 January, got %q", content)
+	}
+
+	history, err := fileLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 settlements, got %d", len(history))
+	}
+	if history[1].Timestamp != first.Add(8*time.Hour).Unix() || history[1].FundingRate != 0.0002 {
+		t.Errorf("Expected the binance settlement at its own time, got %+v", history[1])
+	}
+}
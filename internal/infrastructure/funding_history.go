@@ -0,0 +1,118 @@
+package infrastructure
+
+import (
+	"sort"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// settlementPage reads one page of a funding history. full reports whether
+// the venue returned as many entries as asked, so more may follow.
+type settlementPage func(bound time.Time) (settlements []domain.FundingSettlement, full bool, err error)
+
+// pageSettlementsBackward reads a funding history served newest first, each
+// page holding the settlements at or before bound, until a page comes back
+// short or reaches start
+func pageSettlementsBackward(start, end time.Time, page settlementPage) ([]domain.FundingSettlement, error) {
+	var settlements []domain.FundingSettlement
+	bound := end.Add(-time.Millisecond)
+	for {
+		batch, full, err := page(bound)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, batch...)
+		if !full || len(batch) == 0 {
+			break
+		}
+		oldest := batch[0].FundingTime
+		for _, settlement := range batch {
+			if settlement.FundingTime.Before(oldest) {
+				oldest = settlement.FundingTime
+			}
+		}
+		if !oldest.After(start) || !oldest.Before(bound.Add(time.Millisecond)) {
+			break
+		}
+		bound = oldest.Add(-time.Millisecond)
+	}
+	return finishSettlements(settlements, start, end), nil
+}
+
+// pageSettlementsForward reads a funding history served oldest first, each
+// page holding the settlements at or after bound, until a page comes back
+// short or reaches end
+func pageSettlementsForward(start, end time.Time, page settlementPage) ([]domain.FundingSettlement, error) {
+	var settlements []domain.FundingSettlement
+	bound := start
+	for {
+		batch, full, err := page(bound)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, batch...)
+		if !full || len(batch) == 0 {
+			break
+		}
+		newest := batch[0].FundingTime
+		for _, settlement := range batch {
+			if settlement.FundingTime.After(newest) {
+				newest = settlement.FundingTime
+			}
+		}
+		if !newest.Before(end) || newest.Before(bound) {
+			break
+		}
+		bound = newest.Add(time.Millisecond)
+	}
+	return finishSettlements(settlements, start, end), nil
+}
+
+// pageSettlementsByNumber reads a funding history served newest first in
+// pages numbered from 1, until a page comes back short or reaches start
+func pageSettlementsByNumber(start, end time.Time, page func(number int) ([]domain.FundingSettlement, bool, error)) ([]domain.FundingSettlement, error) {
+	var settlements []domain.FundingSettlement
+	var previous time.Time
+	for number := 1; ; number++ {
+		batch, full, err := page(number)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, batch...)
+		if !full || len(batch) == 0 {
+			break
+		}
+		oldest := batch[0].FundingTime
+		for _, settlement := range batch {
+			if settlement.FundingTime.Before(oldest) {
+				oldest = settlement.FundingTime
+			}
+		}
+		// Stop as well when the venue keeps serving the same page
+		if !oldest.After(start) || (!previous.IsZero() && !oldest.Before(previous)) {
+			break
+		}
+		previous = oldest
+	}
+	return finishSettlements(settlements, start, end), nil
+}
+
+// finishSettlements keeps the settlements within [start, end), once per
+// funding time, and sorts them oldest first
+func finishSettlements(settlements []domain.FundingSettlement, start, end time.Time) []domain.FundingSettlement {
+	seen := make(map[int64]bool, len(settlements))
+	result := make([]domain.FundingSettlement, 0, len(settlements))
+	for _, settlement := range settlements {
+		if settlement.FundingTime.Before(start) || !settlement.FundingTime.Before(end) {
+			continue
+		}
+		if seen[settlement.FundingTime.UnixMilli()] {
+			continue
+		}
+		seen[settlement.FundingTime.UnixMilli()] = true
+		result = append(result, settlement)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].FundingTime.Before(result[j].FundingTime) })
+	return result
+}
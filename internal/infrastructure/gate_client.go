@@ -1,51 +1,94 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// GateClient reads the USDT and BTC settled perpetuals of Gate.io. Contracts
+// only carry the running rate, settled rates come from the funding history.
 type GateClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
 }
 
 type GateContract struct {
-	Name              string `json:"name"`
-	Underlying        string `json:"underlying"`
-	QuoteCurrency     string `json:"quote_currency"`
-	MarkPrice         string `json:"mark_price"`
-	IndexPrice        string `json:"index_price"`
-	FundingRate       string `json:"funding_rate"`
-	FundingNextApply  int64  `json:"funding_next_apply"`
-	Status            string `json:"status"`
+	Name             string `json:"name"`
+	Underlying       string `json:"underlying"`
+	QuoteCurrency    string `json:"quote_currency"`
+	MarkPrice        string `json:"mark_price"`
+	IndexPrice       string `json:"index_price"`
+	FundingRate      string `json:"funding_rate"`
+	FundingNextApply int64  `json:"funding_next_apply"`
+	FundingInterval  int64  `json:"funding_interval"` // seconds
+	Status           string `json:"status"`
+}
+
+// GateFundingRateHistory is a settlement of the funding rate history, at t in seconds
+type GateFundingRateHistory struct {
+	T int64  `json:"t"`
+	R string `json:"r"`
 }
 
-func NewGateClient(config domain.ExchangeConfig, logger *logrus.Logger) *GateClient {
-	return &GateClient{
+// gateHistoryLimit is the most settlements a funding rate history request returns
+const gateHistoryLimit = 1000
+
+// gateSettlements are the settlement currencies of the perpetuals: USDT for
+// the linear contracts, BTC for the coin-margined ones
+var gateSettlements = []struct {
+	settle     string
+	marginType domain.MarginType
+}{
+	{"usdt", domain.MarginTypeLinear},
+	{"btc", domain.MarginTypeInverse},
+}
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "gate",
+		DefaultBaseURL: "https://api.gateio.ws",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, InverseContracts: true},
+		SymbolParser:   parseGateSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewGateClient(config, client, logger)
+		},
+	})
+}
+
+func NewGateClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *GateClient {
+	g := &GateClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	g.settlements = newSettlementTracker(g, time.Now, logger)
+	return g
 }
 
 func (g *GateClient) GetName() string {
 	return "gate"
 }
 
-func (g *GateClient) IsHealthy() bool {
+func (g *GateClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/api/v4/futures/usdt/contracts", g.config.BaseURL)
-	resp, err := g.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := g.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -53,10 +96,68 @@ func (g *GateClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (g *GateClient) GetFundingRates() ([]domain.FundingRate, error) {
-	url := fmt.Sprintf("%s/api/v4/futures/usdt/contracts", g.config.BaseURL)
-	
-	req, err := http.NewRequest("GET", url, nil)
+// GetFundingRates reads the contracts of every settlement currency
+func (g *GateClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	var rates []domain.FundingRate
+	var lastErr error
+	failed := 0
+
+	for _, settlement := range gateSettlements {
+		contracts, err := g.getContracts(ctx, settlement.settle)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			g.logger.Warnf("Failed to get %s settled contracts from Gate.io: %v", settlement.settle, err)
+			lastErr = err
+			failed++
+			continue
+		}
+
+		for _, contract := range contracts {
+			// Skip if contract is not active or funding rate is empty
+			if contract.Status != "trading" || contract.FundingRate == "" {
+				continue
+			}
+
+			fundingRate, err := strconv.ParseFloat(contract.FundingRate, 64)
+			if err != nil {
+				g.logger.Warnf("Failed to parse funding rate for %s: %v", contract.Name, err)
+				continue
+			}
+
+			markPrice, _ := strconv.ParseFloat(contract.MarkPrice, 64)
+			indexPrice, _ := strconv.ParseFloat(contract.IndexPrice, 64)
+
+			rates = append(rates, domain.FundingRate{
+				Symbol:               contract.Name,
+				Exchange:             g.GetName(),
+				FundingRate:          fundingRate,
+				NextFundingTime:      time.Unix(contract.FundingNextApply, 0),
+				Timestamp:            time.Now(),
+				MarkPrice:            markPrice,
+				IndexPrice:           indexPrice,
+				FundingIntervalHours: int(contract.FundingInterval / int64(time.Hour/time.Second)),
+				MarginType:           settlement.marginType,
+			})
+		}
+	}
+
+	if failed == len(gateSettlements) {
+		return nil, lastErr
+	}
+
+	g.settlements.update(ctx, rates)
+
+	g.logger.Infof("Retrieved %d funding rates from Gate.io", len(rates))
+	return rates, nil
+}
+
+// getContracts returns the perpetual contracts settled in settle
+func (g *GateClient) getContracts(ctx context.Context, settle string) ([]GateContract, error) {
+	url := fmt.Sprintf("%s/api/v4/futures/%s/contracts", g.config.BaseURL, settle)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -72,45 +173,68 @@ func (g *GateClient) GetFundingRates() ([]domain.FundingRate, error) {
 		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
 	var contracts []GateContract
-	if err := json.Unmarshal(body, &contracts); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(&contracts); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	return contracts, nil
+}
 
-	var rates []domain.FundingRate
-	
-	for _, contract := range contracts {
-		// Skip if contract is not active or funding rate is empty
-		if contract.Status != "trading" || contract.FundingRate == "" {
-			continue
+// GetFundingHistory reads a contract's settlements, which Gate.io serves
+// newest first. BTC_USD style contracts are settled in BTC.
+func (g *GateClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	settle := "usdt"
+	if strings.HasSuffix(symbol, "_USD") {
+		settle = "btc"
+	}
+
+	return pageSettlementsBackward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"contract": {symbol},
+			"to":       {strconv.FormatInt(bound.Unix(), 10)},
+			"limit":    {strconv.Itoa(gateHistoryLimit)},
+		}
+		url := fmt.Sprintf("%s/api/v4/futures/%s/funding_rate?%s", g.config.BaseURL, settle, query.Encode())
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
 		}
 
-		fundingRate, err := strconv.ParseFloat(contract.FundingRate, 64)
+		resp, err := g.client.Do(req)
 		if err != nil {
-			g.logger.Warnf("Failed to parse funding rate for %s: %v", contract.Name, err)
-			continue
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
 		}
+		defer resp.Body.Close()
 
-		markPrice, _ := strconv.ParseFloat(contract.MarkPrice, 64)
-		indexPrice, _ := strconv.ParseFloat(contract.IndexPrice, 64)
-
-		rates = append(rates, domain.FundingRate{
-			Symbol:          contract.Name,
-			Exchange:        g.GetName(),
-			FundingRate:     fundingRate,
-			NextFundingTime: time.Unix(contract.FundingNextApply, 0),
-			Timestamp:       time.Now(),
-			MarkPrice:       markPrice,
-			IndexPrice:      indexPrice,
-			LastFundingRate: 0, // Not provided in this endpoint
-		})
-	}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
 
-	g.logger.Infof("Retrieved %d funding rates from Gate.io", len(rates))
-	return rates, nil
-} 
\ No newline at end of file
+		var history []GateFundingRateHistory
+		if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range history {
+			fundingRate, err := strconv.ParseFloat(entry.R, 64)
+			if err != nil {
+				g.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    g.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.Unix(entry.T, 0),
+			})
+		}
+		return settlements, len(history) >= gateHistoryLimit, nil
+	})
+}
+
+// parseGateSymbol handles BTC_USDT and coin-margined BTC_USD perpetuals
+func parseGateSymbol(symbol string) (domain.Instrument, bool) {
+	return parseUnderscoreSymbol(symbol)
+}
@@ -0,0 +1,120 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newGateServer serves the recorded contracts in testdata/gate, failing the
+// paths listed in failing
+func newGateServer(t *testing.T, failing ...string) *httptest.Server {
+	fixtures := map[string]string{
+		"/api/v4/futures/usdt/contracts": "contracts_usdt.json",
+		"/api/v4/futures/btc/contracts":  "contracts_btc.json",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range failing {
+			if r.URL.Path == path {
+				http.Error(w, `{"label":"SERVER_ERROR","message":"Internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "gate", fixtures[r.URL.Path]))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGateClient_GetFundingRates(t *testing.T) {
+	server := newGateServer(t)
+
+	client := NewGateClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// LUNC_USDT is delisting and skipped
+	if len(rates) != 4 {
+		t.Fatalf("Expected 4 rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		markPrice   float64
+		interval    int
+		nextFunding int64
+		marginType  domain.MarginType
+	}{
+		{"BTC_USDT", 0.0001, 42651.8, 8, 1704096000, domain.MarginTypeLinear},
+		{"ETH_USDT", -0.000035, 2250.34, 8, 1704096000, domain.MarginTypeLinear},
+		{"ORDI_USDT", 0.000812, 67.413, 4, 1704081600, domain.MarginTypeLinear},
+		{"BTC_USD", 0.00015, 42655.2, 8, 1704096000, domain.MarginTypeInverse},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.FundingRate != tt.fundingRate || rate.MarkPrice != tt.markPrice {
+			t.Errorf("Expected funding rate/mark %v/%v for %s, got %v/%v", tt.fundingRate, tt.markPrice, tt.symbol, rate.FundingRate, rate.MarkPrice)
+		}
+		if rate.FundingIntervalHours != tt.interval {
+			t.Errorf("Expected a %dh interval for %s, got %d", tt.interval, tt.symbol, rate.FundingIntervalHours)
+		}
+		if !rate.NextFundingTime.Equal(time.Unix(tt.nextFunding, 0)) {
+			t.Errorf("Expected next funding time %v for %s, got %v", time.Unix(tt.nextFunding, 0), tt.symbol, rate.NextFundingTime)
+		}
+		if rate.MarginType != tt.marginType {
+			t.Errorf("Expected margin type %s for %s, got %s", tt.marginType, tt.symbol, rate.MarginType)
+		}
+	}
+}
+
+func TestGateClient_GetFundingRatesSettlementFailure(t *testing.T) {
+	// The BTC settled contracts failing leaves the USDT ones
+	server := newGateServer(t, "/api/v4/futures/btc/contracts")
+
+	client := NewGateClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("Expected the 3 USDT rates, got %d", len(rates))
+	}
+	for _, rate := range rates {
+		if rate.MarginType != domain.MarginTypeLinear {
+			t.Errorf("Expected only linear rates, got %s for %s", rate.MarginType, rate.Symbol)
+		}
+	}
+
+	// Every settlement failing is an error
+	server = newGateServer(t, "/api/v4/futures/usdt/contracts", "/api/v4/futures/btc/contracts")
+	client = NewGateClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	if _, err := client.GetFundingRates(context.Background()); err == nil {
+		t.Error("Expected an error when every settlement fails")
+	}
+}
@@ -0,0 +1,262 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+const (
+	// DefaultUserAgent identifies the monitor to the exchanges
+	DefaultUserAgent = "funding-monitor/1.0"
+
+	// exchangeRequestTimeout bounds one exchange request, retries included
+	exchangeRequestTimeout = 10 * time.Second
+
+	// defaultRateLimit is the requests per second allowed to an exchange
+	// when neither its config nor its registration sets a limit
+	defaultRateLimit = 10
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// HTTPTransport is the HTTP layer shared by the exchange clients. They reuse
+// one connection pool, proxy and User-Agent, while each exchange gets its own
+// token bucket. Idempotent requests failing with 429, 5xx or a network error
+// are retried with jittered exponential backoff, honoring Retry-After.
+type HTTPTransport struct {
+	base       *http.Transport
+	userAgent  string
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// NewHTTPTransport creates the shared transport. Responses are requested and
+// decoded with gzip.
+func NewHTTPTransport(config domain.HTTPConfig) (*HTTPTransport, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DisableCompression = false
+	if config.Proxy != "" {
+		proxy, err := url.Parse(config.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("%w: invalid proxy %q", domain.ErrInvalidConfig, config.Proxy)
+		}
+		base.Proxy = http.ProxyURL(proxy)
+	}
+
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+
+	return &HTTPTransport{
+		base:       base,
+		userAgent:  userAgent,
+		maxRetries: config.MaxRetries,
+		baseDelay:  retryBaseDelay,
+		maxDelay:   retryMaxDelay,
+	}, nil
+}
+
+// Client returns the HTTP client of an exchange, limited to its configured rate
+func (t *HTTPTransport) Client(config domain.ExchangeConfig) *http.Client {
+	rate := config.RateLimit
+	if rate <= 0 {
+		rate = defaultRateLimit
+	}
+	burst := config.Burst
+	if burst <= 0 {
+		burst = int(rate)
+	}
+
+	return &http.Client{
+		Timeout: exchangeRequestTimeout,
+		Transport: &exchangeTransport{
+			HTTPTransport: t,
+			limiter:       newRateLimiter(rate, burst),
+		},
+	}
+}
+
+// exchangeTransport applies one exchange's rate limit and the retry policy
+type exchangeTransport struct {
+	*HTTPTransport
+	limiter *rateLimiter
+}
+
+func (t *exchangeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req.Clone(ctx)
+		if attemptReq.Header.Get("User-Agent") == "" {
+			attemptReq.Header.Set("User-Agent", t.userAgent)
+		}
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if attempt >= t.maxRetries || !retryable(req, resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		// Give up rather than wait past the caller's deadline
+		delay := t.retryDelay(resp, attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retrySafeKey marks a request context whose requests may be resent
+type retrySafeKey struct{}
+
+// withRetrySafe lets the transport retry requests made with the returned
+// context whatever their method, for POST endpoints that only read
+func withRetrySafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retrySafeKey{}, true)
+}
+
+// retryable reports whether a failed attempt may be repeated: only requests
+// that are safe to resend (GET, HEAD or marked with withRetrySafe) and only
+// for throttling, server or network errors
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	retrySafe, _ := req.Context().Value(retrySafeKey{}).(bool)
+	if req.Method != http.MethodGet && req.Method != http.MethodHead && !retrySafe {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the next attempt: the
+// Retry-After the exchange asked for, or an exponential backoff with jitter
+func (t *HTTPTransport) retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if delay > t.maxDelay {
+				return t.maxDelay
+			}
+			return delay
+		}
+	}
+
+	backoff := t.baseDelay << attempt
+	if backoff > t.maxDelay || backoff <= 0 {
+		backoff = t.maxDelay
+	}
+	// Equal jitter keeps at least half the backoff while spreading clients out
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// rateLimiter is a token bucket refilled at rate tokens per second
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token, blocking until one is available or ctx is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// Reserve the token now so waiters are served in arrival order
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// Hand the reservation back for the next caller
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,224 @@
+package infrastructure
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// newTestTransport returns a transport retrying without noticeable delays
+func newTestTransport(t *testing.T, config domain.HTTPConfig) *HTTPTransport {
+	transport, err := NewHTTPTransport(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport.baseDelay = time.Millisecond
+	transport.maxDelay = 50 * time.Millisecond
+	return transport
+}
+
+func TestHTTPTransport_RetriesThrottledAndFailedRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		status   int
+		attempts int32
+	}{
+		{"recovers after 429", []int{http.StatusTooManyRequests}, http.StatusOK, 2},
+		{"recovers after 5xx", []int{http.StatusBadGateway, http.StatusServiceUnavailable}, http.StatusOK, 3},
+		{"gives up after max retries", []int{500, 500, 500, 500, 500}, http.StatusInternalServerError, 3},
+		{"client errors are final", []int{http.StatusBadRequest}, http.StatusBadRequest, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+				if int(attempt) <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[attempt-1])
+					return
+				}
+				io.WriteString(w, "ok")
+			}))
+			defer server.Close()
+
+			client := newTestTransport(t, domain.HTTPConfig{MaxRetries: 2}).Client(domain.ExchangeConfig{RateLimit: 1000})
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status || attempts != tt.attempts {
+				t.Errorf("Expected status %d after %d attempts, got %d after %d", tt.status, tt.attempts, resp.StatusCode, attempts)
+			}
+		})
+	}
+}
+
+func TestHTTPTransport_HonorsRetryAfter(t *testing.T) {
+	var attempts int32
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if waited := time.Since(first); waited < time.Second {
+			t.Errorf("Expected the retry to wait 1s, waited %v", waited)
+		}
+	}))
+	defer server.Close()
+
+	transport := newTestTransport(t, domain.HTTPConfig{MaxRetries: 1})
+	transport.maxDelay = 2 * time.Second
+	resp, err := transport.Client(domain.ExchangeConfig{}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || attempts != 2 {
+		t.Errorf("Expected success on the second attempt, got %d after %d", resp.StatusCode, attempts)
+	}
+
+	// A Retry-After past the caller's deadline returns the throttled response
+	atomic.StoreInt32(&attempts, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err = transport.Client(domain.ExchangeConfig{}).Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || attempts != 1 {
+		t.Errorf("Expected the 429 without retrying, got %d after %d", resp.StatusCode, attempts)
+	}
+}
+
+func TestHTTPTransport_DoesNotRetryPosts(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestTransport(t, domain.HTTPConfig{MaxRetries: 3}).Client(domain.ExchangeConfig{})
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+
+	// Posts marked safe to resend are retried with their body
+	atomic.StoreInt32(&attempts, 0)
+	req, _ := http.NewRequestWithContext(withRetrySafe(context.Background()), "POST", server.URL, strings.NewReader("{}"))
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if attempts != 4 {
+		t.Errorf("Expected 4 attempts, got %d", attempts)
+	}
+}
+
+func TestHTTPTransport_HeadersAndGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "desk/2.0" {
+			t.Errorf("Expected User-Agent desk/2.0, got %q", ua)
+		}
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("Expected gzip to be accepted, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, `{"ok":true}`)
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client := newTestTransport(t, domain.HTTPConfig{UserAgent: "desk/2.0"}).Client(domain.ExchangeConfig{})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"ok":true}` {
+		t.Errorf("Expected the decompressed body, got %q", body)
+	}
+}
+
+func TestHTTPTransport_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client := newTestTransport(t, domain.HTTPConfig{Proxy: proxy.URL}).Client(domain.ExchangeConfig{})
+	resp, err := client.Get("http://api-futures.kucoin.example/api/v1/contracts/active")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api-futures.kucoin.example/api/v1/contracts/active" {
+		t.Errorf("Expected the request to go through the proxy, got %q", proxied)
+	}
+
+	if _, err := NewHTTPTransport(domain.HTTPConfig{Proxy: "not a url"}); !errors.Is(err, domain.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for a bad proxy, got %v", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(20, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	// The burst of 2 passes at once and the next 2 take 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected the requests beyond the burst to wait, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled wait, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("3"); !ok || delay != 3*time.Second {
+		t.Errorf("Expected 3s, got %v", delay)
+	}
+	at := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(at); !ok || delay < 8*time.Second || delay > 10*time.Second {
+		t.Errorf("Expected about 10s, got %v", delay)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("Expected an invalid Retry-After to be ignored")
+	}
+}
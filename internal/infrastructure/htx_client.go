@@ -0,0 +1,270 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HTXClient reads the USDT-margined linear swaps of HTX (formerly Huobi).
+// HTX only publishes mark prices as per-contract klines, so rates carry the
+// index price alone. Settled rates come from the historical funding rates.
+type HTXClient struct {
+	config domain.ExchangeConfig
+	logger *logrus.Logger
+	client *http.Client
+
+	settlements *settlementTracker
+}
+
+// HTXFundingRate is an entry of the batch funding rate endpoint. FundingTime
+// is the settlement of the current rate, NextFundingTime is no longer set.
+type HTXFundingRate struct {
+	ContractCode   string `json:"contract_code"`
+	Symbol         string `json:"symbol"`
+	FeeAsset       string `json:"fee_asset"`
+	FundingRate    string `json:"funding_rate"`
+	FundingTime    string `json:"funding_time"`
+	TradePartition string `json:"trade_partition"`
+}
+
+type HTXFundingRatesResponse struct {
+	Status string           `json:"status"`
+	ErrMsg string           `json:"err_msg"`
+	Data   []HTXFundingRate `json:"data"`
+	Ts     int64            `json:"ts"`
+}
+
+type HTXIndex struct {
+	ContractCode string  `json:"contract_code"`
+	IndexPrice   float64 `json:"index_price"`
+	IndexTs      int64   `json:"index_ts"`
+}
+
+type HTXIndexResponse struct {
+	Status string     `json:"status"`
+	ErrMsg string     `json:"err_msg"`
+	Data   []HTXIndex `json:"data"`
+}
+
+// HTXHistoricalFundingRate is a settlement of the historical funding rates;
+// RealizedRate is the rate actually settled
+type HTXHistoricalFundingRate struct {
+	ContractCode string `json:"contract_code"`
+	FundingRate  string `json:"funding_rate"`
+	RealizedRate string `json:"realized_rate"`
+	FundingTime  string `json:"funding_time"`
+}
+
+type HTXHistoricalFundingRateResponse struct {
+	Status string `json:"status"`
+	ErrMsg string `json:"err_msg"`
+	Data   struct {
+		Data        []HTXHistoricalFundingRate `json:"data"`
+		TotalPage   int                        `json:"total_page"`
+		CurrentPage int                        `json:"current_page"`
+	} `json:"data"`
+}
+
+// htxHistoryPageSize is the settlements asked per historical funding rate
+// page, the most HTX serves
+const htxHistoryPageSize = 50
+
+// htxOKStatus is the status of successful HTX responses
+const htxOKStatus = "ok"
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "htx",
+		DefaultBaseURL: "https://api.hbdm.com",
+		Capabilities:   domain.ExchangeCapabilities{IndexPrice: true, NextFundingTime: true},
+		SymbolParser:   parseHTXSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewHTXClient(config, client, logger)
+		},
+	})
+}
+
+func NewHTXClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *HTXClient {
+	h := &HTXClient{
+		config: config,
+		logger: logger,
+		client: client,
+	}
+	h.settlements = newSettlementTracker(h, time.Now, logger)
+	return h
+}
+
+func (h *HTXClient) GetName() string {
+	return "htx"
+}
+
+func (h *HTXClient) IsHealthy(ctx context.Context) bool {
+	url := fmt.Sprintf("%s/api/v1/timestamp", h.config.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (h *HTXClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	var response HTXFundingRatesResponse
+	if err := h.get(ctx, "/linear-swap-api/v1/swap_batch_funding_rate", &response); err != nil {
+		return nil, err
+	}
+	if response.Status != htxOKStatus {
+		return nil, fmt.Errorf("API returned error: %s", response.ErrMsg)
+	}
+
+	indexPrices, err := h.getIndexPrices(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		h.logger.Warnf("Failed to get index prices from HTX: %v", err)
+	}
+
+	timestamp := time.Now()
+	if response.Ts > 0 {
+		timestamp = time.UnixMilli(response.Ts)
+	}
+
+	var rates []domain.FundingRate
+	for _, rate := range response.Data {
+		if rate.TradePartition != "" && rate.TradePartition != "USDT" {
+			continue
+		}
+
+		fundingRate, err := strconv.ParseFloat(rate.FundingRate, 64)
+		if err != nil {
+			h.logger.Warnf("Failed to parse funding rate for %s: %v", rate.ContractCode, err)
+			continue
+		}
+
+		fundingTime, err := strconv.ParseInt(rate.FundingTime, 10, 64)
+		if err != nil {
+			h.logger.Warnf("Failed to parse funding time for %s: %v", rate.ContractCode, err)
+			continue
+		}
+
+		rates = append(rates, domain.FundingRate{
+			Symbol:          rate.ContractCode,
+			Exchange:        h.GetName(),
+			FundingRate:     fundingRate,
+			NextFundingTime: time.UnixMilli(fundingTime),
+			Timestamp:       timestamp,
+			IndexPrice:      indexPrices[rate.ContractCode],
+			MarginType:      domain.MarginTypeLinear,
+		})
+	}
+
+	h.settlements.update(ctx, rates)
+
+	h.logger.Infof("Retrieved %d funding rates from HTX", len(rates))
+	return rates, nil
+}
+
+// GetFundingHistory reads a swap's settlements, which HTX serves newest
+// first in numbered pages
+func (h *HTXClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsByNumber(start, end, func(number int) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"contract_code": {symbol},
+			"page_index":    {strconv.Itoa(number)},
+			"page_size":     {strconv.Itoa(htxHistoryPageSize)},
+		}
+		var response HTXHistoricalFundingRateResponse
+		if err := h.get(ctx, "/linear-swap-api/v1/swap_historical_funding_rate?"+query.Encode(), &response); err != nil {
+			return nil, false, err
+		}
+		if response.Status != htxOKStatus {
+			return nil, false, fmt.Errorf("API returned error: %s", response.ErrMsg)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range response.Data.Data {
+			value := entry.RealizedRate
+			if value == "" {
+				value = entry.FundingRate
+			}
+			fundingRate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				h.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			fundingTime, err := strconv.ParseInt(entry.FundingTime, 10, 64)
+			if err != nil {
+				h.logger.Warnf("Failed to parse funding time for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    h.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(fundingTime),
+			})
+		}
+		return settlements, number < response.Data.TotalPage, nil
+	})
+}
+
+// getIndexPrices returns the index price of every swap by contract code
+func (h *HTXClient) getIndexPrices(ctx context.Context) (map[string]float64, error) {
+	var response HTXIndexResponse
+	if err := h.get(ctx, "/linear-swap-api/v1/swap_index", &response); err != nil {
+		return nil, err
+	}
+	if response.Status != htxOKStatus {
+		return nil, fmt.Errorf("API returned error: %s", response.ErrMsg)
+	}
+
+	prices := make(map[string]float64, len(response.Data))
+	for _, index := range response.Data {
+		prices[index.ContractCode] = index.IndexPrice
+	}
+	return prices, nil
+}
+
+// get requests a public endpoint and decodes its JSON body into out
+func (h *HTXClient) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.config.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// parseHTXSymbol handles BTC-USDT swap contract codes
+func parseHTXSymbol(symbol string) (domain.Instrument, bool) {
+	return parseDashSymbol(symbol)
+}
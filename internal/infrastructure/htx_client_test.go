@@ -0,0 +1,122 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newHTXServer serves the recorded HTX responses in testdata/htx, failing the
+// paths listed in failing
+func newHTXServer(t *testing.T, failing ...string) *httptest.Server {
+	fixtures := map[string]string{
+		"/linear-swap-api/v1/swap_batch_funding_rate": "swap_batch_funding_rate.json",
+		"/linear-swap-api/v1/swap_index":              "swap_index.json",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range failing {
+			if r.URL.Path == path {
+				w.Write([]byte(`{"status":"error","err_code":1000,"err_msg":"System error.","ts":1704090000321}`))
+				return
+			}
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "htx", fixtures[r.URL.Path]))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTXClient_GetFundingRates(t *testing.T) {
+	server := newHTXServer(t)
+
+	client := NewHTXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// LUNC-USDT has no funding rate and is skipped
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		indexPrice  float64
+		nextFunding int64
+	}{
+		{"BTC-USDT", 0.0001, 42648.465, 1704096000000},
+		{"ETH-USDT", -0.000042195866317245, 2249.8875, 1704096000000},
+		// ORDI-USDT has no index price
+		{"ORDI-USDT", 0.0008125, 0, 1704081600000},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.Exchange != "htx" {
+			t.Errorf("Expected exchange htx for %s, got %s", tt.symbol, rate.Exchange)
+		}
+		if rate.FundingRate != tt.fundingRate {
+			t.Errorf("Expected funding rate %v for %s, got %v", tt.fundingRate, tt.symbol, rate.FundingRate)
+		}
+		if rate.IndexPrice != tt.indexPrice {
+			t.Errorf("Expected index price %v for %s, got %v", tt.indexPrice, tt.symbol, rate.IndexPrice)
+		}
+		if !rate.NextFundingTime.Equal(time.UnixMilli(tt.nextFunding)) {
+			t.Errorf("Expected next funding time %v for %s, got %v", time.UnixMilli(tt.nextFunding), tt.symbol, rate.NextFundingTime)
+		}
+		if !rate.Timestamp.Equal(time.UnixMilli(1704090000321)) {
+			t.Errorf("Expected the response timestamp for %s, got %v", tt.symbol, rate.Timestamp)
+		}
+	}
+}
+
+func TestHTXClient_GetFundingRatesWithoutIndexPrices(t *testing.T) {
+	server := newHTXServer(t, "/linear-swap-api/v1/swap_index")
+
+	client := NewHTXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+	for _, rate := range rates {
+		if rate.IndexPrice != 0 {
+			t.Errorf("Expected no index price for %s, got %v", rate.Symbol, rate.IndexPrice)
+		}
+	}
+}
+
+func TestHTXClient_GetFundingRatesError(t *testing.T) {
+	server := newHTXServer(t, "/linear-swap-api/v1/swap_batch_funding_rate")
+
+	client := NewHTXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	if _, err := client.GetFundingRates(context.Background()); err == nil {
+		t.Error("Expected an error for an HTX error status")
+	}
+}
@@ -0,0 +1,322 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HyperliquidClient reads the perpetuals of Hyperliquid's main dex. Settled
+// rates come from each coin's funding history.
+type HyperliquidClient struct {
+	config domain.ExchangeConfig
+	logger *logrus.Logger
+	client *http.Client
+
+	settlements *settlementTracker
+}
+
+// HyperliquidAsset is a perpetual of the meta universe; its position in the
+// universe matches its asset context
+type HyperliquidAsset struct {
+	Name        string `json:"name"`
+	SzDecimals  int    `json:"szDecimals"`
+	MaxLeverage int    `json:"maxLeverage"`
+	IsDelisted  bool   `json:"isDelisted"`
+}
+
+type HyperliquidMeta struct {
+	Universe []HyperliquidAsset `json:"universe"`
+}
+
+// HyperliquidAssetContext holds the live market data of a perpetual. Funding
+// is the current hourly rate and the oracle price serves as index price.
+type HyperliquidAssetContext struct {
+	Funding      string `json:"funding"`
+	OpenInterest string `json:"openInterest"`
+	Premium      string `json:"premium"`
+	OraclePx     string `json:"oraclePx"`
+	MarkPx       string `json:"markPx"`
+}
+
+// HyperliquidPredictedFunding is the projected next settlement of a venue
+type HyperliquidPredictedFunding struct {
+	FundingRate          string `json:"fundingRate"`
+	NextFundingTime      int64  `json:"nextFundingTime"`
+	FundingIntervalHours int    `json:"fundingIntervalHours"`
+}
+
+// HyperliquidFundingHistory is an hourly settlement of a coin's funding history
+type HyperliquidFundingHistory struct {
+	Coin        string `json:"coin"`
+	FundingRate string `json:"fundingRate"`
+	Premium     string `json:"premium"`
+	Time        int64  `json:"time"`
+}
+
+// hyperliquidHistoryLimit is the most settlements a funding history request returns
+const hyperliquidHistoryLimit = 500
+
+// hyperliquidSettlementsPerPoll keeps the funding histories read per poll
+// within the one request per second allowed
+const hyperliquidSettlementsPerPoll = 4
+
+// Hyperliquid settles funding every hour
+const hyperliquidFundingIntervalHours = 1
+
+// hyperliquidVenue names Hyperliquid among the venues of predictedFundings,
+// which also lists Binance and Bybit predictions
+const hyperliquidVenue = "HlPerp"
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "hyperliquid",
+		DefaultBaseURL: "https://api.hyperliquid.xyz",
+		RateLimit:      1, // info requests cost 20 of the 1200 weight allowed per minute
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, PredictedFunding: true},
+		SymbolParser:   parseHyperliquidSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewHyperliquidClient(config, client, logger)
+		},
+	})
+}
+
+func NewHyperliquidClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *HyperliquidClient {
+	h := &HyperliquidClient{
+		config: config,
+		logger: logger,
+		client: client,
+	}
+	h.settlements = newSettlementTracker(h, time.Now, logger)
+	h.settlements.perPoll = hyperliquidSettlementsPerPoll
+	return h
+}
+
+func (h *HyperliquidClient) GetName() string {
+	return "hyperliquid"
+}
+
+func (h *HyperliquidClient) IsHealthy(ctx context.Context) bool {
+	var meta HyperliquidMeta
+	return h.postInfo(ctx, "meta", nil, &meta) == nil
+}
+
+// GetFundingRates reads every listed perpetual from the meta and asset
+// contexts, completed by the predicted next settlement
+func (h *HyperliquidClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	var response []json.RawMessage
+	if err := h.postInfo(ctx, "metaAndAssetCtxs", nil, &response); err != nil {
+		return nil, err
+	}
+	if len(response) != 2 {
+		return nil, fmt.Errorf("failed to unmarshal response: expected meta and asset contexts, got %d elements", len(response))
+	}
+
+	var meta HyperliquidMeta
+	if err := json.Unmarshal(response[0], &meta); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	var contexts []HyperliquidAssetContext
+	if err := json.Unmarshal(response[1], &contexts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(contexts) != len(meta.Universe) {
+		return nil, fmt.Errorf("failed to unmarshal response: %d assets but %d contexts", len(meta.Universe), len(contexts))
+	}
+
+	// Without predictions the rates still settle on the next hour
+	predictions, err := h.getPredictedFundings(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		h.logger.Warnf("Failed to get predicted fundings from Hyperliquid: %v", err)
+	}
+
+	now := time.Now()
+	var rates []domain.FundingRate
+	for i, asset := range meta.Universe {
+		if asset.IsDelisted {
+			continue
+		}
+		assetContext := contexts[i]
+
+		fundingRate, err := strconv.ParseFloat(assetContext.Funding, 64)
+		if err != nil {
+			h.logger.Warnf("Failed to parse funding rate for %s: %v", asset.Name, err)
+			continue
+		}
+
+		markPrice, err := strconv.ParseFloat(assetContext.MarkPx, 64)
+		if err != nil {
+			h.logger.Warnf("Failed to parse mark price for %s: %v", asset.Name, err)
+		}
+
+		oraclePrice, err := strconv.ParseFloat(assetContext.OraclePx, 64)
+		if err != nil {
+			h.logger.Warnf("Failed to parse oracle price for %s: %v", asset.Name, err)
+		}
+
+		rate := domain.FundingRate{
+			Symbol:               asset.Name,
+			Exchange:             h.GetName(),
+			FundingRate:          fundingRate,
+			NextFundingTime:      nextHyperliquidFundingTime(now),
+			Timestamp:            now,
+			MarkPrice:            markPrice,
+			IndexPrice:           oraclePrice,
+			FundingIntervalHours: hyperliquidFundingIntervalHours,
+			MarginType:           domain.MarginTypeLinear,
+		}
+
+		if prediction, ok := predictions[asset.Name]; ok {
+			if predicted, err := strconv.ParseFloat(prediction.FundingRate, 64); err == nil {
+				rate.PredictedFundingRate = predicted
+			}
+			if prediction.NextFundingTime > 0 {
+				rate.NextFundingTime = time.UnixMilli(prediction.NextFundingTime)
+			}
+			if prediction.FundingIntervalHours > 0 {
+				rate.FundingIntervalHours = prediction.FundingIntervalHours
+			}
+		}
+
+		rates = append(rates, rate)
+	}
+
+	h.settlements.update(ctx, rates)
+
+	h.logger.Infof("Retrieved %d funding rates from Hyperliquid", len(rates))
+	return rates, nil
+}
+
+// GetFundingHistory reads a coin's hourly settlements, which Hyperliquid
+// serves oldest first from startTime on
+func (h *HyperliquidClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsForward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		params := map[string]interface{}{
+			"coin":      symbol,
+			"startTime": bound.UnixMilli(),
+			"endTime":   end.UnixMilli() - 1,
+		}
+		var history []HyperliquidFundingHistory
+		if err := h.postInfo(ctx, "fundingHistory", params, &history); err != nil {
+			return nil, false, err
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range history {
+			fundingRate, err := strconv.ParseFloat(entry.FundingRate, 64)
+			if err != nil {
+				h.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    h.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(entry.Time),
+			})
+		}
+		return settlements, len(history) >= hyperliquidHistoryLimit, nil
+	})
+}
+
+// getPredictedFundings returns Hyperliquid's own prediction for every coin.
+// Each entry pairs a coin with [venue, prediction] tuples, where a venue not
+// listing the coin has a null prediction.
+func (h *HyperliquidClient) getPredictedFundings(ctx context.Context) (map[string]HyperliquidPredictedFunding, error) {
+	var response [][]json.RawMessage
+	if err := h.postInfo(ctx, "predictedFundings", nil, &response); err != nil {
+		return nil, err
+	}
+
+	predictions := make(map[string]HyperliquidPredictedFunding, len(response))
+	for _, entry := range response {
+		if len(entry) != 2 {
+			continue
+		}
+		var coin string
+		var venues [][]json.RawMessage
+		if json.Unmarshal(entry[0], &coin) != nil || json.Unmarshal(entry[1], &venues) != nil {
+			continue
+		}
+
+		for _, venue := range venues {
+			var name string
+			if len(venue) != 2 || json.Unmarshal(venue[0], &name) != nil || name != hyperliquidVenue {
+				continue
+			}
+			var prediction *HyperliquidPredictedFunding
+			if err := json.Unmarshal(venue[1], &prediction); err == nil && prediction != nil {
+				predictions[coin] = *prediction
+			}
+		}
+	}
+	return predictions, nil
+}
+
+// postInfo sends a request of the given type and parameters to the info endpoint
+func (h *HyperliquidClient) postInfo(ctx context.Context, requestType string, params map[string]interface{}, result interface{}) error {
+	request := map[string]interface{}{"type": requestType}
+	for name, value := range params {
+		request[name] = value
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Info requests only read, so the shared transport may retry them
+	req, err := http.NewRequestWithContext(withRetrySafe(ctx), "POST", h.config.BaseURL+"/info", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// nextHyperliquidFundingTime returns the next hourly settlement after now
+func nextHyperliquidFundingTime(now time.Time) time.Time {
+	return now.UTC().Truncate(time.Hour).Add(time.Hour)
+}
+
+// parseHyperliquidSymbol handles perpetual coin names, all margined in USDC.
+// Coins prefixed with k are quoted per thousand units, like Binance's 1000PEPE.
+// Spot pairs (@107, PURR/USDC) and builder deployed markets (xyz:TSLA) are not
+// perpetuals of the main dex.
+func parseHyperliquidSymbol(symbol string) (domain.Instrument, bool) {
+	coin := strings.TrimSpace(symbol)
+	if coin == "" || strings.ContainsAny(coin, "@/:") {
+		return domain.Instrument{}, false
+	}
+	if len(coin) > 1 && coin[0] == 'k' && coin[1:] == strings.ToUpper(coin[1:]) {
+		coin = "1000" + coin[1:]
+	}
+	return perpetualInstrument(coin, "USDC"), true
+}
@@ -1,53 +1,96 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// KuCoinClient reads the active KuCoin futures perpetuals. Contracts carry
+// the running and predicted rates, settled rates come from the funding history.
 type KuCoinClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
 }
 
 type KuCoinContract struct {
-	Symbol                    string  `json:"symbol"`
-	MarkPrice                 float64 `json:"markPrice"`
-	IndexPrice                float64 `json:"indexPrice"`
-	FundingFeeRate            float64 `json:"fundingFeeRate"`
-	NextFundingRateDateTime   int64   `json:"nextFundingRateDateTime"`
-	Status                    string  `json:"status"`
+	Symbol                  string  `json:"symbol"`
+	MarkPrice               float64 `json:"markPrice"`
+	IndexPrice              float64 `json:"indexPrice"`
+	FundingFeeRate          float64 `json:"fundingFeeRate"`
+	PredictedFundingFeeRate float64 `json:"predictedFundingFeeRate"`
+	NextFundingRateDateTime int64   `json:"nextFundingRateDateTime"`
+	FundingRateGranularity  int64   `json:"fundingRateGranularity"` // funding interval in ms
+	Status                  string  `json:"status"`
+	IsInverse               bool    `json:"isInverse"` // coin-margined, such as XBTUSDM
 }
 
 type KuCoinContractsResponse struct {
-	Code string            `json:"code"`
+	Code string           `json:"code"`
 	Data []KuCoinContract `json:"data"`
 }
 
-func NewKuCoinClient(config domain.ExchangeConfig, logger *logrus.Logger) *KuCoinClient {
-	return &KuCoinClient{
+// KuCoinFundingRateHistory is a settlement of the funding rate history
+type KuCoinFundingRateHistory struct {
+	Symbol      string  `json:"symbol"`
+	FundingRate float64 `json:"fundingRate"`
+	Timepoint   int64   `json:"timepoint"`
+}
+
+type KuCoinFundingRateHistoryResponse struct {
+	Code string                     `json:"code"`
+	Msg  string                     `json:"msg"`
+	Data []KuCoinFundingRateHistory `json:"data"`
+}
+
+// kucoinHistoryLimit is the most settlements a funding rate history request returns
+const kucoinHistoryLimit = 100
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "kucoin",
+		DefaultBaseURL: "https://api-futures.kucoin.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, InverseContracts: true},
+		SymbolParser:   parseKuCoinSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewKuCoinClient(config, client, logger)
+		},
+	})
+}
+
+func NewKuCoinClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *KuCoinClient {
+	k := &KuCoinClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	k.settlements = newSettlementTracker(k, time.Now, logger)
+	return k
 }
 
 func (k *KuCoinClient) GetName() string {
 	return "kucoin"
 }
 
-func (k *KuCoinClient) IsHealthy() bool {
+func (k *KuCoinClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/api/v1/contracts/active", k.config.BaseURL)
-	resp, err := k.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := k.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -55,10 +98,10 @@ func (k *KuCoinClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (k *KuCoinClient) GetFundingRates() ([]domain.FundingRate, error) {
+func (k *KuCoinClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
 	url := fmt.Sprintf("%s/api/v1/contracts/active", k.config.BaseURL)
-	
-	req, err := http.NewRequest("GET", url, nil)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -95,17 +138,90 @@ func (k *KuCoinClient) GetFundingRates() ([]domain.FundingRate, error) {
 		}
 
 		rates = append(rates, domain.FundingRate{
-			Symbol:          contract.Symbol,
-			Exchange:        k.GetName(),
-			FundingRate:     contract.FundingFeeRate,
-			NextFundingTime: time.Unix(contract.NextFundingRateDateTime/1000, 0),
-			Timestamp:       time.Now(),
-			MarkPrice:       contract.MarkPrice,
-			IndexPrice:      contract.IndexPrice,
-			LastFundingRate: 0,
+			Symbol:               contract.Symbol,
+			Exchange:             k.GetName(),
+			FundingRate:          contract.FundingFeeRate,
+			NextFundingTime:      time.Unix(contract.NextFundingRateDateTime/1000, 0),
+			Timestamp:            time.Now(),
+			MarkPrice:            contract.MarkPrice,
+			IndexPrice:           contract.IndexPrice,
+			PredictedFundingRate: contract.PredictedFundingFeeRate,
+			FundingIntervalHours: int(contract.FundingRateGranularity / int64(time.Hour/time.Millisecond)),
+			MarginType:           kucoinMarginType(contract),
 		})
 	}
 
+	k.settlements.update(ctx, rates)
+
 	k.logger.Infof("Retrieved %d funding rates from KuCoin", len(rates))
 	return rates, nil
-} 
\ No newline at end of file
+}
+
+// GetFundingHistory reads a contract's settlements, asking for ever earlier
+// ones while KuCoin returns full pages
+func (k *KuCoinClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsBackward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"symbol": {symbol},
+			"from":   {strconv.FormatInt(start.UnixMilli(), 10)},
+			"to":     {strconv.FormatInt(bound.UnixMilli(), 10)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", k.config.BaseURL+"/api/v1/contract/funding-rates?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := k.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var response KuCoinFundingRateHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if response.Code != "200000" {
+			return nil, false, fmt.Errorf("KuCoin API error: code %s", response.Code)
+		}
+
+		settlements := make([]domain.FundingSettlement, 0, len(response.Data))
+		for _, entry := range response.Data {
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    k.GetName(),
+				Symbol:      symbol,
+				FundingRate: entry.FundingRate,
+				FundingTime: time.UnixMilli(entry.Timepoint),
+			})
+		}
+		return settlements, len(response.Data) >= kucoinHistoryLimit, nil
+	})
+}
+
+// kucoinMarginType tags the coin-margined contracts listed among the USDT
+// and USDC margined ones
+func kucoinMarginType(contract KuCoinContract) domain.MarginType {
+	if contract.IsInverse {
+		return domain.MarginTypeInverse
+	}
+	return domain.MarginTypeLinear
+}
+
+// parseKuCoinSymbol handles perpetuals such as XBTUSDTM and XBTUSDM, where the
+// trailing M marks the perpetual and XBT stands for BTC
+func parseKuCoinSymbol(symbol string) (domain.Instrument, bool) {
+	name := strings.ToUpper(symbol)
+	if !strings.HasSuffix(name, "M") {
+		return domain.Instrument{}, false
+	}
+	base, quote, ok := splitConcatenatedSymbol(strings.TrimSuffix(name, "M"))
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	return perpetualInstrument(base, quote), true
+}
@@ -0,0 +1,154 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestKuCoinClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/contracts/active":
+			w.Write([]byte(`{"code":"200000","data":[
+				{"symbol":"XBTUSDTM","markPrice":42500.1,"indexPrice":42490.5,"fundingFeeRate":0.0001,"predictedFundingFeeRate":0.00015,"nextFundingRateDateTime":1704096000000,"fundingRateGranularity":28800000,"status":"Open","isInverse":false},
+				{"symbol":"XBTUSDM","markPrice":42502.3,"indexPrice":42490.5,"fundingFeeRate":-0.00002,"predictedFundingFeeRate":0.00001,"nextFundingRateDateTime":1704081600000,"fundingRateGranularity":14400000,"status":"Open","isInverse":true},
+				{"symbol":"ETHUSDTM","markPrice":2280.4,"indexPrice":2280.1,"fundingFeeRate":0.0001,"predictedFundingFeeRate":0.0001,"nextFundingRateDateTime":1704096000000,"fundingRateGranularity":28800000,"status":"Paused","isInverse":false}
+			]}`))
+		case "/api/v1/contract/funding-rates":
+			query := r.URL.Query()
+			if query.Get("from") == "" || query.Get("to") == "" {
+				t.Errorf("Expected a bounded history request, got %s", r.URL.RawQuery)
+			}
+			if query.Get("symbol") != "XBTUSDTM" {
+				// XBTUSDM has not settled yet
+				w.Write([]byte(`{"code":"200000","data":[]}`))
+				return
+			}
+			w.Write([]byte(`{"code":"200000","data":[
+				{"symbol":"XBTUSDTM","fundingRate":0.00012,"timepoint":1704067200000},
+				{"symbol":"XBTUSDTM","fundingRate":0.0001,"timepoint":1704038400000}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewKuCoinClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The paused ETHUSDTM contract is skipped
+	if len(rates) != 2 {
+		t.Fatalf("Expected 2 rates, got %d", len(rates))
+	}
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		predicted   float64
+		lastRate    float64
+		lastFunding int64
+		nextFunding int64
+		interval    int
+		marginType  domain.MarginType
+	}{
+		{"XBTUSDTM", 0.0001, 0.00015, 0.00012, 1704067200000, 1704096000000, 8, domain.MarginTypeLinear},
+		{"XBTUSDM", -0.00002, 0.00001, 0, 0, 1704081600000, 4, domain.MarginTypeInverse},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.FundingRate != tt.fundingRate || rate.PredictedFundingRate != tt.predicted {
+			t.Errorf("Expected funding/predicted rate %v/%v for %s, got %v/%v", tt.fundingRate, tt.predicted, tt.symbol, rate.FundingRate, rate.PredictedFundingRate)
+		}
+		if rate.LastFundingRate != tt.lastRate {
+			t.Errorf("Expected last funding rate %v for %s, got %v", tt.lastRate, tt.symbol, rate.LastFundingRate)
+		}
+		if tt.lastFunding == 0 && rate.LastFundingTime != nil {
+			t.Errorf("Expected no last settlement for %s, got %v", tt.symbol, rate.LastFundingTime)
+		}
+		if tt.lastFunding != 0 && (rate.LastFundingTime == nil || !rate.LastFundingTime.Equal(time.UnixMilli(tt.lastFunding))) {
+			t.Errorf("Expected the last settlement at %v for %s, got %v", time.UnixMilli(tt.lastFunding), tt.symbol, rate.LastFundingTime)
+		}
+		if !rate.NextFundingTime.Equal(time.UnixMilli(tt.nextFunding)) || rate.FundingIntervalHours != tt.interval {
+			t.Errorf("Expected a %dh cycle settling at %v for %s, got %dh at %v", tt.interval, time.UnixMilli(tt.nextFunding), tt.symbol, rate.FundingIntervalHours, rate.NextFundingTime)
+		}
+		if rate.MarginType != tt.marginType {
+			t.Errorf("Expected %s margin for %s, got %s", tt.marginType, tt.symbol, rate.MarginType)
+		}
+	}
+}
+
+func TestKuCoinClient_GetFundingHistory(t *testing.T) {
+	// Settlements every 8h since 2023, served newest first and at most 100
+	// per request within from and to
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contract/funding-rates" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+
+		var entries []string
+		for at := last; !at.Before(first) && at.UnixMilli() >= from && len(entries) < kucoinHistoryLimit; at = at.Add(-8 * time.Hour) {
+			if at.UnixMilli() > to {
+				continue
+			}
+			entries = append(entries, fmt.Sprintf(`{"symbol":"XBTUSDTM","fundingRate":0.0001,"timepoint":%d}`, at.UnixMilli()))
+		}
+		w.Write([]byte(`{"code":"200000","data":[` + strings.Join(entries, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client := NewKuCoinClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "XBTUSDTM", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 61 days of 8h settlements take two pages of 100
+	if len(settlements) != 61*3 {
+		t.Fatalf("Expected %d settlements, got %d", 61*3, len(settlements))
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if !settlements[0].FundingTime.Equal(start) || !settlements[len(settlements)-1].FundingTime.Equal(end.Add(-8*time.Hour)) {
+		t.Errorf("Expected settlements from %v until before %v oldest first, got %v to %v", start, end, settlements[0].FundingTime, settlements[len(settlements)-1].FundingTime)
+	}
+	for i, settlement := range settlements {
+		if i > 0 && !settlement.FundingTime.Equal(settlements[i-1].FundingTime.Add(8*time.Hour)) {
+			t.Fatalf("Expected consecutive settlements, got %v after %v", settlement.FundingTime, settlements[i-1].FundingTime)
+		}
+		if settlement.Exchange != "kucoin" || settlement.Symbol != "XBTUSDTM" || settlement.FundingRate != 0.0001 {
+			t.Fatalf("Unexpected settlement %+v", settlement)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package infrastructure
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "funding_monitor"
+
+// Metrics exposes exchange polls, HTTP requests and, for an allowlist of
+// symbols, funding rates in the Prometheus format. It observes polls through
+// MultiExchangeUseCase.AddPollObserver, snapshots as a FundingListener and
+// requests as router middleware.
+type Metrics struct {
+	registry *prometheus.Registry
+	symbols  map[string]bool
+
+	pollDuration    *prometheus.HistogramVec
+	polls           *prometheus.CounterVec
+	rows            *prometheus.GaugeVec
+	lastSuccess     *prometheus.GaugeVec
+	requestDuration *prometheus.HistogramVec
+	fundingRate     *prometheus.GaugeVec
+	fundingRate8h   *prometheus.GaugeVec
+}
+
+func NewMetrics(config domain.MetricsConfig) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		symbols:  make(map[string]bool, len(config.FundingRateSymbols)),
+
+		pollDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "exchange_poll_duration_seconds",
+			Help:      "Duration of exchange funding rate polls.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 30, 60},
+		}, []string{"exchange"}),
+		polls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "exchange_polls_total",
+			Help:      "Exchange funding rate polls by outcome (ok, timeout or error).",
+		}, []string{"exchange", "status"}),
+		rows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "exchange_rows",
+			Help:      "Funding rates returned by the last successful poll.",
+		}, []string{"exchange"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "exchange_last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful poll.",
+		}, []string{"exchange"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		fundingRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "funding_rate",
+			Help:      "Current funding rate of the allowlisted symbols.",
+		}, []string{"exchange", "symbol"}),
+		fundingRate8h: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "funding_rate_8h",
+			Help:      "Current funding rate of the allowlisted symbols scaled to an 8h cycle.",
+		}, []string{"exchange", "symbol"}),
+	}
+
+	for _, symbol := range config.FundingRateSymbols {
+		m.symbols[strings.ToUpper(symbol)] = true
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.pollDuration, m.polls, m.rows, m.lastSuccess, m.requestDuration,
+	)
+	if len(m.symbols) > 0 {
+		m.registry.MustRegister(m.fundingRate, m.fundingRate8h)
+	}
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObservePoll records the duration and outcome of an exchange poll
+func (m *Metrics) ObservePoll(exchange string, status domain.ExchangeStatus) {
+	m.pollDuration.WithLabelValues(exchange).Observe(float64(status.LatencyMs) / 1000)
+	m.polls.WithLabelValues(exchange, status.Status).Inc()
+	if status.Status == domain.ExchangeStatusOK {
+		m.rows.WithLabelValues(exchange).Set(float64(status.Rows))
+		m.lastSuccess.WithLabelValues(exchange).Set(float64(status.AsOf))
+	}
+}
+
+// OnFundingSnapshot replaces the funding rate gauges with the allowlisted
+// rates of the snapshot, so delisted markets disappear
+func (m *Metrics) OnFundingSnapshot(snapshot domain.FundingSnapshot) {
+	if len(m.symbols) == 0 {
+		return
+	}
+
+	m.fundingRate.Reset()
+	m.fundingRate8h.Reset()
+	for _, rate := range snapshot.Rates {
+		symbol := rate.MarketSymbol()
+		if !m.symbols[symbol] && !m.symbols[strings.ToUpper(rate.Symbol)] {
+			continue
+		}
+		m.fundingRate.WithLabelValues(rate.Exchange, symbol).Set(rate.FundingRate)
+		m.fundingRate8h.WithLabelValues(rate.Exchange, symbol).Set(rate.EightHourRate())
+	}
+}
+
+// Middleware records the latency of every request by route template, so
+// path variables don't multiply the series. WebSocket streams are skipped.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		if strings.HasPrefix(route, "/ws/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+		m.requestDuration.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,114 @@
+package infrastructure
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// scrapeMetrics returns the exposition served by the metrics handler
+func scrapeMetrics(t *testing.T, metrics *Metrics) string {
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	return rr.Body.String()
+}
+
+func expectMetrics(t *testing.T, exposition string, lines ...string) {
+	for _, line := range lines {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, exposition)
+		}
+	}
+}
+
+func TestMetrics_ObservePoll(t *testing.T) {
+	metrics := NewMetrics(domain.MetricsConfig{})
+
+	metrics.ObservePoll("binance", domain.ExchangeStatus{Status: domain.ExchangeStatusOK, LatencyMs: 300, Rows: 250, AsOf: 1704067200})
+	metrics.ObservePoll("binance", domain.ExchangeStatus{Status: domain.ExchangeStatusTimeout, LatencyMs: 15000})
+	metrics.ObservePoll("okx", domain.ExchangeStatus{Status: domain.ExchangeStatusError, LatencyMs: 40, Error: "boom"})
+
+	exposition := scrapeMetrics(t, metrics)
+	expectMetrics(t, exposition,
+		`funding_monitor_exchange_polls_total{exchange="binance",status="ok"} 1`,
+		`funding_monitor_exchange_polls_total{exchange="binance",status="timeout"} 1`,
+		`funding_monitor_exchange_polls_total{exchange="okx",status="error"} 1`,
+		`funding_monitor_exchange_poll_duration_seconds_bucket{exchange="binance",le="0.5"} 1`,
+		`funding_monitor_exchange_poll_duration_seconds_count{exchange="binance"} 2`,
+		`funding_monitor_exchange_poll_duration_seconds_sum{exchange="binance"} 15.3`,
+		// Failed polls keep the last successful rows and timestamp
+		`funding_monitor_exchange_rows{exchange="binance"} 250`,
+		`funding_monitor_exchange_last_success_timestamp_seconds{exchange="binance"} 1.7040672e+09`,
+	)
+	if strings.Contains(exposition, `funding_monitor_exchange_rows{exchange="okx"}`) {
+		t.Errorf("Expected no rows for an exchange never polled successfully")
+	}
+	if strings.Contains(exposition, "funding_monitor_funding_rate") {
+		t.Errorf("Expected no funding rate gauges without an allowlist")
+	}
+}
+
+func TestMetrics_FundingRateAllowlist(t *testing.T) {
+	metrics := NewMetrics(domain.MetricsConfig{FundingRateSymbols: []string{"btcusdt", "XBTUSDTM"}})
+
+	metrics.OnFundingSnapshot(domain.FundingSnapshot{Rates: []domain.FundingRate{
+		{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0001},
+		{Exchange: "okx", Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0002, FundingIntervalHours: 4},
+		{Exchange: "kucoin", Symbol: "XBTUSDTM", FundingRate: 0.0003},
+		{Exchange: "binance", Symbol: "ETHUSDT", FundingRate: 0.0004},
+	}})
+
+	exposition := scrapeMetrics(t, metrics)
+	expectMetrics(t, exposition,
+		`funding_monitor_funding_rate{exchange="binance",symbol="BTCUSDT"} 0.0001`,
+		`funding_monitor_funding_rate{exchange="okx",symbol="BTCUSDT"} 0.0002`,
+		`funding_monitor_funding_rate{exchange="kucoin",symbol="XBTUSDTM"} 0.0003`,
+		`funding_monitor_funding_rate_8h{exchange="okx",symbol="BTCUSDT"} 0.0004`,
+	)
+	if strings.Contains(exposition, `symbol="ETHUSDT"`) {
+		t.Errorf("Expected symbols outside the allowlist to be left out")
+	}
+
+	// Markets missing from the next snapshot are dropped
+	metrics.OnFundingSnapshot(domain.FundingSnapshot{Rates: []domain.FundingRate{
+		{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0005},
+	}})
+	exposition = scrapeMetrics(t, metrics)
+	expectMetrics(t, exposition, `funding_monitor_funding_rate{exchange="binance",symbol="BTCUSDT"} 0.0005`)
+	if strings.Contains(exposition, `exchange="okx"`) {
+		t.Errorf("Expected the okx gauge to be removed")
+	}
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	metrics := NewMetrics(domain.MetricsConfig{})
+
+	router := mux.NewRouter()
+	router.Use(metrics.Middleware)
+	router.HandleFunc("/api/funding/{exchange}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["exchange"] == "unknown" {
+			http.Error(w, "Exchange not found", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "{}")
+	}).Methods("GET")
+
+	for _, path := range []string{"/api/funding/binance", "/api/funding/okx", "/api/funding/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// Requests are grouped by route template rather than by path
+	expectMetrics(t, scrapeMetrics(t, metrics),
+		`funding_monitor_http_request_duration_seconds_count{code="200",method="GET",route="/api/funding/{exchange}"} 2`,
+		`funding_monitor_http_request_duration_seconds_count{code="404",method="GET",route="/api/funding/{exchange}"} 1`,
+	)
+}
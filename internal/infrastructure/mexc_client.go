@@ -1,20 +1,27 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// MEXCClient reads the MEXC perpetuals. The funding rate endpoint only
+// carries the running rate, settled rates come from the funding history.
 type MEXCClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
 	client *http.Client
+
+	settlements *settlementTracker
 }
 
 type MEXCFundingRate struct {
@@ -22,7 +29,7 @@ type MEXCFundingRate struct {
 	FundingRate    float64 `json:"fundingRate"`
 	MaxFundingRate float64 `json:"maxFundingRate"`
 	MinFundingRate float64 `json:"minFundingRate"`
-	CollectCycle   int     `json:"collectCycle"`
+	CollectCycle   int     `json:"collectCycle"` // funding interval in hours
 	NextSettleTime int64   `json:"nextSettleTime"`
 	Timestamp      int64   `json:"timestamp"`
 	MarkPrice      float64 `json:"markPrice"`
@@ -36,23 +43,59 @@ type MEXCFundingRateResponse struct {
 	Data    []MEXCFundingRate `json:"data"`
 }
 
-func NewMEXCClient(config domain.ExchangeConfig, logger *logrus.Logger) *MEXCClient {
-	return &MEXCClient{
+// MEXCFundingRateHistory is a settlement of the funding rate history
+type MEXCFundingRateHistory struct {
+	Symbol      string  `json:"symbol"`
+	FundingRate float64 `json:"fundingRate"`
+	SettleTime  int64   `json:"settleTime"`
+}
+
+type MEXCFundingRateHistoryResponse struct {
+	Success bool   `json:"success"`
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	Data    struct {
+		TotalPage  int                      `json:"totalPage"`
+		ResultList []MEXCFundingRateHistory `json:"resultList"`
+	} `json:"data"`
+}
+
+// mexcHistoryPageSize is the settlements asked per funding rate history page
+const mexcHistoryPageSize = 100
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "mexc",
+		DefaultBaseURL: "https://contract.mexc.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true},
+		SymbolParser:   parseMEXCSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewMEXCClient(config, client, logger)
+		},
+	})
+}
+
+func NewMEXCClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *MEXCClient {
+	m := &MEXCClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
+	m.settlements = newSettlementTracker(m, time.Now, logger)
+	return m
 }
 
 func (m *MEXCClient) GetName() string {
 	return "mexc"
 }
 
-func (m *MEXCClient) IsHealthy() bool {
+func (m *MEXCClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/api/v1/contract/funding_rate", m.config.BaseURL)
-	resp, err := m.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := m.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -60,10 +103,10 @@ func (m *MEXCClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (m *MEXCClient) GetFundingRates() ([]domain.FundingRate, error) {
+func (m *MEXCClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
 	url := fmt.Sprintf("%s/api/v1/contract/funding_rate", m.config.BaseURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -96,17 +139,71 @@ func (m *MEXCClient) GetFundingRates() ([]domain.FundingRate, error) {
 	var rates []domain.FundingRate
 	for _, rate := range mexcResponse.Data {
 		rates = append(rates, domain.FundingRate{
-			Symbol:          rate.Symbol,
-			Exchange:        m.GetName(),
-			FundingRate:     rate.FundingRate,
-			NextFundingTime: time.Unix(rate.NextSettleTime/1000, 0),
-			Timestamp:       time.Unix(rate.Timestamp/1000, 0),
-			MarkPrice:       0, // MEXC doesn't provide mark price in this endpoint
-			IndexPrice:      0, // MEXC doesn't provide index price in this endpoint
-			LastFundingRate: 0, // MEXC doesn't provide last funding rate in this endpoint
+			Symbol:               rate.Symbol,
+			Exchange:             m.GetName(),
+			FundingRate:          rate.FundingRate,
+			NextFundingTime:      time.Unix(rate.NextSettleTime/1000, 0),
+			Timestamp:            time.Unix(rate.Timestamp/1000, 0),
+			MarkPrice:            0, // MEXC doesn't provide mark price in this endpoint
+			IndexPrice:           0, // MEXC doesn't provide index price in this endpoint
+			FundingIntervalHours: rate.CollectCycle,
+			MarginType:           domain.MarginTypeLinear,
 		})
 	}
 
+	m.settlements.update(ctx, rates)
+
 	m.logger.Infof("Retrieved %d funding rates from MEXC", len(rates))
 	return rates, nil
 }
+
+// GetFundingHistory reads a contract's settlements, which MEXC serves newest
+// first in numbered pages
+func (m *MEXCClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsByNumber(start, end, func(number int) ([]domain.FundingSettlement, bool, error) {
+		query := url.Values{
+			"symbol":    {symbol},
+			"page_num":  {strconv.Itoa(number)},
+			"page_size": {strconv.Itoa(mexcHistoryPageSize)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", m.config.BaseURL+"/api/v1/contract/funding_rate/history?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := m.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var response MEXCFundingRateHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if !response.Success && response.Code != 0 {
+			return nil, false, fmt.Errorf("MEXC API error: %s", response.Msg)
+		}
+
+		settlements := make([]domain.FundingSettlement, 0, len(response.Data.ResultList))
+		for _, entry := range response.Data.ResultList {
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    m.GetName(),
+				Symbol:      symbol,
+				FundingRate: entry.FundingRate,
+				FundingTime: time.UnixMilli(entry.SettleTime),
+			})
+		}
+		return settlements, number < response.Data.TotalPage, nil
+	})
+}
+
+// parseMEXCSymbol handles BTC_USDT perpetuals
+func parseMEXCSymbol(symbol string) (domain.Instrument, bool) {
+	return parseUnderscoreSymbol(symbol)
+}
@@ -0,0 +1,142 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMEXCClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/contract/funding_rate":
+			w.Write([]byte(`{"success":true,"code":0,"data":[
+				{"symbol":"BTC_USDT","fundingRate":0.0001,"maxFundingRate":0.003,"minFundingRate":-0.003,"collectCycle":8,"nextSettleTime":1704096000000,"timestamp":1704090000000},
+				{"symbol":"ORDI_USDT","fundingRate":0.0005,"maxFundingRate":0.003,"minFundingRate":-0.003,"collectCycle":4,"nextSettleTime":1704081600000,"timestamp":1704090000000}
+			]}`))
+		case "/api/v1/contract/funding_rate/history":
+			if r.URL.Query().Get("symbol") != "BTC_USDT" {
+				// A freshly listed contract has not settled yet
+				w.Write([]byte(`{"success":true,"code":0,"data":{"totalPage":0,"resultList":[]}}`))
+				return
+			}
+			w.Write([]byte(`{"success":true,"code":0,"data":{"totalPage":1,"resultList":[
+				{"symbol":"BTC_USDT","fundingRate":0.00008,"settleTime":1704067200000},
+				{"symbol":"BTC_USDT","fundingRate":0.0001,"settleTime":1704038400000}
+			]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewMEXCClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("Expected 2 rates, got %d", len(rates))
+	}
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		lastRate    float64
+		lastFunding int64
+		nextFunding int64
+		interval    int
+	}{
+		// The funding rate endpoint carries the running rate, the settled one
+		// comes from the history
+		{"BTC_USDT", 0.0001, 0.00008, 1704067200000, 1704096000000, 8},
+		{"ORDI_USDT", 0.0005, 0, 0, 1704081600000, 4},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.FundingRate != tt.fundingRate || rate.LastFundingRate != tt.lastRate {
+			t.Errorf("Expected running/last rate %v/%v for %s, got %v/%v", tt.fundingRate, tt.lastRate, tt.symbol, rate.FundingRate, rate.LastFundingRate)
+		}
+		if tt.lastFunding == 0 && rate.LastFundingTime != nil {
+			t.Errorf("Expected no last settlement for %s, got %v", tt.symbol, rate.LastFundingTime)
+		}
+		if tt.lastFunding != 0 && (rate.LastFundingTime == nil || !rate.LastFundingTime.Equal(time.UnixMilli(tt.lastFunding))) {
+			t.Errorf("Expected the last settlement at %v for %s, got %v", time.UnixMilli(tt.lastFunding), tt.symbol, rate.LastFundingTime)
+		}
+		if !rate.NextFundingTime.Equal(time.UnixMilli(tt.nextFunding)) || rate.FundingIntervalHours != tt.interval {
+			t.Errorf("Expected a %dh cycle settling at %v for %s, got %dh at %v", tt.interval, time.UnixMilli(tt.nextFunding), tt.symbol, rate.FundingIntervalHours, rate.NextFundingTime)
+		}
+	}
+}
+
+func TestMEXCClient_GetFundingHistory(t *testing.T) {
+	// Settlements every 8h since 2023, served newest first in numbered pages
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/contract/funding_rate/history" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		number, _ := strconv.Atoi(r.URL.Query().Get("page_num"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+
+		var all []time.Time
+		for at := last; !at.Before(first); at = at.Add(-8 * time.Hour) {
+			all = append(all, at)
+		}
+		var entries []string
+		for i := (number - 1) * size; i < number*size && i < len(all); i++ {
+			entries = append(entries, fmt.Sprintf(`{"symbol":"BTC_USDT","fundingRate":0.0001,"settleTime":%d}`, all[i].UnixMilli()))
+		}
+		totalPage := (len(all) + size - 1) / size
+		w.Write([]byte(fmt.Sprintf(`{"success":true,"code":0,"data":{"totalPage":%d,"resultList":[%s]}}`, totalPage, strings.Join(entries, ","))))
+	}))
+	defer server.Close()
+
+	client := NewMEXCClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "BTC_USDT", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Reaching back 92 days from the newest settlement takes three pages of 100
+	if len(settlements) != 61*3 {
+		t.Fatalf("Expected %d settlements, got %d", 61*3, len(settlements))
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if !settlements[0].FundingTime.Equal(start) || !settlements[len(settlements)-1].FundingTime.Equal(end.Add(-8*time.Hour)) {
+		t.Errorf("Expected settlements from %v until before %v oldest first, got %v to %v", start, end, settlements[0].FundingTime, settlements[len(settlements)-1].FundingTime)
+	}
+	for i, settlement := range settlements {
+		if i > 0 && !settlement.FundingTime.Equal(settlements[i-1].FundingTime.Add(8*time.Hour)) {
+			t.Fatalf("Expected consecutive settlements, got %v after %v", settlement.FundingTime, settlements[i-1].FundingTime)
+		}
+		if settlement.Exchange != "mexc" || settlement.Symbol != "BTC_USDT" || settlement.FundingRate != 0.0001 {
+			t.Fatalf("Unexpected settlement %+v", settlement)
+		}
+	}
+}
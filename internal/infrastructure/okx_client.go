@@ -1,17 +1,23 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fundingmonitor/internal/domain"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// OKXClient reads every OKX swap. FundingTime is the settlement of the
+// running rate and NextFundingTime the one after, whose rate OKX forecasts as
+// nextFundingRate; settFundingRate is the rate settled last.
 type OKXClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
@@ -19,29 +25,60 @@ type OKXClient struct {
 }
 
 type OKXFundingRate struct {
-	InstId        string `json:"instId"`
-	InstType      string `json:"instType"`
-	FundingRate   string `json:"fundingRate"`
-	NextFundingTime string `json:"nextFundingTime"`
+	InstId               string `json:"instId"`
+	InstType             string `json:"instType"`
+	FundingRate          string `json:"fundingRate"`
+	FundingTime          string `json:"fundingTime"`
+	NextFundingTime      string `json:"nextFundingTime"`
 	FundingRatePrecision string `json:"fundingRatePrecision"`
-	MarkPrice     string `json:"markPx"`
-	IndexPrice    string `json:"idxPx"`
-	LastFundingRate string `json:"lastFundingRate"`
+	MarkPrice            string `json:"markPx"`
+	IndexPrice           string `json:"idxPx"`
+	NextFundingRate      string `json:"nextFundingRate"`
+	SettFundingRate      string `json:"settFundingRate"`
+	SettState            string `json:"settState"`
 }
 
 type OKXFundingRateResponse struct {
-	Code string               `json:"code"`
-	Msg  string               `json:"msg"`
-	Data []OKXFundingRate    `json:"data"`
+	Code string           `json:"code"`
+	Msg  string           `json:"msg"`
+	Data []OKXFundingRate `json:"data"`
 }
 
-func NewOKXClient(config domain.ExchangeConfig, logger *logrus.Logger) *OKXClient {
+// OKXFundingRateHistory is a settlement of the funding rate history;
+// realizedRate is the rate actually settled, once known
+type OKXFundingRateHistory struct {
+	InstId       string `json:"instId"`
+	FundingRate  string `json:"fundingRate"`
+	RealizedRate string `json:"realizedRate"`
+	FundingTime  string `json:"fundingTime"`
+}
+
+type OKXFundingRateHistoryResponse struct {
+	Code string                  `json:"code"`
+	Msg  string                  `json:"msg"`
+	Data []OKXFundingRateHistory `json:"data"`
+}
+
+// okxHistoryLimit is the most settlements a funding rate history request returns
+const okxHistoryLimit = 100
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "okx",
+		DefaultBaseURL: "https://www.okx.com",
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true, InverseContracts: true},
+		SymbolParser:   parseOKXSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewOKXClient(config, client, logger)
+		},
+	})
+}
+
+func NewOKXClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *OKXClient {
 	return &OKXClient{
 		config: config,
 		logger: logger,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: client,
 	}
 }
 
@@ -49,9 +86,13 @@ func (o *OKXClient) GetName() string {
 	return "okx"
 }
 
-func (o *OKXClient) IsHealthy() bool {
+func (o *OKXClient) IsHealthy(ctx context.Context) bool {
 	url := fmt.Sprintf("%s/api/v5/public/funding-rate", o.config.BaseURL)
-	resp, err := o.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := o.client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -59,17 +100,19 @@ func (o *OKXClient) IsHealthy() bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-func (o *OKXClient) GetFundingRates() ([]domain.FundingRate, error) {
+func (o *OKXClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
 	url := fmt.Sprintf("%s/api/v5/public/funding-rate", o.config.BaseURL)
-	
-	req, err := http.NewRequest("GET", url, nil)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Add query parameters
+	// Add query parameters; instId ANY returns every swap, the USDT and USDC
+	// margined ones as well as the coin-margined USD ones
 	q := req.URL.Query()
 	q.Add("instType", "SWAP")
+	q.Add("instId", "ANY")
 	req.URL.RawQuery = q.Encode()
 
 	resp, err := o.client.Do(req)
@@ -115,29 +158,135 @@ func (o *OKXClient) GetFundingRates() ([]domain.FundingRate, error) {
 			o.logger.Warnf("Failed to parse index price for %s: %v", rate.InstId, err)
 		}
 
-		lastFundingRate, err := strconv.ParseFloat(rate.LastFundingRate, 64)
+		// The running rate settles at fundingTime
+		fundingTime, err := strconv.ParseInt(rate.FundingTime, 10, 64)
 		if err != nil {
-			o.logger.Warnf("Failed to parse last funding rate for %s: %v", rate.InstId, err)
+			o.logger.Warnf("Failed to parse funding time for %s: %v", rate.InstId, err)
 		}
 
-		// Parse next funding time
-		nextFundingTime, err := strconv.ParseInt(rate.NextFundingTime, 10, 64)
-		if err != nil {
-			o.logger.Warnf("Failed to parse next funding time for %s: %v", rate.InstId, err)
+		// The funding cycle is the gap between the current and the next settlement
+		nextFundingTime, _ := strconv.ParseInt(rate.NextFundingTime, 10, 64)
+		var fundingIntervalHours int
+		if fundingTime > 0 && nextFundingTime > fundingTime {
+			fundingIntervalHours = int((nextFundingTime - fundingTime) / int64(time.Hour/time.Millisecond))
+		}
+
+		// Only set once the next cycle is forecast
+		predictedFundingRate, _ := strconv.ParseFloat(rate.NextFundingRate, 64)
+
+		// The last settlement took place one cycle before the running one settles
+		var lastFundingRate float64
+		var lastFundingTime *time.Time
+		if rate.SettFundingRate != "" && fundingIntervalHours > 0 {
+			if lastFundingRate, err = strconv.ParseFloat(rate.SettFundingRate, 64); err != nil {
+				o.logger.Warnf("Failed to parse settled funding rate for %s: %v", rate.InstId, err)
+			} else {
+				settledAt := time.UnixMilli(fundingTime).Add(-time.Duration(fundingIntervalHours) * time.Hour)
+				lastFundingTime = &settledAt
+			}
 		}
 
 		rates = append(rates, domain.FundingRate{
-			Symbol:           rate.InstId,
-			Exchange:         o.GetName(),
-			FundingRate:      fundingRate,
-			NextFundingTime:  time.Unix(nextFundingTime/1000, 0),
-			Timestamp:        time.Now(),
-			MarkPrice:        markPrice,
-			IndexPrice:       indexPrice,
-			LastFundingRate:  lastFundingRate,
+			Symbol:               rate.InstId,
+			Exchange:             o.GetName(),
+			FundingRate:          fundingRate,
+			NextFundingTime:      time.Unix(fundingTime/1000, 0),
+			Timestamp:            time.Now(),
+			MarkPrice:            markPrice,
+			IndexPrice:           indexPrice,
+			LastFundingRate:      lastFundingRate,
+			LastFundingTime:      lastFundingTime,
+			PredictedFundingRate: predictedFundingRate,
+			FundingIntervalHours: fundingIntervalHours,
+			MarginType:           okxMarginType(rate.InstId),
 		})
 	}
 
 	o.logger.Infof("Retrieved %d funding rates from OKX", len(rates))
 	return rates, nil
-} 
\ No newline at end of file
+}
+
+// GetFundingHistory reads a swap's settlements, which OKX serves newest first
+func (o *OKXClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	return pageSettlementsBackward(start, end, func(bound time.Time) ([]domain.FundingSettlement, bool, error) {
+		// after returns the settlements strictly earlier than it
+		query := url.Values{
+			"instId": {symbol},
+			"after":  {strconv.FormatInt(bound.UnixMilli()+1, 10)},
+			"limit":  {strconv.Itoa(okxHistoryLimit)},
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", o.config.BaseURL+"/api/v5/public/funding-rate-history?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := o.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			return nil, false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var response OKXFundingRateHistoryResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if response.Code != "0" {
+			return nil, false, fmt.Errorf("OKX API error: %s", response.Msg)
+		}
+
+		var settlements []domain.FundingSettlement
+		for _, entry := range response.Data {
+			value := entry.RealizedRate
+			if value == "" {
+				value = entry.FundingRate
+			}
+			fundingRate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				o.logger.Warnf("Failed to parse settled funding rate for %s: %v", symbol, err)
+				continue
+			}
+			fundingTime, err := strconv.ParseInt(entry.FundingTime, 10, 64)
+			if err != nil {
+				o.logger.Warnf("Failed to parse funding time for %s: %v", symbol, err)
+				continue
+			}
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    o.GetName(),
+				Symbol:      symbol,
+				FundingRate: fundingRate,
+				FundingTime: time.UnixMilli(fundingTime),
+			})
+		}
+		return settlements, len(response.Data) >= okxHistoryLimit, nil
+	})
+}
+
+// okxMarginType tells coin-margined swaps, quoted in USD like BTC-USD-SWAP,
+// from the USDT and USDC margined ones
+func okxMarginType(instId string) domain.MarginType {
+	if parts := strings.Split(strings.ToUpper(instId), "-"); len(parts) > 1 && parts[1] == "USD" {
+		return domain.MarginTypeInverse
+	}
+	return domain.MarginTypeLinear
+}
+
+// parseOKXSymbol handles BTC-USDT-SWAP perpetuals and BTC-USD-240628 futures
+func parseOKXSymbol(symbol string) (domain.Instrument, bool) {
+	parts := strings.Split(strings.ToUpper(symbol), "-")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return domain.Instrument{}, false
+	}
+	if parts[2] == "SWAP" {
+		return perpetualInstrument(parts[0], parts[1]), true
+	}
+	expiry, ok := parseExpiry("060102", parts[2])
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	return futureInstrument(parts[0], parts[1], expiry), true
+}
@@ -0,0 +1,131 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestOKXClient_GetFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v5/public/funding-rate" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"code":"0","msg":"","data":[
+			{"instId":"BTC-USDT-SWAP","instType":"SWAP","fundingRate":"0.0001","fundingTime":"1704096000000","nextFundingRate":"0.00012","nextFundingTime":"1704124800000","settFundingRate":"0.00008","settState":"settled"},
+			{"instId":"BTC-USD-SWAP","instType":"SWAP","fundingRate":"-0.00002","fundingTime":"1704096000000","nextFundingRate":"","nextFundingTime":"1704124800000","settFundingRate":"0.00003","settState":"settled"},
+			{"instId":"ORDI-USDT-SWAP","instType":"SWAP","fundingRate":"0.0005","fundingTime":"1704081600000","nextFundingRate":"","nextFundingTime":"1704096000000","settFundingRate":"","settState":""}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewOKXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		predicted   float64
+		lastRate    float64
+		lastFunding int64
+		nextFunding int64
+		interval    int
+	}{
+		{"BTC-USDT-SWAP", 0.0001, 0.00012, 0.00008, 1704067200000, 1704096000000, 8},
+		{"BTC-USD-SWAP", -0.00002, 0, 0.00003, 1704067200000, 1704096000000, 8},
+		// A freshly listed swap has not settled yet
+		{"ORDI-USDT-SWAP", 0.0005, 0, 0, 0, 1704081600000, 4},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.FundingRate != tt.fundingRate || rate.PredictedFundingRate != tt.predicted {
+			t.Errorf("Expected funding/predicted rate %v/%v for %s, got %v/%v", tt.fundingRate, tt.predicted, tt.symbol, rate.FundingRate, rate.PredictedFundingRate)
+		}
+		if rate.LastFundingRate != tt.lastRate {
+			t.Errorf("Expected last funding rate %v for %s, got %v", tt.lastRate, tt.symbol, rate.LastFundingRate)
+		}
+		if tt.lastFunding == 0 && rate.LastFundingTime != nil {
+			t.Errorf("Expected no last settlement for %s, got %v", tt.symbol, rate.LastFundingTime)
+		}
+		if tt.lastFunding != 0 && (rate.LastFundingTime == nil || !rate.LastFundingTime.Equal(time.UnixMilli(tt.lastFunding))) {
+			t.Errorf("Expected the last settlement at %v for %s, got %v", time.UnixMilli(tt.lastFunding), tt.symbol, rate.LastFundingTime)
+		}
+		if !rate.NextFundingTime.Equal(time.UnixMilli(tt.nextFunding)) || rate.FundingIntervalHours != tt.interval {
+			t.Errorf("Expected a %dh cycle settling at %v for %s, got %dh at %v", tt.interval, time.UnixMilli(tt.nextFunding), tt.symbol, rate.FundingIntervalHours, rate.NextFundingTime)
+		}
+	}
+}
+
+func TestOKXClient_GetFundingHistory(t *testing.T) {
+	// Settlements every 8h since 2023, served newest first and at most limit
+	// per request before the after cursor
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v5/public/funding-rate-history" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		after, _ := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var entries []string
+		for at := last; !at.Before(first) && len(entries) < limit; at = at.Add(-8 * time.Hour) {
+			if at.UnixMilli() >= after {
+				continue
+			}
+			entries = append(entries, fmt.Sprintf(`{"instId":"BTC-USDT-SWAP","fundingRate":"0.0001","realizedRate":"0.00009","fundingTime":"%d"}`, at.UnixMilli()))
+		}
+		w.Write([]byte(`{"code":"0","msg":"","data":[` + strings.Join(entries, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client := NewOKXClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	settlements, err := client.GetFundingHistory(context.Background(), "BTC-USDT-SWAP", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 61 days of 8h settlements take two pages of 100
+	if len(settlements) != 61*3 {
+		t.Fatalf("Expected %d settlements, got %d", 61*3, len(settlements))
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if !settlements[0].FundingTime.Equal(start) || !settlements[len(settlements)-1].FundingTime.Equal(end.Add(-8*time.Hour)) {
+		t.Errorf("Expected settlements from %v until before %v oldest first, got %v to %v", start, end, settlements[0].FundingTime, settlements[len(settlements)-1].FundingTime)
+	}
+	if settlements[0].FundingRate != 0.00009 {
+		t.Errorf("Expected the realized rate 0.00009, got %v", settlements[0].FundingRate)
+	}
+}
@@ -0,0 +1,193 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// settlementWorkers bounds the concurrent funding history requests of a
+// tracker, venues serve the history of one market per request
+const settlementWorkers = 4
+
+// settlementFetchesPerPoll bounds the funding histories read per poll, so a
+// venue listing hundreds of markets catches up over a few polls instead of
+// outliving the poll deadline
+const settlementFetchesPerPoll = 20
+
+// settlementBudget is the share of the time left before the poll deadline
+// the tracker spends reading funding histories, so the poll still returns
+// its rates when a funding history API is slow
+const settlementBudget = 0.5
+
+// settlementPublishDelay is how long a venue may take to publish a settlement
+// in its funding history; a market is asked again until then
+const settlementPublishDelay = 5 * time.Minute
+
+// settlementTracker keeps the last settlement of every market of a venue
+// whose bulk endpoints only carry the running rate, reading each market's
+// funding history again once it settled since it was last checked.
+type settlementTracker struct {
+	history domain.FundingHistoryRepository
+	logger  *logrus.Logger
+	now     func() time.Time
+
+	// Funding histories read per poll at most
+	perPoll int
+
+	mu      sync.Mutex
+	markets map[string]trackedSettlement
+}
+
+// trackedSettlement is the last settlement known of a market; its funding
+// time is zero when the history had none
+type trackedSettlement struct {
+	settlement domain.FundingSettlement
+	checked    time.Time
+}
+
+func newSettlementTracker(history domain.FundingHistoryRepository, now func() time.Time, logger *logrus.Logger) *settlementTracker {
+	return &settlementTracker{
+		history: history,
+		logger:  logger,
+		now:     now,
+		perPoll: settlementFetchesPerPoll,
+		markets: make(map[string]trackedSettlement),
+	}
+}
+
+// lastSettlementTime returns when a rate's market settled last going by its
+// next settlement and cycle, or one cycle before now when the venue doesn't
+// report the next settlement
+func lastSettlementTime(rate domain.FundingRate, now time.Time) time.Time {
+	interval := time.Duration(rate.IntervalHours()) * time.Hour
+	if rate.NextFundingTime.IsZero() {
+		return now.Add(-interval)
+	}
+	return rate.NextFundingTime.Add(-interval)
+}
+
+// stale reports whether the market settled since it was checked, or may not
+// have published its last settlement when it was
+func (t trackedSettlement) stale(lastSettled time.Time) bool {
+	if t.checked.Before(lastSettled) {
+		return true
+	}
+	return t.settlement.FundingTime.Before(lastSettled) && t.checked.Before(lastSettled.Add(settlementPublishDelay))
+}
+
+// update reads the funding history of the stale markets among rates, those
+// never read first, then sets the last settlement of every rate known. The
+// reads are best effort and stop at settlementBudget of the time left before
+// ctx's deadline: a history that can't be read in time is asked again on the
+// next poll.
+func (s *settlementTracker) update(ctx context.Context, rates []domain.FundingRate) {
+	ctx, cancel := withBudget(ctx, settlementBudget)
+	defer cancel()
+
+	now := s.now()
+	stale := s.staleRates(rates, now)
+	if len(stale) > s.perPoll {
+		stale = stale[:s.perPoll]
+	}
+
+	jobs := make(chan domain.FundingRate)
+	var wg sync.WaitGroup
+	for w := 0; w < settlementWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rate := range jobs {
+				settlement, err := s.lastSettlement(ctx, rate, now)
+				if err != nil {
+					if ctx.Err() == nil {
+						s.logger.Warnf("Failed to get funding history for %s on %s: %v", rate.Symbol, rate.Exchange, err)
+					}
+					continue
+				}
+				s.mu.Lock()
+				s.markets[rate.Symbol] = trackedSettlement{settlement: settlement, checked: now}
+				s.mu.Unlock()
+			}
+		}()
+	}
+
+	for _, rate := range stale {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- rate:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		s.logger.Warnf("Stopped reading funding histories, the rest are read on the next poll: %v", err)
+	}
+	s.apply(rates)
+}
+
+// withBudget bounds ctx to share of the time left before its deadline, so a
+// client can stop early enough to return what it has before the poll gives up
+func withBudget(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
+
+// staleRates returns the rates whose market is stale, those never checked
+// first and the longest unchecked after them
+func (s *settlementTracker) staleRates(rates []domain.FundingRate, now time.Time) []domain.FundingRate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stale []domain.FundingRate
+	for _, rate := range rates {
+		if s.markets[rate.Symbol].stale(lastSettlementTime(rate, now)) {
+			stale = append(stale, rate)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return s.markets[stale[i].Symbol].checked.Before(s.markets[stale[j].Symbol].checked)
+	})
+	return stale
+}
+
+// lastSettlement returns the latest settlement in the market's funding
+// history within a cycle of its last settlement due, a zero one when there
+// is none
+func (s *settlementTracker) lastSettlement(ctx context.Context, rate domain.FundingRate, now time.Time) (domain.FundingSettlement, error) {
+	interval := time.Duration(rate.IntervalHours()) * time.Hour
+	lastSettled := lastSettlementTime(rate, now)
+	settlements, err := s.history.GetFundingHistory(ctx, rate.Symbol, lastSettled.Add(-interval), lastSettled.Add(interval))
+	if err != nil || len(settlements) == 0 {
+		return domain.FundingSettlement{}, err
+	}
+	return settlements[len(settlements)-1], nil
+}
+
+// apply sets the last settled rate and time of the rates whose market has one
+func (s *settlementTracker) apply(rates []domain.FundingRate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range rates {
+		settlement := s.markets[rates[i].Symbol].settlement
+		if settlement.FundingTime.IsZero() {
+			continue
+		}
+		settledAt := settlement.FundingTime
+		rates[i].LastFundingRate = settlement.FundingRate
+		rates[i].LastFundingTime = &settledAt
+	}
+}
@@ -0,0 +1,222 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeFundingHistory settles every symbol on the hour with the hour as rate,
+// up to published, and records the symbols asked. Hanging symbols answer
+// once ctx ends.
+type fakeFundingHistory struct {
+	mu        sync.Mutex
+	published time.Time
+	failing   map[string]bool
+	hanging   map[string]bool
+	asked     []string
+}
+
+func (f *fakeFundingHistory) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	if f.hanging[symbol] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.asked = append(f.asked, symbol)
+	if f.failing[symbol] {
+		return nil, errors.New("unavailable")
+	}
+
+	var settlements []domain.FundingSettlement
+	for at := start.Truncate(time.Hour); at.Before(end) && !at.After(f.published); at = at.Add(time.Hour) {
+		if !at.Before(start) {
+			settlements = append(settlements, domain.FundingSettlement{Symbol: symbol, FundingRate: float64(at.Hour()), FundingTime: at})
+		}
+	}
+	return settlements, nil
+}
+
+// requested returns and resets the symbols asked so far
+func (f *fakeFundingHistory) requested() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	asked := f.asked
+	f.asked = nil
+	sort.Strings(asked)
+	return strings.Join(asked, ",")
+}
+
+// hourlyRates returns hourly rates of the symbols settling next after now
+func hourlyRates(now time.Time, symbols ...string) []domain.FundingRate {
+	var rates []domain.FundingRate
+	for _, symbol := range symbols {
+		rates = append(rates, domain.FundingRate{
+			Symbol:               symbol,
+			NextFundingTime:      now.Truncate(time.Hour).Add(time.Hour),
+			FundingIntervalHours: 1,
+		})
+	}
+	return rates
+}
+
+func TestSettlementTracker_Update(t *testing.T) {
+	now := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+	history := &fakeFundingHistory{published: now, failing: map[string]bool{"SOL": true}}
+	tracker := newSettlementTracker(history, func() time.Time { return now }, logrus.New())
+
+	rates := hourlyRates(now, "BTC", "ETH", "SOL")
+	tracker.update(context.Background(), rates)
+	if asked := history.requested(); asked != "BTC,ETH,SOL" {
+		t.Errorf("Expected every history to be read, got %s", asked)
+	}
+	if rates[0].LastFundingTime == nil || !rates[0].LastFundingTime.Equal(time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)) || rates[0].LastFundingRate != 5 {
+		t.Errorf("Expected the 05:00 settlement, got %v at %v", rates[0].LastFundingRate, rates[0].LastFundingTime)
+	}
+	if rates[2].LastFundingTime != nil {
+		t.Errorf("Expected no settlement for the failing SOL, got %v", rates[2].LastFundingTime)
+	}
+
+	// Within the hour only the failed history is read again, the cached
+	// settlements still apply
+	now = now.Add(20 * time.Minute)
+	rates = hourlyRates(now, "BTC", "ETH", "SOL")
+	tracker.update(context.Background(), rates)
+	if asked := history.requested(); asked != "SOL" {
+		t.Errorf("Expected only SOL to be read again, got %s", asked)
+	}
+	if rates[1].LastFundingRate != 5 {
+		t.Errorf("Expected the cached ETH settlement, got %v", rates[1].LastFundingRate)
+	}
+
+	// The next settlement makes every market stale
+	now = time.Date(2024, 1, 1, 6, 10, 0, 0, time.UTC)
+	history.published = now
+	rates = hourlyRates(now, "BTC", "ETH", "SOL")
+	tracker.update(context.Background(), rates)
+	if asked := history.requested(); asked != "BTC,ETH,SOL" {
+		t.Errorf("Expected every history after the settlement, got %s", asked)
+	}
+	if rates[0].LastFundingRate != 6 {
+		t.Errorf("Expected the 06:00 settlement, got %v", rates[0].LastFundingRate)
+	}
+}
+
+func TestSettlementTracker_WaitsForPublication(t *testing.T) {
+	// Settled at 06:00 but published a few minutes later
+	now := time.Date(2024, 1, 1, 6, 1, 0, 0, time.UTC)
+	history := &fakeFundingHistory{published: time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)}
+	tracker := newSettlementTracker(history, func() time.Time { return now }, logrus.New())
+
+	polls := []struct {
+		at        time.Time
+		published time.Time
+		asked     string
+		lastRate  float64
+	}{
+		{now, history.published, "BTC", 5},
+		// Still unpublished, asked again
+		{now.Add(time.Minute), history.published, "BTC", 5},
+		{now.Add(2 * time.Minute), time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC), "BTC", 6},
+		{now.Add(3 * time.Minute), time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC), "", 6},
+	}
+	for i, poll := range polls {
+		now = poll.at
+		history.published = poll.published
+		rates := hourlyRates(now, "BTC")
+		tracker.update(context.Background(), rates)
+		if asked := history.requested(); asked != poll.asked {
+			t.Errorf("Expected %q to be read on poll %d, got %q", poll.asked, i, asked)
+		}
+		if rates[0].LastFundingRate != poll.lastRate {
+			t.Errorf("Expected last rate %v on poll %d, got %v", poll.lastRate, i, rates[0].LastFundingRate)
+		}
+	}
+
+	// Past the publication delay an unpublished settlement waits for the next one
+	now = time.Date(2024, 1, 1, 7, 10, 0, 0, time.UTC)
+	history.published = time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{now, now.Add(time.Minute)} {
+		now = at
+		tracker.update(context.Background(), hourlyRates(now, "BTC"))
+	}
+	if asked := history.requested(); asked != "BTC" {
+		t.Errorf("Expected a single read past the publication delay, got %q", asked)
+	}
+}
+
+func TestSettlementTracker_BoundsReadsPerPoll(t *testing.T) {
+	now := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+	history := &fakeFundingHistory{published: now}
+	tracker := newSettlementTracker(history, func() time.Time { return now }, logrus.New())
+	tracker.perPoll = 2
+
+	symbols := []string{"A", "B", "C", "D", "E"}
+	polls := []string{"A,B", "C,D", "E"}
+	for i, expected := range polls {
+		now = now.Add(time.Minute)
+		tracker.update(context.Background(), hourlyRates(now, symbols...))
+		if asked := history.requested(); asked != expected {
+			t.Errorf("Expected %s to be read on poll %d, got %s", expected, i, asked)
+		}
+	}
+}
+
+func TestSettlementTracker_Cancelled(t *testing.T) {
+	now := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+	history := &fakeFundingHistory{published: now}
+	tracker := newSettlementTracker(history, func() time.Time { return now }, logrus.New())
+	tracker.update(context.Background(), hourlyRates(now, "BTC"))
+	history.requested()
+
+	// Past the deadline nothing is read but the known settlements still apply
+	now = time.Date(2024, 1, 1, 6, 10, 0, 0, time.UTC)
+	history.published = now
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rates := hourlyRates(now, "BTC", "ETH")
+	tracker.update(ctx, rates)
+	if asked := history.requested(); asked != "" {
+		t.Errorf("Expected no history read, got %s", asked)
+	}
+	if rates[0].LastFundingRate != 5 || rates[1].LastFundingTime != nil {
+		t.Errorf("Expected the cached BTC settlement only, got %+v", rates)
+	}
+
+	// The markets are read on the next poll
+	tracker.update(context.Background(), rates)
+	if asked := history.requested(); asked != "BTC,ETH" {
+		t.Errorf("Expected BTC and ETH to be read, got %s", asked)
+	}
+	if rates[0].LastFundingRate != 6 || rates[1].LastFundingRate != 6 {
+		t.Errorf("Expected the 06:00 settlements, got %+v", rates)
+	}
+}
+
+func TestSettlementTracker_StopsWithinBudget(t *testing.T) {
+	now := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+	history := &fakeFundingHistory{published: now, hanging: map[string]bool{"ETH": true}}
+	tracker := newSettlementTracker(history, func() time.Time { return now }, logrus.New())
+
+	// A hanging history is given up on well before the caller's deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	rates := hourlyRates(now, "BTC", "ETH")
+	tracker.update(ctx, rates)
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond || ctx.Err() != nil {
+		t.Errorf("Expected the update to end within its budget, took %v", elapsed)
+	}
+	if rates[0].LastFundingRate != 5 || rates[1].LastFundingTime != nil {
+		t.Errorf("Expected the BTC settlement only, got %+v", rates)
+	}
+}
@@ -0,0 +1,11 @@
+package infrastructure
+
+import (
+	// Registers the pure-Go "sqlite" database/sql driver, so builds need no
+	// cgo. SQLiteLogger only relies on database/sql, so the driver can be
+	// swapped here without other changes.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriverName is the database/sql driver SQLiteLogger opens
+const sqliteDriverName = "sqlite"
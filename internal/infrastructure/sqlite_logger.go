@@ -0,0 +1,306 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"fmt"
+	"fundingmonitor/internal/domain"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sqliteSchema keys every logged rate on (exchange, symbol, time), which both
+// indexes history reads and makes re-imports of the same rows idempotent
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS funding_rates (
+	exchange     TEXT    NOT NULL,
+	symbol       TEXT    NOT NULL,
+	time         INTEGER NOT NULL,
+	funding_rate REAL    NOT NULL,
+	mark_price   REAL    NOT NULL DEFAULT 0,
+	index_price  REAL    NOT NULL DEFAULT 0,
+	PRIMARY KEY (exchange, symbol, time)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS funding_rates_symbol_time ON funding_rates (symbol, time);
+`
+
+// SQLiteLogger stores funding rates as rows of an embedded SQLite database,
+// so history reads are index range scans instead of re-parsing text logs.
+// Times are stored as unix seconds.
+type SQLiteLogger struct {
+	db     *sql.DB
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewSQLiteLogger opens, or creates, the database at path
+func NewSQLiteLogger(path string, logger *logrus.Logger) (*SQLiteLogger, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+	}
+
+	db, err := sql.Open(sqliteDriverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	// A single connection serializes writers within the process, the busy
+	// timeout covers other processes such as the migration tool
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000", sqliteSchema} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize database %s: %w", path, err)
+		}
+	}
+
+	return &SQLiteLogger{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}, nil
+}
+
+// Close closes the database
+func (s *SQLiteLogger) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteLogger) LogFundingRates(symbol string, rates []domain.FundingRate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for %s: %w", symbol, err)
+	}
+	defer tx.Rollback()
+
+	// Rows are keyed on when they were logged, like the lines of FileLogger
+	timestamp := s.now()
+	logged := make([]domain.FundingRate, len(rates))
+	for i, rate := range rates {
+		rate.Timestamp = timestamp
+		logged[i] = rate
+	}
+
+	if err := insertFundingRates(tx, symbol, logged); err != nil {
+		return fmt.Errorf("failed to write rates for %s: %w", symbol, err)
+	}
+	return tx.Commit()
+}
+
+// BackfillFundingRates writes rates at their own timestamps, replacing the
+// rows already stored for the same exchange, symbol and second
+func (s *SQLiteLogger) BackfillFundingRates(symbol string, rates []domain.FundingRate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for %s: %w", symbol, err)
+	}
+	defer tx.Rollback()
+
+	if err := insertFundingRates(tx, symbol, rates); err != nil {
+		return fmt.Errorf("failed to write rates for %s: %w", symbol, err)
+	}
+	return tx.Commit()
+}
+
+// insertFundingRates writes rates at their timestamps, replacing any row
+// already stored for the same exchange, symbol and second
+func insertFundingRates(tx *sql.Tx, symbol string, rates []domain.FundingRate) error {
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO funding_rates
+		(exchange, symbol, time, funding_rate, mark_price, index_price) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.Exec(rate.Exchange, symbol, rate.Timestamp.Unix(), rate.FundingRate, rate.MarkPrice, rate.IndexPrice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSymbolLogs renders the rows of one local day in the FileLogger line
+// format, so existing consumers of the log endpoint keep working
+func (s *SQLiteLogger) GetSymbolLogs(symbol string, date string) ([]byte, error) {
+	day, ok := parseLogDate(date)
+	if !ok {
+		return nil, domain.ErrLogFileNotFound
+	}
+
+	rows, err := s.db.Query(`SELECT time, exchange, funding_rate, mark_price, index_price
+		FROM funding_rates WHERE symbol = ? AND time >= ? AND time < ?
+		ORDER BY time, exchange`,
+		symbol, day.Unix(), day.AddDate(0, 0, 1).Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs for %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	var content strings.Builder
+	for rows.Next() {
+		var timestamp int64
+		var rate domain.FundingRate
+		if err := rows.Scan(&timestamp, &rate.Exchange, &rate.FundingRate, &rate.MarkPrice, &rate.IndexPrice); err != nil {
+			return nil, fmt.Errorf("failed to read logs for %s: %w", symbol, err)
+		}
+		content.WriteString(formatLogLine(time.Unix(timestamp, 0), symbol, rate))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read logs for %s: %w", symbol, err)
+	}
+
+	if content.Len() == 0 {
+		return nil, domain.ErrLogFileNotFound
+	}
+	return []byte(content.String()), nil
+}
+
+// GetAllLogs lists one entry per symbol and local day, like the daily files
+// of FileLogger. Size is the length of the day as rendered by GetSymbolLogs.
+func (s *SQLiteLogger) GetAllLogs() ([]domain.LogFile, error) {
+	rows, err := s.db.Query(`SELECT symbol, date(time, 'unixepoch', 'localtime') AS day,
+		SUM(length(printf('[0000-00-00 00:00:00] Symbol: %s, Exchange: %s, Funding Rate: %.6f, Mark Price: %.2f, Index Price: %.2f',
+			symbol, exchange, funding_rate, mark_price, index_price)) + 1),
+		MAX(time)
+		FROM funding_rates GROUP BY symbol, day ORDER BY symbol, day`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logFiles []domain.LogFile
+	for rows.Next() {
+		var symbol, day string
+		var size, modified int64
+		if err := rows.Scan(&symbol, &day, &size, &modified); err != nil {
+			return nil, fmt.Errorf("failed to list logs: %w", err)
+		}
+
+		date := day
+		if parsed, err := time.Parse("2006-01-02", day); err == nil {
+			date = parsed.Format("02-01-2006")
+		}
+
+		logFiles = append(logFiles, domain.LogFile{
+			Symbol:   symbol,
+			Date:     date,
+			Path:     filepath.Join(symbol, date+".log"),
+			Size:     size,
+			Modified: time.Unix(modified, 0),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+
+	return logFiles, nil
+}
+
+// GetHistoricalFundingRates range scans the (symbol, time) index, leaving
+// bucketing to domain.AggregateFundingHistory
+func (s *SQLiteLogger) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
+	start, end := int64(math.MinInt64), int64(math.MaxInt64)
+	if !query.From.IsZero() {
+		start = query.From.Unix()
+	}
+	if !query.To.IsZero() {
+		end = query.To.Unix()
+	}
+
+	statement := `SELECT exchange, time, funding_rate, mark_price, index_price FROM funding_rates
+		WHERE symbol = ? AND time >= ? AND time < ?`
+	args := []interface{}{query.Symbol, start, end}
+	if len(query.Exchanges) > 0 {
+		statement += " AND exchange IN (?" + strings.Repeat(", ?", len(query.Exchanges)-1) + ")"
+		for _, exchange := range query.Exchanges {
+			args = append(args, exchange)
+		}
+	}
+	statement += " ORDER BY exchange, time"
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history for %s: %w", query.Symbol, err)
+	}
+	defer rows.Close()
+
+	history := []domain.FundingRateHistory{}
+	for rows.Next() {
+		var point domain.FundingRateHistory
+		if err := rows.Scan(&point.Exchange, &point.Timestamp, &point.FundingRate, &point.MarkPrice, &point.IndexPrice); err != nil {
+			return nil, fmt.Errorf("failed to read history for %s: %w", query.Symbol, err)
+		}
+		history = append(history, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history for %s: %w", query.Symbol, err)
+	}
+
+	return domain.AggregateFundingHistory(history, query.Resolution), nil
+}
+
+// ImportFileLogs imports the <symbol>/<DD-MM-YYYY>.log files FileLogger wrote
+// under logDir, one transaction per file, and returns the number of rates
+// imported. Rows already stored are replaced, so imports can be re-run.
+func (s *SQLiteLogger) ImportFileLogs(logDir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(logDir, "*", "*.log"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	imported := 0
+	for _, filename := range files {
+		count, err := s.importFileLog(filepath.Base(filepath.Dir(filename)), filename)
+		if err != nil {
+			return imported, err
+		}
+		imported += count
+		s.logger.Infof("Imported %d funding rates from %s", count, filename)
+	}
+
+	return imported, nil
+}
+
+// importFileLog imports a single daily log file of symbol
+func (s *SQLiteLogger) importFileLog(symbol string, filename string) (int, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read log file %s: %w", filename, err)
+	}
+
+	var rates []domain.FundingRate
+	for number, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		_, rate, ok := parseLogLine(line)
+		if !ok {
+			s.logger.Warnf("Skipping unparseable line %d of %s", number+1, filename)
+			continue
+		}
+
+		rates = append(rates, rate)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction for %s: %w", filename, err)
+	}
+	defer tx.Rollback()
+
+	if err := insertFundingRates(tx, symbol, rates); err != nil {
+		return 0, fmt.Errorf("failed to import %s: %w", filename, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to import %s: %w", filename, err)
+	}
+	return len(rates), nil
+}
@@ -0,0 +1,262 @@
+package infrastructure
+
+import (
+	"fundingmonitor/internal/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newTestSQLiteLogger opens a database in a temporary directory with a clock
+// set by the returned func
+func newTestSQLiteLogger(t *testing.T) (*SQLiteLogger, func(time.Time)) {
+	sqliteLogger, err := NewSQLiteLogger(filepath.Join(t.TempDir(), "funding.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqliteLogger.Close() })
+
+	now := time.Now()
+	sqliteLogger.now = func() time.Time { return now }
+	return sqliteLogger, func(t time.Time) { now = t }
+}
+
+func TestSQLiteLogger_LogAndHistory(t *testing.T) {
+	sqliteLogger, setNow := newTestSQLiteLogger(t)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+
+	for i, rate := range []float64{0.0001, 0.0002, 0.0003} {
+		setNow(start.Add(time.Duration(i) * time.Hour))
+		err := sqliteLogger.LogFundingRates("BTCUSDT", []domain.FundingRate{
+			{Symbol: "BTCUSDT", Exchange: "binance", FundingRate: rate, MarkPrice: 42000},
+			{Symbol: "BTC-USDT-SWAP", Exchange: "okx", FundingRate: -rate},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	history, err := sqliteLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT", Exchanges: []string{"binance"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(history))
+	}
+	for i, point := range history {
+		expected := start.Add(time.Duration(i) * time.Hour).Unix()
+		if point.Timestamp != expected {
+			t.Errorf("Expected point %d at %d, got %d", i, expected, point.Timestamp)
+		}
+	}
+	if history[2].FundingRate != 0.0003 || history[2].MarkPrice != 42000 || history[2].Exchange != "binance" {
+		t.Errorf("Expected latest binance rate 0.0003 at 42000, got %+v", history[2])
+	}
+
+	// Every exchange is returned when none are selected, grouped by exchange
+	history, _ = sqliteLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT"})
+	if len(history) != 6 || history[0].Exchange != "binance" || history[3].Exchange != "okx" {
+		t.Errorf("Expected binance then okx points, got %+v", history)
+	}
+
+	history, _ = sqliteLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT", Exchanges: []string{"bybit"}})
+	if len(history) != 0 {
+		t.Errorf("Expected no history for bybit, got %d points", len(history))
+	}
+}
+
+func TestSQLiteLogger_GetHistoricalFundingRatesRange(t *testing.T) {
+	sqliteLogger, setNow := newTestSQLiteLogger(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 24; i++ {
+		setNow(start.Add(time.Duration(i) * time.Hour))
+		sqliteLogger.LogFundingRates("ETHUSDT", []domain.FundingRate{{Exchange: "bybit", FundingRate: float64(i)}})
+	}
+
+	tests := []struct {
+		name       string
+		from       time.Time
+		to         time.Time
+		resolution domain.HistoryResolution
+		expected   int
+	}{
+		{"unbounded", time.Time{}, time.Time{}, domain.ResolutionRaw, 24},
+		{"from", start.Add(20 * time.Hour), time.Time{}, domain.ResolutionRaw, 4},
+		{"to is exclusive", time.Time{}, start.Add(2 * time.Hour), domain.ResolutionRaw, 2},
+		{"window", start.Add(8 * time.Hour), start.Add(16 * time.Hour), domain.ResolutionRaw, 8},
+		{"empty", start.Add(48 * time.Hour), time.Time{}, domain.ResolutionRaw, 0},
+		{"8h buckets", time.Time{}, time.Time{}, domain.Resolution8h, 3},
+		{"1d bucket of a window", start.Add(4 * time.Hour), start.Add(6 * time.Hour), domain.Resolution1d, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := sqliteLogger.GetHistoricalFundingRates(domain.HistoryQuery{
+				Symbol:     "ETHUSDT",
+				Exchanges:  []string{"bybit"},
+				From:       tt.from,
+				To:         tt.to,
+				Resolution: tt.resolution,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(history) != tt.expected {
+				t.Errorf("Expected %d points, got %d", tt.expected, len(history))
+			}
+		})
+	}
+}
+
+func TestSQLiteLogger_GetSymbolLogs(t *testing.T) {
+	sqliteLogger, setNow := newTestSQLiteLogger(t)
+	setNow(time.Date(2024, 3, 5, 12, 30, 0, 0, time.Local))
+
+	rates := []domain.FundingRate{
+		{Exchange: "bybit", FundingRate: 0.0002, MarkPrice: 42001, IndexPrice: 41999.5},
+		{Exchange: "binance", FundingRate: 0.0001, MarkPrice: 42000, IndexPrice: 41999.5},
+	}
+	if err := sqliteLogger.LogFundingRates("BTCUSDT", rates); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Both date spellings accepted by FileLogger address the same day
+	for _, date := range []string{"05-03-2024", "2024-03-05"} {
+		content, err := sqliteLogger.GetSymbolLogs("BTCUSDT", date)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", date, err)
+		}
+
+		expected := "[2024-03-05 12:30:00] Symbol: BTCUSDT, Exchange: binance, Funding Rate: 0.000100, Mark Price: 42000.00, Index Price: 41999.50\n" +
+			"[2024-03-05 12:30:00] Symbol: BTCUSDT, Exchange: bybit, Funding Rate: 0.000200, Mark Price: 42001.00, Index Price: 41999.50\n"
+		if string(content) != expected {
+			t.Errorf("Expected FileLogger lines for %s, got:\n%s", date, content)
+		}
+	}
+
+	for _, date := range []string{"06-03-2024", "not-a-date"} {
+		if _, err := sqliteLogger.GetSymbolLogs("BTCUSDT", date); err != domain.ErrLogFileNotFound {
+			t.Errorf("Expected ErrLogFileNotFound for %s, got %v", date, err)
+		}
+	}
+}
+
+func TestSQLiteLogger_GetAllLogs(t *testing.T) {
+	sqliteLogger, setNow := newTestSQLiteLogger(t)
+
+	for _, day := range []int{1, 1, 2} {
+		setNow(time.Date(2024, 1, day, 12, 0, day, 0, time.Local))
+		sqliteLogger.LogFundingRates("BTCUSDT", []domain.FundingRate{{Exchange: "binance", FundingRate: 0.0001}})
+	}
+	setNow(time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local))
+	sqliteLogger.LogFundingRates("ETHUSDT", []domain.FundingRate{{Exchange: "binance", FundingRate: 0.0001}})
+
+	logFiles, err := sqliteLogger.GetAllLogs()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(logFiles) != 3 {
+		t.Fatalf("Expected 3 daily logs, got %d", len(logFiles))
+	}
+	if logFiles[0].Symbol != "BTCUSDT" || logFiles[0].Date != "01-01-2024" || logFiles[1].Date != "02-01-2024" {
+		t.Errorf("Expected BTCUSDT logs per day, got %+v", logFiles)
+	}
+
+	// Size matches the rendered log, as for a file
+	content, err := sqliteLogger.GetSymbolLogs("BTCUSDT", "01-01-2024")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if logFiles[0].Size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d", len(content), logFiles[0].Size)
+	}
+}
+
+func TestSQLiteLogger_ImportFileLogs(t *testing.T) {
+	logDir := t.TempDir()
+	symbolDir := filepath.Join(logDir, "BTCUSDT")
+	if err := os.MkdirAll(symbolDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{
+		"[2024-01-01 10:00:00] Symbol: BTCUSDT, Exchange: binance, Funding Rate: 0.000100, Mark Price: 42000.00, Index Price: 41990.00",
+		"[2024-01-01 10:00:00] Symbol: BTCUSDT, Exchange: bybit, Funding Rate: -0.000050, Mark Price: 42001.00, Index Price: 41991.00",
+		"garbage",
+		"[2024-01-01 10:01:00] Symbol: BTCUSDT, Exchange: binance, Funding Rate: 0.000110, Mark Price: 42010.00, Index Price: 42000.00",
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(symbolDir, "01-01-2024.log"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sqliteLogger, _ := newTestSQLiteLogger(t)
+	for i := 0; i < 2; i++ {
+		imported, err := sqliteLogger.ImportFileLogs(logDir)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if imported != 3 {
+			t.Errorf("Expected 3 imported rates, got %d", imported)
+		}
+	}
+
+	// Re-importing replaces rows instead of duplicating them
+	history, err := sqliteLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT", Exchanges: []string{"binance"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 binance points, got %d", len(history))
+	}
+	expected := time.Date(2024, 1, 1, 10, 1, 0, 0, time.Local).Unix()
+	if history[1].Timestamp != expected || history[1].FundingRate != 0.00011 {
+		t.Errorf("Expected 0.00011 at %d, got %+v", expected, history[1])
+	}
+
+	// The imported day reads back as the original file, minus unparseable lines
+	logs, err := sqliteLogger.GetSymbolLogs("BTCUSDT", "01-01-2024")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedLogs := strings.Join([]string{lines[0], lines[1], lines[3]}, "\n") + "\n"
+	if string(logs) != expectedLogs {
+		t.Errorf("Expected the original lines, got:\n%s", logs)
+	}
+}
+
+func TestSQLiteLogger_BackfillFundingRates(t *testing.T) {
+	sqliteLogger, setNow := newTestSQLiteLogger(t)
+	settledAt := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	// A rate logged at the settlement second is replaced
+	setNow(settledAt)
+	sqliteLogger.LogFundingRates("BTCUSDT", []domain.FundingRate{{Exchange: "binance", FundingRate: 0.0005}})
+
+	rates := []domain.FundingRate{
+		{Exchange: "binance", FundingRate: 0.0001, Timestamp: settledAt},
+		{Exchange: "binance", FundingRate: 0.0002, Timestamp: settledAt.Add(8 * time.Hour)},
+	}
+	for i := 0; i < 2; i++ {
+		if err := sqliteLogger.BackfillFundingRates("BTCUSDT", rates); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	history, err := sqliteLogger.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 points, got %d", len(history))
+	}
+	if history[0].Timestamp != settledAt.Unix() || history[0].FundingRate != 0.0001 {
+		t.Errorf("Expected the settled rate at %v, got %+v", settledAt, history[0])
+	}
+}
@@ -0,0 +1,133 @@
+package infrastructure
+
+import (
+	"strings"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// SymbolParser converts a venue specific instrument id into its canonical identity
+type SymbolParser func(symbol string) (domain.Instrument, bool)
+
+// knownQuotes lists the quote assets recognised in concatenated symbols such
+// as BTCUSDT, longest first so USDT wins over USD
+var knownQuotes = []string{"FDUSD", "USDT", "USDC", "BUSD", "USD"}
+
+// assetAliases maps venue specific asset codes to their common ticker
+var assetAliases = map[string]string{
+	"XBT": "BTC",
+}
+
+// SymbolMapper resolves venue symbols into canonical instruments. Entries from
+// the config override table take precedence over the built-in parsers.
+type SymbolMapper struct {
+	parsers   map[string]SymbolParser
+	overrides map[string]map[string]domain.Instrument
+}
+
+func NewSymbolMapper(overrides map[string]map[string]domain.Instrument) *SymbolMapper {
+	// Viper lowercases map keys, so overrides are indexed case-insensitively
+	normalized := make(map[string]map[string]domain.Instrument, len(overrides))
+	for exchange, symbols := range overrides {
+		exchangeOverrides := make(map[string]domain.Instrument, len(symbols))
+		for symbol, instrument := range symbols {
+			instrument.Base = canonicalAsset(instrument.Base)
+			instrument.Quote = canonicalAsset(instrument.Quote)
+			if instrument.ContractType == "" {
+				instrument.ContractType = domain.ContractTypePerpetual
+			}
+			exchangeOverrides[strings.ToUpper(symbol)] = instrument
+		}
+		normalized[strings.ToLower(exchange)] = exchangeOverrides
+	}
+
+	// Every registered exchange brings its own parser
+	parsers := make(map[string]SymbolParser)
+	for _, registration := range RegisteredExchanges() {
+		if registration.SymbolParser != nil {
+			parsers[registration.Name] = registration.SymbolParser
+		}
+	}
+
+	return &SymbolMapper{
+		parsers:   parsers,
+		overrides: normalized,
+	}
+}
+
+// Normalize returns the canonical instrument for a venue symbol
+func (m *SymbolMapper) Normalize(exchange string, symbol string) (domain.Instrument, bool) {
+	if instrument, ok := m.overrides[strings.ToLower(exchange)][strings.ToUpper(symbol)]; ok {
+		return instrument, true
+	}
+
+	parser, ok := m.parsers[strings.ToLower(exchange)]
+	if !ok {
+		return domain.Instrument{}, false
+	}
+	return parser(symbol)
+}
+
+func canonicalAsset(asset string) string {
+	asset = strings.ToUpper(strings.TrimSpace(asset))
+	if alias, ok := assetAliases[asset]; ok {
+		return alias
+	}
+	return asset
+}
+
+func perpetualInstrument(base, quote string) domain.Instrument {
+	return domain.Instrument{
+		Base:         canonicalAsset(base),
+		Quote:        canonicalAsset(quote),
+		ContractType: domain.ContractTypePerpetual,
+	}
+}
+
+func futureInstrument(base, quote, expiry string) domain.Instrument {
+	return domain.Instrument{
+		Base:         canonicalAsset(base),
+		Quote:        canonicalAsset(quote),
+		ContractType: domain.ContractTypeFuture,
+		Expiry:       expiry,
+	}
+}
+
+// splitConcatenatedSymbol splits symbols like BTCUSDT into base and quote
+func splitConcatenatedSymbol(symbol string) (string, string, bool) {
+	symbol = strings.ToUpper(symbol)
+	for _, quote := range knownQuotes {
+		if strings.HasSuffix(symbol, quote) && len(symbol) > len(quote) {
+			return strings.TrimSuffix(symbol, quote), quote, true
+		}
+	}
+	return "", "", false
+}
+
+// parseExpiry converts a venue specific expiry date into YYYYMMDD
+func parseExpiry(layout, value string) (string, bool) {
+	expiry, err := time.Parse(layout, value)
+	if err != nil {
+		return "", false
+	}
+	return expiry.Format("20060102"), true
+}
+
+// parseUnderscoreSymbol handles BASE_QUOTE perpetual symbols (MEXC, Gate, XT)
+func parseUnderscoreSymbol(symbol string) (domain.Instrument, bool) {
+	base, quote, ok := strings.Cut(strings.ToUpper(symbol), "_")
+	if !ok || base == "" || quote == "" {
+		return domain.Instrument{}, false
+	}
+	return perpetualInstrument(base, quote), true
+}
+
+// parseDashSymbol handles BASE-QUOTE perpetual symbols (HTX, BingX)
+func parseDashSymbol(symbol string) (domain.Instrument, bool) {
+	base, quote, ok := strings.Cut(strings.ToUpper(symbol), "-")
+	if !ok || base == "" || quote == "" || strings.Contains(quote, "-") {
+		return domain.Instrument{}, false
+	}
+	return perpetualInstrument(base, quote), true
+}
@@ -0,0 +1,131 @@
+package infrastructure
+
+import (
+	"fundingmonitor/internal/domain"
+	"testing"
+)
+
+func TestSymbolMapper_Normalize(t *testing.T) {
+	mapper := NewSymbolMapper(nil)
+
+	tests := []struct {
+		exchange string
+		symbol   string
+		expected domain.Instrument
+	}{
+		// Binance: concatenated perpetuals and underscore-dated quarterlies
+		{"binance", "BTCUSDT", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"binance", "ETHUSDC", domain.Instrument{Base: "ETH", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		{"binance", "1000PEPEUSDT", domain.Instrument{Base: "1000PEPE", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"binance", "BTCUSDT_240628", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypeFuture, Expiry: "20240628"}},
+		// Bybit: USDC perpetuals are suffixed PERP, dated contracts use DDMMMYY
+		{"bybit", "BTCUSDT", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"bybit", "ETHPERP", domain.Instrument{Base: "ETH", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		{"bybit", "BTC-26JUL24", domain.Instrument{Base: "BTC", Quote: "USDC", ContractType: domain.ContractTypeFuture, Expiry: "20240726"}},
+		{"bybit", "BTCUSDT-5JUL24", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypeFuture, Expiry: "20240705"}},
+		// OKX: dash separated with SWAP or date suffix
+		{"okx", "BTC-USDT-SWAP", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"okx", "ETH-USD-SWAP", domain.Instrument{Base: "ETH", Quote: "USD", ContractType: domain.ContractTypePerpetual}},
+		{"okx", "BTC-USD-240927", domain.Instrument{Base: "BTC", Quote: "USD", ContractType: domain.ContractTypeFuture, Expiry: "20240927"}},
+		// MEXC, Gate and XT: underscore separated, XT in lower case
+		{"mexc", "BTC_USDT", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"gate", "DOGE_USDT", domain.Instrument{Base: "DOGE", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"xt", "btc_usdt", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		// Bitget: product type suffix
+		{"bitget", "BTCUSDT_UMCBL", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"bitget", "BTCUSD_DMCBL", domain.Instrument{Base: "BTC", Quote: "USD", ContractType: domain.ContractTypePerpetual}},
+		{"bitget", "ETHPERP_CMCBL", domain.Instrument{Base: "ETH", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		// KuCoin: trailing M marks perpetuals, XBT is BTC
+		{"kucoin", "XBTUSDTM", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"kucoin", "XBTUSDM", domain.Instrument{Base: "BTC", Quote: "USD", ContractType: domain.ContractTypePerpetual}},
+		{"kucoin", "SOLUSDTM", domain.Instrument{Base: "SOL", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		// Deribit: inverse perpetuals quote USD, linear ones carry the quote
+		{"deribit", "BTC-PERPETUAL", domain.Instrument{Base: "BTC", Quote: "USD", ContractType: domain.ContractTypePerpetual}},
+		{"deribit", "SOL_USDC-PERPETUAL", domain.Instrument{Base: "SOL", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		{"deribit", "ETH-28JUN24", domain.Instrument{Base: "ETH", Quote: "USD", ContractType: domain.ContractTypeFuture, Expiry: "20240628"}},
+		// Hyperliquid: bare USDC-margined coins, k marks thousand unit coins
+		{"hyperliquid", "BTC", domain.Instrument{Base: "BTC", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		{"hyperliquid", "kPEPE", domain.Instrument{Base: "1000PEPE", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		{"hyperliquid", "kNEIRO", domain.Instrument{Base: "1000NEIRO", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		// dYdX: USD quoted tickers settled in USDC
+		{"dydx", "BTC-USD", domain.Instrument{Base: "BTC", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		{"dydx", "1000PEPE-USD", domain.Instrument{Base: "1000PEPE", Quote: "USDC", ContractType: domain.ContractTypePerpetual}},
+		// HTX and BingX: BASE-QUOTE swaps
+		{"htx", "BTC-USDT", domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+		{"bingx", "1000PEPE-USDT", domain.Instrument{Base: "1000PEPE", Quote: "USDT", ContractType: domain.ContractTypePerpetual}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.exchange+"/"+tc.symbol, func(t *testing.T) {
+			instrument, ok := mapper.Normalize(tc.exchange, tc.symbol)
+			if !ok {
+				t.Fatalf("Expected %s to be recognised", tc.symbol)
+			}
+			if instrument != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, instrument)
+			}
+		})
+	}
+}
+
+func TestSymbolMapper_NormalizeUnknown(t *testing.T) {
+	mapper := NewSymbolMapper(nil)
+
+	tests := []struct {
+		exchange string
+		symbol   string
+	}{
+		{"binance", "BTCETH"},
+		{"okx", "BTC-USDT"},
+		{"kucoin", "XBTMH24"},
+		{"deribit", "BTC-28JUN24-60000-C"},
+		{"hyperliquid", "@107"},
+		{"hyperliquid", "PURR/USDC"},
+		{"hyperliquid", "xyz:TSLA"},
+		{"dydx", "BTC-USDT-SWAP"},
+		{"htx", "BTC-USDT-240628"},
+		{"unknown", "BTCUSDT"},
+	}
+
+	for _, tc := range tests {
+		if instrument, ok := mapper.Normalize(tc.exchange, tc.symbol); ok {
+			t.Errorf("Expected %s/%s to be unrecognised, got %+v", tc.exchange, tc.symbol, instrument)
+		}
+	}
+}
+
+func TestSymbolMapper_Overrides(t *testing.T) {
+	// Keys arrive lower-cased from viper
+	mapper := NewSymbolMapper(map[string]map[string]domain.Instrument{
+		"binance": {
+			"1000pepeusdt": {Base: "pepe", Quote: "usdt"},
+		},
+	})
+
+	instrument, ok := mapper.Normalize("binance", "1000PEPEUSDT")
+	if !ok {
+		t.Fatal("Expected override to be applied")
+	}
+
+	expected := domain.Instrument{Base: "PEPE", Quote: "USDT", ContractType: domain.ContractTypePerpetual}
+	if instrument != expected {
+		t.Errorf("Expected %+v, got %+v", expected, instrument)
+	}
+
+	// Other symbols still go through the parser
+	if instrument, _ := mapper.Normalize("binance", "BTCUSDT"); instrument.Symbol() != "BTCUSDT" {
+		t.Errorf("Expected BTCUSDT, got %s", instrument.Symbol())
+	}
+}
+
+func TestInstrument_Symbol(t *testing.T) {
+	perpetual := domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}
+	if perpetual.Symbol() != "BTCUSDT" {
+		t.Errorf("Expected BTCUSDT, got %s", perpetual.Symbol())
+	}
+
+	future := domain.Instrument{Base: "BTC", Quote: "USD", ContractType: domain.ContractTypeFuture, Expiry: "20240628"}
+	if future.Symbol() != "BTCUSD-20240628" {
+		t.Errorf("Expected BTCUSD-20240628, got %s", future.Symbol())
+	}
+}
@@ -0,0 +1,405 @@
+package infrastructure
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Headers carrying the delivery time and the HMAC-SHA256 of
+	// "<timestamp>.<body>" keyed with the target's secret
+	WebhookTimestampHeader = "X-Funding-Timestamp"
+	WebhookSignatureHeader = "X-Funding-Signature"
+
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultDeadLetterRetry    = 5 * time.Minute
+	webhookQueueSize          = 64
+)
+
+// WebhookNotifier posts the rates of every logging cycle to the configured
+// targets. Deliveries run in the background (Run), each target draining its
+// own queue so one that is down doesn't hold up the others. Failed deliveries
+// are retried with exponential backoff and, once out of attempts, appended to
+// a JSON lines dead-letter file that is retried periodically and on start.
+type WebhookNotifier struct {
+	targets        []domain.WebhookTarget
+	client         *http.Client
+	logger         *logrus.Logger
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryInterval  time.Duration
+	deadLetterPath string
+	now            func() time.Time
+
+	// queues holds the pending deliveries of each target by url
+	queues map[string]chan webhookDelivery
+
+	// deadLetterMu serializes access to the dead-letter file
+	deadLetterMu sync.Mutex
+}
+
+// webhookDelivery is a payload bound for one target, as stored in the dead-letter file
+type webhookDelivery struct {
+	URL      string          `json:"url"`
+	Payload  json.RawMessage `json:"payload"`
+	Attempts int             `json:"attempts,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	FailedAt time.Time       `json:"failed_at,omitempty"`
+}
+
+// webhookPayload is the body posted to targets
+type webhookPayload struct {
+	Timestamp int64                `json:"timestamp"`
+	Count     int                  `json:"count"`
+	Rates     []domain.FundingRate `json:"rates"`
+}
+
+// webhookStatusError is a delivery rejected by the target
+type webhookStatusError struct {
+	status int
+	body   string
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d: %s", e.status, e.body)
+}
+
+// NewWebhookNotifier validates the targets and creates a notifier for them
+func NewWebhookNotifier(config domain.WebhooksConfig, logger *logrus.Logger) (*WebhookNotifier, error) {
+	urls := make(map[string]bool, len(config.Targets))
+	for _, target := range config.Targets {
+		if err := target.Validate(); err != nil {
+			return nil, err
+		}
+		if urls[target.URL] {
+			return nil, fmt.Errorf("%w: duplicate webhook url %s", domain.ErrInvalidConfig, target.URL)
+		}
+		urls[target.URL] = true
+	}
+	if config.DeadLetterPath == "" {
+		return nil, fmt.Errorf("%w: webhook dead-letter path is required", domain.ErrInvalidConfig)
+	}
+
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	retryInterval := defaultDeadLetterRetry
+	if config.DeadLetterRetryInterval > 0 {
+		retryInterval = time.Duration(config.DeadLetterRetryInterval) * time.Second
+	}
+
+	queues := make(map[string]chan webhookDelivery, len(config.Targets))
+	for _, target := range config.Targets {
+		queues[target.URL] = make(chan webhookDelivery, webhookQueueSize)
+	}
+
+	return &WebhookNotifier{
+		targets:        config.Targets,
+		client:         &http.Client{},
+		logger:         logger,
+		maxAttempts:    maxAttempts,
+		initialBackoff: time.Second,
+		maxBackoff:     time.Minute,
+		retryInterval:  retryInterval,
+		deadLetterPath: config.DeadLetterPath,
+		now:            time.Now,
+		queues:         queues,
+	}, nil
+}
+
+// OnFundingSnapshot queues a delivery of the matching rates for every target
+func (n *WebhookNotifier) OnFundingSnapshot(snapshot domain.FundingSnapshot) {
+	for _, target := range n.targets {
+		rates := make([]domain.FundingRate, 0)
+		for _, rate := range snapshot.Rates {
+			if target.Matches(rate) {
+				rates = append(rates, rate)
+			}
+		}
+		if len(rates) == 0 {
+			continue
+		}
+
+		payload, err := json.Marshal(webhookPayload{
+			Timestamp: snapshot.Timestamp.Unix(),
+			Count:     len(rates),
+			Rates:     rates,
+		})
+		if err != nil {
+			n.logger.Errorf("Failed to marshal webhook payload for %s: %v", target.URL, err)
+			continue
+		}
+
+		n.enqueue(webhookDelivery{URL: target.URL, Payload: payload})
+	}
+}
+
+// enqueue queues a delivery for its target's worker
+func (n *WebhookNotifier) enqueue(delivery webhookDelivery) {
+	select {
+	case n.queues[delivery.URL] <- delivery:
+	default:
+		// A target too slow to keep up must not hold back logging
+		n.deadLetter(delivery, errors.New("delivery queue is full"))
+	}
+}
+
+// Run starts a worker per target and retries the dead letters on start and
+// every retry interval until the context is cancelled. Deliveries cut short
+// or still queued on shutdown are dead-lettered so the next start retries them.
+func (n *WebhookNotifier) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, target := range n.targets {
+		wg.Add(1)
+		go func(target domain.WebhookTarget) {
+			defer wg.Done()
+			n.work(ctx, target, n.queues[target.URL])
+		}(target)
+	}
+
+	ticker := time.NewTicker(n.retryInterval)
+	defer ticker.Stop()
+
+	n.retryDeadLetters(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			n.retryDeadLetters(ctx)
+		}
+	}
+}
+
+// work delivers the queued payloads of one target until the context is cancelled
+func (n *WebhookNotifier) work(ctx context.Context, target domain.WebhookTarget, queue chan webhookDelivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case delivery := <-queue:
+					n.deadLetter(delivery, ctx.Err())
+				default:
+					return
+				}
+			}
+		case delivery := <-queue:
+			if ctx.Err() != nil {
+				n.deadLetter(delivery, ctx.Err())
+				continue
+			}
+			n.deliver(ctx, target, delivery)
+		}
+	}
+}
+
+// deliver posts a payload, retrying with exponential backoff, and
+// dead-letters it when every attempt fails
+func (n *WebhookNotifier) deliver(ctx context.Context, target domain.WebhookTarget, delivery webhookDelivery) {
+	backoff := n.initialBackoff
+	var err error
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		delivery.Attempts++
+		if err = n.post(ctx, target, delivery.Payload); err == nil {
+			return
+		}
+		if !retryableWebhookError(err) || attempt == n.maxAttempts {
+			break
+		}
+
+		n.logger.Warnf("Webhook delivery to %s failed (attempt %d/%d), retrying in %v: %v", target.URL, attempt, n.maxAttempts, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			n.deadLetter(delivery, ctx.Err())
+			return
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > n.maxBackoff {
+			backoff = n.maxBackoff
+		}
+	}
+
+	n.deadLetter(delivery, err)
+}
+
+// post sends the payload signed with the target's secret. A post in flight
+// on shutdown runs to completion within the target's timeout, so a payload
+// the target accepted is not dead-lettered and delivered twice.
+func (n *WebhookNotifier) post(ctx context.Context, target domain.WebhookTarget, payload []byte) error {
+	timeout := defaultWebhookTimeout
+	if target.Timeout > 0 {
+		timeout = time.Duration(target.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(n.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	if target.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(target.Secret, timestamp, payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &webhookStatusError{status: resp.StatusCode, body: string(body)}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<payload>",
+// the value receivers compare against the signature header
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryableWebhookError reports whether a failed delivery may succeed later:
+// network errors, rate limiting and server errors are retried, other
+// rejections are not
+func retryableWebhookError(err error) bool {
+	var statusErr *webhookStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == http.StatusTooManyRequests || statusErr.status >= 500
+	}
+	return true
+}
+
+func (n *WebhookNotifier) target(url string) (domain.WebhookTarget, bool) {
+	for _, target := range n.targets {
+		if target.URL == url {
+			return target, true
+		}
+	}
+	return domain.WebhookTarget{}, false
+}
+
+// deadLetter appends a failed delivery to the dead-letter file
+func (n *WebhookNotifier) deadLetter(delivery webhookDelivery, cause error) {
+	delivery.Error = cause.Error()
+	delivery.FailedAt = n.now()
+	n.logger.Errorf("Dead-lettered webhook delivery to %s after %d attempts: %v", delivery.URL, delivery.Attempts, cause)
+
+	line, err := json.Marshal(delivery)
+	if err != nil {
+		n.logger.Errorf("Failed to marshal dead letter: %v", err)
+		return
+	}
+
+	n.deadLetterMu.Lock()
+	defer n.deadLetterMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.deadLetterPath), 0755); err != nil {
+		n.logger.Errorf("Failed to create dead-letter directory: %v", err)
+		return
+	}
+	file, err := os.OpenFile(n.deadLetterPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		n.logger.Errorf("Failed to open dead-letter file: %v", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		n.logger.Errorf("Failed to write dead letter: %v", err)
+	}
+}
+
+// retryDeadLetters takes every dead letter out of the file and queues it for
+// its target again; those failing once more are appended back
+func (n *WebhookNotifier) retryDeadLetters(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	deliveries, err := n.takeDeadLetters()
+	if err != nil {
+		n.logger.Errorf("Failed to read dead letters: %v", err)
+		return
+	}
+	if len(deliveries) > 0 {
+		n.logger.Infof("Retrying %d dead-lettered webhook deliveries", len(deliveries))
+	}
+
+	for _, delivery := range deliveries {
+		if _, ok := n.target(delivery.URL); !ok {
+			n.logger.Warnf("Dropping webhook delivery to %s: target is no longer configured", delivery.URL)
+			continue
+		}
+		delivery.Attempts = 0
+		n.enqueue(delivery)
+	}
+}
+
+// takeDeadLetters reads and empties the dead-letter file
+func (n *WebhookNotifier) takeDeadLetters() ([]webhookDelivery, error) {
+	n.deadLetterMu.Lock()
+	defer n.deadLetterMu.Unlock()
+
+	file, err := os.Open(n.deadLetterPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var deliveries []webhookDelivery
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var delivery webhookDelivery
+		if err := json.Unmarshal(scanner.Bytes(), &delivery); err != nil {
+			n.logger.Warnf("Skipping unreadable dead letter: %v", err)
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, os.Truncate(n.deadLetterPath, 0)
+}
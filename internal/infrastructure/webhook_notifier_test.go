@@ -0,0 +1,338 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// webhookReceiver is a local webhook endpoint replying with scripted statuses
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int // replies in order, 200 once exhausted
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses, received: make(chan struct{}, 16)}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		status := http.StatusOK
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+		receiver.mu.Unlock()
+
+		w.WriteHeader(status)
+		receiver.received <- struct{}{}
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+// wait blocks until the receiver has been called n more times
+func (r *webhookReceiver) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for webhook delivery %d", i+1)
+		}
+	}
+}
+
+func (r *webhookReceiver) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func newTestWebhookNotifier(t *testing.T, targets ...domain.WebhookTarget) *WebhookNotifier {
+	notifier, err := NewWebhookNotifier(domain.WebhooksConfig{
+		Targets:        targets,
+		MaxAttempts:    3,
+		DeadLetterPath: filepath.Join(t.TempDir(), "dead_letters.jsonl"),
+	}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	notifier.initialBackoff = time.Millisecond
+	notifier.maxBackoff = 2 * time.Millisecond
+	notifier.now = func() time.Time { return time.Unix(1704067200, 0) }
+	return notifier
+}
+
+// runWebhookNotifier runs the notifier until the returned func stops it
+func runWebhookNotifier(notifier *WebhookNotifier) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		notifier.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []webhookDelivery {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	var deliveries []webhookDelivery
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if line == "" {
+			continue
+		}
+		var delivery webhookDelivery
+		if err := json.Unmarshal([]byte(line), &delivery); err != nil {
+			t.Fatalf("Invalid dead letter %q: %v", line, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+var webhookTestSnapshot = domain.FundingSnapshot{
+	Timestamp: time.Unix(1704067100, 0),
+	Rates: []domain.FundingRate{
+		{Exchange: "binance", Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0002, FundingIntervalHours: 1},
+		{Exchange: "okx", Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: -0.0006},
+		{Exchange: "okx", Symbol: "ETH-USDT-SWAP", CanonicalSymbol: "ETHUSDT", FundingRate: 0.0001},
+		{Exchange: "bybit", Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.01},
+	},
+}
+
+func TestWebhookNotifier_DeliversSignedMatchingRates(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	notifier := newTestWebhookNotifier(t,
+		domain.WebhookTarget{
+			URL:       receiver.URL + "/btc",
+			Secret:    "s3cret",
+			Symbols:   []string{"btcusdt"},
+			Exchanges: []string{"binance", "okx"},
+			Top:       0.001,
+			Basis:     domain.RateBasis8h,
+		},
+		domain.WebhookTarget{URL: receiver.URL + "/all"},
+		// Nothing matches, so nothing is sent
+		domain.WebhookTarget{URL: receiver.URL + "/sol", Symbols: []string{"SOLUSDT"}},
+	)
+	stop := runWebhookNotifier(notifier)
+	defer stop()
+
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	receiver.wait(t, 2)
+	stop()
+
+	if receiver.calls() != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", receiver.calls())
+	}
+
+	bodies := make(map[string][]byte)
+	for i, req := range receiver.requests {
+		bodies[req.URL.Path] = receiver.bodies[i]
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s %s", req.Method, req.Header.Get("Content-Type"))
+		}
+		if req.Header.Get(WebhookTimestampHeader) != "1704067200" {
+			t.Errorf("Expected timestamp header 1704067200, got %q", req.Header.Get(WebhookTimestampHeader))
+		}
+
+		signature := req.Header.Get(WebhookSignatureHeader)
+		switch req.URL.Path {
+		case "/btc":
+			if expected := "sha256=" + SignWebhookPayload("s3cret", "1704067200", receiver.bodies[i]); signature != expected {
+				t.Errorf("Expected signature %s, got %s", expected, signature)
+			}
+		case "/all":
+			if signature != "" {
+				t.Errorf("Expected no signature without a secret, got %s", signature)
+			}
+		}
+	}
+
+	// On the 8h basis binance's 1h 0.0002 is 0.0016 and okx stays at -0.0006
+	var payload webhookPayload
+	if err := json.Unmarshal(bodies["/btc"], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Timestamp != 1704067100 || payload.Count != 1 || payload.Rates[0].Exchange != "binance" {
+		t.Errorf("Expected the binance rate only, got %+v", payload)
+	}
+
+	if err := json.Unmarshal(bodies["/all"], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Count != 4 || len(payload.Rates) != 4 {
+		t.Errorf("Expected every rate, got %+v", payload)
+	}
+}
+
+func TestWebhookNotifier_RetriesWithBackoff(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	notifier := newTestWebhookNotifier(t, domain.WebhookTarget{URL: receiver.URL})
+	stop := runWebhookNotifier(notifier)
+	defer stop()
+
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	receiver.wait(t, 3)
+	stop()
+
+	if receiver.calls() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", receiver.calls())
+	}
+	if dead := readDeadLetters(t, notifier.deadLetterPath); len(dead) != 0 {
+		t.Errorf("Expected no dead letters, got %+v", dead)
+	}
+}
+
+func TestWebhookNotifier_DeadLettersAndReplays(t *testing.T) {
+	failing := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusBadRequest)
+	notifier := newTestWebhookNotifier(t, domain.WebhookTarget{URL: failing.URL, Secret: "s3cret"})
+	stop := runWebhookNotifier(notifier)
+
+	// Server errors use up every attempt, client errors aren't retried
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	failing.wait(t, 4)
+	stop()
+
+	dead := readDeadLetters(t, notifier.deadLetterPath)
+	if len(dead) != 2 {
+		t.Fatalf("Expected 2 dead letters, got %+v", dead)
+	}
+	if dead[0].URL != failing.URL || dead[0].Attempts != 3 || !strings.Contains(dead[0].Error, "status 500") {
+		t.Errorf("Expected 3 failed attempts with status 500, got %+v", dead[0])
+	}
+	if dead[1].Attempts != 1 || !strings.Contains(dead[1].Error, "status 400") {
+		t.Errorf("Expected a single attempt with status 400, got %+v", dead[1])
+	}
+
+	// The next start replays the dead letters and empties the file
+	replayed := newTestWebhookNotifier(t, domain.WebhookTarget{URL: failing.URL, Secret: "s3cret"})
+	replayed.deadLetterPath = notifier.deadLetterPath
+	stop = runWebhookNotifier(replayed)
+	failing.wait(t, 2)
+	stop()
+
+	if dead := readDeadLetters(t, notifier.deadLetterPath); len(dead) != 0 {
+		t.Errorf("Expected the dead letters to be delivered, got %+v", dead)
+	}
+
+	var payload webhookPayload
+	last := failing.bodies[len(failing.bodies)-1]
+	if err := json.Unmarshal(last, &payload); err != nil || payload.Count != 4 {
+		t.Errorf("Expected the original payload to be replayed, got %s", last)
+	}
+	if signature := failing.requests[len(failing.requests)-1].Header.Get(WebhookSignatureHeader); signature != "sha256="+SignWebhookPayload("s3cret", "1704067200", last) {
+		t.Errorf("Expected the replay to be signed, got %s", signature)
+	}
+}
+
+func TestWebhookNotifier_TargetDownDoesNotBlockOthers(t *testing.T) {
+	down := newWebhookReceiver(t, http.StatusServiceUnavailable)
+	up := newWebhookReceiver(t)
+	notifier := newTestWebhookNotifier(t, domain.WebhookTarget{URL: down.URL}, domain.WebhookTarget{URL: up.URL})
+	// The failing target waits out its backoff until shutdown
+	notifier.initialBackoff = time.Hour
+	stop := runWebhookNotifier(notifier)
+
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	down.wait(t, 1)
+	up.wait(t, 2)
+	stop()
+
+	dead := readDeadLetters(t, notifier.deadLetterPath)
+	if len(dead) != 2 || dead[0].URL != down.URL || dead[1].URL != down.URL {
+		t.Errorf("Expected the down target's deliveries dead-lettered, got %+v", dead)
+	}
+}
+
+func TestWebhookNotifier_RetriesDeadLettersPeriodically(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	notifier := newTestWebhookNotifier(t, domain.WebhookTarget{URL: receiver.URL})
+	notifier.retryInterval = 10 * time.Millisecond
+	stop := runWebhookNotifier(notifier)
+	defer stop()
+
+	// Out of attempts, then delivered by a retry while running
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	receiver.wait(t, 4)
+	stop()
+
+	if dead := readDeadLetters(t, notifier.deadLetterPath); len(dead) != 0 {
+		t.Errorf("Expected the dead letter to be delivered, got %+v", dead)
+	}
+	if receiver.calls() != 4 {
+		t.Errorf("Expected 4 calls, got %d", receiver.calls())
+	}
+}
+
+func TestWebhookNotifier_ShutdownDeadLettersPendingDeliveries(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	notifier := newTestWebhookNotifier(t, domain.WebhookTarget{URL: receiver.URL})
+
+	// Nothing runs the queue, so the deliveries are still pending on shutdown
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+	notifier.OnFundingSnapshot(webhookTestSnapshot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifier.Run(ctx)
+
+	dead := readDeadLetters(t, notifier.deadLetterPath)
+	if len(dead) != 2 || !strings.Contains(dead[0].Error, context.Canceled.Error()) {
+		t.Errorf("Expected 2 cancelled dead letters, got %+v", dead)
+	}
+	if receiver.calls() != 0 {
+		t.Errorf("Expected no delivery after shutdown, got %d", receiver.calls())
+	}
+}
+
+func TestNewWebhookNotifier_InvalidTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []domain.WebhookTarget
+	}{
+		{"missing url", []domain.WebhookTarget{{}}},
+		{"unsupported scheme", []domain.WebhookTarget{{URL: "ftp://example.com"}}},
+		{"negative top", []domain.WebhookTarget{{URL: "http://example.com", Top: -1}}},
+		{"unknown basis", []domain.WebhookTarget{{URL: "http://example.com", Basis: "4h"}}},
+		{"duplicate url", []domain.WebhookTarget{{URL: "http://example.com"}, {URL: "http://example.com"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhookNotifier(domain.WebhooksConfig{Targets: tt.targets, DeadLetterPath: "dead.jsonl"}, logrus.New())
+			if !errors.Is(err, domain.ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
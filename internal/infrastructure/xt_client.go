@@ -1,30 +1,330 @@
 package infrastructure
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"fundingmonitor/internal/domain"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
+// xtFundingWorkers bounds the concurrent per-symbol funding requests, XT has
+// no endpoint returning the funding rate of every contract at once
+const xtFundingWorkers = 8
+
+// xtFundingBudget is the share of the time left before the poll deadline
+// spent on the per-symbol funding requests, leaving the rest to the
+// settlement lookups and the rates fetched so far
+const xtFundingBudget = 0.6
+
+// XTClient reads the XT perpetuals. The funding rate endpoint only carries
+// the running rate, settled rates come from the funding rate records.
 type XTClient struct {
 	config domain.ExchangeConfig
 	logger *logrus.Logger
+	client *http.Client
+
+	settlements *settlementTracker
 }
 
-func NewXTClient(config domain.ExchangeConfig, logger *logrus.Logger) *XTClient {
-	return &XTClient{
+// XTTicker is an entry of the aggregated ticker endpoint, which abbreviates its fields
+type XTTicker struct {
+	Symbol     string   `json:"s"`
+	Timestamp  int64    `json:"t"`
+	IndexPrice xtNumber `json:"i"`
+	MarkPrice  xtNumber `json:"m"`
+}
+
+type XTTickersResponse struct {
+	ReturnCode int        `json:"returnCode"`
+	MsgInfo    string     `json:"msgInfo"`
+	Result     []XTTicker `json:"result"`
+}
+
+type XTFundingRate struct {
+	Symbol             string   `json:"symbol"`
+	FundingRate        xtNumber `json:"fundingRate"`
+	NextCollectionTime int64    `json:"nextCollectionTime"`
+	CollectionInternal int      `json:"collectionInternal"` // hours, the API's own spelling
+}
+
+type XTFundingRateResponse struct {
+	ReturnCode int           `json:"returnCode"`
+	MsgInfo    string        `json:"msgInfo"`
+	Result     XTFundingRate `json:"result"`
+}
+
+// XTFundingRateRecord is a settlement of the funding rate records
+type XTFundingRateRecord struct {
+	ID          json.Number `json:"id"` // sent as a string or a number
+	Symbol      string      `json:"symbol"`
+	FundingRate xtNumber    `json:"fundingRate"`
+	CreatedTime int64       `json:"createdTime"`
+}
+
+type XTFundingRateRecordsResponse struct {
+	ReturnCode int    `json:"returnCode"`
+	MsgInfo    string `json:"msgInfo"`
+	Result     struct {
+		HasNext bool                  `json:"hasNext"`
+		Items   []XTFundingRateRecord `json:"items"`
+	} `json:"result"`
+}
+
+// xtHistoryLimit is the settlements asked per funding rate records page
+const xtHistoryLimit = 100
+
+// xtNumber accepts decimals sent either as JSON strings or numbers
+type xtNumber float64
+
+func (n *xtNumber) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*n = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return err
+	}
+	*n = xtNumber(value)
+	return nil
+}
+
+func init() {
+	RegisterExchange(ExchangeRegistration{
+		Name:           "xt",
+		DefaultBaseURL: "https://fapi.xt.com",
+		RateLimit:      40, // each poll sends one funding rate request per contract
+		Burst:          80,
+		Timeout:        30 * time.Second,
+		Capabilities:   domain.ExchangeCapabilities{MarkPrice: true, IndexPrice: true, FundingInterval: true, NextFundingTime: true},
+		SymbolParser:   parseXTSymbol,
+		New: func(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) domain.ExchangeRepository {
+			return NewXTClient(config, client, logger)
+		},
+	})
+}
+
+func NewXTClient(config domain.ExchangeConfig, client *http.Client, logger *logrus.Logger) *XTClient {
+	x := &XTClient{
 		config: config,
 		logger: logger,
+		client: client,
 	}
+	x.settlements = newSettlementTracker(x, time.Now, logger)
+	return x
 }
 
 func (x *XTClient) GetName() string {
 	return "xt"
 }
 
-func (x *XTClient) IsHealthy() bool {
-	return true // TODO: implement
+func (x *XTClient) IsHealthy(ctx context.Context) bool {
+	url := fmt.Sprintf("%s/future/market/v1/public/q/agg-tickers", x.config.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
 }
 
-func (x *XTClient) GetFundingRates() ([]domain.FundingRate, error) {
-	return []domain.FundingRate{}, nil // TODO: implement
-} 
\ No newline at end of file
+func (x *XTClient) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	tickers, err := x.getTickers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the funding rate of every contract with a bounded worker pool,
+	// keeping what was fetched when the budget runs out
+	fetchCtx, cancel := withBudget(ctx, xtFundingBudget)
+	defer cancel()
+	fundingRates := make([]XTFundingRate, len(tickers))
+	fetched := make([]bool, len(tickers))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < xtFundingWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fundingRate, err := x.getFundingRate(fetchCtx, tickers[i].Symbol)
+				if err != nil {
+					if fetchCtx.Err() == nil {
+						x.logger.Warnf("Failed to get funding rate for %s: %v", tickers[i].Symbol, err)
+					}
+					continue
+				}
+				fundingRates[i] = fundingRate
+				fetched[i] = true
+			}
+		}()
+	}
+
+	for i := range tickers {
+		select {
+		case jobs <- i:
+		case <-fetchCtx.Done():
+		}
+		if fetchCtx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := fetchCtx.Err(); err != nil {
+		count := 0
+		for _, ok := range fetched {
+			if ok {
+				count++
+			}
+		}
+		if count == 0 {
+			return nil, err
+		}
+		x.logger.Warnf("Fetched %d of %d XT funding rates before running out of time, the rest are fetched on the next poll: %v", count, len(tickers), err)
+	}
+
+	var rates []domain.FundingRate
+	for i, ticker := range tickers {
+		if !fetched[i] {
+			continue
+		}
+
+		fundingIntervalHours := fundingRates[i].CollectionInternal
+		if fundingIntervalHours <= 0 {
+			fundingIntervalHours = domain.DefaultFundingIntervalHours
+		}
+
+		rates = append(rates, domain.FundingRate{
+			Symbol:               ticker.Symbol,
+			Exchange:             x.GetName(),
+			FundingRate:          float64(fundingRates[i].FundingRate),
+			NextFundingTime:      time.UnixMilli(fundingRates[i].NextCollectionTime),
+			Timestamp:            time.UnixMilli(ticker.Timestamp),
+			MarkPrice:            float64(ticker.MarkPrice),
+			IndexPrice:           float64(ticker.IndexPrice),
+			FundingIntervalHours: fundingIntervalHours,
+			MarginType:           domain.MarginTypeLinear,
+		})
+	}
+
+	x.settlements.update(ctx, rates)
+
+	x.logger.Infof("Retrieved %d funding rates from XT", len(rates))
+	return rates, nil
+}
+
+// GetFundingHistory reads a contract's settlements. XT serves its funding
+// rate records newest first, each page continuing after the id of the last
+// record of the previous one.
+func (x *XTClient) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	var settlements []domain.FundingSettlement
+	cursor := ""
+	for {
+		query := url.Values{
+			"symbol":    {symbol},
+			"direction": {"NEXT"},
+			"limit":     {strconv.Itoa(xtHistoryLimit)},
+		}
+		if cursor != "" {
+			query.Set("id", cursor)
+		}
+
+		var response XTFundingRateRecordsResponse
+		if err := x.get(ctx, "/future/market/v1/public/q/funding-rate-record", query, &response); err != nil {
+			return nil, err
+		}
+		if response.ReturnCode != 0 {
+			return nil, fmt.Errorf("API returned error: %s", response.MsgInfo)
+		}
+
+		items := response.Result.Items
+		for _, item := range items {
+			settlements = append(settlements, domain.FundingSettlement{
+				Exchange:    x.GetName(),
+				Symbol:      symbol,
+				FundingRate: float64(item.FundingRate),
+				FundingTime: time.UnixMilli(item.CreatedTime),
+			})
+		}
+
+		if !response.Result.HasNext || len(items) == 0 || items[len(items)-1].ID.String() == cursor {
+			break
+		}
+		if !time.UnixMilli(items[len(items)-1].CreatedTime).After(start) {
+			break
+		}
+		cursor = items[len(items)-1].ID.String()
+	}
+	return finishSettlements(settlements, start, end), nil
+}
+
+// getTickers returns the mark and index prices of every contract
+func (x *XTClient) getTickers(ctx context.Context) ([]XTTicker, error) {
+	var response XTTickersResponse
+	if err := x.get(ctx, "/future/market/v1/public/q/agg-tickers", nil, &response); err != nil {
+		return nil, err
+	}
+	if response.ReturnCode != 0 {
+		return nil, fmt.Errorf("API returned error: %s", response.MsgInfo)
+	}
+	return response.Result, nil
+}
+
+// getFundingRate returns the current funding rate of a single contract
+func (x *XTClient) getFundingRate(ctx context.Context, symbol string) (XTFundingRate, error) {
+	var response XTFundingRateResponse
+	query := url.Values{"symbol": {symbol}}
+	if err := x.get(ctx, "/future/market/v1/public/q/funding-rate", query, &response); err != nil {
+		return XTFundingRate{}, err
+	}
+	if response.ReturnCode != 0 {
+		return XTFundingRate{}, fmt.Errorf("API returned error: %s", response.MsgInfo)
+	}
+	return response.Result, nil
+}
+
+// get requests a public endpoint and decodes its JSON body into out
+func (x *XTClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", x.config.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// parseXTSymbol handles lower-case btc_usdt perpetuals
+func parseXTSymbol(symbol string) (domain.Instrument, bool) {
+	return parseUnderscoreSymbol(symbol)
+}
@@ -0,0 +1,206 @@
+package infrastructure
+
+import (
+	"context"
+	"fundingmonitor/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newXTServer serves the recorded XT responses in testdata/xt
+func newXTServer(t *testing.T) *httptest.Server {
+	serveFixture := func(w http.ResponseWriter, r *http.Request, name string) {
+		data, err := os.ReadFile(filepath.Join("testdata", "xt", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/future/market/v1/public/q/agg-tickers":
+			serveFixture(w, r, "agg_tickers.json")
+		case "/future/market/v1/public/q/funding-rate":
+			serveFixture(w, r, "funding_rate_"+r.URL.Query().Get("symbol")+".json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestXTClient_GetFundingRates(t *testing.T) {
+	server := newXTServer(t)
+
+	client := NewXTClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+
+	bySymbol := map[string]domain.FundingRate{}
+	for _, rate := range rates {
+		bySymbol[rate.Symbol] = rate
+	}
+
+	tests := []struct {
+		symbol      string
+		fundingRate float64
+		markPrice   float64
+		indexPrice  float64
+		nextFunding int64
+		interval    int
+	}{
+		{"btc_usdt", 0.0001, 42651.02, 42648.21, 1704096000000, 8},
+		{"eth_usdt", -0.00025, 2250.3, 2249.87, 1704096000000, 8},
+		{"ordi_usdt", 0.00375, 61.31, 61.3, 1704092400000, 4},
+	}
+	for _, tt := range tests {
+		rate, ok := bySymbol[tt.symbol]
+		if !ok {
+			t.Errorf("Expected a rate for %s", tt.symbol)
+			continue
+		}
+		if rate.Exchange != "xt" {
+			t.Errorf("Expected exchange xt for %s, got %s", tt.symbol, rate.Exchange)
+		}
+		if rate.FundingRate != tt.fundingRate {
+			t.Errorf("Expected funding rate %v for %s, got %v", tt.fundingRate, tt.symbol, rate.FundingRate)
+		}
+		if rate.MarkPrice != tt.markPrice || rate.IndexPrice != tt.indexPrice {
+			t.Errorf("Expected mark/index %v/%v for %s, got %v/%v", tt.markPrice, tt.indexPrice, tt.symbol, rate.MarkPrice, rate.IndexPrice)
+		}
+		if !rate.NextFundingTime.Equal(time.UnixMilli(tt.nextFunding)) {
+			t.Errorf("Expected next funding time %v for %s, got %v", time.UnixMilli(tt.nextFunding), tt.symbol, rate.NextFundingTime)
+		}
+		if rate.FundingIntervalHours != tt.interval {
+			t.Errorf("Expected %dh interval for %s, got %d", tt.interval, tt.symbol, rate.FundingIntervalHours)
+		}
+	}
+
+	if !bySymbol["btc_usdt"].Timestamp.Equal(time.UnixMilli(1704090000123)) {
+		t.Errorf("Expected ticker timestamp, got %v", bySymbol["btc_usdt"].Timestamp)
+	}
+}
+
+func TestXTClient_GetFundingRatesPartial(t *testing.T) {
+	// eth_usdt answers only once its request is abandoned
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var name string
+		switch r.URL.Path {
+		case "/future/market/v1/public/q/agg-tickers":
+			name = "agg_tickers.json"
+		case "/future/market/v1/public/q/funding-rate":
+			if r.URL.Query().Get("symbol") == "eth_usdt" {
+				<-r.Context().Done()
+				return
+			}
+			name = "funding_rate_" + r.URL.Query().Get("symbol") + ".json"
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "xt", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	client := NewXTClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(ctx)
+	if err != nil {
+		t.Fatalf("Expected the rates fetched in time, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected the rates before the deadline")
+	}
+	if len(rates) != 2 {
+		t.Fatalf("Expected 2 rates, got %+v", rates)
+	}
+	for _, rate := range rates {
+		if rate.Symbol == "eth_usdt" {
+			t.Errorf("Expected no eth_usdt rate, got %+v", rate)
+		}
+	}
+}
+
+func TestXTClient_SkipsSymbolsWithoutFundingRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/future/market/v1/public/q/agg-tickers":
+			w.Write([]byte(`{"returnCode":0,"msgInfo":"success","result":[
+				{"t":1704090000123,"s":"btc_usdt","i":"42648.21","m":"42651.02"},
+				{"t":1704090000123,"s":"delisted_usdt","i":"1","m":"1"}
+			]}`))
+		case "/future/market/v1/public/q/funding-rate":
+			if r.URL.Query().Get("symbol") == "btc_usdt" {
+				w.Write([]byte(`{"returnCode":0,"msgInfo":"success","result":{"symbol":"btc_usdt","fundingRate":"0.0001","nextCollectionTime":1704096000000,"collectionInternal":8}}`))
+				return
+			}
+			w.Write([]byte(`{"returnCode":1,"msgInfo":"failure","error":{"code":"invalid_symbol","msg":"invalid symbol"},"result":null}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewXTClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	rates, err := client.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rates) != 1 || rates[0].Symbol != "btc_usdt" {
+		t.Errorf("Expected only btc_usdt, got %+v", rates)
+	}
+}
+
+func TestXTClient_GetFundingRatesError(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"http error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+		}},
+		{"api error", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"returnCode":1,"msgInfo":"failure","result":null}`))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := NewXTClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+			if _, err := client.GetFundingRates(context.Background()); err == nil {
+				t.Error("Expected an error")
+			}
+			if tt.name == "http error" && client.IsHealthy(context.Background()) {
+				t.Error("Expected XT to be unhealthy")
+			}
+		})
+	}
+}
+
+func TestXTClient_IsHealthy(t *testing.T) {
+	server := newXTServer(t)
+
+	client := NewXTClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logrus.New())
+	if !client.IsHealthy(context.Background()) {
+		t.Error("Expected XT to be healthy")
+	}
+}
@@ -0,0 +1,323 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAlertHistorySize is how many fired alerts are kept when none is configured
+const DefaultAlertHistorySize = 500
+
+// AlertEngine evaluates the alert rules against every snapshot refresh.
+//
+// An alert fires when its condition starts to hold: while it keeps holding
+// across refreshes it is not raised again, and once it has fired the same
+// rule, symbol and exchange stay quiet for the rule's cooldown. Change and
+// flip rules compare each rate with the one of the previous snapshot.
+type AlertEngine struct {
+	rules       []domain.AlertRule
+	notifiers   []domain.AlertNotifier
+	historySize int
+	logger      *logrus.Logger
+	now         func() time.Time
+
+	mu       sync.Mutex
+	previous map[alertRateKey]domain.FundingRate
+	active   map[string]bool      // alerts whose condition held at the last refresh
+	fired    map[string]time.Time // when each alert last fired
+	history  []domain.Alert       // oldest first
+	lastID   int64
+}
+
+type alertRateKey struct {
+	exchange string
+	symbol   string
+}
+
+// alertCandidate is a rule breach found in a snapshot, keyed for deduplication
+type alertCandidate struct {
+	key      string
+	cooldown time.Duration
+	alert    domain.Alert
+}
+
+// NewAlertEngine validates the configured rules and creates an engine for them
+func NewAlertEngine(config domain.AlertsConfig, logger *logrus.Logger) (*AlertEngine, error) {
+	names := make(map[string]bool, len(config.Rules))
+	for _, rule := range config.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("%w: duplicate rule name %s", domain.ErrInvalidAlertRule, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	historySize := config.HistorySize
+	if historySize <= 0 {
+		historySize = DefaultAlertHistorySize
+	}
+
+	return &AlertEngine{
+		rules:       config.Rules,
+		historySize: historySize,
+		logger:      logger,
+		now:         time.Now,
+		previous:    make(map[alertRateKey]domain.FundingRate),
+		active:      make(map[string]bool),
+		fired:       make(map[string]time.Time),
+	}, nil
+}
+
+// AddNotifier registers a notifier called with every fired alert
+func (e *AlertEngine) AddNotifier(notifier domain.AlertNotifier) {
+	e.notifiers = append(e.notifiers, notifier)
+}
+
+// OnFundingSnapshot evaluates every rule against the snapshot and notifies
+// the alerts that fire
+func (e *AlertEngine) OnFundingSnapshot(snapshot domain.FundingSnapshot) {
+	current := make(map[alertRateKey]domain.FundingRate, len(snapshot.Rates))
+	for _, rate := range snapshot.Rates {
+		current[alertRateKey{exchange: rate.Exchange, symbol: rate.MarketSymbol()}] = rate
+	}
+
+	e.mu.Lock()
+	now := e.now()
+
+	var candidates []alertCandidate
+	for _, rule := range e.rules {
+		switch rule.Type {
+		case domain.AlertThreshold:
+			candidates = append(candidates, e.thresholdAlerts(rule, current)...)
+		case domain.AlertChange:
+			candidates = append(candidates, e.changeAlerts(rule, current)...)
+		case domain.AlertFlip:
+			candidates = append(candidates, e.flipAlerts(rule, current)...)
+		case domain.AlertSpread:
+			candidates = append(candidates, e.spreadAlerts(rule, current)...)
+		}
+	}
+
+	// Alerts fired by the same refresh are numbered in a stable order
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].key < candidates[j].key
+	})
+
+	active := make(map[string]bool, len(candidates))
+	var fired []domain.Alert
+	for _, candidate := range candidates {
+		active[candidate.key] = true
+		if e.active[candidate.key] {
+			continue
+		}
+
+		if last, ok := e.fired[candidate.key]; ok && now.Sub(last) < candidate.cooldown {
+			continue
+		}
+		e.fired[candidate.key] = now
+
+		e.lastID++
+		alert := candidate.alert
+		alert.ID = e.lastID
+		alert.Timestamp = now
+		fired = append(fired, alert)
+	}
+
+	e.active = active
+	e.previous = current
+	e.history = append(e.history, fired...)
+	if overflow := len(e.history) - e.historySize; overflow > 0 {
+		e.history = append([]domain.Alert(nil), e.history[overflow:]...)
+	}
+	e.mu.Unlock()
+
+	for _, alert := range fired {
+		for _, notifier := range e.notifiers {
+			if err := notifier.Notify(alert); err != nil {
+				e.logger.Warnf("Failed to notify alert %s on %s: %v", alert.Rule, alert.Symbol, err)
+			}
+		}
+	}
+}
+
+// GetAlerts returns the fired alerts matching the filter, newest first
+func (e *AlertEngine) GetAlerts(filter domain.AlertFilter) []domain.Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alerts := make([]domain.Alert, 0)
+	for i := len(e.history) - 1; i >= 0; i-- {
+		alert := e.history[i]
+		if filter.Limit > 0 && len(alerts) >= filter.Limit {
+			break
+		}
+		if filter.Rule != "" && alert.Rule != filter.Rule {
+			continue
+		}
+		if filter.Symbol != "" && alert.Symbol != filter.Symbol {
+			continue
+		}
+		if filter.Exchange != "" && !containsString(alert.Exchanges, filter.Exchange) {
+			continue
+		}
+		if !filter.Since.IsZero() && alert.Timestamp.Before(filter.Since) {
+			continue
+		}
+		if filter.MarginType != "" && alert.MarginType != filter.MarginType {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// GetAlertRules returns the configured rules
+func (e *AlertEngine) GetAlertRules() []domain.AlertRule {
+	return append([]domain.AlertRule(nil), e.rules...)
+}
+
+func (e *AlertEngine) thresholdAlerts(rule domain.AlertRule, current map[alertRateKey]domain.FundingRate) []alertCandidate {
+	basis := rule.RateBasis()
+	var candidates []alertCandidate
+	for key, rate := range current {
+		if !rule.Matches(rate) {
+			continue
+		}
+		if !rate.ExceedsRate(rule.Threshold, basis) {
+			continue
+		}
+		value := rate.RateForBasis(basis)
+		candidates = append(candidates, newAlertCandidate(rule, key, rate.MarginType, value, 0,
+			fmt.Sprintf("%s funding on %s is %s (%s), beyond %s", key.symbol, key.exchange, formatAlertRate(value), basis, formatAlertRate(rule.Threshold))))
+	}
+	return candidates
+}
+
+func (e *AlertEngine) changeAlerts(rule domain.AlertRule, current map[alertRateKey]domain.FundingRate) []alertCandidate {
+	basis := rule.RateBasis()
+	var candidates []alertCandidate
+	for key, rate := range current {
+		previous, ok := e.previous[key]
+		if !ok || !rule.Matches(rate) {
+			continue
+		}
+		value, before := rate.RateForBasis(basis), previous.RateForBasis(basis)
+		if math.Abs(value-before) <= rule.Threshold {
+			continue
+		}
+		candidates = append(candidates, newAlertCandidate(rule, key, rate.MarginType, value, before,
+			fmt.Sprintf("%s funding on %s moved from %s to %s (%s), more than %s", key.symbol, key.exchange, formatAlertRate(before), formatAlertRate(value), basis, formatAlertRate(rule.Threshold))))
+	}
+	return candidates
+}
+
+func (e *AlertEngine) flipAlerts(rule domain.AlertRule, current map[alertRateKey]domain.FundingRate) []alertCandidate {
+	basis := rule.RateBasis()
+	var candidates []alertCandidate
+	for key, rate := range current {
+		previous, ok := e.previous[key]
+		if !ok || !rule.Matches(rate) {
+			continue
+		}
+		value, before := rate.RateForBasis(basis), previous.RateForBasis(basis)
+		if value*before >= 0 || math.Abs(value) < rule.Threshold {
+			continue
+		}
+		candidates = append(candidates, newAlertCandidate(rule, key, rate.MarginType, value, before,
+			fmt.Sprintf("%s funding on %s flipped from %s to %s (%s)", key.symbol, key.exchange, formatAlertRate(before), formatAlertRate(value), basis)))
+	}
+	return candidates
+}
+
+// spreadAlerts raises one alert per market, for its lowest and highest rates
+func (e *AlertEngine) spreadAlerts(rule domain.AlertRule, current map[alertRateKey]domain.FundingRate) []alertCandidate {
+	basis := rule.RateBasis()
+	low := make(map[string]domain.FundingRate)
+	high := make(map[string]domain.FundingRate)
+	for key, rate := range current {
+		if !rule.Matches(rate) {
+			continue
+		}
+		if lowest, ok := low[key.symbol]; !ok || rate.RateForBasis(basis) < lowest.RateForBasis(basis) {
+			low[key.symbol] = rate
+		}
+		if highest, ok := high[key.symbol]; !ok || rate.RateForBasis(basis) > highest.RateForBasis(basis) {
+			high[key.symbol] = rate
+		}
+	}
+
+	var candidates []alertCandidate
+	for symbol, lowest := range low {
+		highest := high[symbol]
+		spread := highest.RateForBasis(basis) - lowest.RateForBasis(basis)
+		if lowest.Exchange == highest.Exchange || spread <= rule.Threshold {
+			continue
+		}
+		var marginType domain.MarginType
+		if lowest.MarginType == highest.MarginType {
+			marginType = lowest.MarginType
+		}
+		candidates = append(candidates, alertCandidate{
+			key:      rule.Name + "|" + symbol,
+			cooldown: rule.CooldownDuration(),
+			alert: domain.Alert{
+				Rule:      rule.Name,
+				Type:      rule.Type,
+				Symbol:    symbol,
+				Exchanges: []string{lowest.Exchange, highest.Exchange},
+				Value:     spread,
+				Threshold: rule.Threshold,
+				Basis:     basis,
+				Message: fmt.Sprintf("%s funding spread between %s and %s is %s (%s), beyond %s",
+					symbol, lowest.Exchange, highest.Exchange, formatAlertRate(spread), basis, formatAlertRate(rule.Threshold)),
+
+				MarginType: marginType,
+			},
+		})
+	}
+	return candidates
+}
+
+func newAlertCandidate(rule domain.AlertRule, key alertRateKey, marginType domain.MarginType, value, previous float64, message string) alertCandidate {
+	return alertCandidate{
+		key:      rule.Name + "|" + key.symbol + "|" + key.exchange,
+		cooldown: rule.CooldownDuration(),
+		alert: domain.Alert{
+			Rule:      rule.Name,
+			Type:      rule.Type,
+			Symbol:    key.symbol,
+			Exchanges: []string{key.exchange},
+			Value:     value,
+			Previous:  previous,
+			Threshold: rule.Threshold,
+			Basis:     rule.RateBasis(),
+			Message:   message,
+
+			MarginType: marginType,
+		},
+	}
+}
+
+// formatAlertRate renders a rate as a percentage
+func formatAlertRate(rate float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.4f", rate*100), "0"), ".") + "%"
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockAlertNotifier records the alerts it was notified with
+type MockAlertNotifier struct {
+	alerts []domain.Alert
+	err    error
+}
+
+func (m *MockAlertNotifier) Notify(alert domain.Alert) error {
+	m.alerts = append(m.alerts, alert)
+	return m.err
+}
+
+// newTestAlertEngine creates an engine with a notifier and a clock set by the returned func
+func newTestAlertEngine(t *testing.T, rules ...domain.AlertRule) (*AlertEngine, *MockAlertNotifier, func(time.Duration)) {
+	engine, err := NewAlertEngine(domain.AlertsConfig{Rules: rules}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	notifier := &MockAlertNotifier{}
+	engine.AddNotifier(notifier)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+	return engine, notifier, func(d time.Duration) { now = now.Add(d) }
+}
+
+func alertSnapshot(rates ...domain.FundingRate) domain.FundingSnapshot {
+	return domain.FundingSnapshot{Rates: rates}
+}
+
+func TestAlertEngine_Threshold(t *testing.T) {
+	engine, notifier, advance := newTestAlertEngine(t, domain.AlertRule{
+		Name:      "extreme",
+		Type:      domain.AlertThreshold,
+		Exchanges: []string{"binance", "okx"},
+		Threshold: 0.001,
+		Cooldown:  600,
+	})
+
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.002},
+		domain.FundingRate{Exchange: "okx", Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: -0.003},
+		domain.FundingRate{Exchange: "okx", Symbol: "ETH-USDT-SWAP", CanonicalSymbol: "ETHUSDT", FundingRate: 0.0005},
+		domain.FundingRate{Exchange: "bybit", Symbol: "BTCUSDT", FundingRate: 0.01},
+	))
+
+	if len(notifier.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %+v", notifier.alerts)
+	}
+	first, second := notifier.alerts[0], notifier.alerts[1]
+	if first.ID != 1 || first.Exchanges[0] != "binance" || first.Value != 0.002 {
+		t.Errorf("Expected the binance alert first, got %+v", first)
+	}
+	if second.ID != 2 || second.Symbol != "BTCUSDT" || second.Exchanges[0] != "okx" || second.Value != -0.003 {
+		t.Errorf("Expected the okx alert on the canonical symbol, got %+v", second)
+	}
+	if second.Message != "BTCUSDT funding on okx is -0.3% (raw), beyond 0.1%" {
+		t.Errorf("Unexpected message: %s", second.Message)
+	}
+
+	// A breach that persists is not raised again
+	advance(time.Hour)
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.002},
+	))
+	if len(notifier.alerts) != 2 {
+		t.Errorf("Expected no new alert while the breach persists, got %d", len(notifier.alerts))
+	}
+
+	// It fires again once it has cleared and the cooldown has elapsed
+	advance(time.Minute)
+	engine.OnFundingSnapshot(alertSnapshot())
+	advance(time.Minute)
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "okx", Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.002},
+	))
+	if len(notifier.alerts) != 3 || notifier.alerts[2].ID != 3 {
+		t.Errorf("Expected the okx alert to fire again, got %+v", notifier.alerts)
+	}
+}
+
+func TestAlertEngine_Cooldown(t *testing.T) {
+	engine, notifier, advance := newTestAlertEngine(t, domain.AlertRule{
+		Name:      "extreme",
+		Type:      domain.AlertThreshold,
+		Threshold: 0.001,
+		Cooldown:  600,
+	})
+
+	breach := alertSnapshot(domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.002})
+	calm := alertSnapshot(domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0001})
+
+	engine.OnFundingSnapshot(breach)
+	advance(time.Minute)
+	engine.OnFundingSnapshot(calm)
+	advance(time.Minute)
+	engine.OnFundingSnapshot(breach)
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("Expected the flapping breach to stay quiet within the cooldown, got %d alerts", len(notifier.alerts))
+	}
+
+	advance(10 * time.Minute)
+	engine.OnFundingSnapshot(calm)
+	engine.OnFundingSnapshot(breach)
+	if len(notifier.alerts) != 2 {
+		t.Errorf("Expected a second alert after the cooldown, got %d", len(notifier.alerts))
+	}
+}
+
+func TestAlertEngine_Change(t *testing.T) {
+	engine, notifier, advance := newTestAlertEngine(t, domain.AlertRule{
+		Name:      "jump",
+		Type:      domain.AlertChange,
+		Symbols:   []string{"btcusdt"},
+		Threshold: 0.001,
+		Basis:     domain.RateBasis8h,
+	})
+
+	// 1h venue: 0.0001 per hour is 0.0008 per 8h
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0001, FundingIntervalHours: 1},
+		domain.FundingRate{Exchange: "binance", Symbol: "ETHUSDT", FundingRate: 0.0001, FundingIntervalHours: 1},
+	))
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("Expected no alert without a previous snapshot, got %+v", notifier.alerts)
+	}
+
+	advance(time.Minute)
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0003, FundingIntervalHours: 1},
+		domain.FundingRate{Exchange: "binance", Symbol: "ETHUSDT", FundingRate: 0.0003, FundingIntervalHours: 1},
+	))
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %+v", notifier.alerts)
+	}
+
+	alert := notifier.alerts[0]
+	if alert.Symbol != "BTCUSDT" || alert.Basis != domain.RateBasis8h {
+		t.Errorf("Expected a BTCUSDT alert on the 8h basis, got %+v", alert)
+	}
+	if math.Abs(alert.Previous-0.0008) > 1e-12 || math.Abs(alert.Value-0.0024) > 1e-12 {
+		t.Errorf("Expected a move from 0.0008 to 0.0024, got %v to %v", alert.Previous, alert.Value)
+	}
+}
+
+func TestAlertEngine_Flip(t *testing.T) {
+	engine, notifier, advance := newTestAlertEngine(t, domain.AlertRule{
+		Name:      "flip",
+		Type:      domain.AlertFlip,
+		Threshold: 0.0001,
+	})
+
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0002},
+		domain.FundingRate{Exchange: "bybit", Symbol: "BTCUSDT", FundingRate: 0.0002},
+		domain.FundingRate{Exchange: "okx", Symbol: "BTCUSDT", FundingRate: 0.0002},
+	))
+	advance(time.Minute)
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: -0.0002},
+		domain.FundingRate{Exchange: "bybit", Symbol: "BTCUSDT", FundingRate: -0.00005}, // below the threshold
+		domain.FundingRate{Exchange: "okx", Symbol: "BTCUSDT", FundingRate: 0},          // zero is no sign
+	))
+
+	if len(notifier.alerts) != 1 || notifier.alerts[0].Exchanges[0] != "binance" {
+		t.Fatalf("Expected a single binance flip, got %+v", notifier.alerts)
+	}
+	if notifier.alerts[0].Previous != 0.0002 || notifier.alerts[0].Value != -0.0002 {
+		t.Errorf("Expected a flip from 0.0002 to -0.0002, got %+v", notifier.alerts[0])
+	}
+}
+
+func TestAlertEngine_Spread(t *testing.T) {
+	engine, notifier, _ := newTestAlertEngine(t, domain.AlertRule{
+		Name:      "spread",
+		Type:      domain.AlertSpread,
+		Threshold: 0.0005,
+	})
+
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0001},
+		domain.FundingRate{Exchange: "okx", Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0002},
+		// 4h venue: 0.0004 per 4h is 0.0008 per 8h
+		domain.FundingRate{Exchange: "bybit", Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0004, FundingIntervalHours: 4},
+		domain.FundingRate{Exchange: "binance", Symbol: "ETHUSDT", CanonicalSymbol: "ETHUSDT", FundingRate: 0.0001},
+		domain.FundingRate{Exchange: "okx", Symbol: "ETH-USDT-SWAP", CanonicalSymbol: "ETHUSDT", FundingRate: 0.0003},
+		domain.FundingRate{Exchange: "binance", Symbol: "SOLUSDT", CanonicalSymbol: "SOLUSDT", FundingRate: 0.01},
+	))
+
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %+v", notifier.alerts)
+	}
+	alert := notifier.alerts[0]
+	if alert.Symbol != "BTCUSDT" || alert.Exchanges[0] != "binance" || alert.Exchanges[1] != "bybit" {
+		t.Errorf("Expected a BTCUSDT spread from binance to bybit, got %+v", alert)
+	}
+	if alert.Basis != domain.RateBasis8h || math.Abs(alert.Value-0.0007) > 1e-12 {
+		t.Errorf("Expected a 0.0007 spread on the 8h basis, got %v on %s", alert.Value, alert.Basis)
+	}
+}
+
+func TestAlertEngine_GetAlerts(t *testing.T) {
+	engine, _, advance := newTestAlertEngine(t, domain.AlertRule{
+		Name:      "extreme",
+		Type:      domain.AlertThreshold,
+		Threshold: 0.001,
+	})
+	engine.historySize = 3
+
+	start := engine.now()
+	for _, exchange := range []string{"binance", "bybit", "okx", "gate"} {
+		engine.OnFundingSnapshot(alertSnapshot(domain.FundingRate{Exchange: exchange, Symbol: "BTCUSDT", FundingRate: 0.002}))
+		advance(time.Minute)
+	}
+
+	alerts := engine.GetAlerts(domain.AlertFilter{})
+	if len(alerts) != 3 || alerts[0].Exchanges[0] != "gate" || alerts[2].Exchanges[0] != "bybit" {
+		t.Fatalf("Expected the 3 newest alerts, newest first, got %+v", alerts)
+	}
+
+	tests := []struct {
+		name     string
+		filter   domain.AlertFilter
+		expected int
+	}{
+		{"rule", domain.AlertFilter{Rule: "other"}, 0},
+		{"symbol", domain.AlertFilter{Symbol: "BTCUSDT"}, 3},
+		{"exchange", domain.AlertFilter{Exchange: "okx"}, 1},
+		{"since", domain.AlertFilter{Since: start.Add(2 * time.Minute)}, 2},
+		{"limit", domain.AlertFilter{Limit: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if alerts := engine.GetAlerts(tt.filter); len(alerts) != tt.expected {
+				t.Errorf("Expected %d alerts, got %d", tt.expected, len(alerts))
+			}
+		})
+	}
+}
+
+func TestAlertEngine_MarginType(t *testing.T) {
+	engine, notifier, _ := newTestAlertEngine(t, domain.AlertRule{
+		Name:       "inverse",
+		Type:       domain.AlertThreshold,
+		Threshold:  0.001,
+		MarginType: domain.MarginTypeInverse,
+	})
+
+	engine.OnFundingSnapshot(alertSnapshot(
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.002, MarginType: domain.MarginTypeLinear},
+		domain.FundingRate{Exchange: "binance", Symbol: "BTCUSD_PERP", CanonicalSymbol: "BTCUSD", FundingRate: 0.002, MarginType: domain.MarginTypeInverse},
+	))
+
+	if len(notifier.alerts) != 1 || notifier.alerts[0].Symbol != "BTCUSD" || notifier.alerts[0].MarginType != domain.MarginTypeInverse {
+		t.Fatalf("Expected only the inverse alert, got %+v", notifier.alerts)
+	}
+	if alerts := engine.GetAlerts(domain.AlertFilter{MarginType: domain.MarginTypeLinear}); len(alerts) != 0 {
+		t.Errorf("Expected no linear alerts, got %+v", alerts)
+	}
+	if alerts := engine.GetAlerts(domain.AlertFilter{MarginType: domain.MarginTypeInverse}); len(alerts) != 1 {
+		t.Errorf("Expected 1 inverse alert, got %+v", alerts)
+	}
+}
+
+func TestAlertEngine_NotifierError(t *testing.T) {
+	engine, notifier, _ := newTestAlertEngine(t, domain.AlertRule{Name: "extreme", Type: domain.AlertThreshold, Threshold: 0.001})
+	notifier.err = errors.New("unreachable")
+	other := &MockAlertNotifier{}
+	engine.AddNotifier(other)
+
+	engine.OnFundingSnapshot(alertSnapshot(domain.FundingRate{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.002}))
+
+	// A failing notifier doesn't keep the alert from the others or the history
+	if len(other.alerts) != 1 || len(engine.GetAlerts(domain.AlertFilter{})) != 1 {
+		t.Errorf("Expected the alert delivered and recorded, got %d and %d", len(other.alerts), len(engine.GetAlerts(domain.AlertFilter{})))
+	}
+}
+
+func TestNewAlertEngine_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []domain.AlertRule
+	}{
+		{"no name", []domain.AlertRule{{Type: domain.AlertThreshold}}},
+		{"unknown type", []domain.AlertRule{{Name: "a", Type: "volume"}}},
+		{"negative threshold", []domain.AlertRule{{Name: "a", Type: domain.AlertChange, Threshold: -1}}},
+		{"unknown basis", []domain.AlertRule{{Name: "a", Type: domain.AlertFlip, Basis: "4h"}}},
+		{"unknown margin type", []domain.AlertRule{{Name: "a", Type: domain.AlertThreshold, MarginType: "cross"}}},
+		{"duplicate name", []domain.AlertRule{{Name: "a", Type: domain.AlertFlip}, {Name: "a", Type: domain.AlertSpread}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAlertEngine(domain.AlertsConfig{Rules: tt.rules}, logrus.New())
+			if !errors.Is(err, domain.ErrInvalidAlertRule) {
+				t.Errorf("Expected ErrInvalidAlertRule, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// ArbitrageUseCase finds the same market priced with diverging funding across exchanges
+type ArbitrageUseCase struct {
+	fundingUseCase domain.MultiExchangeUseCaseInterface
+	now            func() time.Time
+}
+
+// NewArbitrageUseCase creates a new arbitrage use case
+func NewArbitrageUseCase(fundingUseCase domain.MultiExchangeUseCaseInterface) *ArbitrageUseCase {
+	return &ArbitrageUseCase{
+		fundingUseCase: fundingUseCase,
+		now:            time.Now,
+	}
+}
+
+// GetOpportunities pairs every market listed on two or more exchanges and
+// returns the resulting opportunities ranked by spread, widest first
+func (a *ArbitrageUseCase) GetOpportunities(ctx context.Context, filter domain.ArbitrageFilter) ([]domain.ArbitrageOpportunity, error) {
+	rates, err := a.fundingUseCase.GetAllFundingRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(filter.Exchanges))
+	for _, exchange := range filter.Exchanges {
+		allowed[exchange] = true
+	}
+
+	// Group rates by market, keeping one rate per exchange
+	markets := make(map[string][]domain.FundingRate)
+	for _, rate := range rates {
+		if len(allowed) > 0 && !allowed[rate.Exchange] {
+			continue
+		}
+		if !rate.MatchesMarginType(filter.MarginType) {
+			continue
+		}
+		symbol := rate.MarketSymbol()
+		if filter.Symbol != "" && symbol != filter.Symbol {
+			continue
+		}
+		if listedOn(markets[symbol], rate.Exchange) {
+			continue
+		}
+		markets[symbol] = append(markets[symbol], rate)
+	}
+
+	now := a.now()
+	opportunities := make([]domain.ArbitrageOpportunity, 0)
+	for symbol, listings := range markets {
+		for i := 0; i < len(listings); i++ {
+			for j := i + 1; j < len(listings); j++ {
+				// Compare per hour so 1h, 4h and 8h venues line up
+				long, short := listings[i], listings[j]
+				if long.HourlyRate() > short.HourlyRate() {
+					long, short = short, long
+				}
+
+				spread := short.EightHourRate() - long.EightHourRate()
+				if spread < filter.MinSpread {
+					continue
+				}
+
+				opportunities = append(opportunities, domain.ArbitrageOpportunity{
+					Symbol:          symbol,
+					Long:            newArbitrageLeg(long, now),
+					Short:           newArbitrageLeg(short, now),
+					Spread:          spread,
+					AnnualizedYield: short.AnnualizedRate() - long.AnnualizedRate(),
+				})
+			}
+		}
+	}
+
+	sort.Slice(opportunities, func(i, j int) bool {
+		if opportunities[i].Spread != opportunities[j].Spread {
+			return opportunities[i].Spread > opportunities[j].Spread
+		}
+		return opportunities[i].Symbol < opportunities[j].Symbol
+	})
+
+	if filter.Limit > 0 && len(opportunities) > filter.Limit {
+		opportunities = opportunities[:filter.Limit]
+	}
+
+	return opportunities, nil
+}
+
+func listedOn(rates []domain.FundingRate, exchange string) bool {
+	for _, rate := range rates {
+		if rate.Exchange == exchange {
+			return true
+		}
+	}
+	return false
+}
+
+func newArbitrageLeg(rate domain.FundingRate, now time.Time) domain.ArbitrageLeg {
+	var timeToFunding int64
+	if !rate.NextFundingTime.IsZero() && rate.NextFundingTime.After(now) {
+		timeToFunding = int64(rate.NextFundingTime.Sub(now).Seconds())
+	}
+
+	return domain.ArbitrageLeg{
+		Exchange:             rate.Exchange,
+		Symbol:               rate.Symbol,
+		FundingRate:          rate.FundingRate,
+		FundingIntervalHours: rate.IntervalHours(),
+		NextFundingTime:      rate.NextFundingTime,
+		TimeToFunding:        timeToFunding,
+		MarkPrice:            rate.MarkPrice,
+		MarginType:           rate.MarginType,
+	}
+}
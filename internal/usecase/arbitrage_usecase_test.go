@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"fundingmonitor/internal/domain"
+	"math"
+	"testing"
+	"time"
+)
+
+func newArbitrageTestUseCase(now time.Time) *ArbitrageUseCase {
+	nextFunding := now.Add(2 * time.Hour)
+
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name: "binance",
+			rates: []domain.FundingRate{
+				{Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0001, NextFundingTime: nextFunding},
+				{Symbol: "ETHUSDT", CanonicalSymbol: "ETHUSDT", FundingRate: 0.0002, NextFundingTime: nextFunding},
+				{Symbol: "SOLUSDT", CanonicalSymbol: "SOLUSDT", FundingRate: 0.0005},
+			},
+		},
+		"okx": &MockExchangeRepository{
+			name: "okx",
+			rates: []domain.FundingRate{
+				{Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0004, NextFundingTime: now.Add(30 * time.Minute)},
+				{Symbol: "ETH-USDT-SWAP", CanonicalSymbol: "ETHUSDT", FundingRate: -0.0001, NextFundingTime: now.Add(-time.Minute)},
+			},
+		},
+		"bybit": &MockExchangeRepository{
+			name: "bybit",
+			rates: []domain.FundingRate{
+				{Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0002, NextFundingTime: nextFunding},
+			},
+		},
+	}
+
+	useCase := NewArbitrageUseCase(NewMultiExchangeUseCase(exchanges, &MockLogRepository{}))
+	useCase.now = func() time.Time { return now }
+	return useCase
+}
+
+func TestArbitrageUseCase_GetOpportunities(t *testing.T) {
+	now := time.Now()
+	useCase := newArbitrageTestUseCase(now)
+
+	opportunities, err := useCase.GetOpportunities(context.Background(), domain.ArbitrageFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// BTC: 3 venues -> 3 pairs, ETH: 2 venues -> 1 pair, SOL: single venue
+	if len(opportunities) != 4 {
+		t.Fatalf("Expected 4 opportunities, got %d", len(opportunities))
+	}
+
+	best := opportunities[0]
+	if best.Symbol != "BTCUSDT" || best.Long.Exchange != "binance" || best.Short.Exchange != "okx" {
+		t.Errorf("Expected long binance / short okx on BTCUSDT first, got %+v", best)
+	}
+	if math.Abs(best.Spread-0.0003) > 1e-12 {
+		t.Errorf("Expected spread 0.0003, got %v", best.Spread)
+	}
+	if math.Abs(best.AnnualizedYield-0.0003*3*365) > 1e-9 {
+		t.Errorf("Expected annualized yield %v, got %v", 0.0003*3*365, best.AnnualizedYield)
+	}
+	if best.Short.TimeToFunding != int64((30 * time.Minute).Seconds()) {
+		t.Errorf("Expected 1800s to short leg funding, got %d", best.Short.TimeToFunding)
+	}
+
+	for i := 1; i < len(opportunities); i++ {
+		if opportunities[i].Spread > opportunities[i-1].Spread {
+			t.Errorf("Expected opportunities sorted by spread, got %v before %v", opportunities[i-1].Spread, opportunities[i].Spread)
+		}
+	}
+
+	// Past settlement times don't produce negative countdowns
+	for _, opportunity := range opportunities {
+		if opportunity.Symbol == "ETHUSDT" && opportunity.Long.TimeToFunding != 0 {
+			t.Errorf("Expected 0s for elapsed settlement, got %d", opportunity.Long.TimeToFunding)
+		}
+	}
+}
+
+func TestArbitrageUseCase_GetOpportunitiesFiltered(t *testing.T) {
+	useCase := newArbitrageTestUseCase(time.Now())
+
+	tests := []struct {
+		name          string
+		filter        domain.ArbitrageFilter
+		expectedCount int
+	}{
+		{"Min spread", domain.ArbitrageFilter{MinSpread: 0.00025}, 2},
+		{"Exchanges", domain.ArbitrageFilter{Exchanges: []string{"binance", "bybit"}}, 1},
+		{"Symbol", domain.ArbitrageFilter{Symbol: "ETHUSDT"}, 1},
+		{"Limit", domain.ArbitrageFilter{Limit: 2}, 2},
+		{"Single exchange", domain.ArbitrageFilter{Exchanges: []string{"okx"}}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opportunities, err := useCase.GetOpportunities(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(opportunities) != tc.expectedCount {
+				t.Errorf("Expected %d opportunities, got %d", tc.expectedCount, len(opportunities))
+			}
+		})
+	}
+}
+
+func TestArbitrageUseCase_MarginType(t *testing.T) {
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name: "binance",
+			rates: []domain.FundingRate{
+				{Symbol: "BTCUSDT", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0001, MarginType: domain.MarginTypeLinear},
+				{Symbol: "BTCUSD_PERP", CanonicalSymbol: "BTCUSD", FundingRate: 0.0001, MarginType: domain.MarginTypeInverse},
+			},
+		},
+		"okx": &MockExchangeRepository{
+			name: "okx",
+			rates: []domain.FundingRate{
+				{Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0002, MarginType: domain.MarginTypeLinear},
+				{Symbol: "BTC-USD-SWAP", CanonicalSymbol: "BTCUSD", FundingRate: 0.0004, MarginType: domain.MarginTypeInverse},
+			},
+		},
+	}
+	useCase := NewArbitrageUseCase(NewMultiExchangeUseCase(exchanges, &MockLogRepository{}))
+
+	opportunities, err := useCase.GetOpportunities(context.Background(), domain.ArbitrageFilter{MarginType: domain.MarginTypeInverse})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(opportunities) != 1 || opportunities[0].Symbol != "BTCUSD" {
+		t.Fatalf("Expected only the inverse BTCUSD opportunity, got %+v", opportunities)
+	}
+	if legs := opportunities[0]; legs.Long.MarginType != domain.MarginTypeInverse || legs.Short.MarginType != domain.MarginTypeInverse {
+		t.Errorf("Expected inverse legs, got %s and %s", legs.Long.MarginType, legs.Short.MarginType)
+	}
+}
+
+func TestArbitrageUseCase_MixedFundingIntervals(t *testing.T) {
+	// 0.0002 per 1h beats 0.0010 per 8h once normalized
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "ORDIUSDT", CanonicalSymbol: "ORDIUSDT", FundingRate: 0.0010, FundingIntervalHours: 8}},
+		},
+		"bybit": &MockExchangeRepository{
+			name:  "bybit",
+			rates: []domain.FundingRate{{Symbol: "ORDIUSDT", CanonicalSymbol: "ORDIUSDT", FundingRate: 0.0002, FundingIntervalHours: 1}},
+		},
+	}
+
+	useCase := NewArbitrageUseCase(NewMultiExchangeUseCase(exchanges, &MockLogRepository{}))
+	opportunities, err := useCase.GetOpportunities(context.Background(), domain.ArbitrageFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(opportunities) != 1 {
+		t.Fatalf("Expected 1 opportunity, got %d", len(opportunities))
+	}
+
+	opportunity := opportunities[0]
+	if opportunity.Short.Exchange != "bybit" || opportunity.Long.Exchange != "binance" {
+		t.Errorf("Expected short bybit / long binance, got %+v", opportunity)
+	}
+	if math.Abs(opportunity.Spread-0.0006) > 1e-12 {
+		t.Errorf("Expected 8h spread 0.0006, got %v", opportunity.Spread)
+	}
+	if opportunity.Short.FundingIntervalHours != 1 {
+		t.Errorf("Expected short leg interval 1h, got %d", opportunity.Short.FundingIntervalHours)
+	}
+}
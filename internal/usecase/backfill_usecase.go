@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBackfillRange is backfilled when a request has no start
+const DefaultBackfillRange = 30 * 24 * time.Hour
+
+// DefaultBackfillWindow is the range of a single funding history read; each
+// window is stored before the next is read, so an interrupted backfill keeps
+// its progress and a repeated one only rewrites what it already stored
+const DefaultBackfillWindow = 7 * 24 * time.Hour
+
+// BackfillUseCase stores the funding rates the exchanges settled before
+// logging started, read from their funding history APIs and written through
+// the log repository at their settlement times
+type BackfillUseCase struct {
+	exchanges      map[string]domain.ExchangeRepository
+	fundingUseCase domain.MultiExchangeUseCaseInterface
+	logRepo        domain.LogRepository
+	logger         *logrus.Logger
+	now            func() time.Time
+
+	window  time.Duration
+	timeout time.Duration
+
+	requests chan domain.BackfillRequest
+
+	mu       sync.Mutex
+	progress *domain.BackfillProgress
+}
+
+// NewBackfillUseCase creates a backfill use case reading the history of the
+// exchanges, resolving symbols among the markets the funding use case lists
+func NewBackfillUseCase(exchanges map[string]domain.ExchangeRepository, fundingUseCase domain.MultiExchangeUseCaseInterface, logRepo domain.LogRepository, logger *logrus.Logger) *BackfillUseCase {
+	return &BackfillUseCase{
+		exchanges:      exchanges,
+		fundingUseCase: fundingUseCase,
+		logRepo:        logRepo,
+		logger:         logger,
+		now:            time.Now,
+		window:         DefaultBackfillWindow,
+		timeout:        DefaultSettlementTimeout,
+		requests:       make(chan domain.BackfillRequest, 1),
+	}
+}
+
+// Run serves the backfills started with StartBackfill, one at a time, until
+// the context is cancelled
+func (b *BackfillUseCase) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request := <-b.requests:
+			progress, err := b.Backfill(ctx, request, b.record)
+			if err != nil && ctx.Err() == nil {
+				b.record(domain.BackfillProgress{
+					Status:     domain.BackfillStatusFailed,
+					Request:    request,
+					StartedAt:  progress.StartedAt,
+					FinishedAt: b.now(),
+					Error:      err.Error(),
+				})
+			}
+		}
+	}
+}
+
+// StartBackfill checks a request and queues it for Run, failing with
+// ErrBackfillRunning while another backfill runs
+func (b *BackfillUseCase) StartBackfill(request domain.BackfillRequest) (domain.BackfillProgress, error) {
+	request, _, err := b.prepare(request)
+	if err != nil {
+		return domain.BackfillProgress{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.progress != nil && b.progress.Status == domain.BackfillStatusRunning {
+		return domain.BackfillProgress{}, domain.ErrBackfillRunning
+	}
+
+	progress := domain.BackfillProgress{
+		Status:    domain.BackfillStatusRunning,
+		Request:   request,
+		StartedAt: b.now(),
+	}
+	b.progress = &progress
+	b.requests <- request
+	return progress, nil
+}
+
+// GetBackfillProgress returns the progress of the running backfill, or of the
+// last one, and false before any was started
+func (b *BackfillUseCase) GetBackfillProgress() (domain.BackfillProgress, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.progress == nil {
+		return domain.BackfillProgress{}, false
+	}
+	return *b.progress, true
+}
+
+// record keeps the progress reported by a backfill run for GetBackfillProgress
+func (b *BackfillUseCase) record(progress domain.BackfillProgress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.progress = &progress
+}
+
+// Backfill stores the settlements a request selects and returns the final
+// progress, reported to observe after every window stored. The exchanges are
+// read concurrently and the markets of each in turn, as they share its rate
+// limit. A market failing is reported in its task while the others go on; an
+// error is only returned for an invalid request or once ctx ends.
+func (b *BackfillUseCase) Backfill(ctx context.Context, request domain.BackfillRequest, observe func(domain.BackfillProgress)) (domain.BackfillProgress, error) {
+	request, histories, err := b.prepare(request)
+	if err != nil {
+		return domain.BackfillProgress{}, err
+	}
+
+	run := &backfillRun{
+		progress: domain.BackfillProgress{
+			Status:    domain.BackfillStatusRunning,
+			Request:   request,
+			StartedAt: b.now(),
+		},
+		observe: observe,
+	}
+	tasks, unmatched := b.planTasks(ctx, request)
+	run.update(func(progress *domain.BackfillProgress) {
+		progress.Tasks = append([]domain.BackfillTask(nil), tasks...)
+		progress.Unmatched = unmatched
+	})
+
+	var wg sync.WaitGroup
+	for _, name := range request.Exchanges {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for i, task := range tasks {
+				if task.Exchange == name && !task.Done() && ctx.Err() == nil {
+					b.runTask(ctx, histories[name], request, run, i)
+				}
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	progress := run.update(func(progress *domain.BackfillProgress) {
+		progress.FinishedAt = b.now()
+		failed := 0
+		for _, task := range progress.Tasks {
+			if task.Error != "" {
+				failed++
+			}
+		}
+		switch {
+		case ctx.Err() != nil:
+			progress.Status = domain.BackfillStatusFailed
+			progress.Error = ctx.Err().Error()
+		case failed > 0:
+			progress.Status = domain.BackfillStatusFailed
+			progress.Error = fmt.Sprintf("%d of %d markets failed", failed, len(progress.Tasks))
+		default:
+			progress.Status = domain.BackfillStatusDone
+		}
+	})
+	return progress, ctx.Err()
+}
+
+// prepare applies the request defaults, every exchange serving funding
+// history and the DefaultBackfillRange up to now, validates it and returns
+// the history of every exchange selected
+func (b *BackfillUseCase) prepare(request domain.BackfillRequest) (domain.BackfillRequest, map[string]domain.FundingHistoryRepository, error) {
+	if request.To.IsZero() {
+		request.To = b.now()
+	}
+	if request.From.IsZero() {
+		request.From = request.To.Add(-DefaultBackfillRange)
+	}
+	if err := request.Validate(); err != nil {
+		return request, nil, err
+	}
+
+	histories := make(map[string]domain.FundingHistoryRepository)
+	if len(request.Exchanges) == 0 {
+		for name, exchange := range b.exchanges {
+			if history, ok := exchange.(domain.FundingHistoryRepository); ok {
+				histories[name] = history
+			}
+		}
+	}
+	for _, name := range request.Exchanges {
+		exchange, exists := b.exchanges[name]
+		if !exists {
+			return request, nil, fmt.Errorf("%s: %w", name, domain.ErrExchangeNotFound)
+		}
+		history, ok := exchange.(domain.FundingHistoryRepository)
+		if !ok {
+			return request, nil, fmt.Errorf("%s: %w", name, domain.ErrHistoryUnsupported)
+		}
+		histories[name] = history
+	}
+
+	request.Exchanges = make([]string, 0, len(histories))
+	for name := range histories {
+		request.Exchanges = append(request.Exchanges, name)
+	}
+	sort.Strings(request.Exchanges)
+	return request, histories, nil
+}
+
+// planTasks resolves the requested symbols among the markets every exchange
+// lists, matching venue or canonical symbols regardless of case. A market is
+// logged under its canonical symbol, as the polled rates are. When an
+// exchange can't list its markets, each symbol gets a failed task there.
+func (b *BackfillUseCase) planTasks(ctx context.Context, request domain.BackfillRequest) ([]domain.BackfillTask, []string) {
+	listings := make([][]domain.FundingRate, len(request.Exchanges))
+	errs := make([]error, len(request.Exchanges))
+	var wg sync.WaitGroup
+	for i, name := range request.Exchanges {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			listings[i], errs[i] = b.fundingUseCase.GetExchangeFundingRates(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	windows := int((request.To.Sub(request.From) + b.window - 1) / b.window)
+	matched := make(map[string]bool)
+	var tasks []domain.BackfillTask
+	for i, name := range request.Exchanges {
+		if errs[i] != nil {
+			b.logger.Warnf("Failed to list the markets of %s for backfill: %v", name, errs[i])
+			for _, symbol := range request.Symbols {
+				tasks = append(tasks, domain.BackfillTask{
+					Exchange: name,
+					Symbol:   symbol,
+					Windows:  windows,
+					Error:    fmt.Sprintf("failed to list markets: %v", errs[i]),
+				})
+				matched[symbol] = true
+			}
+			continue
+		}
+
+		planned := make(map[string]bool)
+		for _, symbol := range request.Symbols {
+			for _, rate := range listings[i] {
+				if !strings.EqualFold(rate.Symbol, symbol) && !strings.EqualFold(rate.CanonicalSymbol, symbol) {
+					continue
+				}
+				matched[symbol] = true
+				if planned[rate.Symbol] {
+					continue
+				}
+				planned[rate.Symbol] = true
+				tasks = append(tasks, domain.BackfillTask{
+					Exchange:  name,
+					Symbol:    rate.Symbol,
+					LogSymbol: rate.MarketSymbol(),
+					Windows:   windows,
+				})
+			}
+		}
+	}
+
+	var unmatched []string
+	for _, symbol := range request.Symbols {
+		if !matched[symbol] {
+			unmatched = append(unmatched, symbol)
+		}
+	}
+	return tasks, unmatched
+}
+
+// runTask stores the windows of a task oldest first, stopping at the first
+// that fails
+func (b *BackfillUseCase) runTask(ctx context.Context, history domain.FundingHistoryRepository, request domain.BackfillRequest, run *backfillRun, index int) {
+	task := run.task(index)
+	for start := request.From; start.Before(request.To); start = start.Add(b.window) {
+		end := start.Add(b.window)
+		if end.After(request.To) {
+			end = request.To
+		}
+
+		stored, err := b.backfillWindow(ctx, history, task, start, end)
+		if err != nil {
+			if ctx.Err() == nil {
+				b.logger.Warnf("Failed to backfill %s on %s: %v", task.Symbol, task.Exchange, err)
+			}
+			run.update(func(progress *domain.BackfillProgress) {
+				progress.Tasks[index].Error = err.Error()
+			})
+			return
+		}
+		run.update(func(progress *domain.BackfillProgress) {
+			progress.Tasks[index].Completed++
+			progress.Tasks[index].Settlements += stored
+			progress.Settlements += stored
+		})
+	}
+}
+
+// backfillWindow stores the settlements of a task within [start, end) and
+// returns how many there were
+func (b *BackfillUseCase) backfillWindow(ctx context.Context, history domain.FundingHistoryRepository, task domain.BackfillTask, start, end time.Time) (int, error) {
+	windowCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	settlements, err := history.GetFundingHistory(windowCtx, task.Symbol, start, end)
+	if err != nil {
+		if errors.Is(windowCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return 0, fmt.Errorf("%s: %w", task.Exchange, domain.ErrExchangeTimeout)
+		}
+		return 0, err
+	}
+	if len(settlements) == 0 {
+		return 0, nil
+	}
+
+	rates := make([]domain.FundingRate, len(settlements))
+	for i, settlement := range settlements {
+		rates[i] = domain.FundingRate{
+			Symbol:      task.Symbol,
+			Exchange:    task.Exchange,
+			FundingRate: settlement.FundingRate,
+			Timestamp:   settlement.FundingTime,
+		}
+	}
+	if err := b.logRepo.BackfillFundingRates(task.LogSymbol, rates); err != nil {
+		return 0, fmt.Errorf("failed to store settlements: %w", err)
+	}
+	return len(rates), nil
+}
+
+// backfillRun is the progress of a backfill shared by its exchange workers
+type backfillRun struct {
+	mu       sync.Mutex
+	progress domain.BackfillProgress
+	observe  func(domain.BackfillProgress)
+}
+
+// update applies change to the progress and reports, and returns, a copy of it
+func (r *backfillRun) update(change func(progress *domain.BackfillProgress)) domain.BackfillProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&r.progress)
+	report := r.progress
+	report.Tasks = append([]domain.BackfillTask(nil), r.progress.Tasks...)
+	if r.observe != nil {
+		r.observe(report)
+	}
+	return report
+}
+
+// task returns a copy of a task
+func (r *backfillRun) task(index int) domain.BackfillTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress.Tasks[index]
+}
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockSettlingExchange settles every 8h since the epoch at a rate of its
+// hour, recording the windows asked
+type MockSettlingExchange struct {
+	MockExchangeRepository
+	failing map[string]bool
+
+	mu      sync.Mutex
+	windows int
+}
+
+func (m *MockSettlingExchange) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	m.mu.Lock()
+	m.windows++
+	m.mu.Unlock()
+	if m.failing[symbol] {
+		return nil, errors.New("unavailable")
+	}
+
+	var settlements []domain.FundingSettlement
+	for at := start.Truncate(8 * time.Hour); at.Before(end); at = at.Add(8 * time.Hour) {
+		if !at.Before(start) {
+			settlements = append(settlements, domain.FundingSettlement{Exchange: m.name, Symbol: symbol, FundingRate: float64(at.Hour()), FundingTime: at})
+		}
+	}
+	return settlements, nil
+}
+
+// BackfillLogRepository stores backfilled rates keyed on exchange, symbol
+// and second
+type BackfillLogRepository struct {
+	MockLogRepository
+	mu     sync.Mutex
+	stored map[string]domain.FundingRate
+	err    error
+}
+
+func (r *BackfillLogRepository) BackfillFundingRates(symbol string, rates []domain.FundingRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	for _, rate := range rates {
+		r.stored[fmt.Sprintf("%s/%s/%d", rate.Exchange, symbol, rate.Timestamp.Unix())] = rate
+	}
+	return nil
+}
+
+func newBackfillTestUseCase(logRepo domain.LogRepository) (*BackfillUseCase, map[string]*MockSettlingExchange) {
+	btc := domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}
+	settling := map[string]*MockSettlingExchange{
+		"binance": {MockExchangeRepository: MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT"}, {Symbol: "ETHUSDT"}},
+		}},
+		"okx": {MockExchangeRepository: MockExchangeRepository{
+			name:  "okx",
+			rates: []domain.FundingRate{{Symbol: "BTC-USDT-SWAP"}},
+		}},
+	}
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": settling["binance"],
+		"okx":     settling["okx"],
+		"mexc":    &MockExchangeRepository{name: "mexc", rates: []domain.FundingRate{{Symbol: "BTC_USDT"}}},
+	}
+
+	fundingUseCase := NewMultiExchangeUseCase(exchanges, logRepo)
+	fundingUseCase.SetSymbolNormalizer(&MockSymbolNormalizer{instruments: map[string]domain.Instrument{
+		"binance:BTCUSDT":   btc,
+		"okx:BTC-USDT-SWAP": btc,
+	}})
+	return NewBackfillUseCase(exchanges, fundingUseCase, logRepo, logrus.New()), settling
+}
+
+func TestBackfillUseCase_Backfill(t *testing.T) {
+	logRepo := &BackfillLogRepository{stored: map[string]domain.FundingRate{}}
+	useCase, settling := newBackfillTestUseCase(logRepo)
+
+	// 20 days are read in windows of 7, 7 and 6 days
+	request := domain.BackfillRequest{
+		Symbols: []string{"btcusdt", "DOGEUSDT"},
+		From:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC),
+	}
+	var reports int
+	progress, err := useCase.Backfill(context.Background(), request, func(domain.BackfillProgress) { reports++ })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if progress.Status != domain.BackfillStatusDone || progress.FinishedAt.IsZero() {
+		t.Errorf("Expected a finished backfill, got %s", progress.Status)
+	}
+	// mexc serves no history and is left out
+	if len(progress.Request.Exchanges) != 2 || progress.Request.Exchanges[0] != "binance" || progress.Request.Exchanges[1] != "okx" {
+		t.Errorf("Expected binance and okx, got %v", progress.Request.Exchanges)
+	}
+	if len(progress.Unmatched) != 1 || progress.Unmatched[0] != "DOGEUSDT" {
+		t.Errorf("Expected DOGEUSDT unmatched, got %v", progress.Unmatched)
+	}
+
+	if len(progress.Tasks) != 2 {
+		t.Fatalf("Expected a task per exchange, got %+v", progress.Tasks)
+	}
+	for _, task := range progress.Tasks {
+		if task.LogSymbol != "BTCUSDT" || task.Windows != 3 || task.Completed != 3 || task.Settlements != 60 {
+			t.Errorf("Expected 60 settlements over 3 windows logged under BTCUSDT, got %+v", task)
+		}
+	}
+	if progress.Tasks[1].Symbol != "BTC-USDT-SWAP" {
+		t.Errorf("Expected the okx venue symbol, got %s", progress.Tasks[1].Symbol)
+	}
+	if progress.Settlements != 120 || len(logRepo.stored) != 120 {
+		t.Errorf("Expected 120 settlements stored, got %d and %d", progress.Settlements, len(logRepo.stored))
+	}
+	// The plan, every window and the outcome are reported
+	if reports != 8 {
+		t.Errorf("Expected 8 progress reports, got %d", reports)
+	}
+
+	stored := logRepo.stored[fmt.Sprintf("okx/BTCUSDT/%d", time.Date(2024, 1, 20, 16, 0, 0, 0, time.UTC).Unix())]
+	if stored.Symbol != "BTC-USDT-SWAP" || stored.FundingRate != 16 {
+		t.Errorf("Expected the okx 16:00 settlement, got %+v", stored)
+	}
+
+	// Backfilling again rewrites the same entries
+	if _, err := useCase.Backfill(context.Background(), request, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(logRepo.stored) != 120 || settling["okx"].windows != 6 {
+		t.Errorf("Expected the same 120 settlements over 6 okx windows, got %d over %d", len(logRepo.stored), settling["okx"].windows)
+	}
+}
+
+func TestBackfillUseCase_BackfillFailures(t *testing.T) {
+	logRepo := &BackfillLogRepository{stored: map[string]domain.FundingRate{}}
+	useCase, settling := newBackfillTestUseCase(logRepo)
+	settling["binance"].failing = map[string]bool{"BTCUSDT": true}
+
+	request := domain.BackfillRequest{
+		Exchanges: []string{"binance"},
+		Symbols:   []string{"BTCUSDT", "ETHUSDT"},
+		From:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+	progress, err := useCase.Backfill(context.Background(), request, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The failing market stops at its first window, the other goes on
+	if progress.Status != domain.BackfillStatusFailed || progress.Error != "1 of 2 markets failed" {
+		t.Errorf("Expected 1 of 2 markets failed, got %s: %s", progress.Status, progress.Error)
+	}
+	if progress.Tasks[0].Error == "" || progress.Tasks[0].Completed != 0 {
+		t.Errorf("Expected BTCUSDT to fail, got %+v", progress.Tasks[0])
+	}
+	if progress.Tasks[1].Completed != 2 || progress.Tasks[1].LogSymbol != "ETHUSDT" {
+		t.Errorf("Expected ETHUSDT stored under its venue symbol, got %+v", progress.Tasks[1])
+	}
+
+	// A store failing fails every market
+	logRepo.err = errors.New("disk full")
+	settling["binance"].failing = nil
+	progress, _ = useCase.Backfill(context.Background(), request, nil)
+	if progress.Error != "2 of 2 markets failed" {
+		t.Errorf("Expected 2 of 2 markets failed, got %s", progress.Error)
+	}
+
+	tests := []struct {
+		request  domain.BackfillRequest
+		expected error
+	}{
+		{domain.BackfillRequest{Symbols: []string{"BTCUSDT"}, Exchanges: []string{"bybit"}}, domain.ErrExchangeNotFound},
+		{domain.BackfillRequest{Symbols: []string{"BTCUSDT"}, Exchanges: []string{"mexc"}}, domain.ErrHistoryUnsupported},
+		{domain.BackfillRequest{}, domain.ErrInvalidBackfill},
+		{domain.BackfillRequest{Symbols: []string{"BTCUSDT"}, From: request.To, To: request.From}, domain.ErrInvalidBackfill},
+	}
+	for _, tt := range tests {
+		if _, err := useCase.Backfill(context.Background(), tt.request, nil); !errors.Is(err, tt.expected) {
+			t.Errorf("Expected %v for %+v, got %v", tt.expected, tt.request, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := useCase.Backfill(ctx, request, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBackfillUseCase_StartBackfill(t *testing.T) {
+	logRepo := &BackfillLogRepository{stored: map[string]domain.FundingRate{}}
+	useCase, _ := newBackfillTestUseCase(logRepo)
+	now := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	useCase.now = func() time.Time { return now }
+
+	if _, ok := useCase.GetBackfillProgress(); ok {
+		t.Error("Expected no progress before a backfill")
+	}
+
+	// Defaults to the last 30 days
+	progress, err := useCase.StartBackfill(domain.BackfillRequest{Symbols: []string{"BTCUSDT"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if progress.Status != domain.BackfillStatusRunning || !progress.Request.From.Equal(now.Add(-DefaultBackfillRange)) || !progress.Request.To.Equal(now) {
+		t.Errorf("Expected a running backfill of the last 30 days, got %+v", progress)
+	}
+	if _, err := useCase.StartBackfill(domain.BackfillRequest{Symbols: []string{"ETHUSDT"}}); !errors.Is(err, domain.ErrBackfillRunning) {
+		t.Errorf("Expected ErrBackfillRunning, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		useCase.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		progress, _ = useCase.GetBackfillProgress()
+		if progress.Status != domain.BackfillStatusRunning || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if progress.Status != domain.BackfillStatusDone || progress.Settlements != 180 {
+		t.Errorf("Expected 180 settlements stored, got %s with %d", progress.Status, progress.Settlements)
+	}
+
+	// Another backfill may start once done
+	if _, err := useCase.StartBackfill(domain.BackfillRequest{Symbols: []string{"ETHUSDT"}}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
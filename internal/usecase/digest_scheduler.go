@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DigestScheduler posts a digest of the most extreme funding rates to every
+// chat channel on the channel's own schedule
+type DigestScheduler struct {
+	fundingUseCase domain.MultiExchangeUseCaseInterface
+	logger         *logrus.Logger
+	channels       []digestChannel
+	now            func() time.Time
+}
+
+type digestChannel struct {
+	config   domain.DigestChannel
+	notifier domain.DigestNotifier
+}
+
+// NewDigestScheduler creates a scheduler reading rates from the funding use case
+func NewDigestScheduler(fundingUseCase domain.MultiExchangeUseCaseInterface, logger *logrus.Logger) *DigestScheduler {
+	return &DigestScheduler{
+		fundingUseCase: fundingUseCase,
+		logger:         logger,
+		now:            time.Now,
+	}
+}
+
+// AddChannel schedules digests for a channel, posted through its notifier.
+// Channel names must be unique.
+func (s *DigestScheduler) AddChannel(channel domain.DigestChannel, notifier domain.DigestNotifier) error {
+	for _, existing := range s.channels {
+		if existing.config.Name == channel.Name {
+			return fmt.Errorf("%w: duplicate digest channel %s", domain.ErrInvalidConfig, channel.Name)
+		}
+	}
+	s.channels = append(s.channels, digestChannel{config: channel, notifier: notifier})
+	return nil
+}
+
+// Run posts every channel's digest once per interval until the context is cancelled
+func (s *DigestScheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, channel := range s.channels {
+		wg.Add(1)
+		go func(channel digestChannel) {
+			defer wg.Done()
+
+			interval := channel.config.IntervalDuration()
+			s.logger.Infof("Posting %s digests to %s every %v", channel.config.Type, channel.config.Name, interval)
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := s.SendDigest(ctx, channel.config.Name); err != nil {
+						s.logger.Errorf("Failed to post digest to %s: %v", channel.config.Name, err)
+					}
+				}
+			}
+		}(channel)
+	}
+	wg.Wait()
+}
+
+// SendDigest posts the current digest to the named channel right away.
+// Nothing is posted when no rate is selected.
+func (s *DigestScheduler) SendDigest(ctx context.Context, channelName string) error {
+	for _, channel := range s.channels {
+		if channel.config.Name != channelName {
+			continue
+		}
+
+		rates, err := s.fundingUseCase.GetAllFundingRates(ctx)
+		if err != nil {
+			return err
+		}
+
+		selected := channel.config.SelectRates(rates)
+		if len(selected) == 0 {
+			return nil
+		}
+
+		return channel.notifier.SendDigest(ctx, domain.FundingDigest{
+			Channel:   channel.config.Name,
+			Timestamp: s.now(),
+			Basis:     channel.config.RateBasis(),
+			Threshold: channel.config.Top,
+			Rates:     selected,
+		})
+	}
+	return domain.ErrDigestChannelNotFound
+}
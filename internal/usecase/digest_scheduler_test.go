@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockDigestNotifier records the digests it was asked to post
+type MockDigestNotifier struct {
+	digests []domain.FundingDigest
+}
+
+func (m *MockDigestNotifier) SendDigest(ctx context.Context, digest domain.FundingDigest) error {
+	m.digests = append(m.digests, digest)
+	return nil
+}
+
+func newTestDigestScheduler() *DigestScheduler {
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name: "binance",
+			rates: []domain.FundingRate{
+				{Symbol: "BTCUSDT", FundingRate: 0.0001},
+				{Symbol: "ETHUSDT", FundingRate: -0.0030},
+				{Symbol: "SOLUSDT", FundingRate: 0.0010, FundingIntervalHours: 1},
+			},
+		},
+		"okx": &MockExchangeRepository{
+			name: "okx",
+			rates: []domain.FundingRate{
+				{Symbol: "BTC-USDT-SWAP", CanonicalSymbol: "BTCUSDT", FundingRate: 0.0050},
+				{Symbol: "DOGE-USDT-SWAP", CanonicalSymbol: "DOGEUSDT", FundingRate: 0.0020},
+			},
+		},
+	}
+
+	scheduler := NewDigestScheduler(NewMultiExchangeUseCase(exchanges, &MockLogRepository{}), logrus.New())
+	scheduler.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return scheduler
+}
+
+func digestSymbols(digest domain.FundingDigest) []string {
+	symbols := make([]string, 0, len(digest.Rates))
+	for _, rate := range digest.Rates {
+		symbols = append(symbols, rate.Exchange+":"+rate.MarketSymbol())
+	}
+	return symbols
+}
+
+func TestDigestScheduler_SendDigest(t *testing.T) {
+	tests := []struct {
+		name     string
+		channel  domain.DigestChannel
+		expected []string
+	}{
+		{
+			name:     "largest magnitude first",
+			channel:  domain.DigestChannel{Limit: 3},
+			expected: []string{"okx:BTCUSDT", "binance:ETHUSDT", "okx:DOGEUSDT"},
+		},
+		{
+			name:     "threshold like funding-top",
+			channel:  domain.DigestChannel{Top: 0.002},
+			expected: []string{"okx:BTCUSDT", "binance:ETHUSDT"},
+		},
+		{
+			// SOL's 1h 0.001 is 0.008 per 8h
+			name:     "8h basis",
+			channel:  domain.DigestChannel{Basis: domain.RateBasis8h, Limit: 2},
+			expected: []string{"binance:SOLUSDT", "okx:BTCUSDT"},
+		},
+		{
+			name:     "symbols match canonical ids",
+			channel:  domain.DigestChannel{Symbols: []string{"btcusdt"}},
+			expected: []string{"okx:BTCUSDT", "binance:BTCUSDT"},
+		},
+		{
+			name:     "exchanges",
+			channel:  domain.DigestChannel{Exchanges: []string{"binance"}, Limit: 1},
+			expected: []string{"binance:ETHUSDT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newTestDigestScheduler()
+			notifier := &MockDigestNotifier{}
+			tt.channel.Name = "desk"
+			if err := scheduler.AddChannel(tt.channel, notifier); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if err := scheduler.SendDigest(context.Background(), "desk"); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(notifier.digests) != 1 {
+				t.Fatalf("Expected 1 digest, got %d", len(notifier.digests))
+			}
+
+			digest := notifier.digests[0]
+			symbols := digestSymbols(digest)
+			if len(symbols) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, symbols)
+			}
+			for i := range symbols {
+				if symbols[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, symbols)
+					break
+				}
+			}
+			if digest.Channel != "desk" || digest.Basis != tt.channel.RateBasis() || digest.Threshold != tt.channel.Top || digest.Timestamp.IsZero() {
+				t.Errorf("Unexpected digest header: %+v", digest)
+			}
+		})
+	}
+}
+
+func TestDigestScheduler_SkipsEmptyDigests(t *testing.T) {
+	scheduler := newTestDigestScheduler()
+	notifier := &MockDigestNotifier{}
+	scheduler.AddChannel(domain.DigestChannel{Name: "desk", Top: 0.1}, notifier)
+
+	if err := scheduler.SendDigest(context.Background(), "desk"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(notifier.digests) != 0 {
+		t.Errorf("Expected no digest without rates, got %d", len(notifier.digests))
+	}
+}
+
+func TestDigestScheduler_Channels(t *testing.T) {
+	scheduler := newTestDigestScheduler()
+	if err := scheduler.AddChannel(domain.DigestChannel{Name: "desk"}, &MockDigestNotifier{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := scheduler.AddChannel(domain.DigestChannel{Name: "desk"}, &MockDigestNotifier{}); !errors.Is(err, domain.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for a duplicate channel, got %v", err)
+	}
+	if err := scheduler.SendDigest(context.Background(), "other"); err != domain.ErrDigestChannelNotFound {
+		t.Errorf("Expected ErrDigestChannelNotFound, got %v", err)
+	}
+}
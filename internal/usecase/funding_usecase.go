@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"fundingmonitor/internal/domain"
 )
 
@@ -19,15 +20,15 @@ func NewFundingUseCase(exchangeRepo domain.ExchangeRepository, logRepo domain.Lo
 }
 
 // GetFundingRates retrieves funding rates from the exchange
-func (f *FundingUseCase) GetFundingRates() ([]domain.FundingRate, error) {
-	return f.exchangeRepo.GetFundingRates()
+func (f *FundingUseCase) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	return f.exchangeRepo.GetFundingRates(ctx)
 }
 
 // GetExchangeInfo returns exchange information
-func (f *FundingUseCase) GetExchangeInfo() domain.ExchangeInfo {
+func (f *FundingUseCase) GetExchangeInfo(ctx context.Context) domain.ExchangeInfo {
 	return domain.ExchangeInfo{
 		Name:    f.exchangeRepo.GetName(),
-		Healthy: f.exchangeRepo.IsHealthy(),
+		Healthy: f.exchangeRepo.IsHealthy(ctx),
 	}
 }
 
@@ -44,4 +45,4 @@ func (f *FundingUseCase) GetSymbolLogs(symbol string, date string) ([]byte, erro
 // GetAllLogs retrieves all available logs
 func (f *FundingUseCase) GetAllLogs() ([]domain.LogFile, error) {
 	return f.logRepo.GetAllLogs()
-} 
\ No newline at end of file
+}
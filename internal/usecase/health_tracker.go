@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// HealthTracker derives the health of every exchange from the outcomes of
+// its recent polls, which it observes through
+// MultiExchangeUseCase.AddPollObserver. Reporting health never calls an
+// exchange.
+type HealthTracker struct {
+	config domain.HealthConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	exchanges map[string]*pollHistory
+}
+
+// pollHistory is the recent poll record of one exchange
+type pollHistory struct {
+	failures            []bool // outcome of the polls in the window, oldest first
+	consecutiveFailures int
+	lastSuccess         time.Time
+	lastError           string
+	lastErrorAt         time.Time
+}
+
+// NewHealthTracker creates a tracker for the given exchanges, which are
+// reported down until their first successful poll
+func NewHealthTracker(exchangeNames []string, config domain.HealthConfig) *HealthTracker {
+	exchanges := make(map[string]*pollHistory, len(exchangeNames))
+	for _, name := range exchangeNames {
+		exchanges[name] = &pollHistory{}
+	}
+
+	return &HealthTracker{
+		config:    config,
+		now:       time.Now,
+		exchanges: exchanges,
+	}
+}
+
+// ObservePoll records the outcome of an exchange poll
+func (t *HealthTracker) ObservePoll(exchange string, status domain.ExchangeStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	history, ok := t.exchanges[exchange]
+	if !ok {
+		history = &pollHistory{}
+		t.exchanges[exchange] = history
+	}
+
+	failed := status.Status != domain.ExchangeStatusOK
+	history.failures = append(history.failures, failed)
+	if window := t.config.WindowSize(); len(history.failures) > window {
+		history.failures = history.failures[len(history.failures)-window:]
+	}
+
+	now := t.now()
+	if failed {
+		history.consecutiveFailures++
+		history.lastError = status.Error
+		if history.lastError == "" {
+			history.lastError = status.Status
+		}
+		history.lastErrorAt = now
+		return
+	}
+	history.consecutiveFailures = 0
+	history.lastSuccess = now
+}
+
+// GetHealth reports every exchange and the overall status
+func (t *HealthTracker) GetHealth() domain.HealthReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	report := domain.HealthReport{Exchanges: make(map[string]domain.ExchangeHealth, len(t.exchanges))}
+	for name, history := range t.exchanges {
+		report.Exchanges[name] = t.exchangeHealth(name, history, now)
+	}
+	report.Status = domain.OverallHealth(report.Exchanges)
+	return report
+}
+
+// IsReady reports whether every exchange has been polled and at least one
+// of them serves data
+func (t *HealthTracker) IsReady() bool {
+	report := t.GetHealth()
+	for _, exchange := range report.Exchanges {
+		if exchange.Polls == 0 {
+			return false
+		}
+	}
+	return report.Status != domain.HealthDown
+}
+
+// exchangeHealth evaluates one exchange. It is down when it has not
+// succeeded within the staleness limit or failed too many polls in a row,
+// and degraded when its last poll failed or its error rate is too high.
+func (t *HealthTracker) exchangeHealth(name string, history *pollHistory, now time.Time) domain.ExchangeHealth {
+	failures := 0
+	for _, failed := range history.failures {
+		if failed {
+			failures++
+		}
+	}
+
+	health := domain.ExchangeHealth{
+		Name:                name,
+		Polls:               len(history.failures),
+		ConsecutiveFailures: history.consecutiveFailures,
+		LastError:           history.lastError,
+	}
+	if health.Polls > 0 {
+		health.ErrorRate = float64(failures) / float64(health.Polls)
+	}
+	if !history.lastSuccess.IsZero() {
+		health.LastSuccess = history.lastSuccess.Unix()
+	}
+	if !history.lastErrorAt.IsZero() {
+		health.LastErrorAt = history.lastErrorAt.Unix()
+	}
+
+	switch {
+	case history.lastSuccess.IsZero(),
+		now.Sub(history.lastSuccess) > t.config.StalenessLimit(),
+		history.consecutiveFailures >= t.config.FailureLimit():
+		health.Status = domain.HealthDown
+	case history.consecutiveFailures > 0,
+		health.ErrorRate > t.config.ErrorRateThreshold():
+		health.Status = domain.HealthDegraded
+	default:
+		health.Status = domain.HealthHealthy
+	}
+	health.Healthy = health.Status == domain.HealthHealthy
+	return health
+}
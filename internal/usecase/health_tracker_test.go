@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// newTestHealthTracker returns a tracker whose clock is advanced by the returned func
+func newTestHealthTracker(exchangeNames []string, config domain.HealthConfig) (*HealthTracker, func(time.Duration)) {
+	tracker := NewHealthTracker(exchangeNames, config)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	return tracker, func(d time.Duration) { now = now.Add(d) }
+}
+
+var (
+	okPoll    = domain.ExchangeStatus{Status: domain.ExchangeStatusOK, Rows: 10}
+	errorPoll = domain.ExchangeStatus{Status: domain.ExchangeStatusError, Error: "boom"}
+)
+
+func TestHealthTracker_ExchangeStates(t *testing.T) {
+	tests := []struct {
+		name     string
+		polls    []domain.ExchangeStatus
+		wait     time.Duration
+		expected string
+	}{
+		{"never polled", nil, 0, domain.HealthDown},
+		{"never succeeded", []domain.ExchangeStatus{errorPoll}, 0, domain.HealthDown},
+		{"succeeding", []domain.ExchangeStatus{okPoll, okPoll}, 0, domain.HealthHealthy},
+		{"last poll failed", []domain.ExchangeStatus{okPoll, errorPoll}, 0, domain.HealthDegraded},
+		{
+			// 2 failures out of 6 polls exceed the 25% error rate
+			name:     "error rate",
+			polls:    []domain.ExchangeStatus{okPoll, errorPoll, okPoll, errorPoll, okPoll, okPoll},
+			expected: domain.HealthDegraded,
+		},
+		{
+			name:     "failures in a row",
+			polls:    []domain.ExchangeStatus{okPoll, errorPoll, errorPoll, errorPoll},
+			expected: domain.HealthDown,
+		},
+		{"no recent success", []domain.ExchangeStatus{okPoll}, 6 * time.Minute, domain.HealthDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, advance := newTestHealthTracker([]string{"binance"}, domain.HealthConfig{DownAfterFailures: 3})
+			for _, poll := range tt.polls {
+				tracker.ObservePoll("binance", poll)
+				advance(time.Second)
+			}
+			advance(tt.wait)
+
+			health := tracker.GetHealth().Exchanges["binance"]
+			if health.Status != tt.expected {
+				t.Errorf("Expected %s, got %+v", tt.expected, health)
+			}
+			if health.Healthy != (tt.expected == domain.HealthHealthy) {
+				t.Errorf("Expected healthy to match the status, got %+v", health)
+			}
+		})
+	}
+}
+
+func TestHealthTracker_ErrorRateWindow(t *testing.T) {
+	tracker, _ := newTestHealthTracker([]string{"binance"}, domain.HealthConfig{Window: 4})
+	tracker.ObservePoll("binance", errorPoll)
+	tracker.ObservePoll("binance", errorPoll)
+	for i := 0; i < 3; i++ {
+		tracker.ObservePoll("binance", okPoll)
+	}
+
+	health := tracker.GetHealth().Exchanges["binance"]
+	if health.Polls != 4 || health.ErrorRate != 0.25 || health.Status != domain.HealthHealthy {
+		t.Errorf("Expected 1 failure in a window of 4, got %+v", health)
+	}
+	if health.LastError != "boom" || health.LastErrorAt == 0 || health.LastSuccess == 0 || health.ConsecutiveFailures != 0 {
+		t.Errorf("Expected the last error and success to be kept, got %+v", health)
+	}
+}
+
+func TestHealthTracker_Overall(t *testing.T) {
+	tracker, _ := newTestHealthTracker([]string{"binance", "okx"}, domain.HealthConfig{})
+
+	if report := tracker.GetHealth(); report.Status != domain.HealthDown || tracker.IsReady() {
+		t.Errorf("Expected down and not ready before any poll, got %s", report.Status)
+	}
+
+	tracker.ObservePoll("binance", okPoll)
+	if report := tracker.GetHealth(); report.Status != domain.HealthDegraded || tracker.IsReady() {
+		t.Errorf("Expected degraded and not ready until every exchange was polled, got %s", report.Status)
+	}
+
+	tracker.ObservePoll("okx", errorPoll)
+	if report := tracker.GetHealth(); report.Status != domain.HealthDegraded || !tracker.IsReady() {
+		t.Errorf("Expected degraded and ready, got %s", report.Status)
+	}
+
+	// Recovering takes enough good polls to bring the error rate down
+	for i := 0; i < 3; i++ {
+		tracker.ObservePoll("okx", okPoll)
+	}
+	if report := tracker.GetHealth(); report.Status != domain.HealthHealthy || !tracker.IsReady() {
+		t.Errorf("Expected healthy and ready, got %s", report.Status)
+	}
+}
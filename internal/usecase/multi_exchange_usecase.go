@@ -1,6 +1,12 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
 	"fundingmonitor/internal/domain"
 )
 
@@ -8,8 +14,17 @@ import (
 type MultiExchangeUseCase struct {
 	exchanges map[string]domain.ExchangeRepository
 	logRepo   domain.LogRepository
+	symbols   domain.SymbolNormalizer
+	listeners []domain.FundingListener
+	observers []domain.PollObserver
+
+	defaultTimeout   time.Duration
+	exchangeTimeouts map[string]time.Duration
 }
 
+// DefaultExchangeTimeout bounds a single exchange poll when none is configured
+const DefaultExchangeTimeout = 15 * time.Second
+
 // NewMultiExchangeUseCase creates a new multi-exchange use case
 func NewMultiExchangeUseCase(exchanges map[string]domain.ExchangeRepository, logRepo domain.LogRepository) *MultiExchangeUseCase {
 	return &MultiExchangeUseCase{
@@ -18,46 +33,255 @@ func NewMultiExchangeUseCase(exchanges map[string]domain.ExchangeRepository, log
 	}
 }
 
+// SetSymbolNormalizer enables canonical symbol resolution for retrieved rates
+func (m *MultiExchangeUseCase) SetSymbolNormalizer(symbols domain.SymbolNormalizer) {
+	m.symbols = symbols
+}
+
+// SetTimeouts sets the default poll deadline and per-exchange overrides
+func (m *MultiExchangeUseCase) SetTimeouts(defaultTimeout time.Duration, exchangeTimeouts map[string]time.Duration) {
+	m.defaultTimeout = defaultTimeout
+	m.exchangeTimeouts = exchangeTimeouts
+}
+
+// AddPollObserver registers an observer told the outcome of every exchange poll
+func (m *MultiExchangeUseCase) AddPollObserver(observer domain.PollObserver) {
+	m.observers = append(m.observers, observer)
+}
+
+// AddListener registers a listener notified with every snapshot logged, by
+// LogAllFundingRates or a store logging its cached rates
+func (m *MultiExchangeUseCase) AddListener(listener domain.FundingListener) {
+	m.listeners = append(m.listeners, listener)
+}
+
 // GetAllFundingRates retrieves funding rates from all exchanges
-func (m *MultiExchangeUseCase) GetAllFundingRates() ([]domain.FundingRate, error) {
-	var allRates []domain.FundingRate
+func (m *MultiExchangeUseCase) GetAllFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	snapshot, err := m.GetFundingSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Rates, nil
+}
+
+// GetFundingSnapshot polls every exchange concurrently, each under its own
+// deadline, and returns whatever arrived along with a status per exchange
+func (m *MultiExchangeUseCase) GetFundingSnapshot(ctx context.Context) (domain.FundingSnapshot, error) {
+	type exchangeResult struct {
+		name   string
+		rates  []domain.FundingRate
+		status domain.ExchangeStatus
+	}
 
+	results := make(chan exchangeResult, len(m.exchanges))
 	for name, exchange := range m.exchanges {
-		rates, err := exchange.GetFundingRates()
-		if err != nil {
-			// Log error but continue with other exchanges
-			continue
-		}
+		go func(name string, exchange domain.ExchangeRepository) {
+			rates, status := m.fetchExchange(ctx, name, exchange)
+			results <- exchangeResult{name: name, rates: rates, status: status}
+		}(name, exchange)
+	}
 
-		// Add exchange name to each rate
-		for i := range rates {
-			rates[i].Exchange = name
-		}
+	ratesByExchange := make(map[string][]domain.FundingRate, len(m.exchanges))
+	snapshot := domain.FundingSnapshot{
+		Timestamp: time.Now(),
+		Rates:     []domain.FundingRate{},
+		Exchanges: make(map[string]domain.ExchangeStatus, len(m.exchanges)),
+	}
+	for range m.exchanges {
+		result := <-results
+		ratesByExchange[result.name] = result.rates
+		snapshot.Exchanges[result.name] = result.status
+	}
 
-		allRates = append(allRates, rates...)
+	// Keep a stable exchange order regardless of which venue answered first
+	names := make([]string, 0, len(ratesByExchange))
+	for name := range ratesByExchange {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		snapshot.Rates = append(snapshot.Rates, ratesByExchange[name]...)
 	}
 
-	return allRates, nil
+	return snapshot, nil
 }
 
 // GetExchangeFundingRates retrieves funding rates from a specific exchange
-func (m *MultiExchangeUseCase) GetExchangeFundingRates(exchangeName string) ([]domain.FundingRate, error) {
+func (m *MultiExchangeUseCase) GetExchangeFundingRates(ctx context.Context, exchangeName string) ([]domain.FundingRate, error) {
 	exchange, exists := m.exchanges[exchangeName]
 	if !exists {
 		return nil, domain.ErrExchangeNotFound
 	}
 
-	return exchange.GetFundingRates()
+	rates, status := m.fetchExchange(ctx, exchangeName, exchange)
+	if err := statusError(exchangeName, status); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// RefreshFundingRates does nothing: reads are always live, and reading an
+// unknown exchange reports it as not found
+func (m *MultiExchangeUseCase) RefreshFundingRates(ctx context.Context, exchangeNames ...string) error {
+	return nil
+}
+
+// FetchExchange polls a single exchange under its deadline
+func (m *MultiExchangeUseCase) FetchExchange(ctx context.Context, exchangeName string) ([]domain.FundingRate, domain.ExchangeStatus, error) {
+	exchange, exists := m.exchanges[exchangeName]
+	if !exists {
+		return nil, domain.ExchangeStatus{}, domain.ErrExchangeNotFound
+	}
+
+	rates, status := m.fetchExchange(ctx, exchangeName, exchange)
+	return rates, status, nil
+}
+
+// ExchangeNames returns the configured exchanges in alphabetical order
+func (m *MultiExchangeUseCase) ExchangeNames() []string {
+	names := make([]string, 0, len(m.exchanges))
+	for name := range m.exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statusError converts a failed poll into the error reported to callers
+func statusError(exchangeName string, status domain.ExchangeStatus) error {
+	switch status.Status {
+	case domain.ExchangeStatusTimeout:
+		return fmt.Errorf("%s: %w", exchangeName, domain.ErrExchangeTimeout)
+	case domain.ExchangeStatusError:
+		return fmt.Errorf("%s: %s", exchangeName, status.Error)
+	}
+	return nil
+}
+
+// fetchExchange polls one exchange and reports the outcome to the observers
+func (m *MultiExchangeUseCase) fetchExchange(ctx context.Context, name string, exchange domain.ExchangeRepository) ([]domain.FundingRate, domain.ExchangeStatus) {
+	rates, status := m.pollExchange(ctx, name, exchange)
+	for _, observer := range m.observers {
+		observer.ObservePoll(name, status)
+	}
+	return rates, status
+}
+
+// pollExchange polls one exchange under its deadline and enriches the result.
+// The deadline and any cancellation of ctx abort the exchange's requests; a
+// fetch that still outlives them is abandoned and its result discarded.
+func (m *MultiExchangeUseCase) pollExchange(ctx context.Context, name string, exchange domain.ExchangeRepository) ([]domain.FundingRate, domain.ExchangeStatus) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeoutFor(name))
+	defer cancel()
+
+	type fetchResult struct {
+		rates []domain.FundingRate
+		err   error
+	}
+
+	start := time.Now()
+	done := make(chan fetchResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fetchResult{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		rates, err := exchange.GetFundingRates(ctx)
+		done <- fetchResult{rates: rates, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, domain.ExchangeStatus{
+			Status:    cancelledStatus(ctx),
+			LatencyMs: time.Since(start).Milliseconds(),
+			Error:     ctx.Err().Error(),
+		}
+	case result := <-done:
+		status := domain.ExchangeStatus{
+			Status:    domain.ExchangeStatusOK,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Rows:      len(result.rates),
+			AsOf:      time.Now().Unix(),
+		}
+		if result.err != nil {
+			status.Status = domain.ExchangeStatusError
+			if ctx.Err() != nil {
+				// The client gave up because the deadline or caller cancelled it
+				status.Status = cancelledStatus(ctx)
+			}
+			status.Error = result.err.Error()
+			status.Rows = 0
+			status.AsOf = 0
+			return nil, status
+		}
+
+		// Add exchange name to each rate
+		for i := range result.rates {
+			result.rates[i].Exchange = name
+		}
+		m.normalizeSymbols(name, result.rates)
+		normalizeIntervals(result.rates)
+		return result.rates, status
+	}
+}
+
+// cancelledStatus reports a poll cut short by its context as a timeout when
+// the deadline passed, or as an error when the caller cancelled it
+func cancelledStatus(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return domain.ExchangeStatusTimeout
+	}
+	return domain.ExchangeStatusError
+}
+
+// timeoutFor returns the poll deadline of an exchange
+func (m *MultiExchangeUseCase) timeoutFor(name string) time.Duration {
+	if timeout, ok := m.exchangeTimeouts[name]; ok && timeout > 0 {
+		return timeout
+	}
+	if m.defaultTimeout > 0 {
+		return m.defaultTimeout
+	}
+	return DefaultExchangeTimeout
+}
+
+// normalizeSymbols attaches the canonical instrument to every recognised rate
+func (m *MultiExchangeUseCase) normalizeSymbols(exchangeName string, rates []domain.FundingRate) {
+	if m.symbols == nil {
+		return
+	}
+
+	for i := range rates {
+		instrument, ok := m.symbols.Normalize(exchangeName, rates[i].Symbol)
+		if !ok {
+			continue
+		}
+		rates[i].CanonicalSymbol = instrument.Symbol()
+		rates[i].Base = instrument.Base
+		rates[i].Quote = instrument.Quote
+		rates[i].ContractType = instrument.ContractType
+	}
+}
+
+// normalizeIntervals fills the hourly, 8h and annualized equivalents of each rate
+func normalizeIntervals(rates []domain.FundingRate) {
+	for i := range rates {
+		rates[i].FundingRate1h = rates[i].HourlyRate()
+		rates[i].FundingRate8h = rates[i].EightHourRate()
+		rates[i].FundingRateAPR = rates[i].AnnualizedRate()
+	}
 }
 
 // GetExchangeInfo returns information about all exchanges
-func (m *MultiExchangeUseCase) GetExchangeInfo() map[string]domain.ExchangeInfo {
+func (m *MultiExchangeUseCase) GetExchangeInfo(ctx context.Context) map[string]domain.ExchangeInfo {
 	info := make(map[string]domain.ExchangeInfo)
 
 	for name, exchange := range m.exchanges {
 		info[name] = domain.ExchangeInfo{
 			Name:    exchange.GetName(),
-			Healthy: exchange.IsHealthy(),
+			Healthy: exchange.IsHealthy(ctx),
 		}
 	}
 
@@ -65,16 +289,28 @@ func (m *MultiExchangeUseCase) GetExchangeInfo() map[string]domain.ExchangeInfo
 }
 
 // LogAllFundingRates logs funding rates from all exchanges grouped by symbol
-func (m *MultiExchangeUseCase) LogAllFundingRates() error {
-	allRates, err := m.GetAllFundingRates()
+func (m *MultiExchangeUseCase) LogAllFundingRates(ctx context.Context) error {
+	snapshot, err := m.GetFundingSnapshot(ctx)
 	if err != nil {
 		return err
 	}
+	return m.LogFundingSnapshot(snapshot)
+}
+
+// LogFundingSnapshot notifies the listeners and logs the rates of a snapshot
+// grouped by symbol
+func (m *MultiExchangeUseCase) LogFundingSnapshot(snapshot domain.FundingSnapshot) error {
+	for _, listener := range m.listeners {
+		listener.OnFundingSnapshot(snapshot)
+	}
+
+	allRates := snapshot.Rates
 
-	// Group rates by symbol
+	// Group rates by market so every venue listing it lands in the same log
 	symbolRates := make(map[string][]domain.FundingRate)
 	for _, rate := range allRates {
-		symbolRates[rate.Symbol] = append(symbolRates[rate.Symbol], rate)
+		symbol := rate.MarketSymbol()
+		symbolRates[symbol] = append(symbolRates[symbol], rate)
 	}
 
 	// Log each symbol
@@ -98,7 +334,7 @@ func (m *MultiExchangeUseCase) GetAllLogs() ([]domain.LogFile, error) {
 	return m.logRepo.GetAllLogs()
 }
 
-// GetHistoricalFundingRates retrieves historical funding rates for a symbol and exchange
-func (m *MultiExchangeUseCase) GetHistoricalFundingRates(symbol string, exchange string) ([]domain.FundingRateHistory, error) {
-	return m.logRepo.GetHistoricalFundingRates(symbol, exchange)
+// GetHistoricalFundingRates retrieves the logged funding rates matching a query
+func (m *MultiExchangeUseCase) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
+	return m.logRepo.GetHistoricalFundingRates(query)
 }
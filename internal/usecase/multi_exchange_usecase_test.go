@@ -1,8 +1,13 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fundingmonitor/internal/domain"
+	"math"
+	"sync"
 	"testing"
+	"time"
 )
 
 // MockExchangeRepository implements domain.ExchangeRepository for testing
@@ -11,9 +16,11 @@ type MockExchangeRepository struct {
 	healthy bool
 	rates   []domain.FundingRate
 	err     error
+	delay   time.Duration
 }
 
-func (m *MockExchangeRepository) GetFundingRates() ([]domain.FundingRate, error) {
+func (m *MockExchangeRepository) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	time.Sleep(m.delay)
 	return m.rates, m.err
 }
 
@@ -21,7 +28,7 @@ func (m *MockExchangeRepository) GetName() string {
 	return m.name
 }
 
-func (m *MockExchangeRepository) IsHealthy() bool {
+func (m *MockExchangeRepository) IsHealthy(ctx context.Context) bool {
 	return m.healthy
 }
 
@@ -36,6 +43,10 @@ func (m *MockLogRepository) LogFundingRates(symbol string, rates []domain.Fundin
 	return m.logErr
 }
 
+func (m *MockLogRepository) BackfillFundingRates(symbol string, rates []domain.FundingRate) error {
+	return m.logErr
+}
+
 func (m *MockLogRepository) GetSymbolLogs(symbol string, date string) ([]byte, error) {
 	return []byte("test"), m.getErr
 }
@@ -44,7 +55,7 @@ func (m *MockLogRepository) GetAllLogs() ([]domain.LogFile, error) {
 	return m.logFiles, m.getErr
 }
 
-func (m *MockLogRepository) GetHistoricalFundingRates(symbol string, exchange string) ([]domain.FundingRateHistory, error) {
+func (m *MockLogRepository) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
 	return []domain.FundingRateHistory{}, m.getErr
 }
 
@@ -76,7 +87,7 @@ func TestMultiExchangeUseCase_GetAllFundingRates(t *testing.T) {
 	useCase := NewMultiExchangeUseCase(exchanges, logRepo)
 
 	// Test getting all funding rates
-	rates, err := useCase.GetAllFundingRates()
+	rates, err := useCase.GetAllFundingRates(context.Background())
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -111,7 +122,7 @@ func TestMultiExchangeUseCase_GetExchangeFundingRates(t *testing.T) {
 	useCase := NewMultiExchangeUseCase(exchanges, logRepo)
 
 	// Test getting rates from existing exchange
-	rates, err := useCase.GetExchangeFundingRates("binance")
+	rates, err := useCase.GetExchangeFundingRates(context.Background(), "binance")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -121,7 +132,7 @@ func TestMultiExchangeUseCase_GetExchangeFundingRates(t *testing.T) {
 	}
 
 	// Test getting rates from non-existing exchange
-	_, err = useCase.GetExchangeFundingRates("nonexistent")
+	_, err = useCase.GetExchangeFundingRates(context.Background(), "nonexistent")
 	if err != domain.ErrExchangeNotFound {
 		t.Fatalf("Expected ErrExchangeNotFound, got %v", err)
 	}
@@ -146,7 +157,7 @@ func TestMultiExchangeUseCase_GetExchangeInfo(t *testing.T) {
 	logRepo := &MockLogRepository{}
 	useCase := NewMultiExchangeUseCase(exchanges, logRepo)
 
-	info := useCase.GetExchangeInfo()
+	info := useCase.GetExchangeInfo(context.Background())
 
 	if len(info) != 2 {
 		t.Fatalf("Expected 2 exchanges, got %d", len(info))
@@ -188,8 +199,341 @@ func TestMultiExchangeUseCase_LogAllFundingRates(t *testing.T) {
 	useCase := NewMultiExchangeUseCase(exchanges, logRepo)
 
 	// Test logging all funding rates
-	err := useCase.LogAllFundingRates()
+	err := useCase.LogAllFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+// MockSymbolNormalizer maps venue symbols through a fixed table
+type MockSymbolNormalizer struct {
+	instruments map[string]domain.Instrument
+}
+
+func (m *MockSymbolNormalizer) Normalize(exchange string, symbol string) (domain.Instrument, bool) {
+	instrument, ok := m.instruments[exchange+":"+symbol]
+	return instrument, ok
+}
+
+// RecordingLogRepository records the symbols rates were logged under
+type RecordingLogRepository struct {
+	MockLogRepository
+	logged map[string][]domain.FundingRate
+}
+
+func (r *RecordingLogRepository) LogFundingRates(symbol string, rates []domain.FundingRate) error {
+	r.logged[symbol] = append(r.logged[symbol], rates...)
+	return nil
+}
+
+func TestMultiExchangeUseCase_SymbolNormalization(t *testing.T) {
+	btc := domain.Instrument{Base: "BTC", Quote: "USDT", ContractType: domain.ContractTypePerpetual}
+
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT", FundingRate: 0.0001}},
+		},
+		"okx": &MockExchangeRepository{
+			name: "okx",
+			rates: []domain.FundingRate{
+				{Symbol: "BTC-USDT-SWAP", FundingRate: 0.0002},
+				{Symbol: "UNKNOWN", FundingRate: 0.0003},
+			},
+		},
+	}
+
+	logRepo := &RecordingLogRepository{logged: make(map[string][]domain.FundingRate)}
+	useCase := NewMultiExchangeUseCase(exchanges, logRepo)
+	useCase.SetSymbolNormalizer(&MockSymbolNormalizer{instruments: map[string]domain.Instrument{
+		"binance:BTCUSDT":   btc,
+		"okx:BTC-USDT-SWAP": btc,
+	}})
+
+	rates, err := useCase.GetExchangeFundingRates(context.Background(), "okx")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rates[0].CanonicalSymbol != "BTCUSDT" || rates[0].Base != "BTC" || rates[0].Quote != "USDT" {
+		t.Errorf("Expected canonical BTC/USDT identity, got %+v", rates[0])
+	}
+	if rates[0].Symbol != "BTC-USDT-SWAP" {
+		t.Errorf("Expected venue symbol to be preserved, got %s", rates[0].Symbol)
+	}
+	if rates[1].CanonicalSymbol != "" {
+		t.Errorf("Expected unknown symbol to stay unmapped, got %s", rates[1].CanonicalSymbol)
+	}
+
+	if err := useCase.LogAllFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Both venues share the canonical group, unmapped symbols keep their own
+	if len(logRepo.logged["BTCUSDT"]) != 2 {
+		t.Errorf("Expected 2 BTCUSDT rates in one group, got %d", len(logRepo.logged["BTCUSDT"]))
+	}
+	if len(logRepo.logged["UNKNOWN"]) != 1 {
+		t.Errorf("Expected UNKNOWN to be logged under its venue symbol, got %d", len(logRepo.logged["UNKNOWN"]))
+	}
+}
+
+func TestMultiExchangeUseCase_IntervalNormalization(t *testing.T) {
+	exchanges := map[string]domain.ExchangeRepository{
+		"bybit": &MockExchangeRepository{
+			name: "bybit",
+			rates: []domain.FundingRate{
+				{Symbol: "ORDIUSDT", FundingRate: 0.0001, FundingIntervalHours: 1},
+				{Symbol: "BTCUSDT", FundingRate: 0.0008},
+			},
+		},
+	}
+
+	useCase := NewMultiExchangeUseCase(exchanges, &MockLogRepository{})
+	rates, err := useCase.GetExchangeFundingRates(context.Background(), "bybit")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	hourly := rates[0]
+	if !almostEqual(hourly.FundingRate1h, 0.0001) || !almostEqual(hourly.FundingRate8h, 0.0008) || !almostEqual(hourly.FundingRateAPR, 0.0001*24*365) {
+		t.Errorf("Unexpected normalization for 1h rate: %+v", hourly)
+	}
+
+	// Unknown intervals default to 8h
+	eightHour := rates[1]
+	if !almostEqual(eightHour.FundingRate1h, 0.0001) || !almostEqual(eightHour.FundingRate8h, 0.0008) {
+		t.Errorf("Unexpected normalization for default interval rate: %+v", eightHour)
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+// RecordingListener records the snapshots it is notified with
+type RecordingListener struct {
+	snapshots []domain.FundingSnapshot
+}
+
+func (r *RecordingListener) OnFundingSnapshot(snapshot domain.FundingSnapshot) {
+	r.snapshots = append(r.snapshots, snapshot)
+}
+
+func TestMultiExchangeUseCase_NotifiesListeners(t *testing.T) {
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT", FundingRate: 0.0001}},
+		},
+	}
+
+	useCase := NewMultiExchangeUseCase(exchanges, &MockLogRepository{})
+	listener := &RecordingListener{}
+	useCase.AddListener(listener)
+
+	if err := useCase.LogAllFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(listener.snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(listener.snapshots))
+	}
+	snapshot := listener.snapshots[0]
+	if len(snapshot.Rates) != 1 || snapshot.Rates[0].Exchange != "binance" || snapshot.Timestamp.IsZero() {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+}
+
+func TestMultiExchangeUseCase_GetFundingSnapshotPartialResults(t *testing.T) {
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT", FundingRate: 0.0001}},
+			delay: 50 * time.Millisecond,
+		},
+		"bybit": &MockExchangeRepository{
+			name:  "bybit",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT", FundingRate: 0.0002}},
+			delay: 50 * time.Millisecond,
+		},
+		"okx": &MockExchangeRepository{
+			name: "okx",
+			err:  errors.New("boom"),
+		},
+		"deribit": &MockExchangeRepository{
+			name:  "deribit",
+			rates: []domain.FundingRate{{Symbol: "BTC-PERPETUAL", FundingRate: 0.0003}},
+			delay: time.Second,
+		},
+	}
+
+	useCase := NewMultiExchangeUseCase(exchanges, &MockLogRepository{})
+	useCase.SetTimeouts(500*time.Millisecond, map[string]time.Duration{"deribit": 100 * time.Millisecond})
+
+	start := time.Now()
+	snapshot, err := useCase.GetFundingSnapshot(context.Background())
+	elapsed := time.Since(start)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
+
+	// Exchanges are polled concurrently, so the slowest deadline bounds the call
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("Expected concurrent polling, took %v", elapsed)
+	}
+
+	if len(snapshot.Rates) != 2 {
+		t.Fatalf("Expected 2 rates from healthy exchanges, got %d", len(snapshot.Rates))
+	}
+	if snapshot.Rates[0].Exchange != "binance" || snapshot.Rates[1].Exchange != "bybit" {
+		t.Errorf("Expected rates ordered by exchange, got %s, %s", snapshot.Rates[0].Exchange, snapshot.Rates[1].Exchange)
+	}
+
+	tests := []struct {
+		exchange string
+		status   string
+		rows     int
+	}{
+		{"binance", domain.ExchangeStatusOK, 1},
+		{"bybit", domain.ExchangeStatusOK, 1},
+		{"okx", domain.ExchangeStatusError, 0},
+		{"deribit", domain.ExchangeStatusTimeout, 0},
+	}
+	for _, tt := range tests {
+		status, ok := snapshot.Exchanges[tt.exchange]
+		if !ok {
+			t.Errorf("Expected status for %s", tt.exchange)
+			continue
+		}
+		if status.Status != tt.status {
+			t.Errorf("Expected %s status %s, got %s", tt.exchange, tt.status, status.Status)
+		}
+		if status.Rows != tt.rows {
+			t.Errorf("Expected %s rows %d, got %d", tt.exchange, tt.rows, status.Rows)
+		}
+	}
+
+	if snapshot.Exchanges["okx"].Error != "boom" {
+		t.Errorf("Expected okx error message, got %q", snapshot.Exchanges["okx"].Error)
+	}
+	if snapshot.Exchanges["binance"].LatencyMs < 50 {
+		t.Errorf("Expected binance latency of at least 50ms, got %d", snapshot.Exchanges["binance"].LatencyMs)
+	}
+	if latency := snapshot.Exchanges["deribit"].LatencyMs; latency < 100 || latency >= 1000 {
+		t.Errorf("Expected deribit to be cut off at its deadline, got %dms", latency)
+	}
+}
+
+func TestMultiExchangeUseCase_GetExchangeFundingRatesTimeout(t *testing.T) {
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT"}},
+			delay: 200 * time.Millisecond,
+		},
+	}
+
+	useCase := NewMultiExchangeUseCase(exchanges, &MockLogRepository{})
+	useCase.SetTimeouts(20*time.Millisecond, nil)
+
+	_, err := useCase.GetExchangeFundingRates(context.Background(), "binance")
+	if !errors.Is(err, domain.ErrExchangeTimeout) {
+		t.Errorf("Expected ErrExchangeTimeout, got %v", err)
+	}
+}
+
+// BlockingExchangeRepository blocks until its context is done
+type BlockingExchangeRepository struct {
+	MockExchangeRepository
+	cancelled chan struct{}
+}
+
+func (b *BlockingExchangeRepository) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	<-ctx.Done()
+	close(b.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestMultiExchangeUseCase_PropagatesCancellation(t *testing.T) {
+	exchange := &BlockingExchangeRepository{
+		MockExchangeRepository: MockExchangeRepository{name: "binance"},
+		cancelled:              make(chan struct{}),
+	}
+	useCase := NewMultiExchangeUseCase(map[string]domain.ExchangeRepository{"binance": exchange}, &MockLogRepository{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	snapshot, err := useCase.GetFundingSnapshot(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case <-exchange.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the exchange call to see the cancellation")
+	}
+
+	status := snapshot.Exchanges["binance"]
+	if status.Status != domain.ExchangeStatusError || status.Error != context.Canceled.Error() {
+		t.Errorf("Expected cancelled error status, got %+v", status)
+	}
+}
+
+func TestMultiExchangeUseCase_DeadlineReportedAsTimeout(t *testing.T) {
+	exchange := &BlockingExchangeRepository{
+		MockExchangeRepository: MockExchangeRepository{name: "binance"},
+		cancelled:              make(chan struct{}),
+	}
+	useCase := NewMultiExchangeUseCase(map[string]domain.ExchangeRepository{"binance": exchange}, &MockLogRepository{})
+	useCase.SetTimeouts(20*time.Millisecond, nil)
+
+	snapshot, _ := useCase.GetFundingSnapshot(context.Background())
+	if status := snapshot.Exchanges["binance"]; status.Status != domain.ExchangeStatusTimeout {
+		t.Errorf("Expected timeout status, got %+v", status)
+	}
+}
+
+// RecordingPollObserver records the polls it is told about
+type RecordingPollObserver struct {
+	mu    sync.Mutex
+	polls map[string]domain.ExchangeStatus
+}
+
+func (r *RecordingPollObserver) ObservePoll(exchange string, status domain.ExchangeStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.polls[exchange] = status
+}
+
+func TestMultiExchangeUseCase_ObservesPolls(t *testing.T) {
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": &MockExchangeRepository{
+			name:  "binance",
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT", FundingRate: 0.0001}},
+		},
+		"okx": &MockExchangeRepository{
+			name: "okx",
+			err:  errors.New("boom"),
+		},
+	}
+
+	useCase := NewMultiExchangeUseCase(exchanges, &MockLogRepository{})
+	observer := &RecordingPollObserver{polls: make(map[string]domain.ExchangeStatus)}
+	useCase.AddPollObserver(observer)
+
+	if _, err := useCase.GetFundingSnapshot(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if status := observer.polls["binance"]; status.Status != domain.ExchangeStatusOK || status.Rows != 1 {
+		t.Errorf("Expected a successful binance poll of 1 row, got %+v", status)
+	}
+	if status := observer.polls["okx"]; status.Status != domain.ExchangeStatusError || status.Error != "boom" {
+		t.Errorf("Expected a failed okx poll, got %+v", status)
+	}
 }
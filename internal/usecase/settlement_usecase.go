@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// SettlementUseCase reads the funding rates the exchanges settled, straight
+// from their funding history APIs rather than the logged snapshots
+type SettlementUseCase struct {
+	exchanges map[string]domain.ExchangeRepository
+	timeout   time.Duration
+}
+
+// DefaultSettlementTimeout bounds reading a settlement history, which may
+// take several pages
+const DefaultSettlementTimeout = 30 * time.Second
+
+// NewSettlementUseCase creates a settlement use case over the exchanges
+func NewSettlementUseCase(exchanges map[string]domain.ExchangeRepository) *SettlementUseCase {
+	return &SettlementUseCase{
+		exchanges: exchanges,
+		timeout:   DefaultSettlementTimeout,
+	}
+}
+
+// GetSettlements returns the settlements of the query's venue symbol within
+// [From, To), oldest first
+func (s *SettlementUseCase) GetSettlements(ctx context.Context, query domain.SettlementQuery) ([]domain.FundingSettlement, error) {
+	exchange, exists := s.exchanges[query.Exchange]
+	if !exists {
+		return nil, domain.ErrExchangeNotFound
+	}
+	history, ok := exchange.(domain.FundingHistoryRepository)
+	if !ok {
+		return nil, domain.ErrHistoryUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	settlements, err := history.GetFundingHistory(ctx, query.Symbol, query.From, query.To)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%s: %w", query.Exchange, domain.ErrExchangeTimeout)
+		}
+		return nil, fmt.Errorf("%s: %w", query.Exchange, err)
+	}
+	return settlements, nil
+}
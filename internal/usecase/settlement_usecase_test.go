@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// MockHistoryExchange is an exchange serving funding history, waiting for
+// ctx when it has no settlements
+type MockHistoryExchange struct {
+	MockExchangeRepository
+	settlements []domain.FundingSettlement
+	err         error
+	symbol      string
+}
+
+func (m *MockHistoryExchange) GetFundingHistory(ctx context.Context, symbol string, start, end time.Time) ([]domain.FundingSettlement, error) {
+	m.symbol = symbol
+	if m.settlements == nil && m.err == nil {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return m.settlements, m.err
+}
+
+func TestSettlementUseCase_GetSettlements(t *testing.T) {
+	settledAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	binance := &MockHistoryExchange{
+		MockExchangeRepository: MockExchangeRepository{name: "binance"},
+		settlements:            []domain.FundingSettlement{{Exchange: "binance", Symbol: "BTCUSDT", FundingRate: 0.0001, FundingTime: settledAt}},
+	}
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": binance,
+		"okx":     &MockHistoryExchange{MockExchangeRepository: MockExchangeRepository{name: "okx"}, err: errors.New("unavailable")},
+		"slow":    &MockHistoryExchange{MockExchangeRepository: MockExchangeRepository{name: "slow"}},
+		"mexc":    &MockExchangeRepository{name: "mexc"},
+	}
+	useCase := NewSettlementUseCase(exchanges)
+	useCase.timeout = 10 * time.Millisecond
+
+	query := domain.SettlementQuery{Exchange: "binance", Symbol: "BTCUSDT", From: settledAt.Add(-time.Hour), To: settledAt.Add(time.Hour)}
+	settlements, err := useCase.GetSettlements(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(settlements) != 1 || binance.symbol != "BTCUSDT" {
+		t.Errorf("Expected the BTCUSDT settlement, got %v", settlements)
+	}
+
+	tests := []struct {
+		exchange string
+		expected error
+	}{
+		{"bybit", domain.ErrExchangeNotFound},
+		{"mexc", domain.ErrHistoryUnsupported},
+		{"slow", domain.ErrExchangeTimeout},
+	}
+	for _, tt := range tests {
+		query.Exchange = tt.exchange
+		if _, err := useCase.GetSettlements(context.Background(), query); !errors.Is(err, tt.expected) {
+			t.Errorf("Expected %v for %s, got %v", tt.expected, tt.exchange, err)
+		}
+	}
+
+	query.Exchange = "okx"
+	if _, err := useCase.GetSettlements(context.Background(), query); err == nil {
+		t.Error("Expected the okx error")
+	}
+}
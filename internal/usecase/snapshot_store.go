@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+const (
+	// DefaultRefreshInterval is the snapshot refresh cadence when none is configured
+	DefaultRefreshInterval = 30 * time.Second
+
+	// forceRefreshCooldown stops forced refreshes from re-polling an exchange
+	// that was just refreshed, so a busy dashboard can't hammer rate limits
+	forceRefreshCooldown = 5 * time.Second
+
+	// staleAfterIntervals is how many missed refreshes make cached rates stale
+	staleAfterIntervals = 2
+)
+
+// FundingSnapshotStore serves funding rates from memory. A single scheduler
+// (Run) refreshes every exchange on its own cadence, so HTTP reads never
+// trigger exchange calls. When a refresh fails the last good rates are kept
+// and reported as stale.
+type FundingSnapshotStore struct {
+	source          *MultiExchangeUseCase
+	defaultInterval time.Duration
+	intervals       map[string]time.Duration
+	listeners       []domain.FundingListener
+	now             func() time.Time
+
+	// refreshMu serializes refreshes so scheduled and forced ones never poll
+	// the same exchange twice at once
+	refreshMu sync.Mutex
+
+	mu      sync.RWMutex
+	entries map[string]*exchangeEntry
+
+	// logMu guards loggedAsOf, the as_of of the rates last logged per exchange
+	logMu      sync.Mutex
+	loggedAsOf map[string]int64
+}
+
+// exchangeEntry is the cached state of one exchange
+type exchangeEntry struct {
+	rates       []domain.FundingRate
+	status      domain.ExchangeStatus
+	asOf        time.Time // when rates were fetched
+	refreshedAt time.Time // when the last refresh was attempted
+}
+
+// NewFundingSnapshotStore creates a store on top of the live use case
+func NewFundingSnapshotStore(source *MultiExchangeUseCase) *FundingSnapshotStore {
+	return &FundingSnapshotStore{
+		source:     source,
+		now:        time.Now,
+		entries:    make(map[string]*exchangeEntry),
+		loggedAsOf: make(map[string]int64),
+	}
+}
+
+// SetRefreshIntervals sets the default refresh cadence and per-exchange overrides
+func (s *FundingSnapshotStore) SetRefreshIntervals(defaultInterval time.Duration, intervals map[string]time.Duration) {
+	s.defaultInterval = defaultInterval
+	s.intervals = intervals
+}
+
+// AddListener registers a listener notified with the full snapshot after every refresh
+func (s *FundingSnapshotStore) AddListener(listener domain.FundingListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// Run refreshes exchanges as they fall due until the context is cancelled,
+// which also aborts the refresh in flight
+func (s *FundingSnapshotStore) Run(ctx context.Context) {
+	for {
+		s.refresh(ctx, s.dueExchanges)
+
+		timer := time.NewTimer(s.untilNextRefresh())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RefreshFundingRates forces a refresh of the given exchanges, or all of them
+// when none are given. Exchanges refreshed within the cooldown are skipped.
+func (s *FundingSnapshotStore) RefreshFundingRates(ctx context.Context, exchangeNames ...string) error {
+	if err := s.checkExchanges(exchangeNames...); err != nil {
+		return err
+	}
+	if len(exchangeNames) == 0 {
+		exchangeNames = s.source.ExchangeNames()
+	}
+
+	s.refresh(ctx, func() []string {
+		now := s.now()
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		var names []string
+		for _, name := range exchangeNames {
+			entry, ok := s.entries[name]
+			if !ok || now.Sub(entry.refreshedAt) >= forceRefreshCooldown {
+				names = append(names, name)
+			}
+		}
+		return names
+	})
+	return nil
+}
+
+// checkExchanges fails with ErrExchangeNotFound unless every exchange is configured
+func (s *FundingSnapshotStore) checkExchanges(exchangeNames ...string) error {
+	for _, name := range exchangeNames {
+		if _, exists := s.source.exchanges[name]; !exists {
+			return domain.ErrExchangeNotFound
+		}
+	}
+	return nil
+}
+
+// GetAllFundingRates returns the cached funding rates of all exchanges
+func (s *FundingSnapshotStore) GetAllFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	snapshot, err := s.GetFundingSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Rates, nil
+}
+
+// GetFundingSnapshot returns the cached snapshot with as_of and staleness
+// per exchange. Exchanges never polled yet are fetched first.
+func (s *FundingSnapshotStore) GetFundingSnapshot(ctx context.Context) (domain.FundingSnapshot, error) {
+	s.refresh(ctx, s.missingExchanges(s.source.ExchangeNames()))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshotLocked(), nil
+}
+
+// GetExchangeFundingRates returns the cached funding rates of one exchange.
+// It fails only when no rates have ever been fetched for it.
+func (s *FundingSnapshotStore) GetExchangeFundingRates(ctx context.Context, exchangeName string) ([]domain.FundingRate, error) {
+	if err := s.checkExchanges(exchangeName); err != nil {
+		return nil, err
+	}
+	s.refresh(ctx, s.missingExchanges([]string{exchangeName}))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[exchangeName]
+	if !ok {
+		return nil, ctx.Err()
+	}
+	if entry.asOf.IsZero() {
+		return nil, statusError(exchangeName, entry.status)
+	}
+	return append([]domain.FundingRate(nil), entry.rates...), nil
+}
+
+// GetExchangeInfo reports exchanges as healthy when their cached rates are
+// fresh, without calling them
+func (s *FundingSnapshotStore) GetExchangeInfo(ctx context.Context) map[string]domain.ExchangeInfo {
+	snapshot, _ := s.GetFundingSnapshot(ctx)
+
+	info := make(map[string]domain.ExchangeInfo, len(s.source.exchanges))
+	for name, exchange := range s.source.exchanges {
+		status, ok := snapshot.Exchanges[name]
+		info[name] = domain.ExchangeInfo{
+			Name:    exchange.GetName(),
+			Healthy: ok && status.Status == domain.ExchangeStatusOK && !status.Stale,
+		}
+	}
+	return info
+}
+
+// LogAllFundingRates logs the cached rates without polling the exchanges.
+// Only exchanges refreshed since the last log are logged and passed to the
+// log listeners: rows are stamped with the logging time, so logging stale or
+// unchanged rates again would repeat old rates in the history.
+func (s *FundingSnapshotStore) LogAllFundingRates(ctx context.Context) error {
+	snapshot, err := s.GetFundingSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	snapshot = s.unloggedSnapshot(snapshot)
+	if len(snapshot.Exchanges) == 0 {
+		return nil
+	}
+	return s.source.LogFundingSnapshot(snapshot)
+}
+
+// unloggedSnapshot narrows a snapshot to the fresh exchanges whose rates
+// have not been logged yet and records them as logged
+func (s *FundingSnapshotStore) unloggedSnapshot(snapshot domain.FundingSnapshot) domain.FundingSnapshot {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	unlogged := domain.FundingSnapshot{
+		Timestamp: snapshot.Timestamp,
+		Exchanges: make(map[string]domain.ExchangeStatus),
+	}
+	for name, status := range snapshot.Exchanges {
+		if status.Stale || status.AsOf <= s.loggedAsOf[name] {
+			continue
+		}
+		unlogged.Exchanges[name] = status
+		s.loggedAsOf[name] = status.AsOf
+	}
+	for _, rate := range snapshot.Rates {
+		if _, ok := unlogged.Exchanges[rate.Exchange]; ok {
+			unlogged.Rates = append(unlogged.Rates, rate)
+		}
+	}
+	return unlogged
+}
+
+// GetSymbolLogs retrieves logs for a specific symbol
+func (s *FundingSnapshotStore) GetSymbolLogs(symbol string, date string) ([]byte, error) {
+	return s.source.GetSymbolLogs(symbol, date)
+}
+
+// GetAllLogs retrieves all available logs
+func (s *FundingSnapshotStore) GetAllLogs() ([]domain.LogFile, error) {
+	return s.source.GetAllLogs()
+}
+
+// GetHistoricalFundingRates retrieves the logged funding rates matching a query
+func (s *FundingSnapshotStore) GetHistoricalFundingRates(query domain.HistoryQuery) ([]domain.FundingRateHistory, error) {
+	return s.source.GetHistoricalFundingRates(query)
+}
+
+// refresh polls the exchanges picked by selectExchanges concurrently, updates
+// the cache and notifies listeners. The selection runs again once any refresh
+// in flight has finished, so it sees up to date entries; checking it first
+// keeps cached reads from waiting on a refresh they don't need. Polls cut
+// short by cancelling ctx are not recorded, so the next read retries them.
+func (s *FundingSnapshotStore) refresh(ctx context.Context, selectExchanges func() []string) {
+	if len(selectExchanges()) == 0 {
+		return
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	exchangeNames := selectExchanges()
+	if len(exchangeNames) == 0 {
+		return
+	}
+
+	type refreshResult struct {
+		name   string
+		rates  []domain.FundingRate
+		status domain.ExchangeStatus
+	}
+
+	results := make(chan refreshResult, len(exchangeNames))
+	for _, name := range exchangeNames {
+		go func(name string) {
+			rates, status, err := s.source.FetchExchange(ctx, name)
+			if err != nil {
+				status = domain.ExchangeStatus{Status: domain.ExchangeStatusError, Error: err.Error()}
+			}
+			results <- refreshResult{name: name, rates: rates, status: status}
+		}(name)
+	}
+
+	// Readers keep being served the previous data while the polls run
+	fetched := make([]refreshResult, 0, len(exchangeNames))
+	for range exchangeNames {
+		fetched = append(fetched, <-results)
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	for _, result := range fetched {
+		if result.status.Status != domain.ExchangeStatusOK && errors.Is(ctx.Err(), context.Canceled) {
+			continue
+		}
+
+		entry, ok := s.entries[result.name]
+		if !ok {
+			entry = &exchangeEntry{}
+			s.entries[result.name] = entry
+		}
+
+		entry.refreshedAt = now
+		entry.status = result.status
+		if result.status.Status == domain.ExchangeStatusOK {
+			entry.rates = result.rates
+			entry.asOf = now
+		}
+	}
+	snapshot := s.snapshotLocked()
+	s.mu.Unlock()
+
+	for _, listener := range s.listeners {
+		listener.OnFundingSnapshot(snapshot)
+	}
+}
+
+// snapshotLocked assembles the cached snapshot. Must be called with s.mu held.
+func (s *FundingSnapshotStore) snapshotLocked() domain.FundingSnapshot {
+	now := s.now()
+	snapshot := domain.FundingSnapshot{
+		Rates:     []domain.FundingRate{},
+		Exchanges: make(map[string]domain.ExchangeStatus, len(s.entries)),
+	}
+
+	for _, name := range s.source.ExchangeNames() {
+		entry, ok := s.entries[name]
+		if !ok {
+			continue
+		}
+
+		// A failed refresh keeps serving the last good rates
+		status := entry.status
+		status.Rows = len(entry.rates)
+		status.Stale = status.Status != domain.ExchangeStatusOK ||
+			now.Sub(entry.asOf) > staleAfterIntervals*s.intervalFor(name)
+		if !entry.asOf.IsZero() {
+			status.AsOf = entry.asOf.Unix()
+		}
+
+		snapshot.Exchanges[name] = status
+		snapshot.Rates = append(snapshot.Rates, entry.rates...)
+		if entry.refreshedAt.After(snapshot.Timestamp) {
+			snapshot.Timestamp = entry.refreshedAt
+		}
+	}
+
+	return snapshot
+}
+
+// dueExchanges returns the exchanges whose refresh interval has elapsed
+func (s *FundingSnapshotStore) dueExchanges() []string {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []string
+	for _, name := range s.source.ExchangeNames() {
+		entry, ok := s.entries[name]
+		if !ok || !now.Before(entry.refreshedAt.Add(s.intervalFor(name))) {
+			due = append(due, name)
+		}
+	}
+	return due
+}
+
+// missingExchanges selects the exchanges that have never been refreshed
+func (s *FundingSnapshotStore) missingExchanges(exchangeNames []string) func() []string {
+	return func() []string {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		var missing []string
+		for _, name := range exchangeNames {
+			if _, ok := s.entries[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		return missing
+	}
+}
+
+// untilNextRefresh returns how long until the next exchange falls due
+func (s *FundingSnapshotStore) untilNextRefresh() time.Duration {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	next := s.defaultRefreshInterval()
+	for _, name := range s.source.ExchangeNames() {
+		entry, ok := s.entries[name]
+		if !ok {
+			return 0
+		}
+		if wait := entry.refreshedAt.Add(s.intervalFor(name)).Sub(now); wait < next {
+			next = wait
+		}
+	}
+	if next < 0 {
+		return 0
+	}
+	return next
+}
+
+// intervalFor returns the refresh cadence of an exchange
+func (s *FundingSnapshotStore) intervalFor(name string) time.Duration {
+	if interval, ok := s.intervals[name]; ok && interval > 0 {
+		return interval
+	}
+	return s.defaultRefreshInterval()
+}
+
+func (s *FundingSnapshotStore) defaultRefreshInterval() time.Duration {
+	if s.defaultInterval > 0 {
+		return s.defaultInterval
+	}
+	return DefaultRefreshInterval
+}
@@ -0,0 +1,369 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+)
+
+// CountingExchangeRepository counts how often an exchange is polled
+type CountingExchangeRepository struct {
+	MockExchangeRepository
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *CountingExchangeRepository) GetFundingRates(ctx context.Context) ([]domain.FundingRate, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return c.MockExchangeRepository.GetFundingRates(ctx)
+}
+
+func (c *CountingExchangeRepository) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func newCountingExchange(name string, rate float64) *CountingExchangeRepository {
+	return &CountingExchangeRepository{
+		MockExchangeRepository: MockExchangeRepository{
+			name:  name,
+			rates: []domain.FundingRate{{Symbol: "BTCUSDT", FundingRate: rate}},
+		},
+	}
+}
+
+// newTestStore returns a store whose clock is advanced by the returned func
+func newTestStore(exchanges map[string]domain.ExchangeRepository) (*FundingSnapshotStore, func(time.Duration)) {
+	store := NewFundingSnapshotStore(NewMultiExchangeUseCase(exchanges, &MockLogRepository{}))
+
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	return store, advance
+}
+
+func TestFundingSnapshotStore_ServesReadsFromCache(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	store, _ := newTestStore(map[string]domain.ExchangeRepository{"binance": binance})
+
+	for i := 0; i < 3; i++ {
+		rates, err := store.GetAllFundingRates(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(rates) != 1 || rates[0].Exchange != "binance" {
+			t.Fatalf("Expected the binance rate, got %+v", rates)
+		}
+	}
+	if _, err := store.GetExchangeFundingRates(context.Background(), "binance"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if calls := binance.Calls(); calls != 1 {
+		t.Errorf("Expected a single poll, got %d", calls)
+	}
+
+	if _, err := store.GetExchangeFundingRates(context.Background(), "nonexistent"); err != domain.ErrExchangeNotFound {
+		t.Errorf("Expected ErrExchangeNotFound, got %v", err)
+	}
+}
+
+func TestFundingSnapshotStore_RefreshCadencePerExchange(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	deribit := newCountingExchange("deribit", 0.0002)
+	store, advance := newTestStore(map[string]domain.ExchangeRepository{
+		"binance": binance,
+		"deribit": deribit,
+	})
+	store.SetRefreshIntervals(10*time.Second, map[string]time.Duration{"deribit": 30 * time.Second})
+
+	store.refresh(context.Background(), store.dueExchanges)
+	if binance.Calls() != 1 || deribit.Calls() != 1 {
+		t.Fatalf("Expected both exchanges polled once, got %d and %d", binance.Calls(), deribit.Calls())
+	}
+	if wait := store.untilNextRefresh(); wait != 10*time.Second {
+		t.Errorf("Expected next refresh in 10s, got %v", wait)
+	}
+
+	advance(10 * time.Second)
+	store.refresh(context.Background(), store.dueExchanges)
+	if binance.Calls() != 2 || deribit.Calls() != 1 {
+		t.Errorf("Expected only binance to be due, got %d and %d", binance.Calls(), deribit.Calls())
+	}
+
+	advance(20 * time.Second)
+	store.refresh(context.Background(), store.dueExchanges)
+	if binance.Calls() != 3 || deribit.Calls() != 2 {
+		t.Errorf("Expected both exchanges to be due, got %d and %d", binance.Calls(), deribit.Calls())
+	}
+}
+
+func TestFundingSnapshotStore_KeepsLastRatesOnFailure(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	store, advance := newTestStore(map[string]domain.ExchangeRepository{"binance": binance})
+	store.SetRefreshIntervals(10*time.Second, nil)
+
+	snapshot, _ := store.GetFundingSnapshot(context.Background())
+	fetchedAt := snapshot.Exchanges["binance"].AsOf
+	if status := snapshot.Exchanges["binance"]; status.Status != domain.ExchangeStatusOK || status.Stale || fetchedAt == 0 {
+		t.Fatalf("Expected fresh ok status, got %+v", status)
+	}
+
+	binance.err = errors.New("boom")
+	advance(10 * time.Second)
+	store.refresh(context.Background(), store.dueExchanges)
+
+	snapshot, _ = store.GetFundingSnapshot(context.Background())
+	status := snapshot.Exchanges["binance"]
+	if status.Status != domain.ExchangeStatusError || status.Error != "boom" {
+		t.Errorf("Expected error status, got %+v", status)
+	}
+	if !status.Stale || status.AsOf != fetchedAt || status.Rows != 1 {
+		t.Errorf("Expected stale status keeping the last rates, got %+v", status)
+	}
+	if len(snapshot.Rates) != 1 {
+		t.Errorf("Expected the last good rate to be served, got %d rates", len(snapshot.Rates))
+	}
+	if rates, err := store.GetExchangeFundingRates(context.Background(), "binance"); err != nil || len(rates) != 1 {
+		t.Errorf("Expected the last good rate, got %v, %v", rates, err)
+	}
+}
+
+func TestFundingSnapshotStore_StaleAfterMissedRefreshes(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	store, advance := newTestStore(map[string]domain.ExchangeRepository{"binance": binance})
+	store.SetRefreshIntervals(10*time.Second, nil)
+
+	store.GetFundingSnapshot(context.Background())
+
+	advance(20 * time.Second)
+	if snapshot, _ := store.GetFundingSnapshot(context.Background()); snapshot.Exchanges["binance"].Stale {
+		t.Error("Expected rates to be fresh within two intervals")
+	}
+
+	advance(time.Second)
+	if snapshot, _ := store.GetFundingSnapshot(context.Background()); !snapshot.Exchanges["binance"].Stale {
+		t.Error("Expected rates to be stale after two missed intervals")
+	}
+}
+
+func TestFundingSnapshotStore_NeverFetchedExchangeFails(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	binance.err = errors.New("boom")
+	store, _ := newTestStore(map[string]domain.ExchangeRepository{"binance": binance})
+
+	if _, err := store.GetExchangeFundingRates(context.Background(), "binance"); err == nil {
+		t.Error("Expected an error when no rates were ever fetched")
+	}
+
+	snapshot, _ := store.GetFundingSnapshot(context.Background())
+	if status := snapshot.Exchanges["binance"]; status.AsOf != 0 || !status.Stale {
+		t.Errorf("Expected stale status without as_of, got %+v", status)
+	}
+}
+
+func TestFundingSnapshotStore_ForceRefresh(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	bybit := newCountingExchange("bybit", 0.0002)
+	store, advance := newTestStore(map[string]domain.ExchangeRepository{
+		"binance": binance,
+		"bybit":   bybit,
+	})
+
+	store.GetFundingSnapshot(context.Background())
+
+	// Within the cooldown nothing is re-polled
+	if err := store.RefreshFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if binance.Calls() != 1 || bybit.Calls() != 1 {
+		t.Errorf("Expected forced refresh to respect the cooldown, got %d and %d", binance.Calls(), bybit.Calls())
+	}
+
+	advance(forceRefreshCooldown)
+	if err := store.RefreshFundingRates(context.Background(), "binance"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if binance.Calls() != 2 || bybit.Calls() != 1 {
+		t.Errorf("Expected only binance to be refreshed, got %d and %d", binance.Calls(), bybit.Calls())
+	}
+
+	if err := store.RefreshFundingRates(context.Background(), "nonexistent"); err != domain.ErrExchangeNotFound {
+		t.Errorf("Expected ErrExchangeNotFound, got %v", err)
+	}
+
+	// An unknown exchange fails the request before anything is refreshed
+	advance(forceRefreshCooldown)
+	if err := store.RefreshFundingRates(context.Background(), "binance", "nonexistent"); err != domain.ErrExchangeNotFound {
+		t.Errorf("Expected ErrExchangeNotFound, got %v", err)
+	}
+	if binance.Calls() != 2 {
+		t.Errorf("Expected binance not to be refreshed, got %d calls", binance.Calls())
+	}
+}
+
+func TestFundingSnapshotStore_RunNotifiesListeners(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	store := NewFundingSnapshotStore(NewMultiExchangeUseCase(
+		map[string]domain.ExchangeRepository{"binance": binance},
+		&MockLogRepository{},
+	))
+	store.SetRefreshIntervals(20*time.Millisecond, nil)
+
+	listener := &RecordingListener{}
+	store.AddListener(listener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(110 * time.Millisecond)
+	cancel()
+	<-done
+
+	if calls := binance.Calls(); calls < 3 {
+		t.Errorf("Expected the scheduler to poll repeatedly, got %d polls", calls)
+	}
+	if len(listener.snapshots) != binance.Calls() {
+		t.Errorf("Expected a notification per refresh, got %d for %d polls", len(listener.snapshots), binance.Calls())
+	}
+}
+
+func TestFundingSnapshotStore_LogAllFundingRatesNotifiesLogListeners(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	store, advance := newTestStore(map[string]domain.ExchangeRepository{"binance": binance})
+	store.SetRefreshIntervals(10*time.Second, nil)
+
+	listener := &RecordingListener{}
+	store.source.AddListener(listener)
+
+	store.GetFundingSnapshot(context.Background())
+	if len(listener.snapshots) != 0 {
+		t.Fatalf("Expected refreshes not to reach log listeners, got %d", len(listener.snapshots))
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := store.LogAllFundingRates(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// Logging reuses the cached rates instead of polling again, and rates
+	// already logged are not logged twice
+	if len(listener.snapshots) != 1 || binance.Calls() != 1 {
+		t.Fatalf("Expected 1 logged snapshot from 1 poll, got %d from %d", len(listener.snapshots), binance.Calls())
+	}
+	if rates := listener.snapshots[0].Rates; len(rates) != 1 || rates[0].FundingRate != 0.0001 {
+		t.Errorf("Unexpected logged rates: %+v", rates)
+	}
+
+	// Rates fetched by a later refresh are logged again
+	advance(10 * time.Second)
+	store.refresh(context.Background(), store.dueExchanges)
+	if err := store.LogAllFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(listener.snapshots) != 2 || binance.Calls() != 2 {
+		t.Errorf("Expected 2 logged snapshots from 2 polls, got %d from %d", len(listener.snapshots), binance.Calls())
+	}
+}
+
+func TestFundingSnapshotStore_LogAllFundingRatesSkipsStaleExchanges(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	okx := newCountingExchange("okx", 0.0002)
+	store, advance := newTestStore(map[string]domain.ExchangeRepository{"binance": binance, "okx": okx})
+	store.SetRefreshIntervals(10*time.Second, nil)
+
+	listener := &RecordingListener{}
+	store.source.AddListener(listener)
+	store.GetFundingSnapshot(context.Background())
+	if err := store.LogAllFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// okx fails from now on and keeps serving its last rates as stale
+	okx.err = errors.New("boom")
+	advance(10 * time.Second)
+	store.refresh(context.Background(), store.dueExchanges)
+	snapshot, _ := store.GetFundingSnapshot(context.Background())
+	if !snapshot.Exchanges["okx"].Stale {
+		t.Fatalf("Expected okx to be stale, got %+v", snapshot.Exchanges["okx"])
+	}
+	if err := store.LogAllFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(listener.snapshots) != 2 {
+		t.Fatalf("Expected 2 logged snapshots, got %d", len(listener.snapshots))
+	}
+	logged := listener.snapshots[1]
+	if len(logged.Rates) != 1 || logged.Rates[0].Exchange != "binance" {
+		t.Errorf("Expected only the binance rate to be logged, got %+v", logged.Rates)
+	}
+	if _, ok := logged.Exchanges["okx"]; ok {
+		t.Errorf("Expected the stale okx not to be logged, got %+v", logged.Exchanges)
+	}
+
+	// Nothing is logged or notified when no exchange was refreshed
+	if err := store.LogAllFundingRates(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(listener.snapshots) != 2 {
+		t.Errorf("Expected no further logged snapshot, got %d", len(listener.snapshots))
+	}
+}
+
+func TestFundingSnapshotStore_CancelledReadIsNotRecorded(t *testing.T) {
+	exchange := &BlockingExchangeRepository{
+		MockExchangeRepository: MockExchangeRepository{name: "binance"},
+		cancelled:              make(chan struct{}),
+	}
+	store, _ := newTestStore(map[string]domain.ExchangeRepository{"binance": exchange})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	snapshot, _ := store.GetFundingSnapshot(ctx)
+	if _, ok := snapshot.Exchanges["binance"]; ok {
+		t.Errorf("Expected the cancelled poll not to be recorded, got %+v", snapshot.Exchanges["binance"])
+	}
+	if missing := store.missingExchanges([]string{"binance"})(); len(missing) != 1 {
+		t.Error("Expected the next read to poll binance again")
+	}
+}
+
+func TestFundingSnapshotStore_ExchangeInfoFromCache(t *testing.T) {
+	binance := newCountingExchange("binance", 0.0001)
+	okx := newCountingExchange("okx", 0.0002)
+	okx.err = errors.New("boom")
+	store, _ := newTestStore(map[string]domain.ExchangeRepository{"binance": binance, "okx": okx})
+
+	info := store.GetExchangeInfo(context.Background())
+	info = store.GetExchangeInfo(context.Background())
+
+	if !info["binance"].Healthy || info["okx"].Healthy || info["okx"].Name != "okx" {
+		t.Errorf("Expected only binance to be healthy, got %+v", info)
+	}
+	if binance.Calls() != 1 || okx.Calls() != 1 {
+		t.Errorf("Expected one poll per exchange, got %d and %d", binance.Calls(), okx.Calls())
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -51,19 +54,125 @@ func main() {
 	}
 
 	// Create log repository
-	logRepo := factory.CreateLogRepository(logDir, logger)
+	logRepo, err := factory.CreateLogRepository(config, logDir, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize log repository: %v", err)
+	}
+	if closer, ok := logRepo.(io.Closer); ok {
+		defer closer.Close()
+	}
 
 	// Create use cases
 	multiExchangeUseCase := factory.CreateUseCases(exchanges, logRepo)
+	multiExchangeUseCase.SetSymbolNormalizer(factory.CreateSymbolMapper(config))
+	multiExchangeUseCase.SetTimeouts(infrastructure.ExchangeTimeouts(config))
+
+	// Post the rates of every logging cycle to the webhook targets
+	var webhookNotifier *infrastructure.WebhookNotifier
+	if len(config.Webhooks.Targets) > 0 {
+		webhookNotifier, err = factory.CreateWebhookNotifier(config, logDir)
+		if err != nil {
+			logger.Fatalf("Failed to initialize webhooks: %v", err)
+		}
+		multiExchangeUseCase.AddListener(webhookNotifier)
+	}
+
+	// Serve reads from a snapshot store refreshed by a single scheduler
+	snapshotStore := usecase.NewFundingSnapshotStore(multiExchangeUseCase)
+	snapshotStore.SetRefreshIntervals(infrastructure.RefreshIntervals(config))
+
+	// Derive exchange health from the outcome of recent polls
+	healthTracker := usecase.NewHealthTracker(multiExchangeUseCase.ExchangeNames(), config.Health)
+	multiExchangeUseCase.AddPollObserver(healthTracker)
+
+	// Export poll, request and funding rate metrics to Prometheus
+	var metrics *infrastructure.Metrics
+	if config.Metrics.Enabled {
+		metrics = infrastructure.NewMetrics(config.Metrics)
+		multiExchangeUseCase.AddPollObserver(metrics)
+		snapshotStore.AddListener(metrics)
+	}
+
+	arbitrageUseCase := usecase.NewArbitrageUseCase(snapshotStore)
+
+	// Post digests of the most extreme rates to the chat channels
+	digestScheduler := usecase.NewDigestScheduler(snapshotStore, logger)
+	for _, channel := range config.Digests.Channels {
+		notifier, err := factory.CreateDigestNotifier(channel)
+		if err != nil {
+			logger.Fatalf("Failed to initialize digests: %v", err)
+		}
+		if err := digestScheduler.AddChannel(channel, notifier); err != nil {
+			logger.Fatalf("Failed to initialize digests: %v", err)
+		}
+	}
 
 	// Create HTTP handlers
-	handler := delivery.NewFundingHandler(multiExchangeUseCase)
+	handler := delivery.NewFundingHandler(snapshotStore)
+	arbitrageHandler := delivery.NewArbitrageHandler(arbitrageUseCase)
+	healthHandler := delivery.NewHealthHandler(healthTracker)
+	exchangeHandler := delivery.NewExchangeHandler(factory.DescribeExchanges(config))
+	settlementHandler := delivery.NewSettlementHandler(usecase.NewSettlementUseCase(exchanges))
+
+	// Backfill settled funding history into the logs on request, resolving
+	// symbols among the cached rates
+	backfillUseCase := usecase.NewBackfillUseCase(exchanges, snapshotStore, logRepo, logger)
+	backfillHandler := delivery.NewBackfillHandler(backfillUseCase)
+
+	// Stream every refresh to WebSocket subscribers
+	hub := delivery.NewFundingHub(logger)
+	snapshotStore.AddListener(hub)
+
+	// Evaluate the alert rules after every refresh
+	alertEngine, err := usecase.NewAlertEngine(config.Alerts, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize alerts: %v", err)
+	}
+	alertEngine.AddNotifier(infrastructure.NewLogAlertNotifier(logger))
+	snapshotStore.AddListener(alertEngine)
+	alertHandler := delivery.NewAlertHandler(alertEngine)
+
+	// Background work and in-flight requests stop, aborting their exchange
+	// calls, once backgroundCtx is cancelled on shutdown
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	var background sync.WaitGroup
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		snapshotStore.Run(backgroundCtx)
+	}()
 
 	// Start background logging
-	go startBackgroundLogging(multiExchangeUseCase, logger, config)
+	go func() {
+		defer background.Done()
+		startBackgroundLogging(backgroundCtx, snapshotStore, logger, config)
+	}()
+
+	go func() {
+		defer background.Done()
+		backfillUseCase.Run(backgroundCtx)
+	}()
+
+	if len(config.Digests.Channels) > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			digestScheduler.Run(backgroundCtx)
+		}()
+	}
+
+	if webhookNotifier != nil {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			webhookNotifier.Run(backgroundCtx)
+		}()
+	}
 
 	// Start the server
-	server := startServer(handler, config, logger)
+	server := startServer(backgroundCtx, handler, arbitrageHandler, alertHandler, healthHandler, exchangeHandler, settlementHandler, backfillHandler, hub, metrics, config, logger)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -74,27 +183,46 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	stopBackground()
+	hub.Close()
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
+	background.Wait()
 
 	logger.Info("Server exited")
 }
 
-func startServer(handler *delivery.FundingHandler, config *domain.Config, logger *logrus.Logger) *http.Server {
+func startServer(ctx context.Context, handler *delivery.FundingHandler, arbitrageHandler *delivery.ArbitrageHandler, alertHandler *delivery.AlertHandler, healthHandler *delivery.HealthHandler, exchangeHandler *delivery.ExchangeHandler, settlementHandler *delivery.SettlementHandler, backfillHandler *delivery.BackfillHandler, hub *delivery.FundingHub, metrics *infrastructure.Metrics, config *domain.Config, logger *logrus.Logger) *http.Server {
 	router := mux.NewRouter()
 
 	// API routes
 	router.HandleFunc("/api/funding", handler.GetFundingRates).Methods("GET")
 	router.HandleFunc("/api/funding-top", handler.GetFundingRatesTop).Methods("GET")
 	router.HandleFunc("/api/funding/{exchange}", handler.GetExchangeFunding).Methods("GET")
-	router.HandleFunc("/api/health", handler.HealthCheck).Methods("GET")
+	router.HandleFunc("/api/funding/{exchange}/{symbol}/settlements", settlementHandler.GetSettlements).Methods("GET")
+	router.HandleFunc("/api/arbitrage", arbitrageHandler.GetArbitrage).Methods("GET")
+	router.HandleFunc("/api/alerts", alertHandler.GetAlerts).Methods("GET")
+	router.HandleFunc("/api/exchanges", exchangeHandler.GetExchanges).Methods("GET")
+	router.HandleFunc("/api/backfill", backfillHandler.StartBackfill).Methods("POST")
+	router.HandleFunc("/api/backfill", backfillHandler.GetBackfill).Methods("GET")
+	router.HandleFunc("/api/health", healthHandler.Health).Methods("GET")
 	router.HandleFunc("/api/logs/{symbol}", handler.GetSymbolLogs).Methods("GET")
 	router.HandleFunc("/api/logs", handler.GetAllLogs).Methods("GET")
 	router.HandleFunc("/api/logs/{symbol}/history", handler.GetHistoricalFundingRates).Methods("GET")
 
+	// Kubernetes liveness and readiness probes
+	router.HandleFunc("/healthz", healthHandler.Live).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Ready).Methods("GET")
+
 	// WebSocket endpoint for real-time updates
-	router.HandleFunc("/ws/funding", handler.FundingWebSocket)
+	router.HandleFunc("/ws/funding", hub.ServeWS)
+
+	// Prometheus metrics, with request latencies recorded per route
+	if metrics != nil {
+		router.Use(metrics.Middleware)
+		router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	}
 
 	// Static files for web interface
 	router.PathPrefix("/").Handler(http.FileServer(http.Dir("static")))
@@ -102,6 +230,8 @@ func startServer(handler *delivery.FundingHandler, config *domain.Config, logger
 	server := &http.Server{
 		Addr:    ":" + config.Port,
 		Handler: router,
+		// Request contexts derive from ctx so shutdown aborts their exchange calls
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	go func() {
@@ -114,7 +244,7 @@ func startServer(handler *delivery.FundingHandler, config *domain.Config, logger
 	return server
 }
 
-func startBackgroundLogging(useCase *usecase.MultiExchangeUseCase, logger *logrus.Logger, config *domain.Config) {
+func startBackgroundLogging(ctx context.Context, useCase domain.MultiExchangeUseCaseInterface, logger *logrus.Logger, config *domain.Config) {
 	interval := time.Duration(config.LoggingInterval) * time.Minute
 	if interval == 0 {
 		interval = 1 * time.Minute // default to 1 minute
@@ -127,8 +257,11 @@ func startBackgroundLogging(useCase *usecase.MultiExchangeUseCase, logger *logru
 
 	for {
 		select {
+		case <-ctx.Done():
+			logger.Info("Stopping background logging")
+			return
 		case <-ticker.C:
-			if err := useCase.LogAllFundingRates(); err != nil {
+			if err := useCase.LogAllFundingRates(ctx); err != nil {
 				logger.Errorf("Failed to log funding rates: %v", err)
 			}
 		}
@@ -0,0 +1,46 @@
+// Command migrate_logs imports the text logs written by the file logger into
+// the SQLite log storage. Re-running it over the same files is harmless.
+package main
+
+import (
+	"flag"
+	"log"
+	"path/filepath"
+
+	"fundingmonitor/internal/infrastructure"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	// Default to the paths of config.yaml
+	logDir, dbPath := "funding_logs", ""
+	if config, err := infrastructure.LoadConfig(); err == nil {
+		if config.LogDirectory != "" {
+			logDir = config.LogDirectory
+		}
+		dbPath = config.SQLitePath
+	}
+
+	flag.StringVar(&logDir, "logs", logDir, "directory of <symbol>/<DD-MM-YYYY>.log files to import")
+	flag.StringVar(&dbPath, "db", dbPath, "SQLite database to import into (default <logs>/funding.db)")
+	flag.Parse()
+
+	if dbPath == "" {
+		dbPath = filepath.Join(logDir, "funding.db")
+	}
+
+	logger := logrus.New()
+	sqliteLogger, err := infrastructure.NewSQLiteLogger(dbPath, logger)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer sqliteLogger.Close()
+
+	imported, err := sqliteLogger.ImportFileLogs(logDir)
+	if err != nil {
+		log.Fatalf("Failed to import logs: %v", err)
+	}
+
+	log.Printf("Imported %d funding rates from %s into %s", imported, logDir, dbPath)
+}
@@ -0,0 +1,184 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+	"fundingmonitor/internal/infrastructure"
+	"fundingmonitor/internal/usecase"
+
+	"github.com/sirupsen/logrus"
+)
+
+// historyServer is a fake exchange settling every interval since listed,
+// counting the funding history pages it serves
+type historyServer struct {
+	listed   time.Time
+	interval time.Duration
+
+	mu    sync.Mutex
+	pages int
+}
+
+// settlements returns the settlement times within [from, to) ascending
+func (h *historyServer) settlements(from, to time.Time) []time.Time {
+	h.mu.Lock()
+	h.pages++
+	h.mu.Unlock()
+
+	if from.Before(h.listed) {
+		from = h.listed
+	}
+	var times []time.Time
+	for at := from.Truncate(h.interval); at.Before(to); at = at.Add(h.interval) {
+		if !at.Before(from) {
+			times = append(times, at)
+		}
+	}
+	return times
+}
+
+// newBinanceHistoryServer serves BTCUSDT settling every 8h, its history
+// oldest first from startTime
+func newBinanceHistoryServer(t *testing.T, history *historyServer) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","markPrice":"42000","indexPrice":"42000","lastFundingRate":"0.0001","nextFundingTime":1704096000000}]`))
+		case "/fapi/v1/fundingRate":
+			startTime, _ := strconv.ParseInt(query.Get("startTime"), 10, 64)
+			endTime, _ := strconv.ParseInt(query.Get("endTime"), 10, 64)
+			limit, _ := strconv.Atoi(query.Get("limit"))
+
+			var entries []string
+			for _, at := range history.settlements(time.UnixMilli(startTime), time.UnixMilli(endTime+1)) {
+				if len(entries) == limit {
+					break
+				}
+				entries = append(entries, fmt.Sprintf(`{"symbol":"BTCUSDT","fundingRate":"0.0001","fundingTime":%d}`, at.UnixMilli()))
+			}
+			w.Write([]byte("[" + strings.Join(entries, ",") + "]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newOKXHistoryServer serves BTC-USDT-SWAP settling hourly, its history
+// newest first before the after cursor
+func newOKXHistoryServer(t *testing.T, history *historyServer) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/api/v5/public/funding-rate":
+			w.Write([]byte(`{"code":"0","msg":"","data":[{"instId":"BTC-USDT-SWAP","instType":"SWAP","fundingRate":"0.0002","fundingTime":"1704096000000","nextFundingTime":"1704099600000","settFundingRate":"0.0002","settState":"settled"}]}`))
+		case "/api/v5/public/funding-rate-history":
+			after, _ := strconv.ParseInt(query.Get("after"), 10, 64)
+			limit, _ := strconv.Atoi(query.Get("limit"))
+
+			times := history.settlements(history.listed, time.UnixMilli(after))
+			var entries []string
+			for i := len(times) - 1; i >= 0 && len(entries) < limit; i-- {
+				entries = append(entries, fmt.Sprintf(`{"instId":"BTC-USDT-SWAP","fundingRate":"0.0002","realizedRate":"0.0002","fundingTime":"%d"}`, times[i].UnixMilli()))
+			}
+			w.Write([]byte(`{"code":"0","msg":"","data":[` + strings.Join(entries, ",") + `]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIntegration_Backfill(t *testing.T) {
+	tempDir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	listed := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	binanceHistory := &historyServer{listed: listed, interval: 8 * time.Hour}
+	okxHistory := &historyServer{listed: listed, interval: time.Hour}
+	binanceServer := newBinanceHistoryServer(t, binanceHistory)
+	okxServer := newOKXHistoryServer(t, okxHistory)
+
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": infrastructure.NewBinanceClient(domain.ExchangeConfig{BaseURL: binanceServer.URL}, http.DefaultClient, logger),
+		"okx":     infrastructure.NewOKXClient(domain.ExchangeConfig{BaseURL: okxServer.URL}, http.DefaultClient, logger),
+	}
+	logRepo := infrastructure.NewFileLogger(tempDir, logger)
+	fundingUseCase := usecase.NewMultiExchangeUseCase(exchanges, logRepo)
+	fundingUseCase.SetSymbolNormalizer(infrastructure.NewSymbolMapper(nil))
+	backfillUseCase := usecase.NewBackfillUseCase(exchanges, fundingUseCase, logRepo, logger)
+
+	// 10 days are read in a 7 and a 3 day window, the hourly OKX ones taking
+	// two and one pages of 100
+	request := domain.BackfillRequest{
+		Symbols: []string{"BTCUSDT"},
+		From:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC),
+	}
+	var reports []domain.BackfillProgress
+	progress, err := backfillUseCase.Backfill(context.Background(), request, func(progress domain.BackfillProgress) {
+		reports = append(reports, progress)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if progress.Status != domain.BackfillStatusDone || progress.Settlements != 10*3+10*24 {
+		t.Fatalf("Expected %d settlements stored, got %s with %d", 10*3+10*24, progress.Status, progress.Settlements)
+	}
+	// The plan, every window and the outcome are reported
+	if len(reports) != 6 {
+		t.Fatalf("Expected 6 progress reports, got %d", len(reports))
+	}
+	if completed, total := reports[0].Windows(); completed != 0 || total != 4 {
+		t.Errorf("Expected 4 windows planned, got %d of %d done", completed, total)
+	}
+
+	okxPages := okxHistory.pages
+	if okxPages != 3 {
+		t.Errorf("Expected 3 OKX history pages, got %d", okxPages)
+	}
+
+	history, err := logRepo.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT", From: request.From, To: request.To})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	counts := map[string]int{}
+	for _, point := range history {
+		counts[point.Exchange]++
+	}
+	if counts["binance"] != 30 || counts["okx"] != 240 {
+		t.Errorf("Expected 30 binance and 240 okx settlements in the log, got %v", counts)
+	}
+
+	// Backfilling again leaves the log as it was
+	before, _ := os.ReadFile(filepath.Join(tempDir, "BTCUSDT", time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local).Format("02-01-2006")+".log"))
+	if _, err := backfillUseCase.Backfill(context.Background(), request, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	after, _ := os.ReadFile(filepath.Join(tempDir, "BTCUSDT", time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local).Format("02-01-2006")+".log"))
+	if len(before) == 0 || string(before) != string(after) {
+		t.Errorf("Expected the log unchanged by a second backfill, got %d then %d bytes", len(before), len(after))
+	}
+	history, _ = logRepo.GetHistoricalFundingRates(domain.HistoryQuery{Symbol: "BTCUSDT"})
+	if len(history) != 270 {
+		t.Errorf("Expected the same 270 settlements, got %d", len(history))
+	}
+	if okxHistory.pages != 2*okxPages {
+		t.Errorf("Expected the OKX history read again, got %d pages", okxHistory.pages)
+	}
+}
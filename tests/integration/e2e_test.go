@@ -76,7 +76,7 @@ func TestE2E_RealApplication(t *testing.T) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				if err := useCase.LogAllFundingRates(); err != nil {
+				if err := useCase.LogAllFundingRates(ctx); err != nil {
 					logger.Errorf("Failed to log funding rates: %v", err)
 				}
 			}
@@ -97,7 +97,7 @@ func TestE2E_RealApplication(t *testing.T) {
 	}
 
 	// Test that we can get funding rates from use case
-	rates, err := useCase.GetAllFundingRates()
+	rates, err := useCase.GetAllFundingRates(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get funding rates: %v", err)
 	}
@@ -107,7 +107,7 @@ func TestE2E_RealApplication(t *testing.T) {
 	t.Logf("Retrieved %d funding rates from exchanges", len(rates))
 
 	// Test exchange info
-	exchangeInfo := useCase.GetExchangeInfo()
+	exchangeInfo := useCase.GetExchangeInfo(ctx)
 	if len(exchangeInfo) != 2 {
 		t.Errorf("Expected 2 exchanges, got %d", len(exchangeInfo))
 	}
@@ -0,0 +1,50 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fundingmonitor/internal/domain"
+	"fundingmonitor/internal/infrastructure"
+	"fundingmonitor/internal/usecase"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestIntegration_SlowSettlementHistory(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// The funding history answers only once the request is abandoned
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","markPrice":"42000","indexPrice":"41990","lastFundingRate":"0.0001","nextFundingTime":1704096000000,"time":1704090000000}]`))
+		case "/fapi/v1/fundingRate":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	exchanges := map[string]domain.ExchangeRepository{
+		"binance": infrastructure.NewBinanceClient(domain.ExchangeConfig{BaseURL: server.URL}, http.DefaultClient, logger),
+	}
+	fundingUseCase := usecase.NewMultiExchangeUseCase(exchanges, infrastructure.NewFileLogger(t.TempDir(), logger))
+	fundingUseCase.SetTimeouts(500*time.Millisecond, nil)
+
+	rates, status, err := fundingUseCase.FetchExchange(context.Background(), "binance")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.Status != domain.ExchangeStatusOK || len(rates) != 1 {
+		t.Fatalf("Expected the polled rate with status ok, got %d rates and %+v", len(rates), status)
+	}
+	if rates[0].FundingRate != 0.0001 || rates[0].LastFundingTime != nil {
+		t.Errorf("Expected the running rate without a settlement, got %+v", rates[0])
+	}
+}